@@ -18,12 +18,15 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -38,6 +41,16 @@ import (
 )
 
 var analyzeFlags = []cli.Flag{
+	cli.DurationFlag{
+		Name:  "slo",
+		Value: 0,
+		Usage: "统计耗时超过该延迟的请求数, 例如 '200ms'. 基准测试期间会在 --live.interval 的输出中显示, 并在分析中汇总.",
+	},
+	cli.StringFlag{
+		Name:  "sla",
+		Value: "",
+		Usage: "按对象大小类检查请求延迟, 未通过时以错误退出. 例如 '<=1MiB:p99<50ms,>1MiB:p99<500ms'. 指标可以是 avg, p50, p90, p99, max.",
+	},
 	cli.StringFlag{
 		Name:  "analyze.dur",
 		Value: "",
@@ -48,6 +61,39 @@ var analyzeFlags = []cli.Flag{
 		Value: "",
 		Usage: "将聚合数据输出到文件",
 	},
+	cli.StringFlag{
+		Name:  "analyze.grafana",
+		Value: "",
+		Usage: "将每种请求操作和每个主机的吞吐量时间序列以 Grafana JSON 数据源格式写入该文件. 分段按时钟时间对齐.",
+	},
+	cli.StringFlag{
+		Name:  "analyze.parquet",
+		Value: "",
+		Usage: "将每个请求操作作为一行以 Parquet 格式写入该文件. 可以与现有的基准测试数据一起使用.",
+	},
+	cli.BoolFlag{
+		Name:  "analyze.markdown",
+		Usage: "以 GitHub Markdown 表格输出每种请求操作的主要结果 (每秒对象数, 吞吐量, p50/p99 和错误数), 便于粘贴到 issue 或 PR 中.",
+	},
+	cli.StringFlag{
+		Name:  "analyze.normalize",
+		Value: "",
+		Usage: "同时输出按单位平均的吞吐量. 可以是 'host' (除以主机数) 或 'drive' (除以通过管理 API 获取的磁盘数).",
+	},
+	cli.IntFlag{
+		Name:  "analyze.drives",
+		Value: 0,
+		Usage: "analyze.normalize=drive 使用的磁盘总数. 0 表示通过管理 API 获取.",
+	},
+	cli.IntFlag{
+		Name:  "analyze.max-segments",
+		Value: 0,
+		Usage: "将分段输出 (analyze.out 和 JSON) 合并相邻分段, 最多输出该数量的分段, 并保留最小/最大值. 0 表示不限制.",
+	},
+	cli.BoolFlag{
+		Name:  "analyze.percentiles",
+		Usage: "显示分段吞吐量的第 1, 5, 95 和 99 百分位, 比最慢的分段更能反映吞吐量的稳定性.",
+	},
 	cli.StringFlag{
 		Name:  "analyze.op",
 		Value: "",
@@ -58,6 +104,16 @@ var analyzeFlags = []cli.Flag{
 		Value: "",
 		Usage: "仅此主机 host 中的输出.",
 	},
+	cli.StringFlag{
+		Name:  "analyze.min-size",
+		Value: "",
+		Usage: "仅分析对象大小不小于该值的请求操作. 例如 '1MiB'.",
+	},
+	cli.StringFlag{
+		Name:  "analyze.max-size",
+		Value: "",
+		Usage: "仅分析对象大小不大于该值的请求操作. 例如 '10MiB'.",
+	},
 	cli.DurationFlag{
 		Name:   "analyze.skip",
 		Usage:  "分析数据时要跳过的附加持续时间.",
@@ -80,6 +136,42 @@ var analyzeFlags = []cli.Flag{
 		Name:  "analyze.v",
 		Usage: "显示其他分析数据.",
 	},
+	cli.BoolFlag{
+		Name:  "analyze.include-prepare",
+		Usage: "在分析中包含准备阶段的请求操作.",
+	},
+	cli.BoolFlag{
+		Name:  "analyze.wallclock",
+		Usage: "将时间段对齐到整点时间 (例如整秒), 使合并的多个客户端的时间段一致.",
+	},
+	cli.DurationFlag{
+		Name:  "analyze.slide",
+		Value: 0,
+		Usage: "每隔该时间开始一个新的时间段, 使 --analyze.out 和 --analyze.grafana 输出的时间段重叠, 曲线更平滑. 例如 '250ms'.",
+	},
+	cli.BoolFlag{
+		Name:  "analyze.steady",
+		Usage: "自动检测吞吐量稳定的时间范围, 排除启动和结束阶段, 只分析该时间范围内的请求操作.",
+	},
+	cli.Float64Flag{
+		Name:  "analyze.steady.pct",
+		Value: 7.5,
+		Usage: "使用 --analyze.steady 时, 时间段的速度必须在中位数速度的该百分比内才被视为稳定.",
+	},
+	cli.BoolFlag{
+		Name:  "analyze.ttfb.hist",
+		Usage: "显示首个字节时间 (TTFB) 的直方图.",
+	},
+	cli.StringFlag{
+		Name:  "baseline",
+		Value: "",
+		Usage: "与该基准测试数据文件进行比较.",
+	},
+	cli.Float64Flag{
+		Name:  "baseline.regress",
+		Value: 0,
+		Usage: "当中位数吞吐量比基准数据下降超过该百分比时, 以错误退出. 0 表示不检查.",
+	},
 	cli.StringFlag{
 		Name:   serverFlagName,
 		Usage:  "当运行基准测试时，在该 ip:port 上打开一个 web 服务，以让它持续运行.",
@@ -136,18 +228,18 @@ func mainAnalyze(ctx *cli.Context) error {
 			defer f.Close()
 			input = f
 		}
-		err := zstdDec.Reset(input)
+		input, err := benchDataReader(zstdDec, input)
 		fatalIf(probe.NewError(err), "无法读取输入")
-		ops, err := bench.OperationsFromCSV(zstdDec, true, ctx.Int("analyze.offset"), ctx.Int("analyze.limit"), log)
+		ops, err := bench.OperationsFromCSV(input, true, ctx.Int("analyze.offset"), ctx.Int("analyze.limit"), log)
 		fatalIf(probe.NewError(err), "无法解析输入")
 
 		printAnalysis(ctx, ops)
-		monitor.OperationsReady(ops, strings.TrimSuffix(filepath.Base(arg), ".csv.zst"), commandLine(ctx))
+		monitor.OperationsReady(ops, strings.TrimSuffix(strings.TrimSuffix(filepath.Base(arg), ".zst"), ".csv"), commandLine(ctx))
 	}
 	return nil
 }
 
-func printMixedOpAnalysis(ctx *cli.Context, aggr aggregate.Aggregated, details bool) {
+func printMixedOpAnalysis(ctx *cli.Context, aggr aggregate.Aggregated, norm normalization, details bool) {
 	console.SetColor("Print", color.New(color.FgWhite))
 	console.Printf("混合的请求操作.")
 
@@ -177,6 +269,9 @@ func printMixedOpAnalysis(ctx *cli.Context, aggr aggregate.Aggregated, details b
 		if ops.Errors > 0 {
 			console.SetColor("Print", color.New(color.FgHiRed))
 			console.Println("错误:", ops.Errors)
+			if ops.TimedOut > 0 {
+				console.Printf("其中超时 (%v): %d\n", time.Duration(ops.TimeoutMillis)*time.Millisecond, ops.TimedOut)
+			}
 			if details {
 				for _, err := range ops.FirstErrors {
 					console.Println(err)
@@ -184,9 +279,14 @@ func printMixedOpAnalysis(ctx *cli.Context, aggr aggregate.Aggregated, details b
 			}
 			console.SetColor("Print", color.New(color.FgWhite))
 		}
+		if ops.Canceled > 0 {
+			console.Println("停止时取消的请求:", ops.Canceled)
+		}
 		eps := ops.ThroughputByHost
 		if len(eps) == 1 || !details {
 			console.Println("* 吞吐量:", ops.Throughput.StringDetails(details))
+			norm.print(ops.Throughput, details)
+			printRequestRate(ops)
 		}
 
 		if len(eps) > 1 && details {
@@ -205,6 +305,7 @@ func printMixedOpAnalysis(ctx *cli.Context, aggr aggregate.Aggregated, details b
 		}
 
 		if details {
+			printSlowestRequests(ops)
 			printRequestAnalysis(ctx, ops, details)
 			console.SetColor("Print", color.New(color.FgWhite))
 		}
@@ -213,6 +314,7 @@ func printMixedOpAnalysis(ctx *cli.Context, aggr aggregate.Aggregated, details b
 	dur := time.Duration(aggr.MixedServerStats.MeasureDurationMillis) * time.Millisecond
 	dur = dur.Round(time.Second)
 	console.Printf("\n结果总计: %v 持续时间 %v.\n", aggr.MixedServerStats.StringDetails(details), dur)
+	norm.print(*aggr.MixedServerStats, details)
 	if aggr.MixedServerStats.Errors > 0 {
 		console.SetColor("Print", color.New(color.FgHiRed))
 		console.Print("总错误数:", aggr.MixedServerStats.Errors, ".\n")
@@ -226,6 +328,16 @@ func printMixedOpAnalysis(ctx *cli.Context, aggr aggregate.Aggregated, details b
 }
 
 func printAnalysis(ctx *cli.Context, o bench.Operations) {
+	o = filterPrepare(ctx, o)
+	// Check SLA when everything else has been output.
+	var aggr aggregate.Aggregated
+	defer func() { checkSLA(ctx, aggr) }()
+	defer printBaseline(ctx, o)
+	if slide := ctx.Duration("analyze.slide"); slide > 0 {
+		if dur := analysisDur(ctx, o.Duration()); slide >= dur {
+			console.Errorf("警告: analyze.slide (%v) 不小于分段时长 (%v), 将不会使用滑动分段. 请使用 --analyze.dur 指定更长的分段时长\n", slide, dur)
+		}
+	}
 	details := ctx.Bool("analyze.v")
 	var wrSegs io.Writer
 	prefiltered := false
@@ -246,7 +358,7 @@ func printAnalysis(ctx *cli.Context, o bench.Operations) {
 			hosts := o.Endpoints()
 			console.Println("找不到主机 host，有效的主机为:")
 			for _, h := range hosts {
-				console.Println("\t* %s", h)
+				console.Printf("\t* %s\n", h)
 			}
 			return
 		}
@@ -258,16 +370,31 @@ func printAnalysis(ctx *cli.Context, o bench.Operations) {
 		prefiltered = prefiltered || o.IsMixed()
 		o = o.FilterByOp(wantOp)
 	}
+	if min, max := analysisSizeRange(ctx); min > 0 || max > 0 {
+		o = o.FilterBySize(min, max)
+		if len(o) == 0 {
+			console.Println("没有对象大小在指定范围内的请求操作")
+			return
+		}
+		prefiltered = true
+	}
+	if ctx.Bool("analyze.steady") {
+		o = filterSteady(ctx, o)
+		prefiltered = true
+	}
 	durFn := func(total time.Duration) time.Duration {
 		if total <= 0 {
 			return 0
 		}
 		return analysisDur(ctx, total)
 	}
-	aggr := aggregate.Aggregate(o, aggregate.Options{
+	aggr = aggregate.Aggregate(o, aggregate.Options{
 		Prefiltered: prefiltered,
 		DurFunc:     durFn,
 		SkipDur:     ctx.Duration("analyze.skip"),
+
+		WallClockAligned: ctx.Bool("analyze.wallclock"),
+		SLO:              ctx.Duration("slo"),
 	})
 	if wrSegs != nil {
 		for _, ops := range aggr.Operations {
@@ -275,7 +402,21 @@ func printAnalysis(ctx *cli.Context, o bench.Operations) {
 		}
 	}
 
+	if fn := ctx.String("analyze.grafana"); fn != "" {
+		writeGrafana(ctx, fn, o, aggr)
+	}
+
+	if fn := ctx.String("analyze.parquet"); fn != "" {
+		f, err := os.Create(fn)
+		fatalIf(probe.NewError(err), "无法创建 Parquet 输出")
+		err = o.Parquet(f)
+		fatalIf(probe.NewError(err), "无法写入 Parquet 输出")
+		fatalIf(probe.NewError(f.Close()), "无法写入 Parquet 输出")
+		console.Println("请求操作保存到", fn)
+	}
+
 	if globalJSON {
+		aggr.DownsampleSegments(ctx.Int("analyze.max-segments"))
 		b, err := json.MarshalIndent(aggr, "", "  ")
 		fatalIf(probe.NewError(err), "无法组织数据.")
 		if err != nil {
@@ -285,8 +426,15 @@ func printAnalysis(ctx *cli.Context, o bench.Operations) {
 		return
 	}
 
+	if ctx.Bool("analyze.markdown") {
+		err := aggr.Markdown(os.Stdout)
+		fatalIf(probe.NewError(err), "无法写入 Markdown 表格")
+		return
+	}
+
+	norm := newNormalization(ctx, o)
 	if aggr.Mixed {
-		printMixedOpAnalysis(ctx, aggr, details)
+		printMixedOpAnalysis(ctx, aggr, norm, details)
 		return
 	}
 
@@ -303,6 +451,9 @@ func printAnalysis(ctx *cli.Context, o bench.Operations) {
 		if ops.Clients > 1 {
 			hostsString = fmt.Sprintf("%s Warp 实例: %d.", hostsString, ops.Clients)
 		}
+		if len(ops.Protocols) > 0 {
+			hostsString = fmt.Sprintf("%s 协议: %s.", hostsString, protocolsString(ops.Protocols))
+		}
 		if opo > 1 {
 			if details {
 				console.Printf("请求操作: %v (%d). 每次操作的对象数: %d. 并发量: %d.%s\n", typ, ops.N, opo, ops.Concurrency, hostsString)
@@ -319,6 +470,9 @@ func printAnalysis(ctx *cli.Context, o bench.Operations) {
 		if ops.Errors > 0 {
 			console.SetColor("Print", color.New(color.FgHiRed))
 			console.Println("错误:", ops.Errors)
+			if ops.TimedOut > 0 {
+				console.Printf("其中超时 (%v): %d\n", time.Duration(ops.TimeoutMillis)*time.Millisecond, ops.TimedOut)
+			}
 			if details {
 				console.SetColor("Print", color.New(color.FgWhite))
 				console.Println("首个错误:")
@@ -328,6 +482,27 @@ func printAnalysis(ctx *cli.Context, o bench.Operations) {
 				console.Println("")
 			}
 		}
+		if ops.Canceled > 0 {
+			console.SetColor("Print", color.New(color.FgWhite))
+			console.Println("停止时取消的请求:", ops.Canceled)
+		}
+		if s := ops.SLOViolations; s != nil {
+			if s.Violations > 0 {
+				console.SetColor("Print", color.New(color.FgHiYellow))
+			} else {
+				console.SetColor("Print", color.New(color.FgWhite))
+			}
+			console.Printf("超出 SLO (%v) 的请求: %d/%d (%.02f%%)\n", time.Duration(s.ThresholdMicros)*time.Microsecond, s.Violations, s.Requests, s.Percent())
+		}
+		if t := ops.Throttle; t != nil {
+			if t.Sustained {
+				console.SetColor("Print", color.New(color.FgHiYellow))
+				console.Printf("吞吐量似乎被限流在 %s. %d/%d 个分段中服务器返回了 429/503, 这是限流的上限而不是服务器的容量.\n", t, t.Segments, t.TotalSegments)
+			} else {
+				console.SetColor("Print", color.New(color.FgWhite))
+				console.Printf("被限流的请求操作: %d, 在 %d/%d 个分段中.\n", t.Operations, t.Segments, t.TotalSegments)
+			}
+		}
 
 		if ops.Skipped {
 			console.SetColor("Print", color.New(color.FgHiWhite))
@@ -336,12 +511,40 @@ func printAnalysis(ctx *cli.Context, o bench.Operations) {
 		}
 
 		if details {
+			printSlowestRequests(ops)
 			printRequestAnalysis(ctx, ops, details)
 			console.SetColor("Print", color.New(color.FgHiWhite))
 			console.Println("\n吞吐量:")
 		}
 		console.SetColor("Print", color.New(color.FgWhite))
 		console.Println("* 平均值:", ops.Throughput.StringDetails(details))
+		norm.print(ops.Throughput, details)
+		printRequestRate(ops)
+
+		if p := ops.RequestPhases; p != nil {
+			console.SetColor("Print", color.New(color.FgHiWhite))
+			console.Println("\n请求阶段耗时:")
+			console.SetColor("Print", color.New(color.FgWhite))
+			console.Println(" * DNS:", p.DNS)
+			console.Println(" * 连接:", p.Connect)
+			console.Println(" * TLS:", p.TLS)
+			console.Println(" * 服务端 (首字节):", p.Server)
+		}
+		if len(ops.Headers) > 0 {
+			console.SetColor("Print", color.New(color.FgHiWhite))
+			console.Println("\n响应头:")
+			console.SetColor("Print", color.New(color.FgWhite))
+			for _, h := range ops.Headers {
+				console.Printf(" * %s: %d 个请求, %d 个不同的值\n", h.Name, h.N, h.Distinct)
+				if n := h.Numeric; n != nil {
+					console.Printf("   - 最小: %v, 50%%: %v, 90%%: %v, 99%%: %v, 最大: %v\n", n.Min, n.Median, n.P90, n.P99, n.Max)
+					continue
+				}
+				for _, v := range h.Values {
+					console.Printf("   - %q: %d\n", v.Value, v.N)
+				}
+			}
+		}
 
 		if eps := ops.ThroughputByHost; len(eps) > 1 {
 			console.SetColor("Print", color.New(color.FgHiWhite))
@@ -374,6 +577,42 @@ func printAnalysis(ctx *cli.Context, o bench.Operations) {
 				}
 			}
 		}
+		if tenants := ops.ThroughputByTenant; len(tenants) > 1 {
+			console.SetColor("Print", color.New(color.FgHiWhite))
+			console.Println("\n租户吞吐量:")
+			names := make([]string, 0, len(tenants))
+			for name := range tenants {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				t := tenants[name]
+				console.SetColor("Print", color.New(color.FgWhite))
+				console.Print(" * ", name, ": 平均值: ", t.StringDetails(details), "\n")
+				if t.Errors > 0 {
+					console.SetColor("Print", color.New(color.FgHiRed))
+					console.Println("错误:", t.Errors)
+				}
+			}
+		}
+		if classes := ops.ThroughputByStorageClass; len(classes) > 1 {
+			console.SetColor("Print", color.New(color.FgHiWhite))
+			console.Println("\n存储类吞吐量:")
+			names := make([]string, 0, len(classes))
+			for name := range classes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				t := classes[name]
+				console.SetColor("Print", color.New(color.FgWhite))
+				console.Print(" * ", name, ": 平均值: ", t.StringDetails(details), "\n")
+				if t.Errors > 0 {
+					console.SetColor("Print", color.New(color.FgHiRed))
+					console.Println("错误:", t.Errors)
+				}
+			}
+		}
 		segs := ops.Throughput.Segmented
 		dur := time.Millisecond * time.Duration(segs.SegmentDurationMillis)
 		console.SetColor("Print", color.New(color.FgHiWhite))
@@ -382,6 +621,166 @@ func printAnalysis(ctx *cli.Context, o bench.Operations) {
 		console.Println(" * 最快的:", aggregate.SegmentSmall{BPS: segs.FastestBPS, OPS: segs.FastestOPS, Start: segs.FastestStart}.StringLong(dur, details))
 		console.Println(" * 中位数:", aggregate.SegmentSmall{BPS: segs.MedianBPS, OPS: segs.MedianOPS, Start: segs.MedianStart}.StringLong(dur, details))
 		console.Println(" * 最慢的:", aggregate.SegmentSmall{BPS: segs.SlowestBPS, OPS: segs.SlowestOPS, Start: segs.SlowestStart}.StringLong(dur, details))
+		if ctx.Bool("analyze.percentiles") {
+			for _, p := range segs.Percentiles {
+				console.Printf(" * 第 %d 百分位: %s\n", p.Percentile, p.StringLong(dur, details))
+			}
+		}
+		if len(segs.Segments) > 1 {
+			console.Printf(" * 稳定性: 变异系数 (CV) %.1f%%, 标准差 %s\n", segs.CoV*100, stdDevString(segs))
+		}
+		if inf := ops.InFlight; inf != nil {
+			console.Printf(" * 并发请求数: 平均 %.2f, 最少 %.2f, 最多 %.2f\n", inf.Average, inf.Min, inf.Max)
+		}
+	}
+}
+
+// normalization divides throughput by a number of units, like hosts or drives.
+type normalization struct {
+	n    int
+	unit string
+}
+
+// newNormalization returns the normalization requested by --analyze.normalize.
+// Drives are counted using the admin API unless --analyze.drives is set.
+func newNormalization(ctx *cli.Context, o bench.Operations) normalization {
+	switch ctx.String("analyze.normalize") {
+	case "host":
+		return normalization{n: len(o.Endpoints()), unit: "主机"}
+	case "drive":
+		if n := ctx.Int("analyze.drives"); n > 0 {
+			return normalization{n: n, unit: "磁盘"}
+		}
+		if ctx.String("host") == "" {
+			console.Fatal("analyze.normalize=drive 需要 --analyze.drives 或者可以访问管理 API 的 --host")
+		}
+		info, err := newAdminClient(ctx).ServerInfo(context.Background())
+		fatalIf(probe.NewError(err), "无法通过管理 API 获取磁盘数")
+		var n int
+		for _, s := range info.Servers {
+			n += len(s.Disks)
+		}
+		if n == 0 {
+			console.Fatal("管理 API 没有返回任何磁盘, 请使用 --analyze.drives 指定磁盘数")
+		}
+		return normalization{n: n, unit: "磁盘"}
+	}
+	return normalization{}
+}
+
+// print the throughput per unit, if requested.
+func (norm normalization) print(t aggregate.Throughput, details bool) {
+	if norm.n <= 0 {
+		return
+	}
+	console.Printf("* 每%s平均值: %s (%d 个%s)\n", norm.unit, t.Normalized(norm.n).StringDetails(details), norm.n, norm.unit)
+}
+
+// protocolsString returns the protocols used, sorted by name.
+// If several protocols were used, the number of operations for each is included.
+func protocolsString(protos map[string]int) string {
+	names := make([]string, 0, len(protos))
+	for name := range protos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 1 {
+		return names[0]
+	}
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%s (%d)", name, protos[name])
+	}
+	return strings.Join(names, ", ")
+}
+
+// stdDevString returns the standard deviation of segmented throughput as a human readable string.
+func stdDevString(segs *aggregate.ThroughputSegmented) string {
+	if segs.SortedBy == "bps" {
+		return bench.Throughput(segs.StdDev).String()
+	}
+	return fmt.Sprintf("%0.2f obj/s", segs.StdDev)
+}
+
+// filterPrepare removes prepare operations, unless --analyze.include-prepare is set.
+func filterPrepare(ctx *cli.Context, o bench.Operations) bench.Operations {
+	if ctx.Bool("analyze.include-prepare") {
+		return o
+	}
+	return o.FilterByPrepare(false)
+}
+
+// filterSteady returns the operations inside the time range where throughput is stable.
+// If no stable range is found all operations are returned.
+func filterSteady(ctx *cli.Context, o bench.Operations) bench.Operations {
+	from, to := o.TimeRange()
+	start, end, ok := o.SteadyState(ctx.Float64("analyze.steady.pct") / 100)
+	if !ok {
+		if !globalJSON {
+			console.Errorln("找不到吞吐量稳定的时间范围, 将分析所有请求操作.")
+		}
+		return o
+	}
+	if !globalJSON {
+		console.Printf("稳定状态: %v 到 %v (跳过开始的 %v 和结束的 %v)\n",
+			start.Format("15:04:05"), end.Format("15:04:05"),
+			start.Sub(from).Round(time.Second), to.Sub(end).Round(time.Second))
+	}
+	return o.FilterInsideRange(start, end)
+}
+
+// printSlowestRequests prints the request IDs of the slowest requests, if any were recorded.
+func printSlowestRequests(ops aggregate.Operation) {
+	if len(ops.SlowestRequests) == 0 {
+		return
+	}
+	console.SetColor("Print", color.New(color.FgWhite))
+	console.Println("最慢请求的请求 ID:")
+	for _, req := range ops.SlowestRequests {
+		console.Println(" *", req)
+	}
+	console.Println("")
+}
+
+// printBaseline compares the operations to the --baseline benchmark data, if specified.
+// If the median throughput of any operation type has dropped more than
+// --baseline.regress percent, warp will exit with an error.
+func printBaseline(ctx *cli.Context, ops bench.Operations) {
+	fn := ctx.String("baseline")
+	if fn == "" {
+		return
+	}
+	base := readBenchDataFile(ctx, fn, true)
+	if !globalJSON {
+		console.SetColor("Print", color.New(color.FgHiWhite))
+		console.Println("\n与基准数据比较:", fn)
+		printCompare(ctx, base, ops)
+	}
+	maxRegress := ctx.Float64("baseline.regress")
+	if maxRegress <= 0 {
+		return
+	}
+	// Operation types that cannot be compared are regressions,
+	// since the current run failed or didn't run them.
+	allThreads := !ops.IsMixed()
+	for _, typ := range base.OpTypes() {
+		if wantOp := ctx.String("analyze.op"); wantOp != "" && wantOp != typ {
+			continue
+		}
+		before, after := base.FilterByOp(typ), ops.FilterByOp(typ)
+		if len(after) == 0 {
+			console.Fatalf("基准数据中的请求操作 %s 没有出现在本次运行中\n", typ)
+		}
+		cmp, err := bench.Compare(before, after, analysisDur(ctx, before.Duration()), allThreads)
+		if err != nil {
+			console.Fatalf("无法与基准数据比较请求操作 %s: %v\n", typ, err)
+		}
+		change := cmp.Median.ObjPerSec
+		if cmp.Median.ThroughputPerSec != 0 {
+			change = cmp.Median.ThroughputPerSec
+		}
+		if -change > maxRegress {
+			console.Fatalf("请求操作 %s 的中位数吞吐量下降了 %.02f%%, 超过了允许的 %.02f%%\n", cmp.Op, -change, maxRegress)
+		}
 	}
 }
 
@@ -391,13 +790,15 @@ func writeSegs(ctx *cli.Context, wrSegs io.Writer, ops bench.Operations, allThre
 	}
 	totalDur := ops.Duration()
 	segs := ops.Segment(bench.SegmentOptions{
-		From:           time.Time{},
-		PerSegDuration: analysisDur(ctx, totalDur),
-		AllThreads:     allThreads && !ops.HasError(),
+		From:             time.Time{},
+		PerSegDuration:   analysisDur(ctx, totalDur),
+		WallClockAligned: ctx.Bool("analyze.wallclock"),
+		AllThreads:       allThreads && !ops.HasError(),
+		Slide:            ctx.Duration("analyze.slide"),
 	})
 
 	segs.SortByTime()
-	err := segs.CSV(wrSegs)
+	err := segs.Downsample(ctx.Int("analyze.max-segments")).CSV(wrSegs)
 	errorIf(probe.NewError(err), "写入分析时出错")
 
 	// Write segments per endpoint
@@ -406,9 +807,11 @@ func writeSegs(ctx *cli.Context, wrSegs io.Writer, ops bench.Operations, allThre
 		for _, ep := range eps {
 			ops := ops.FilterByEndpoint(ep)
 			segs := ops.Segment(bench.SegmentOptions{
-				From:           time.Time{},
-				PerSegDuration: analysisDur(ctx, totalDur),
-				AllThreads:     false,
+				From:             time.Time{},
+				PerSegDuration:   analysisDur(ctx, totalDur),
+				WallClockAligned: ctx.Bool("analyze.wallclock"),
+				AllThreads:       false,
+				Slide:            ctx.Duration("analyze.slide"),
 			})
 			if len(segs) <= 1 {
 				continue
@@ -420,12 +823,62 @@ func writeSegs(ctx *cli.Context, wrSegs io.Writer, ops bench.Operations, allThre
 				segs.SortByObjsPerSec()
 			}
 			segs.SortByTime()
-			err := segs.CSV(wrSegs)
+			err := segs.Downsample(ctx.Int("analyze.max-segments")).CSV(wrSegs)
 			errorIf(probe.NewError(err), "写入分析时出错")
 		}
 	}
 }
 
+// writeGrafana writes the throughput of each operation type and host over time
+// as Grafana JSON datasource time series to the file fn.
+// Segments are aligned to wall clock time, so series from several runs line up.
+func writeGrafana(ctx *cli.Context, fn string, o bench.Operations, aggr aggregate.Aggregated) {
+	var series []bench.GrafanaSeries
+	add := func(target string, ops bench.Operations, allThreads bool) {
+		segs := ops.Segment(bench.SegmentOptions{
+			From:             time.Time{},
+			PerSegDuration:   analysisDur(ctx, ops.Duration()),
+			WallClockAligned: true,
+			AllThreads:       allThreads && !ops.HasError(),
+			Slide:            ctx.Duration("analyze.slide"),
+		})
+		if len(segs) == 0 {
+			return
+		}
+		segs.SortByTime()
+		bytes := ops.Total(false).TotalBytes > 0
+		unit := "obj/s"
+		if bytes {
+			unit = "MiB/s"
+		}
+		series = append(series, segs.Downsample(ctx.Int("analyze.max-segments")).Grafana(target+" "+unit, bytes))
+	}
+	for _, typ := range aggr.Operations {
+		ops := o.FilterByOp(typ.Type)
+		add(typ.Type, ops, !aggr.Mixed)
+		if eps := ops.Endpoints(); len(eps) > 1 {
+			for _, ep := range eps {
+				add(typ.Type+" "+ep, ops.FilterByEndpoint(ep), false)
+			}
+		}
+	}
+	b, err := json.MarshalIndent(series, "", "  ")
+	fatalIf(probe.NewError(err), "无法组织数据.")
+	err = ioutil.WriteFile(fn, b, 0644)
+	fatalIf(probe.NewError(err), "无法写入 Grafana 输出")
+	console.Println("Grafana 时间序列保存到", fn)
+}
+
+// printRequestRate prints the number of requests per second
+// if each request operates on several objects.
+func printRequestRate(ops aggregate.Operation) {
+	if ops.ObjectsPerOperation <= 1 {
+		return
+	}
+	console.Printf("* 请求速率: %.02f 请求/s, 每个请求 %d 个对象. obj/s 是每秒处理的对象数, 请求/s 是每秒发送到服务器的请求数.\n",
+		ops.Throughput.AverageRPS, ops.ObjectsPerOperation)
+}
+
 func printRequestAnalysis(ctx *cli.Context, ops aggregate.Operation, details bool) {
 	console.SetColor("Print", color.New(color.FgHiWhite))
 
@@ -443,27 +896,28 @@ func printRequestAnalysis(ctx *cli.Context, ops aggregate.Operation, details boo
 		}
 
 		console.Print(
-			" * 平均: ", time.Duration(reqs.DurAvgMillis)*time.Millisecond,
-			", 50%: ", time.Duration(reqs.DurMedianMillis)*time.Millisecond,
-			", 90%: ", time.Duration(reqs.Dur90Millis)*time.Millisecond,
-			", 99%: ", time.Duration(reqs.Dur99Millis)*time.Millisecond,
-			", 最快: ", time.Duration(reqs.FastestMillis)*time.Millisecond,
-			", 最慢: ", time.Duration(reqs.SlowestMillis)*time.Millisecond,
+			" * 平均: ", aggregate.MicrosToDuration(reqs.DurAvgMicros),
+			", 50%: ", aggregate.MicrosToDuration(reqs.DurMedianMicros),
+			", 90%: ", aggregate.MicrosToDuration(reqs.Dur90Micros),
+			", 99%: ", aggregate.MicrosToDuration(reqs.Dur99Micros),
+			", 最快: ", aggregate.MicrosToDuration(reqs.FastestMicros),
+			", 最慢: ", aggregate.MicrosToDuration(reqs.SlowestMicros),
 			"\n")
 
 		if reqs.FirstByte != nil {
 			console.Println("* 首个字节:", reqs.FirstByte)
+			printTTFBHistogram(ctx, reqs.FirstByte)
 		}
 
 		if reqs.FirstAccess != nil {
 			reqs := reqs.FirstAccess
 			console.Print(
-				" * 首次访问: 平均: ", time.Duration(reqs.DurAvgMillis)*time.Millisecond,
-				", 50%: ", time.Duration(reqs.DurMedianMillis)*time.Millisecond,
-				", 90%: ", time.Duration(reqs.Dur90Millis)*time.Millisecond,
-				", 99%: ", time.Duration(reqs.Dur99Millis)*time.Millisecond,
-				", 最快: ", time.Duration(reqs.FastestMillis)*time.Millisecond,
-				", 最慢: ", time.Duration(reqs.SlowestMillis)*time.Millisecond,
+				" * 首次访问: 平均: ", aggregate.MicrosToDuration(reqs.DurAvgMicros),
+				", 50%: ", aggregate.MicrosToDuration(reqs.DurMedianMicros),
+				", 90%: ", aggregate.MicrosToDuration(reqs.Dur90Micros),
+				", 99%: ", aggregate.MicrosToDuration(reqs.Dur99Micros),
+				", 最快: ", aggregate.MicrosToDuration(reqs.FastestMicros),
+				", 最慢: ", aggregate.MicrosToDuration(reqs.SlowestMicros),
 				"\n")
 			if reqs.FirstByte != nil {
 				console.Print(" * 首次访问 TTFB: ", reqs.FirstByte)
@@ -481,11 +935,11 @@ func printRequestAnalysis(ctx *cli.Context, ops aggregate.Operation, details boo
 				}
 				console.SetColor("Print", color.New(color.FgWhite))
 				console.Println(" *", ep, "-", reqs.Requests, "请求量:",
-					"\n\t- 平均:", time.Duration(reqs.DurAvgMillis)*time.Millisecond,
-					"最快:", time.Duration(reqs.FastestMillis)*time.Millisecond,
-					"最慢:", time.Duration(reqs.SlowestMillis)*time.Millisecond,
-					"50%:", time.Duration(reqs.DurMedianMillis)*time.Millisecond,
-					"90%:", time.Duration(reqs.Dur90Millis)*time.Millisecond)
+					"\n\t- 平均:", aggregate.MicrosToDuration(reqs.DurAvgMicros),
+					"最快:", aggregate.MicrosToDuration(reqs.FastestMicros),
+					"最慢:", aggregate.MicrosToDuration(reqs.SlowestMicros),
+					"50%:", aggregate.MicrosToDuration(reqs.DurMedianMicros),
+					"90%:", aggregate.MicrosToDuration(reqs.Dur90Micros))
 				if reqs.FirstByte != nil {
 					console.Println("\t- 首个字节:", reqs.FirstByte)
 				}
@@ -510,7 +964,11 @@ func printRequestAnalysis(ctx *cli.Context, ops aggregate.Operation, details boo
 	for _, s := range sizes {
 
 		console.SetColor("Print", color.New(color.FgHiWhite))
-		console.Print("\n请求操作大小 ", s.MinSizeString, " -> ", s.MaxSizeString, ". Requests - ", s.Requests, ":\n")
+		if s.MinSizeString == s.MaxSizeString {
+			console.Print("\n请求操作大小 ", s.MinSizeString, ". Requests - ", s.Requests, ":\n")
+		} else {
+			console.Print("\n请求操作大小 ", s.MinSizeString, " -> ", s.MaxSizeString, ". Requests - ", s.Requests, ":\n")
+		}
 		console.SetColor("Print", color.New(color.FgWhite))
 
 		console.Print(""+
@@ -524,6 +982,7 @@ func printRequestAnalysis(ctx *cli.Context, ops aggregate.Operation, details boo
 
 		if s.FirstByte != nil {
 			console.Println("首个字节:", s.FirstByte)
+			printTTFBHistogram(ctx, s.FirstByte)
 		}
 
 		if s.FirstAccess != nil {
@@ -564,6 +1023,14 @@ func printRequestAnalysis(ctx *cli.Context, ops aggregate.Operation, details boo
 	}
 }
 
+// printTTFBHistogram prints the time to first byte histogram if requested.
+func printTTFBHistogram(ctx *cli.Context, t *aggregate.TTFB) {
+	if !ctx.Bool("analyze.ttfb.hist") {
+		return
+	}
+	console.Print(t.HistogramString("   "))
+}
+
 // analysisDur returns the analysis duration or 0 if un-parsable.
 func analysisDur(ctx *cli.Context, total time.Duration) time.Duration {
 	dur := ctx.String("analyze.dur")
@@ -594,4 +1061,47 @@ func checkAnalyze(ctx *cli.Context) {
 		err := errors.New("-analyze.dur 的值不能是 0")
 		fatal(probe.NewError(err), "无效的 -analyze.dur 值")
 	}
+	_, err := parseSLA(ctx.String("sla"))
+	fatalIf(probe.NewError(err), "无效的 sla 参数")
+	if pct := ctx.Float64("analyze.steady.pct"); pct <= 0 || pct >= 100 {
+		fatal(errInvalidArgument(), "analyze.steady.pct 的值必须在 0 到 100 之间")
+	}
+	if ctx.Int("analyze.max-segments") < 0 {
+		fatal(errInvalidArgument(), "analyze.max-segments 的值不能是负数")
+	}
+	if ctx.Duration("slo") < 0 {
+		fatal(errInvalidArgument(), "slo 的值不能是负数")
+	}
+	if min, max := analysisSizeRange(ctx); max > 0 && min > max {
+		fatal(errInvalidArgument(), "analyze.min-size 不能大于 analyze.max-size")
+	}
+	switch ctx.String("analyze.normalize") {
+	case "", "host", "drive":
+	default:
+		fatal(errInvalidArgument(), "analyze.normalize 的值必须是 'host' 或 'drive'")
+	}
+	if ctx.Int("analyze.drives") < 0 {
+		fatal(errInvalidArgument(), "analyze.drives 的值不能是负数")
+	}
+	if slide := ctx.Duration("analyze.slide"); slide < 0 {
+		fatal(errInvalidArgument(), "analyze.slide 的值不能是负数")
+	} else if slide > 0 && ctx.String("analyze.dur") != "" && slide >= analysisDur(ctx, time.Minute) {
+		fatal(errInvalidArgument(), "analyze.slide 的值必须小于 analyze.dur")
+	}
+}
+
+// analysisSizeRange returns the object size range given by
+// analyze.min-size and analyze.max-size. 0 means no limit.
+func analysisSizeRange(ctx *cli.Context) (min, max int64) {
+	for _, f := range []struct {
+		name string
+		dst  *int64
+	}{{"analyze.min-size", &min}, {"analyze.max-size", &max}} {
+		if v := ctx.String(f.name); v != "" {
+			sz, err := toSize(v)
+			fatalIf(probe.NewError(err), "无效的 %s 值", f.name)
+			*f.dst = int64(sz)
+		}
+	}
+	return min, max
 }
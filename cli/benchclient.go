@@ -22,6 +22,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -98,6 +99,10 @@ func (s serverRequest) executeBenchmark(ctx context.Context) (*clientBenchmark,
 	return &cb, nil
 }
 
+// clientIdleTimeout is the maximum time to wait for a server command.
+// When exceeded the active benchmark is aborted. Zero disables the timeout.
+var clientIdleTimeout time.Duration
+
 // Information on currently or last connected server.
 var connectedMu sync.Mutex
 var connected serverInfo
@@ -163,8 +168,22 @@ func serveWs(w http.ResponseWriter, r *http.Request) {
 	}
 	for {
 		var req serverRequest
+		if clientIdleTimeout > 0 {
+			ws.SetReadDeadline(time.Now().Add(clientIdleTimeout))
+		}
 		err := ws.ReadJSON(&req)
 		if err != nil {
+			var nErr net.Error
+			if errors.As(err, &nErr) && nErr.Timeout() {
+				console.Errorf("在 %v 内没有收到服务器的命令, 中止当前基准测试\n", clientIdleTimeout)
+				activeBenchmarkMu.Lock()
+				ab := activeBenchmark
+				activeBenchmarkMu.Unlock()
+				if ab != nil {
+					ab.cancel()
+				}
+				return
+			}
 			console.Error("正在读取服务器消息:", err.Error())
 			return
 		}
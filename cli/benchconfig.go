@@ -0,0 +1,266 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/warp/pkg"
+	"gopkg.in/yaml.v2"
+)
+
+var benchDataConfigFlag = cli.BoolFlag{
+	Name:  "benchdata.config",
+	Usage: "将版本和所有参数的生效值写入基准测试数据旁边的 .config.json 文件.",
+}
+
+// benchConfig is the effective configuration of a benchmark run.
+type benchConfig struct {
+	Version    string `json:"version"`
+	ReleaseTag string `json:"release_tag"`
+	CommitID   string `json:"commit_id"`
+	Command    string `json:"command"`
+	// Flags contains all flags with their resolved values.
+	Flags map[string]string `json:"flags"`
+}
+
+// newBenchConfig returns the effective configuration of the current command.
+// Credentials are redacted.
+func newBenchConfig(ctx *cli.Context) benchConfig {
+	cfg := benchConfig{
+		Version:    pkg.Version,
+		ReleaseTag: pkg.ReleaseTag,
+		CommitID:   pkg.CommitID,
+		Command:    ctx.Command.Name,
+		Flags:      make(map[string]string, len(ctx.Command.Flags)),
+	}
+	for _, flag := range ctx.Command.Flags {
+		val, err := flagValue(ctx, flag, true)
+		if err != nil {
+			continue
+		}
+		name := primaryFlagName(flag)
+		switch name {
+		case "access-key", "secret-key", "replica-access-key", "replica-secret-key":
+			val = redactedValue
+		}
+		cfg.Flags[name] = val
+	}
+	return cfg
+}
+
+// writeBenchConfig writes the configuration to fileName + ".config.json"
+// if requested and returns the name of the file written.
+func writeBenchConfig(ctx *cli.Context, fileName string) (string, error) {
+	if !ctx.Bool(benchDataConfigFlag.Name) {
+		return "", nil
+	}
+	b, err := json.MarshalIndent(newBenchConfig(ctx), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	fn := fileName + ".config.json"
+	return fn, ioutil.WriteFile(fn, b, 0644)
+}
+
+// redactedValue is written instead of credentials.
+const redactedValue = "*REDACTED*"
+
+// expandConfigArgs replaces '@file' arguments of a command with the flags in the file.
+// The file can either be a JSON or YAML object with flag names and values
+// or a configuration written by --benchdata.config.
+// Flags from the file are inserted right after the command,
+// so flags given on the command line take precedence.
+// Values of the global flags before the command are skipped when finding the command.
+func expandConfigArgs(args []string, globals []cli.Flag, cmds []cli.Command) ([]string, error) {
+	cmdIdx := -1
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			cmdIdx = i
+			break
+		}
+		if !strings.Contains(arg, "=") && flagTakesValue(globals, strings.TrimLeft(arg, "-")) {
+			i++
+		}
+	}
+	if cmdIdx < 0 {
+		return args, nil
+	}
+	var cmd *cli.Command
+	for i := range cmds {
+		if cmds[i].HasName(args[cmdIdx]) {
+			cmd = &cmds[i]
+			break
+		}
+	}
+	if cmd == nil {
+		return args, nil
+	}
+	var fromFile, rest []string
+	for _, arg := range args[cmdIdx+1:] {
+		if !strings.HasPrefix(arg, "@") || len(arg) == 1 {
+			rest = append(rest, arg)
+			continue
+		}
+		flags, err := loadConfigFlags(arg[1:], *cmd)
+		if err != nil {
+			return nil, err
+		}
+		fromFile = append(fromFile, flags...)
+	}
+	if len(fromFile) == 0 {
+		return args, nil
+	}
+	dst := make([]string, 0, len(args)+len(fromFile))
+	dst = append(dst, args[:cmdIdx+1]...)
+	dst = append(dst, fromFile...)
+	return append(dst, rest...), nil
+}
+
+// flagTakesValue returns whether the flag with the name is in flags
+// and takes a value as the next argument.
+func flagTakesValue(flags []cli.Flag, name string) bool {
+	for _, flag := range flags {
+		for _, n := range strings.Split(flag.GetName(), ",") {
+			if strings.TrimSpace(n) != name {
+				continue
+			}
+			switch flag.(type) {
+			case cli.BoolFlag, cli.BoolTFlag:
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// loadConfigFlags reads the flags in the config file and returns them as arguments.
+func loadConfigFlags(fileName string, cmd cli.Command) ([]string, error) {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseConfigFile(fileName, b)
+	if err != nil {
+		return nil, err
+	}
+	// Configuration written by --benchdata.config
+	if flags, ok := values["flags"].(map[string]interface{}); ok {
+		if c, ok := values["command"].(string); ok && !cmd.HasName(c) {
+			return nil, fmt.Errorf("%s: 配置文件用于命令 %q, 而不是 %q", fileName, c, cmd.Name)
+		}
+		values = flags
+	}
+	known := make(map[string]cli.Flag, len(cmd.Flags))
+	for _, flag := range cmd.Flags {
+		for _, name := range strings.Split(flag.GetName(), ",") {
+			known[strings.TrimSpace(name)] = flag
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var dst []string
+	for _, name := range names {
+		flag, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: 命令 %q 没有参数 %q", fileName, cmd.Name, name)
+		}
+		var val string
+		switch v := values[name].(type) {
+		case string:
+			val = v
+		case int:
+			val = strconv.Itoa(v)
+		case float64:
+			val = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			val = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("%s: 参数 %q 的值类型 %T 无效", fileName, name, v)
+		}
+		if val == redactedValue {
+			// Use credentials from command line or environment.
+			continue
+		}
+		if err := checkFlagValue(flag, val); err != nil {
+			return nil, fmt.Errorf("%s: 参数 %q 的值 %q 无效: %v", fileName, name, val, err)
+		}
+		dst = append(dst, "--"+name+"="+val)
+	}
+	return dst, nil
+}
+
+// parseConfigFile parses the content of a config file.
+// Files ending in .yaml or .yml are parsed as YAML, all others as JSON.
+func parseConfigFile(fileName string, b []byte) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &values); err != nil {
+			return nil, fmt.Errorf("%s: 无法解析 YAML: %v", fileName, err)
+		}
+		// Nested objects are decoded with interface keys.
+		if flags, ok := values["flags"].(map[interface{}]interface{}); ok {
+			m := make(map[string]interface{}, len(flags))
+			for k, v := range flags {
+				m[fmt.Sprint(k)] = v
+			}
+			values["flags"] = m
+		}
+	default:
+		if err := json.Unmarshal(b, &values); err != nil {
+			return nil, fmt.Errorf("%s: 无法解析 JSON: %v", fileName, err)
+		}
+	}
+	return values, nil
+}
+
+// checkFlagValue returns an error if the value cannot be used for the flag.
+func checkFlagValue(flag cli.Flag, val string) error {
+	var err error
+	switch flag.(type) {
+	case cli.StringFlag:
+	case cli.BoolFlag, cli.BoolTFlag:
+		_, err = strconv.ParseBool(val)
+	case cli.IntFlag, cli.Int64Flag:
+		_, err = strconv.ParseInt(val, 10, 64)
+	case cli.UintFlag, cli.Uint64Flag:
+		_, err = strconv.ParseUint(val, 10, 64)
+	case cli.Float64Flag:
+		_, err = strconv.ParseFloat(val, 64)
+	case cli.DurationFlag:
+		_, err = time.ParseDuration(val)
+	default:
+		err = fmt.Errorf("不支持的参数类型 %T", flag)
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/minio/cli"
+)
+
+func TestLoadConfigFlags(t *testing.T) {
+	cmd := cli.Command{
+		Name: "get",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "host, h"},
+			cli.StringFlag{Name: "access-key"},
+			cli.DurationFlag{Name: "duration"},
+			cli.IntFlag{Name: "objects"},
+			cli.Float64Flag{Name: "rate"},
+			cli.BoolFlag{Name: "obj.randsize"},
+			cli.BoolTFlag{Name: "clean"},
+		},
+	}
+	dir, err := ioutil.TempDir("", "warp-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "json",
+			file:    "bench.json",
+			content: `{"duration": "1m", "objects": 10, "obj.randsize": true, "rate": 2.5}`,
+			want:    []string{"--duration=1m", "--obj.randsize=true", "--objects=10", "--rate=2.5"},
+		},
+		{
+			name:    "json-alias",
+			file:    "bench.json",
+			content: `{"h": "localhost:9000"}`,
+			want:    []string{"--h=localhost:9000"},
+		},
+		{
+			name:    "yaml",
+			file:    "bench.yaml",
+			content: "duration: 1m\nobjects: 10\nclean: false\nrate: 2.5\n",
+			want:    []string{"--clean=false", "--duration=1m", "--objects=10", "--rate=2.5"},
+		},
+		{
+			name:    "yml",
+			file:    "bench.YML",
+			content: "host: localhost:9000\n",
+			want:    []string{"--host=localhost:9000"},
+		},
+		{
+			name:    "sidecar-json",
+			file:    "bench.csv.zst.config.json",
+			content: `{"version": "1.0", "command": "get", "flags": {"objects": "5", "clean": "true", "access-key": "*REDACTED*"}}`,
+			want:    []string{"--clean=true", "--objects=5"},
+		},
+		{
+			name:    "sidecar-yaml",
+			file:    "bench.yaml",
+			content: "command: get\nflags:\n  duration: 30s\n  access-key: '*REDACTED*'\n",
+			want:    []string{"--duration=30s"},
+		},
+		{
+			name:    "sidecar-other-command",
+			file:    "bench.json",
+			content: `{"command": "put", "flags": {"objects": "5"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown-flag",
+			file:    "bench.json",
+			content: `{"no-such-flag": "1"}`,
+			wantErr: true,
+		},
+		{
+			name:    "wrong-type",
+			file:    "bench.json",
+			content: `{"objects": "many"}`,
+			wantErr: true,
+		},
+		{
+			name:    "fraction-for-int",
+			file:    "bench.json",
+			content: `{"objects": 1.5}`,
+			wantErr: true,
+		},
+		{
+			name:    "bad-duration",
+			file:    "bench.yaml",
+			content: "duration: soon\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad-bool",
+			file:    "bench.yaml",
+			content: "clean: maybe\n",
+			wantErr: true,
+		},
+		{
+			name:    "list-value",
+			file:    "bench.json",
+			content: `{"host": ["a", "b"]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid-json",
+			file:    "bench.json",
+			content: `duration: 1m`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid-yaml",
+			file:    "bench.yaml",
+			content: "duration: [1m\n",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fn := filepath.Join(dir, test.file)
+			if err := ioutil.WriteFile(fn, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadConfigFlags(fn, cmd)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := loadConfigFlags(filepath.Join(dir, "missing.json"), cmd); err == nil {
+		t.Error("want error for missing file")
+	}
+}
+
+func TestExpandConfigArgs(t *testing.T) {
+	globals := []cli.Flag{
+		cli.BoolFlag{Name: "quiet, q"},
+		cli.StringFlag{Name: "units"},
+	}
+	cmds := []cli.Command{
+		{Name: "get", Flags: []cli.Flag{cli.IntFlag{Name: "objects"}}},
+	}
+	dir, err := ioutil.TempDir("", "warp-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "bench.json")
+	if err := ioutil.WriteFile(fn, []byte(`{"objects": 10}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "no-config", args: []string{"warp", "get", "--objects=5"}, want: []string{"warp", "get", "--objects=5"}},
+		{name: "config", args: []string{"warp", "get", "@" + fn}, want: []string{"warp", "get", "--objects=10"}},
+		{name: "command-line-last", args: []string{"warp", "get", "--objects=5", "@" + fn}, want: []string{"warp", "get", "--objects=10", "--objects=5"}},
+		{name: "global-bool", args: []string{"warp", "-q", "get", "@" + fn}, want: []string{"warp", "-q", "get", "--objects=10"}},
+		{name: "global-value", args: []string{"warp", "--units", "si", "get", "@" + fn}, want: []string{"warp", "--units", "si", "get", "--objects=10"}},
+		{name: "global-value-equals", args: []string{"warp", "--units=si", "get", "@" + fn}, want: []string{"warp", "--units=si", "get", "--objects=10"}},
+		{name: "unknown-command", args: []string{"warp", "foo", "@" + fn}, want: []string{"warp", "foo", "@" + fn}},
+		{name: "no-command", args: []string{"warp", "--units", "si"}, want: []string{"warp", "--units", "si"}},
+		{name: "missing-file", args: []string{"warp", "get", "@" + filepath.Join(dir, "missing.json")}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := expandConfigArgs(test.args, globals, cmds)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
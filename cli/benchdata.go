@@ -0,0 +1,217 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg"
+	"github.com/minio/warp/pkg/bench"
+)
+
+var benchDataCompressionFlag = cli.StringFlag{
+	Name:  "benchdata.compression",
+	Value: "better",
+	Usage: "基准测试数据输出的 zstd 压缩级别. 值可以是 'fast', 'default', 'better' 和 'best'.",
+}
+
+var benchDataRawFlag = cli.BoolFlag{
+	Name:  "benchdata.raw",
+	Usage: "将基准测试数据输出为未压缩的 .csv 文件, 而不是 .csv.zst 文件.",
+}
+
+var benchDataS3Flag = cli.StringFlag{
+	Name:  "benchdata-s3",
+	Value: "",
+	Usage: "基准测试完成后将基准测试数据上传到此 S3 位置, 例如 's3://bucket/prefix'. 使用 --host 指定的服务器, 本地副本会被保留.",
+}
+
+// zstdMagic is the magic number at the start of every zstd frame.
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// benchDataEncoderLevel returns the zstd encoder level selected on the command line.
+func benchDataEncoderLevel(ctx *cli.Context) (zstd.EncoderLevel, error) {
+	switch strings.ToLower(ctx.String("benchdata.compression")) {
+	case "fast":
+		return zstd.SpeedFastest, nil
+	case "default":
+		return zstd.SpeedDefault, nil
+	case "", "better":
+		return zstd.SpeedBetterCompression, nil
+	case "best":
+		return zstd.SpeedBestCompression, nil
+	}
+	return 0, fmt.Errorf("未知的压缩级别: %q", ctx.String("benchdata.compression"))
+}
+
+// newBenchDataEncoder returns an encoder for benchmark data written to w.
+func newBenchDataEncoder(ctx *cli.Context, w io.Writer) (*zstd.Encoder, error) {
+	level, err := benchDataEncoderLevel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(level))
+}
+
+// parseS3URL parses a location given as 's3://bucket/prefix'.
+// The returned prefix will end with a slash if not empty.
+func parseS3URL(s string) (bucket, prefix string, err error) {
+	if !strings.HasPrefix(s, "s3://") {
+		return "", "", fmt.Errorf("S3 位置必须以 's3://' 开头: %q", s)
+	}
+	split := strings.SplitN(strings.TrimPrefix(s, "s3://"), "/", 2)
+	bucket = split[0]
+	if bucket == "" {
+		return "", "", fmt.Errorf("S3 位置中没有存储桶: %q", s)
+	}
+	if len(split) == 2 {
+		prefix = strings.Trim(split[1], "/")
+		if prefix != "" {
+			prefix += "/"
+		}
+	}
+	return bucket, prefix, nil
+}
+
+// maxBenchDataMeta is the maximum length of the command line stored as metadata.
+const maxBenchDataMeta = 1024
+
+// uploadBenchData will upload the files to the location given by --benchdata-s3, if set.
+// Upload failures are logged, and the local files are always kept.
+// Credentials are redacted from the command line added as metadata.
+func uploadBenchData(ctx *cli.Context, logInfo, logErr func(data ...interface{}), files ...string) {
+	dst := ctx.String(benchDataS3Flag.Name)
+	if dst == "" {
+		return
+	}
+	bucket, prefix, err := parseS3URL(dst)
+	if err != nil {
+		logErr("无法上传基准测试数据:", err)
+		return
+	}
+	cl, done := newClient(ctx)()
+	defer done()
+	cmd := strings.Join(strings.Fields(commandLine(ctx)), " ")
+	if len(cmd) > maxBenchDataMeta {
+		// S3 limits the total size of user metadata.
+		cmd = cmd[:maxBenchDataMeta]
+	}
+	meta := map[string]string{
+		"warp-version": pkg.Version,
+		"warp-command": cmd,
+	}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		key := prefix + filepath.Base(file)
+		_, err := cl.FPutObject(context.Background(), bucket, key, file, minio.PutObjectOptions{UserMetadata: meta})
+		if err != nil {
+			logErr(fmt.Sprintf("无法上传 %q 到 s3://%s/%s, 本地副本已保留: %v", file, bucket, key, err))
+			continue
+		}
+		logInfo(fmt.Sprintf("%q 已上传到 s3://%s/%s", file, bucket, key))
+	}
+}
+
+// benchDataFileName returns the name of the output file for the given base name.
+func benchDataFileName(ctx *cli.Context, base string) string {
+	if ctx.Bool("benchdata.raw") {
+		return base + ".csv"
+	}
+	return base + ".csv.zst"
+}
+
+// benchDataFile is an output file for benchmark data.
+// Closing it will flush the encoder, if any, and close the file.
+type benchDataFile struct {
+	io.Writer
+	f   *os.File
+	enc *zstd.Encoder
+}
+
+func (b *benchDataFile) Close() error {
+	if b.enc != nil {
+		if err := b.enc.Close(); err != nil {
+			b.f.Close()
+			return err
+		}
+	}
+	return b.f.Close()
+}
+
+// createBenchDataFile creates the named output file for benchmark data.
+// Unless raw output is requested the data written will be compressed.
+func createBenchDataFile(ctx *cli.Context, name string) (io.WriteCloser, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Bool("benchdata.raw") {
+		return &benchDataFile{Writer: f, f: f}, nil
+	}
+	enc, err := newBenchDataEncoder(ctx, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &benchDataFile{Writer: enc, f: f, enc: enc}, nil
+}
+
+// readBenchDataFile reads all operations from the named benchmark data file.
+// If analyzeOnly is set, file names and clients are mapped to compact values.
+// Prepare operations are removed unless requested.
+func readBenchDataFile(ctx *cli.Context, name string, analyzeOnly bool) bench.Operations {
+	var zstdDec, _ = zstd.NewReader(nil)
+	defer zstdDec.Close()
+	log := console.Printf
+	if globalQuiet {
+		log = nil
+	}
+	f, err := os.Open(name)
+	fatalIf(probe.NewError(err), "无法打开输入文件")
+	defer f.Close()
+	input, err := benchDataReader(zstdDec, f)
+	fatalIf(probe.NewError(err), "无法读取输入文件")
+	ops, err := bench.OperationsFromCSV(input, analyzeOnly, ctx.Int("analyze.offset"), ctx.Int("analyze.limit"), log)
+	fatalIf(probe.NewError(err), "无法解析输入文件")
+	return filterPrepare(ctx, ops)
+}
+
+// benchDataReader returns a reader for benchmark data read from r.
+// Both zstd compressed and uncompressed CSV input is accepted.
+func benchDataReader(dec *zstd.Decoder, r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil || !bytes.Equal(magic, zstdMagic) {
+		return br, nil
+	}
+	return dec, dec.Reset(br)
+}
@@ -24,13 +24,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/cheggaaa/pb"
-	"github.com/klauspost/compress/zstd"
 	"github.com/minio/cli"
 	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/minio/pkg/console"
@@ -39,12 +44,286 @@ import (
 	"github.com/minio/warp/pkg/bench"
 )
 
+// readBucketFlags allow read benchmarks to prepare and read from different buckets.
+var readBucketFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "src-bucket",
+		Value: "",
+		Usage: "准备阶段上传对象使用的桶. 默认使用 --bucket",
+	},
+	cli.StringFlag{
+		Name:  "dst-bucket",
+		Value: "",
+		Usage: "基准测试读取对象使用的桶, 例如复制 (replication) 的目标桶. 默认与 --src-bucket 相同",
+	},
+	cli.DurationFlag{
+		Name:  "dst-bucket.wait",
+		Value: 5 * time.Minute,
+		Usage: "等待准备的对象出现在 --dst-bucket 中的最长时间",
+	},
+}
+
+// setJitter applies --concurrent.jitter to c.
+// The number of workers is set to the maximum of the range.
+func setJitter(ctx *cli.Context, c *bench.Common) {
+	spec := ctx.String("concurrent.jitter")
+	if spec == "" {
+		return
+	}
+	j, err := bench.ParseConcurrencyJitter(spec)
+	fatalIf(probe.NewError(err), "无效的 concurrent.jitter 参数")
+	c.Jitter = j
+	c.Concurrency = j.Max
+}
+
+// jitterComment returns the concurrency timeline as comment lines for the benchmark data.
+func jitterComment(timeline []bench.ConcurrencyChange) string {
+	var sb strings.Builder
+	for _, change := range timeline {
+		fmt.Fprintf(&sb, "\nconcurrency %s %d", change.Time.Format(time.RFC3339Nano), change.Concurrency)
+	}
+	return sb.String()
+}
+
+// setHealthCheck will check the bucket on each host every --healthcheck-interval if set.
+func setHealthCheck(ctx *cli.Context, c *bench.Common) {
+	interval := ctx.Duration("healthcheck-interval")
+	if interval <= 0 {
+		return
+	}
+	hosts := parseHosts(ctx.String("host"))
+	h := bench.HealthCheck{Interval: interval}
+	for _, host := range hosts {
+		cl, err := getClient(ctx, host)
+		fatalIf(probe.NewError(err), "无法创建 MinIO 客户端")
+		h.Clients = append(h.Clients, cl)
+	}
+	c.HealthCheck = &h
+}
+
+// setBufferMemory will limit the memory used by upload buffers if --max-buffer-memory is set.
+func setBufferMemory(ctx *cli.Context, c *bench.Common) {
+	if n := maxBufferMemory(ctx); n > 0 {
+		c.BufferMemory = bench.NewBufferLimiter(n)
+	}
+}
+
+// maxBufferMemory returns the value of --max-buffer-memory or 0 if not set.
+func maxBufferMemory(ctx *cli.Context) int64 {
+	s := ctx.String("max-buffer-memory")
+	if s == "" {
+		return 0
+	}
+	sz, err := toSize(s)
+	fatalIf(probe.NewError(err), "无效的 max-buffer-memory 值")
+	return int64(sz)
+}
+
+// healthComment returns the availability gaps as comment lines for the benchmark data.
+func healthComment(gaps []bench.AvailabilityGap) string {
+	var sb strings.Builder
+	for _, gap := range gaps {
+		fmt.Fprintf(&sb, "\nunavailable %s %s %s %s", gap.Endpoint, gap.Start.Format(time.RFC3339Nano), gap.End.Format(time.RFC3339Nano), strings.Replace(gap.Err, "\n", " ", -1))
+	}
+	return sb.String()
+}
+
+// printAvailabilityGaps prints the availability gaps found by health checks.
+func printAvailabilityGaps(gaps []bench.AvailabilityGap, log func(data ...interface{})) {
+	for _, gap := range gaps {
+		log(fmt.Sprintf("健康检查: %s 不可用 %s - %s (%v): %s", gap.Endpoint, gap.Start.Format("15:04:05 MST"), gap.End.Format("15:04:05 MST"), gap.Duration().Round(time.Millisecond), gap.Err))
+	}
+}
+
+// anonymousBenchmarks are the benchmarks that can run with --anonymous.
+var anonymousBenchmarks = map[string]bool{"get": true, "stat": true, "list": true}
+
+// setAnonymous will send benchmarked requests without credentials if --anonymous is set.
+func setAnonymous(ctx *cli.Context, c *bench.Common) {
+	if ctx.Bool("anonymous") {
+		c.AnonymousClient = newAnonymousClient(ctx)
+	}
+}
+
+// authComment returns the authentication mode as a comment line for the benchmark data.
+func authComment(ctx *cli.Context) string {
+	if ctx.Bool("anonymous") {
+		return "\nauth anonymous"
+	}
+	return "\nauth " + strings.ToLower(ctx.String("signature"))
+}
+
+// versionsComment returns the number of versions created per object as a comment line
+// for benchmarks that create versions while preparing.
+func versionsComment(ctx *cli.Context) string {
+	if !ctx.IsSet("versions-per-object") {
+		return ""
+	}
+	return fmt.Sprintf("\nversions-per-object %d", ctx.Int("versions-per-object"))
+}
+
+// concurrencyComment returns the concurrency of the prepare and benchmark phases for the benchmark data,
+// if --prepare.concurrent is set.
+func concurrencyComment(c *bench.Common) string {
+	if c.PrepareConcurrency <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nconcurrency prepare %d benchmark %d", c.PrepareConcurrency, c.Concurrency)
+}
+
+// partitionComment returns the partitioning of objects between workers for the benchmark data,
+// if --get.partition is set.
+func partitionComment(ctx *cli.Context, workers int) string {
+	if !ctx.Bool("get.partition") {
+		return ""
+	}
+	return fmt.Sprintf("\nget.partition %d objects %d workers", objectCount(ctx), workers)
+}
+
+// captureHeaders returns the lower case names of the response headers to capture.
+func captureHeaders(ctx *cli.Context) []string {
+	var res []string
+	for _, h := range strings.Split(ctx.String("capture-headers"), ",") {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h != "" {
+			res = append(res, h)
+		}
+	}
+	return res
+}
+
+// setFailFast will make the benchmark send the first failed operation
+// on the returned channel if --fail-fast is set. Otherwise nil is returned.
+func setFailFast(ctx *cli.Context, c *bench.Common) chan bench.Operation {
+	if !ctx.Bool("fail-fast") {
+		return nil
+	}
+	failed := make(chan bench.Operation, 1)
+	c.FailFast = func(op bench.Operation) {
+		select {
+		case failed <- op:
+		default:
+		}
+	}
+	return failed
+}
+
+// watchFailFast will call cancel when a failed operation is received on failed.
+// The returned function waits until ctx is done and returns the failed operation, if any.
+func watchFailFast(ctx context.Context, cancel context.CancelFunc, failed <-chan bench.Operation, logErr func(data ...interface{})) func() *bench.Operation {
+	var op *bench.Operation
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case f := <-failed:
+			op = &f
+			logErr("请求操作失败, 中止基准测试:", f.OpType, f.File, f.Err)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return func() *bench.Operation {
+		<-done
+		return op
+	}
+}
+
+// opTimeoutFlags contains the timeout flag of each operation type.
+var opTimeoutFlags = map[string]string{
+	http.MethodPut:    "timeout.put",
+	http.MethodGet:    "timeout.get",
+	"STAT":            "timeout.stat",
+	http.MethodDelete: "timeout.delete",
+}
+
+// timeoutBenchmarks are the benchmarks that apply the operation timeout flags.
+var timeoutBenchmarks = map[string]bool{
+	"get": true, "put": true, "lifecycle": true, "stat": true, "delete": true, "mixed": true,
+	"versioned": true, "replay": true, "replicate": true, "presign": true, "rmw": true,
+}
+
+// setTimeouts applies the operation timeout flags to c.
+func setTimeouts(ctx *cli.Context, c *bench.Common) {
+	for op, flag := range opTimeoutFlags {
+		if d := ctx.Duration(flag); d > 0 {
+			if c.Timeouts == nil {
+				c.Timeouts = make(map[string]time.Duration, len(opTimeoutFlags))
+			}
+			c.Timeouts[op] = d
+		}
+	}
+}
+
+// setLiveStats will collect live statistics if --live.interval is set.
+func setLiveStats(ctx *cli.Context, c *bench.Common) {
+	if ctx.Duration("live.interval") > 0 {
+		c.Live = bench.NewLiveStats()
+		c.Live.SetSLO(ctx.Duration("slo"))
+	}
+}
+
+// printLiveStats will print the operations finished since the last interval by type
+// from when start is closed until ctx is canceled.
+func printLiveStats(ctx context.Context, cliCtx *cli.Context, live *bench.LiveStats, start <-chan struct{}, out func(data ...interface{})) {
+	if live == nil {
+		return
+	}
+	select {
+	case <-start:
+	case <-ctx.Done():
+		return
+	}
+	// Discard prepare operations.
+	live.Snapshot()
+	ticker := time.NewTicker(cliCtx.Duration("live.interval"))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		var sb strings.Builder
+		sb.WriteString("实时:")
+		for i, st := range live.Snapshot() {
+			if i > 0 {
+				sb.WriteString(" |")
+			}
+			fmt.Fprintf(&sb, " %s: %.2f obj/s", st.OpType, st.ObjsPerSec())
+			if st.Bytes > 0 {
+				fmt.Fprintf(&sb, ", %v", st.BytesPerSec())
+			}
+			if st.Errors > 0 {
+				fmt.Fprintf(&sb, ", 错误: %d", st.Errors)
+			}
+			if st.SLOViolations > 0 {
+				fmt.Fprintf(&sb, ", 超出 SLO: %d", st.SLOViolations)
+			}
+		}
+		out(sb.String())
+	}
+}
+
+// setReadBucket applies the read bucket flags to c.
+func setReadBucket(ctx *cli.Context, c *bench.Common) {
+	if b := ctx.String("src-bucket"); b != "" {
+		c.Bucket = b
+	}
+	c.ReadBucket = ctx.String("dst-bucket")
+	c.ReadBucketWait = ctx.Duration("dst-bucket.wait")
+}
+
 var benchFlags = []cli.Flag{
 	cli.StringFlag{
 		Name:  "benchdata",
 		Value: "",
 		Usage: "将基准测试+配置文件的数据输出到此文件. 默认会生成唯一的文件名.",
 	},
+	benchDataCompressionFlag,
+	benchDataRawFlag,
+	benchDataConfigFlag,
+	benchDataS3Flag,
 	cli.StringFlag{
 		Name:  "serverprof",
 		Usage: "在基准测试期间运行 MinIO 服务器配置文件. 值可以是 'cpu', 'mem', 'block', 'mutex' 和 'trace'.",
@@ -69,10 +348,94 @@ var benchFlags = []cli.Flag{
 		Usage: "最后的 6/25 个时间段内的运行速度，必须在当前速度内才能自动终止.",
 		Value: 7.5,
 	},
+	cli.StringFlag{
+		Name:  "autoterm.metric",
+		Usage: "自动终止时需要稳定的指标. 可以是 'throughput', 'latency' (99% 延迟) 或 'both'.",
+		Value: string(bench.AutoTermThroughput),
+	},
+	cli.BoolFlag{
+		Name:  "no-object-contention",
+		Usage: "确保每个对象同一时间只被一个并发请求操作, 避免对同一对象的并发请求影响测试结果.",
+	},
+	cli.BoolFlag{
+		Name:  "fail-fast",
+		Usage: "任何请求操作失败时立即中止基准测试. 已完成的请求操作仍会被保存.",
+	},
+	cli.DurationFlag{
+		Name:  "healthcheck-interval",
+		Value: 0,
+		Usage: "运行基准测试时以该间隔对每个主机的存储桶发送 HEAD 请求, 并记录不可用的时间段. 例如 '5s'.",
+	},
+	cli.BoolFlag{
+		Name:  "collect-server-metrics",
+		Usage: "在基准测试期间通过 MinIO 管理 API 定期收集服务器指标 (磁盘, 网络等), 并保存到基准测试数据旁边.",
+	},
+	cli.DurationFlag{
+		Name:  "collect-server-metrics.interval",
+		Value: 10 * time.Second,
+		Usage: "收集服务器指标的间隔.",
+	},
+	cli.StringFlag{
+		Name:  "max-buffer-memory",
+		Value: "",
+		Usage: "限制并发上传使用的缓冲区总内存, 例如 '1GiB'. 超出时工作线程将等待, 等待时间不计入请求时间. 默认不限制.",
+	},
+	cli.BoolFlag{
+		Name:  "ignore-cleanup-errors",
+		Usage: "清理时不输出删除对象的错误, 只报告无法删除的对象数量. 例如用于 WORM 存储桶.",
+	},
 	cli.BoolFlag{
 		Name:  "noclear",
 		Usage: "在运行基准测试之前或之后，请不要清除存储桶，因为在运行多个客户端时还需要使用.",
 	},
+	cli.StringFlag{
+		Name:  "concurrent.jitter",
+		Value: "",
+		Usage: "在基准测试期间随机改变并发请求数. 格式为 'min:max@间隔', 例如 '10:50@5s'. 设置后将忽略 --concurrent.",
+	},
+	cli.DurationFlag{
+		Name:  "timeout.put",
+		Value: 0,
+		Usage: "单个 PUT 请求操作的超时时间. 超时的请求操作记录为错误. 默认不限制.",
+	},
+	cli.DurationFlag{
+		Name:  "timeout.get",
+		Value: 0,
+		Usage: "单个 GET 请求操作的超时时间, 包括读取对象内容. 超时的请求操作记录为错误. 默认不限制.",
+	},
+	cli.DurationFlag{
+		Name:  "timeout.stat",
+		Value: 0,
+		Usage: "单个 STAT 请求操作的超时时间. 超时的请求操作记录为错误. 默认不限制.",
+	},
+	cli.DurationFlag{
+		Name:  "timeout.delete",
+		Value: 0,
+		Usage: "单个 DELETE 请求操作的超时时间. 超时的请求操作记录为错误. 默认不限制.",
+	},
+	cli.DurationFlag{
+		Name:  "live.interval",
+		Value: 0,
+		Usage: "基准测试期间以该间隔按操作类型输出每秒操作数和错误数, 例如 '10s'. 默认不输出.",
+	},
+	cli.BoolFlag{
+		Name:  "anonymous",
+		Usage: "不使用凭证发送基准测试请求, 用于测试公开读取的桶. 只支持 get, stat 和 list, 准备阶段仍然使用凭证.",
+	},
+	cli.IntFlag{
+		Name:  "prepare.concurrent",
+		Value: 0,
+		Usage: "准备阶段上传对象的并发数. 0 表示与 --concurrent 相同. 不影响基准测试阶段的并发数.",
+	},
+	cli.IntFlag{
+		Name:  "prepare-retries",
+		Value: 0,
+		Usage: "准备阶段上传对象失败时的重试次数. 默认不重试.",
+	},
+	cli.BoolFlag{
+		Name:  "no-bucket-create",
+		Usage: "不要创建存储桶, 假设桶已经存在. 如果桶不存在则失败.",
+	},
 	cli.BoolFlag{
 		Name:   "keep-data",
 		Usage:  "保留基准测试数据. 基准测试结束后请不要清除数据，下次运行基准测试之前数据会自动被清除.",
@@ -83,6 +446,29 @@ var benchFlags = []cli.Flag{
 		Usage: "指定基准测试的开始时间. 时间格式为 'hh:mm'，使用 24h 小时格式.",
 		Value: "",
 	},
+	cli.BoolFlag{
+		Name:  "client-trace",
+		Usage: "在基准测试期间使用 runtime/trace 跟踪 warp 客户端, 并将跟踪文件写入 --pprofdir. 可以使用 'go tool trace' 查看.",
+	},
+	cli.BoolFlag{
+		Name:  "trace-phases",
+		Usage: "记录每个请求的 DNS, 连接, TLS 和服务端首字节的阶段耗时.",
+	},
+	cli.StringFlag{
+		Name:  "capture-headers",
+		Usage: "记录每个请求操作的指定响应头, 并在分析中汇总. 多个响应头用逗号分隔, 例如 'x-amz-version-id,x-amz-server-side-encryption'.",
+		Value: "",
+	},
+	cli.StringFlag{
+		Name:  "otel-endpoint",
+		Usage: "将每个请求操作作为 OpenTelemetry span 发送到该 OTLP/HTTP 端点, 例如 'http://localhost:4318'.",
+		Value: "",
+	},
+	cli.Float64Flag{
+		Name:  "otel-sample",
+		Usage: "发送 span 的请求操作的比例, 取值范围 0 到 1.",
+		Value: 1,
+	},
 	cli.StringFlag{
 		Name:   "warp-client",
 		Usage:  "连接到 warp 客户端，并在客户端中运行基准测.",
@@ -91,6 +477,38 @@ var benchFlags = []cli.Flag{
 	},
 }
 
+// expiresComment returns the Expires header set on uploaded objects for the benchmark data,
+// if --put.expires is set.
+func expiresComment(b bench.Benchmark) string {
+	e, ok := b.(interface{ ExpiresAt() time.Time })
+	if !ok || e.ExpiresAt().IsZero() {
+		return ""
+	}
+	return "\nput.expires " + e.ExpiresAt().UTC().Format(http.TimeFormat)
+}
+
+// applyCommonFlags applies the benchmark flags that are handled the same way
+// by local benchmarks and distributed clients to c.
+// The channel receiving the first failed operation with --fail-fast is returned.
+func applyCommonFlags(ctx *cli.Context, c *bench.Common) chan bench.Operation {
+	c.TracePhases = ctx.Bool("trace-phases") || ctx.Bool("no-keepalive")
+	c.CaptureHeaders = captureHeaders(ctx)
+	c.RecordTenants = ctx.String("creds-file") != ""
+	c.NoBucketCreate = ctx.Bool("no-bucket-create")
+	c.PrepareRetries = ctx.Int("prepare-retries")
+	c.PrepareConcurrency = ctx.Int("prepare.concurrent")
+	c.NoObjectContention = ctx.Bool("no-object-contention")
+	c.IgnoreCleanupErrors = ctx.Bool("ignore-cleanup-errors")
+	c.ExactSizes = ctx.String("obj.sizes") != ""
+	setJitter(ctx, c)
+	setHealthCheck(ctx, c)
+	setBufferMemory(ctx, c)
+	setAnonymous(ctx, c)
+	setLiveStats(ctx, c)
+	setTimeouts(ctx, c)
+	return setFailFast(ctx, c)
+}
+
 // runBench will run the supplied benchmark and save/print the analysis.
 func runBench(ctx *cli.Context, b bench.Benchmark) error {
 	activeBenchmarkMu.Lock()
@@ -113,10 +531,14 @@ func runBench(ctx *cli.Context, b bench.Benchmark) error {
 	pgDone := make(chan struct{})
 	c := b.GetCommon()
 	c.Clear = !ctx.Bool("noclear")
+	c.Spans = newSpanExporter(ctx)
+	defer c.Spans.Close()
+	failed := applyCommonFlags(ctx, c)
 	if ctx.Bool("autoterm") {
 		// TODO: autoterm cannot be used when in client/server mode
 		c.AutoTermDur = ctx.Duration("autoterm.dur")
 		c.AutoTermScale = ctx.Float64("autoterm.pct") / 100
+		c.AutoTermMetric = bench.AutoTermMetric(strings.ToLower(ctx.String("autoterm.metric")))
 	}
 	if !globalQuiet && !globalJSON {
 		c.PrepareProgress = make(chan float64, 1)
@@ -160,11 +582,18 @@ func runBench(ctx *cli.Context, b bench.Benchmark) error {
 	}
 
 	err := b.Prepare(context.Background())
+	if err != nil {
+		// fatalIf exits without running deferred functions.
+		c.Spans.Close()
+	}
 	fatalIf(probe.NewError(err), "准备服务端时出错")
 	if c.PrepareProgress != nil {
 		close(c.PrepareProgress)
 		<-pgDone
 	}
+	if n := c.PrepareRetried(); n > 0 {
+		monitor.InfoLn(fmt.Sprintf("准备阶段有 %d 个上传失败后重试成功, 集群在开始时可能不稳定.", n))
+	}
 
 	// Start after waiting a second or until we reached the start time.
 	tStart := time.Now().Add(time.Second * 3)
@@ -182,6 +611,7 @@ func runBench(ctx *cli.Context, b bench.Benchmark) error {
 	benchDur := ctx.Duration("duration")
 	ctx2, cancel := context.WithDeadline(context.Background(), tStart.Add(benchDur))
 	defer cancel()
+	failedOp := watchFailFast(ctx2, cancel, failed, monitor.Errorln)
 	start := make(chan struct{})
 	go func() {
 		<-time.After(time.Until(tStart))
@@ -197,6 +627,8 @@ func runBench(ctx *cli.Context, b bench.Benchmark) error {
 
 	prof, err := startProfiling(ctx2, ctx)
 	fatalIf(probe.NewError(err), "无法启动 profile 配置文件.")
+	localProf := startLocalProfiling(ctx, fileName)
+	metrics := startServerMetrics(ctx2, ctx, prof, start)
 	monitor.InfoLn("开始启动基准测试 ", time.Until(tStart).Round(time.Second), "...")
 	pgDone = make(chan struct{})
 	if !globalQuiet && !globalJSON {
@@ -227,8 +659,18 @@ func runBench(ctx *cli.Context, b bench.Benchmark) error {
 	} else {
 		close(pgDone)
 	}
+	prepareDone := time.Now()
+	stopJitter := c.StartJitter(ctx2, start)
+	stopHealth := c.StartHealthCheck(ctx2, start)
+	go printLiveStats(ctx2, ctx, c.Live, start, monitor.InfoLn)
 	ops, _ := b.Start(ctx2, start)
 	cancel()
+	firstFailed := failedOp()
+	gaps := stopHealth()
+	printAvailabilityGaps(gaps, monitor.Errorln)
+	comment := commandLine(ctx) + concurrencyMultipleComment(ctx) + authComment(ctx) + versionsComment(ctx) + expiresComment(b) + concurrencyComment(c) + partitionComment(ctx, c.Concurrency) + jitterComment(stopJitter()) + healthComment(gaps)
+	c.Spans.Close()
+	localProf.stop()
 	<-pgDone
 
 	// Previous context is canceled, create a new...
@@ -236,24 +678,32 @@ func runBench(ctx *cli.Context, b bench.Benchmark) error {
 	ctx2 = context.Background()
 	ops.SortByStartTime()
 	ops.SetClientID(cID)
+	ops.SetPrepare(prepareDone)
 	prof.stop(ctx2, ctx, fileName+".profiles.zip")
+	metrics.stop(fileName + ".server-metrics.json")
 
-	f, err := os.Create(fileName + ".csv.zst")
+	var uploads []string
+	outName := benchDataFileName(ctx, fileName)
+	f, err := createBenchDataFile(ctx, outName)
 	if err != nil {
 		monitor.Errorln("无法写入基准测试数据:", err)
 	} else {
 		func() {
 			defer f.Close()
-			enc, err := zstd.NewWriter(f, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
-			fatalIf(probe.NewError(err), "无法压缩基准测试数据到输出")
-
-			defer enc.Close()
-			err = ops.CSV(enc, commandLine(ctx))
+			err = ops.CSV(f, comment)
 			fatalIf(probe.NewError(err), "无法写入基准测试数据到输出")
 
-			monitor.InfoLn(fmt.Sprintf("基准测试数据写入到了 %q\n", fileName+".csv.zst"))
+			monitor.InfoLn(fmt.Sprintf("基准测试数据写入到了 %q\n", outName))
+			uploads = append(uploads, outName)
 		}()
 	}
+	if fn, err := writeBenchConfig(ctx, fileName); err != nil {
+		monitor.Errorln("无法写入基准测试配置:", err)
+	} else if fn != "" {
+		monitor.InfoLn(fmt.Sprintf("基准测试配置写入到了 %q\n", fn))
+		uploads = append(uploads, fn)
+	}
+	uploadBenchData(ctx, monitor.InfoLn, monitor.Errorln, uploads...)
 	monitor.OperationsReady(ops, fileName, commandLine(ctx))
 	printAnalysis(ctx, ops)
 	if !ctx.Bool("keep-data") && !ctx.Bool("noclear") {
@@ -261,6 +711,12 @@ func runBench(ctx *cli.Context, b bench.Benchmark) error {
 		b.Cleanup(context.Background())
 	}
 	monitor.InfoLn("基准测试数据已清理完毕.")
+	if n := c.CleanupFailed(); n > 0 {
+		console.Errorf("警告: 清理时桶 %q 中有 %d 个对象无法删除, 请手动清理.\n", c.Bucket, n)
+	}
+	if firstFailed != nil {
+		fatal(errDummy(), "基准测试因请求操作失败而中止: %s %s: %s", firstFailed.OpType, firstFailed.File, firstFailed.Err)
+	}
 	return nil
 }
 
@@ -362,11 +818,31 @@ func runClientBenchmark(ctx *cli.Context, b bench.Benchmark, cb *clientBenchmark
 	ctx2, cancel := context.WithCancel(cb.ctx)
 	defer cancel()
 	cb.Unlock()
+	b.GetCommon().Spans = newSpanExporter(ctx)
+	// Make sure the root span is ended if the benchmark fails.
+	defer b.GetCommon().Spans.Close()
+	failed := applyCommonFlags(ctx, b.GetCommon())
+
+	// If the benchmark is aborted, remove whatever was uploaded,
+	// so no data is left behind when the server goes away.
+	cleaned := false
+	defer func() {
+		if cleaned || cb.ctx.Err() == nil {
+			return
+		}
+		if !ctx.Bool("keep-data") && !ctx.Bool("noclear") {
+			console.Infoln("基准测试已中止, 开始清理数据 ...")
+			b.Cleanup(context.Background())
+		}
+	}()
 	err = b.Prepare(ctx2)
 	cb.stageDone(stagePrepare, err)
 	if err != nil {
 		return err
 	}
+	if n := b.GetCommon().PrepareRetried(); n > 0 {
+		console.Infof("准备阶段有 %d 个上传失败后重试成功, 集群在开始时可能不稳定.\n", n)
+	}
 
 	// Start after waiting a second or until we reached the start time.
 	benchDur := ctx.Duration("duration")
@@ -398,7 +874,21 @@ func runClientBenchmark(ctx *cli.Context, b bench.Benchmark, cb *clientBenchmark
 		fileName = fmt.Sprintf("%s-%s-%s-%s", appName, ctx.Command.Name, time.Now().Format("2006-01-02[150405]"), cID)
 	}
 
+	localProf := startLocalProfiling(ctx, fileName)
+	prepareDone := time.Now()
+	failedOp := watchFailFast(ctx2, cancel, failed, console.Errorln)
+	stopJitter := b.GetCommon().StartJitter(ctx2, start)
+	stopHealth := b.GetCommon().StartHealthCheck(ctx2, start)
+	go printLiveStats(ctx2, ctx, b.GetCommon().Live, start, console.Infoln)
 	ops, err := b.Start(ctx2, start)
+	cancel()
+	firstFailed := failedOp()
+	gaps := stopHealth()
+	printAvailabilityGaps(gaps, console.Errorln)
+	comment := commandLine(ctx) + concurrencyMultipleComment(ctx) + authComment(ctx) + versionsComment(ctx) + expiresComment(b) + concurrencyComment(b.GetCommon()) + partitionComment(ctx, b.GetCommon().Concurrency) + jitterComment(stopJitter()) + healthComment(gaps)
+	b.GetCommon().Spans.Close()
+	localProf.stop()
+	ops.SetPrepare(prepareDone)
 	cb.Lock()
 	cb.results = ops
 	cb.Unlock()
@@ -409,22 +899,28 @@ func runClientBenchmark(ctx *cli.Context, b bench.Benchmark, cb *clientBenchmark
 	ops.SetClientID(cID)
 	ops.SortByStartTime()
 
-	f, err := os.Create(fileName + ".csv.zst")
+	var uploads []string
+	outName := benchDataFileName(ctx, fileName)
+	f, err := createBenchDataFile(ctx, outName)
 	if err != nil {
 		console.Error("无法写入基准测试数据:", err)
 	} else {
 		func() {
 			defer f.Close()
-			enc, err := zstd.NewWriter(f, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
-			fatalIf(probe.NewError(err), "无法压缩基准测试数据到输出")
-
-			defer enc.Close()
-			err = ops.CSV(enc, commandLine(ctx))
+			err = ops.CSV(f, comment)
 			fatalIf(probe.NewError(err), "无法写入基准测试数据到输出")
 
-			console.Infof("基准测试数据写入到了 %q\n", fileName+".csv.zst")
+			console.Infof("基准测试数据写入到了 %q\n", outName)
+			uploads = append(uploads, outName)
 		}()
 	}
+	if fn, err := writeBenchConfig(ctx, fileName); err != nil {
+		console.Error("无法写入基准测试配置:", err)
+	} else if fn != "" {
+		console.Infof("基准测试配置写入到了 %q\n", fn)
+		uploads = append(uploads, fn)
+	}
+	uploadBenchData(ctx, console.Infoln, console.Errorln, uploads...)
 
 	err = cb.waitForStage(stageCleanup)
 	if err != nil {
@@ -433,10 +929,18 @@ func runClientBenchmark(ctx *cli.Context, b bench.Benchmark, cb *clientBenchmark
 	if !ctx.Bool("keep-data") && !ctx.Bool("noclear") {
 		console.Infoln("开始清理数据 ...")
 		b.Cleanup(context.Background())
+		if n := b.GetCommon().CleanupFailed(); n > 0 {
+			console.Errorf("警告: 清理时桶 %q 中有 %d 个对象无法删除, 请手动清理.\n", b.GetCommon().Bucket, n)
+		}
 	}
-	cb.stageDone(stageCleanup, nil)
+	cleaned = true
+	if firstFailed != nil {
+		// Report the failure after the benchmark data has been downloaded by the server.
+		err = fmt.Errorf("基准测试因请求操作失败而中止: %s %s: %s", firstFailed.OpType, firstFailed.File, firstFailed.Err)
+	}
+	cb.stageDone(stageCleanup, err)
 
-	return nil
+	return err
 }
 
 type runningProfiles struct {
@@ -487,6 +991,89 @@ func (rp *runningProfiles) stop(ctx2 context.Context, ctx *cli.Context, fileName
 	console.Infof("配置文件数据已成功下载为 %s\n", fileName)
 }
 
+// localProfiles are profiles of warp itself while running a benchmark.
+type localProfiles struct {
+	cpu     *os.File
+	memFile string
+	trace   *os.File
+}
+
+// startLocalProfiling will start profiling warp itself when the global
+// --cpu and --mem flags or --client-trace are set. Profiles are written to --pprofdir.
+func startLocalProfiling(ctx *cli.Context, fileName string) *localProfiles {
+	if !ctx.GlobalBool("cpu") && !ctx.GlobalBool("mem") && !ctx.Bool("client-trace") {
+		return nil
+	}
+	dir := ctx.GlobalString("pprofdir")
+	if dir == "" {
+		// Benchmarks started by a server have no global flags.
+		dir = "pprof"
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		console.Error("无法创建配置文件目录:", err)
+		return nil
+	}
+	base := filepath.Join(dir, filepath.Base(fileName))
+	var lp localProfiles
+	if ctx.GlobalBool("cpu") {
+		f, err := os.Create(base + ".cpu.pprof")
+		if err != nil {
+			console.Error("无法写入 CPU 配置文件:", err)
+		} else if err := pprof.StartCPUProfile(f); err != nil {
+			console.Error("无法启动 CPU 配置文件:", err)
+			f.Close()
+		} else {
+			lp.cpu = f
+		}
+	}
+	if ctx.GlobalBool("mem") {
+		lp.memFile = base + ".mem.pprof"
+	}
+	if ctx.Bool("client-trace") {
+		f, err := os.Create(base + ".trace.out")
+		if err != nil {
+			console.Error("无法写入客户端跟踪文件:", err)
+		} else if err := trace.Start(f); err != nil {
+			console.Error("无法启动客户端跟踪:", err)
+			f.Close()
+		} else {
+			lp.trace = f
+		}
+	}
+	return &lp
+}
+
+// stop will stop the CPU profile and write the memory profile.
+func (lp *localProfiles) stop() {
+	if lp == nil {
+		return
+	}
+	if lp.cpu != nil {
+		pprof.StopCPUProfile()
+		lp.cpu.Close()
+		console.Infof("CPU 配置文件已写入到 %s\n", lp.cpu.Name())
+	}
+	if lp.trace != nil {
+		trace.Stop()
+		lp.trace.Close()
+		console.Infof("客户端跟踪已写入到 %s, 可以使用 'go tool trace' 查看\n", lp.trace.Name())
+	}
+	if lp.memFile != "" {
+		f, err := os.Create(lp.memFile)
+		if err != nil {
+			console.Error("无法写入内存配置文件:", err)
+			return
+		}
+		defer f.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			console.Error("无法写入内存配置文件:", err)
+			return
+		}
+		console.Infof("内存配置文件已写入到 %s\n", lp.memFile)
+	}
+}
+
 func checkBenchmark(ctx *cli.Context) {
 	profilerTypes := []madmin.ProfilerType{
 		madmin.ProfilerCPU,
@@ -519,6 +1106,32 @@ func checkBenchmark(ctx *cli.Context) {
 			fatalIf(errDummy(), "syncstart 已通过: %v", t)
 		}
 	}
+	if ctx.Int("prepare.concurrent") < 0 {
+		fatalIf(errDummy(), "prepare.concurrent 的值不能是负数")
+	}
+	if ctx.Int("prepare-retries") < 0 {
+		fatalIf(errDummy(), "prepare-retries 的值不能是负数")
+	}
+	for _, flag := range opTimeoutFlags {
+		if ctx.Duration(flag) < 0 {
+			fatalIf(errDummy(), "%s 的值不能是负数", flag)
+		}
+	}
+	maxBufferMemory(ctx)
+	if ctx.Duration("healthcheck-interval") < 0 {
+		fatalIf(errDummy(), "healthcheck-interval 的值不能是负数")
+	}
+	if ctx.Bool("collect-server-metrics") && ctx.Duration("collect-server-metrics.interval") <= 0 {
+		fatalIf(errDummy(), "collect-server-metrics.interval 的值不能是 0 或者负数")
+	}
+	if ctx.Bool("anonymous") && !anonymousBenchmarks[ctx.Command.Name] {
+		fatalIf(errDummy(), "--anonymous 只支持 get, stat 和 list 基准测试")
+	}
+	for _, flag := range opTimeoutFlags {
+		if ctx.Duration(flag) > 0 && !timeoutBenchmarks[ctx.Command.Name] {
+			fatalIf(errDummy(), "--%s 不支持 %s 基准测试", flag, ctx.Command.Name)
+		}
+	}
 	if ctx.Bool("autoterm") {
 		// TODO: autoterm cannot be used when in client/server mode
 		if ctx.Duration("autoterm.dur") <= 0 {
@@ -527,7 +1140,49 @@ func checkBenchmark(ctx *cli.Context) {
 		if ctx.Float64("autoterm.pct") <= 0 {
 			fatalIf(errDummy(), "autoterm.pct 的值不能是 0 或者负数")
 		}
+		switch bench.AutoTermMetric(strings.ToLower(ctx.String("autoterm.metric"))) {
+		case bench.AutoTermThroughput, bench.AutoTermLatency, bench.AutoTermBoth:
+		default:
+			fatalIf(errDummy(), "autoterm.metric 必须是 'throughput', 'latency' 或 'both'")
+		}
+	}
+	if pct := ctx.Float64("otel-sample"); pct <= 0 || pct > 1 {
+		fatalIf(errDummy(), "otel-sample 的值必须在 0 到 1 之间")
+	}
+	if _, err := benchDataEncoderLevel(ctx); err != nil {
+		fatalIf(probe.NewError(err), "无效的 benchdata.compression 参数")
+	}
+	if dst := ctx.String("benchdata-s3"); dst != "" {
+		if _, _, err := parseS3URL(dst); err != nil {
+			fatalIf(probe.NewError(err), "无效的 benchdata-s3 参数")
+		}
+	}
+	checkRunTag(ctx)
+	if spec := ctx.String("concurrent.jitter"); spec != "" {
+		if _, err := bench.ParseConcurrencyJitter(spec); err != nil {
+			fatalIf(probe.NewError(err), "无效的 concurrent.jitter 参数")
+		}
+		if ctx.Bool("autoterm") {
+			fatalIf(errDummy(), "concurrent.jitter 不能与 autoterm 同时使用")
+		}
+	}
+	if concurrency(ctx) > math.MaxUint16 {
+		fatalIf(errDummy(), "concurrent 的值不能超过 %d", math.MaxUint16)
+	}
+	if ctx.IsSet("dst-bucket") && ctx.Duration("dst-bucket.wait") <= 0 {
+		fatalIf(errDummy(), "dst-bucket.wait 的值不能是 0 或者负数")
+	}
+}
+
+// newSpanExporter returns a span exporter if an OpenTelemetry endpoint has been specified.
+func newSpanExporter(ctx *cli.Context) *bench.SpanExporter {
+	ep := ctx.String("otel-endpoint")
+	if ep == "" {
+		return nil
 	}
+	spans := bench.NewSpanExporter(ep, appName+" "+ctx.Command.Name, ctx.Float64("otel-sample"))
+	spans.Error = printError
+	return spans
 }
 
 // time format for start time.
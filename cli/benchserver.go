@@ -22,14 +22,13 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
-	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
-	"github.com/klauspost/compress/zstd"
 	"github.com/minio/cli"
 	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/warp/api"
@@ -86,6 +85,9 @@ func runServerBenchmark(ctx *cli.Context) (bool, error) {
 	if ctx.String("warp-client") == "" {
 		return false, nil
 	}
+	if ctx.GlobalBool("cpu") || ctx.GlobalBool("mem") {
+		fatal(errInvalidArgument(), "--cpu 和 --mem 不能与 --warp-client 一起使用, 请在客户端上指定, 例如 'warp --cpu client'")
+	}
 
 	conns := newConnections(parseHosts(ctx.String("warp-client")))
 	if len(conns.hosts) == 0 {
@@ -157,6 +159,9 @@ func runServerBenchmark(ctx *cli.Context) (bool, error) {
 	if err != nil {
 		return true, err
 	}
+	metricsStart := make(chan struct{})
+	time.AfterFunc(benchmarkWait, func() { close(metricsStart) })
+	metrics := startServerMetrics(context.Background(), ctx, prof, metricsStart)
 	err = conns.startStageAll(stageBenchmark, time.Now().Add(benchmarkWait), false)
 	if err != nil {
 		errorLn("无法启动所有客户端", err)
@@ -164,7 +169,7 @@ func runServerBenchmark(ctx *cli.Context) (bool, error) {
 	infoLn("正在所有客户端上运行基准测试 ...")
 	err = conns.waitForStage(stageBenchmark, false)
 	if err != nil {
-		errorLn("无法保持与所有客户端的连接", err)
+		errorLn("无法在所有客户端上完成基准测试", err)
 	}
 
 	fileName := ctx.String("benchdata")
@@ -172,6 +177,7 @@ func runServerBenchmark(ctx *cli.Context) (bool, error) {
 		fileName = fmt.Sprintf("%s-%s-%s-%s", appName, "remote", time.Now().Format("2006-01-02[150405]"), pRandASCII(4))
 	}
 	prof.stop(context.Background(), ctx, fileName+".profiles.zip")
+	metrics.stop(fileName + ".server-metrics.json")
 
 	infoLn("已完成. 正在下载相关的请求操作 ...")
 	downloaded := conns.downloadOps()
@@ -188,22 +194,28 @@ func runServerBenchmark(ctx *cli.Context) (bool, error) {
 	}
 
 	allOps.SortByStartTime()
-	f, err := os.Create(fileName + ".csv.zst")
+	var uploads []string
+	outName := benchDataFileName(ctx, fileName)
+	f, err := createBenchDataFile(ctx, outName)
 	if err != nil {
 		errorLn("无法写入基准测试数据:", err)
 	} else {
 		func() {
 			defer f.Close()
-			enc, err := zstd.NewWriter(f, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
-			fatalIf(probe.NewError(err), "无法压缩基准测试数据到输出")
-
-			defer enc.Close()
-			err = allOps.CSV(enc, commandLine(ctx))
+			err = allOps.CSV(f, commandLine(ctx))
 			fatalIf(probe.NewError(err), "无法写入基准测试数据到输出")
 
-			infoLn(fmt.Sprintf("基准测试数据写入到了 %q\n", fileName+".csv.zst"))
+			infoLn(fmt.Sprintf("基准测试数据写入到了 %q\n", outName))
+			uploads = append(uploads, outName)
 		}()
 	}
+	if fn, err := writeBenchConfig(ctx, fileName); err != nil {
+		errorLn("无法写入基准测试配置:", err)
+	} else if fn != "" {
+		infoLn(fmt.Sprintf("基准测试配置写入到了 %q\n", fn))
+		uploads = append(uploads, fn)
+	}
+	uploadBenchData(ctx, infoLn, errorLn, uploads...)
 	monitor.OperationsReady(allOps, fileName, commandLine(ctx))
 	printAnalysis(ctx, allOps)
 
@@ -213,7 +225,7 @@ func runServerBenchmark(ctx *cli.Context) (bool, error) {
 	}
 	err = conns.waitForStage(stageCleanup, false)
 	if err != nil {
-		errorLn("无法保持与所有客户端的连接", err)
+		fatalIf(probe.NewError(err), "客户端清理数据时返回了错误")
 	}
 	infoLn("数据清理完成.\n")
 
@@ -452,8 +464,10 @@ func (c *connections) downloadOps() []bench.Operations {
 }
 
 // waitForStage will wait for stage completion on all clients.
+// If failOnErr is not set, an error is returned when any client failed the stage.
 func (c *connections) waitForStage(stage benchmarkStage, failOnErr bool) error {
 	var wg sync.WaitGroup
+	var failed int32
 	for i, conn := range c.ws {
 		if conn == nil {
 			// log?
@@ -474,6 +488,7 @@ func (c *connections) waitForStage(stage benchmarkStage, failOnErr bool) error {
 						fatalIf(probe.NewError(err), "阶段失败.")
 					}
 					c.errLn(err)
+					atomic.AddInt32(&failed, 1)
 					return
 				}
 				if resp.Err != "" {
@@ -482,6 +497,7 @@ func (c *connections) waitForStage(stage benchmarkStage, failOnErr bool) error {
 						fatalIf(probe.NewError(errors.New(resp.Err)), "阶段失败. 客户端 %v 返回了错误.", c.hostName(i))
 					}
 					c.errorF("客户端 %v 返回了错误: %v\n", c.hostName(i), resp.Err)
+					atomic.AddInt32(&failed, 1)
 					return
 				}
 				if resp.StageInfo.Finished {
@@ -493,46 +509,65 @@ func (c *connections) waitForStage(stage benchmarkStage, failOnErr bool) error {
 		}(i)
 	}
 	wg.Wait()
+	if n := atomic.LoadInt32(&failed); n > 0 {
+		return fmt.Errorf("%d 个客户端在阶段 %s 失败", n, stage)
+	}
 	return nil
 }
 
 // flagToJSON converts a flag to a representation that can be reversed into the flag.
 func flagToJSON(ctx *cli.Context, flag cli.Flag) (string, error) {
+	return flagValue(ctx, flag, false)
+}
+
+// primaryFlagName returns the name of the flag without aliases.
+func primaryFlagName(flag cli.Flag) string {
+	return strings.TrimSpace(strings.Split(flag.GetName(), ",")[0])
+}
+
+// flagValue returns the value of the flag as a string.
+// Unless all is set, an empty string is returned for flags that are not set.
+func flagValue(ctx *cli.Context, flag cli.Flag, all bool) (string, error) {
+	name := primaryFlagName(flag)
 	switch flag.(type) {
 	case cli.StringFlag:
-		if ctx.IsSet(flag.GetName()) {
-			return ctx.String(flag.GetName()), nil
+		if all || ctx.IsSet(name) {
+			return ctx.String(name), nil
 		}
 	case cli.BoolFlag:
-		if ctx.IsSet(flag.GetName()) {
-			return fmt.Sprint(ctx.Bool(flag.GetName())), nil
+		if all || ctx.IsSet(name) {
+			return fmt.Sprint(ctx.Bool(name)), nil
+		}
+	case cli.BoolTFlag:
+		if all || ctx.IsSet(name) {
+			return fmt.Sprint(ctx.BoolT(name)), nil
 		}
 	case cli.Int64Flag:
-		if ctx.IsSet(flag.GetName()) {
-			return fmt.Sprint(ctx.Int64(flag.GetName())), nil
+		if all || ctx.IsSet(name) {
+			return fmt.Sprint(ctx.Int64(name)), nil
 		}
 	case cli.IntFlag:
-		if ctx.IsSet(flag.GetName()) {
-			return fmt.Sprint(ctx.Int(flag.GetName())), nil
+		if all || ctx.IsSet(name) {
+			return fmt.Sprint(ctx.Int(name)), nil
 		}
 	case cli.DurationFlag:
-		if ctx.IsSet(flag.GetName()) {
-			return ctx.Duration(flag.GetName()).String(), nil
+		if all || ctx.IsSet(name) {
+			return ctx.Duration(name).String(), nil
 		}
 	case cli.UintFlag:
-		if ctx.IsSet(flag.GetName()) {
-			return fmt.Sprint(ctx.Uint(flag.GetName())), nil
+		if all || ctx.IsSet(name) {
+			return fmt.Sprint(ctx.Uint(name)), nil
 		}
 	case cli.Uint64Flag:
-		if ctx.IsSet(flag.GetName()) {
-			return fmt.Sprint(ctx.Uint64(flag.GetName())), nil
+		if all || ctx.IsSet(name) {
+			return fmt.Sprint(ctx.Uint64(name)), nil
 		}
 	case cli.Float64Flag:
-		if ctx.IsSet(flag.GetName()) {
-			return fmt.Sprint(ctx.Float64(flag.GetName())), nil
+		if all || ctx.IsSet(name) {
+			return fmt.Sprint(ctx.Float64(name)), nil
 		}
 	default:
-		if ctx.IsSet(flag.GetName()) {
+		if all || ctx.IsSet(name) {
 			return "", fmt.Errorf("unhandled flag type: %T", flag)
 		}
 	}
@@ -0,0 +1,141 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package cli
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"sync/atomic"
+
+	"github.com/minio/cli"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+)
+
+// runTagKey is the object tag key used for --run-tag.
+const runTagKey = "warp-run"
+
+// validRunTag matches the characters allowed in S3 tag values.
+var validRunTag = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]{1,256}$`)
+
+var cleanupCmd = cli.Command{
+	Name:   "cleanup",
+	Usage:  "删除使用 --run-tag 标记的对象",
+	Action: mainCleanup,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} --run-tag=<tag> [FLAGS]
+  -> see https://github.com/minio/warp#cleanup
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// mainCleanup is the entry point for cleanup command.
+// All objects in the bucket are listed and objects with a matching
+// run tag are deleted.
+func mainCleanup(ctx *cli.Context) error {
+	checkCleanupSyntax(ctx)
+	tag := ctx.String("run-tag")
+	bucket := ctx.String("bucket")
+	client := newClient(ctx)
+	bgCtx := context.Background()
+
+	cl, done := client()
+	defer done()
+	console.Infof("正在删除桶 %q 中标签为 %s=%s 的对象...\n", bucket, runTagKey, tag)
+
+	var checked, matched, failed, removeFailed int64
+	candidates := make(chan minio.ObjectInfo, 1000)
+	remove := make(chan minio.ObjectInfo, 1000)
+	go func() {
+		defer close(candidates)
+		for obj := range cl.ListObjects(bgCtx, bucket, minio.ListObjectsOptions{Recursive: true}) {
+			if obj.Err != nil {
+				console.Errorln("列出对象出错:", obj.Err)
+				atomic.AddInt64(&failed, 1)
+				return
+			}
+			candidates <- obj
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency(ctx); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cl, done := client()
+			defer done()
+			for obj := range candidates {
+				atomic.AddInt64(&checked, 1)
+				t, err := cl.GetObjectTagging(bgCtx, bucket, obj.Key, minio.GetObjectTaggingOptions{})
+				if err != nil {
+					console.Errorln("获取对象标签出错:", obj.Key, err)
+					atomic.AddInt64(&failed, 1)
+					continue
+				}
+				if t.ToMap()[runTagKey] != tag {
+					continue
+				}
+				atomic.AddInt64(&matched, 1)
+				remove <- minio.ObjectInfo{Key: obj.Key}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(remove)
+	}()
+
+	for err := range cl.RemoveObjects(bgCtx, bucket, remove, minio.RemoveObjectsOptions{}) {
+		console.Errorln("删除对象出错:", err.ObjectName, err.Err)
+		atomic.AddInt64(&failed, 1)
+		removeFailed++
+	}
+	console.Infof("已检查 %d 个对象, 删除了 %d 个对象.\n", checked, matched-removeFailed)
+	if failed > 0 {
+		console.Fatalf("%d 个操作失败\n", failed)
+	}
+	return nil
+}
+
+func checkCleanupSyntax(ctx *cli.Context) {
+	if ctx.NArg() > 0 {
+		console.Fatal("命令中没有附带参数")
+	}
+	if ctx.String("run-tag") == "" {
+		console.Fatal("需要指定 --run-tag")
+	}
+	if concurrency(ctx) <= 0 {
+		console.Fatal("concurrent 的值必须大于 0")
+	}
+	checkRunTag(ctx)
+}
+
+// checkRunTag verifies that the run tag is a valid object tag value.
+func checkRunTag(ctx *cli.Context) {
+	if tag := ctx.String("run-tag"); tag != "" && !validRunTag.MatchString(tag) {
+		console.Fatal("无效的 run-tag 值: ", tag)
+	}
+}
@@ -78,6 +78,10 @@ func Main(args []string) {
 		globalTermWidth = w
 	}
 
+	// Load flags from '@file' arguments.
+	args, err := expandConfigArgs(args, combineFlags(profileFlags, globalFlags), appCmds)
+	fatalIf(probe.NewError(err), "无法加载配置文件")
+
 	// Set the warp app name.
 	appName := filepath.Base(args[0])
 
@@ -91,17 +95,31 @@ func init() {
 		mixedCmd,
 		getCmd,
 		putCmd,
+		postCmd,
+		presignCmd,
+		rmwCmd,
 		deleteCmd,
 		listCmd,
 		statCmd,
+		copyCmd,
+		metaUpdateCmd,
+		replicateCmd,
+		metaCmd,
 		selectCmd,
 		versionedCmd,
+		restoreCmd,
+		legalHoldCmd,
+		renameCmd,
+		replayCmd,
+		lifecycleCmd,
 	}
 	b := []cli.Command{
 		analyzeCmd,
 		cmpCmd,
 		mergeCmd,
 		clientCmd,
+		cleanupCmd,
+		doctorCmd,
 	}
 	appCmds = append(a, b...)
 	benchCmds = a
@@ -109,6 +127,16 @@ func init() {
 
 var appCmds, benchCmds []cli.Command
 
+// isBenchCmd returns whether the named command is a benchmark.
+func isBenchCmd(name string) bool {
+	for _, cmd := range benchCmds {
+		if cmd.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func combineFlags(flags ...[]cli.Flag) []cli.Flag {
 	var dst []cli.Flag
 	for _, fl := range flags {
@@ -171,10 +199,12 @@ func registerApp(name string, appCmds []cli.Command) *cli.App {
 			CloserHook:          nil,
 			Logger:              nil,
 		}
-		if ctx.Bool("cpu") {
+		// Benchmarks profile CPU and memory only while running the benchmark.
+		benchCmd := isBenchCmd(ctx.Args().First())
+		if ctx.Bool("cpu") && !benchCmd {
 			profiles = append(profiles, mprofile.CPUProfile(cfg).Start())
 		}
-		if ctx.Bool("mem") {
+		if ctx.Bool("mem") && !benchCmd {
 			profiles = append(profiles, mprofile.MemProfile(cfg).Start())
 		}
 		if ctx.Bool("block") {
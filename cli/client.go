@@ -21,13 +21,16 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
-	"log"
+	"fmt"
+	"io/ioutil"
 	"math"
 	"math/rand"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/minio/cli"
@@ -35,10 +38,12 @@ import (
 	md5simd "github.com/minio/md5-simd"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/signer"
 	"github.com/minio/minio/pkg/console"
 	"github.com/minio/minio/pkg/ellipses"
 	"github.com/minio/minio/pkg/madmin"
 	"github.com/minio/warp/pkg"
+	"github.com/minio/warp/pkg/bench"
 	"golang.org/x/net/http2"
 )
 
@@ -49,48 +54,111 @@ const (
 	hostSelectTypeWeighed    hostSelectType = "weighed"
 )
 
+// newClient returns a client selector for all hosts.
+// If --creds-file is set, each call will use the next set of credentials.
+// Hosts are selected the same way for all credentials.
 func newClient(ctx *cli.Context) func() (cl *minio.Client, done func()) {
-	hosts := parseHosts(ctx.String("host"))
+	creds := readCredsFile(ctx)
+	if len(creds) == 0 {
+		return newClientFn(ctx, getClient)
+	}
+	tenants := make([]func(ctx *cli.Context, host string) (*minio.Client, error), len(creds))
+	for i, c := range creds {
+		c := c
+		tenants[i] = func(ctx *cli.Context, host string) (*minio.Client, error) {
+			return getClientCreds(ctx, host, staticCreds(ctx, c[0], c[1]))
+		}
+	}
+	return newClientFn(ctx, tenants...)
+}
+
+// readCredsFile returns the access and secret keys in the file given by --creds-file.
+// Each non-empty line must contain 'access-key:secret-key'. Lines starting with '#' are ignored.
+func readCredsFile(ctx *cli.Context) [][2]string {
+	fn := ctx.String("creds-file")
+	if fn == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(fn)
+	fatalIf(probe.NewError(err), "无法读取凭证文件")
+	var creds [][2]string
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.IndexByte(line, ':')
+		if idx <= 0 || idx == len(line)-1 {
+			fatal(errInvalidArgument(), "凭证文件 %s 第 %d 行格式无效, 应为 'access-key:secret-key'", fn, i+1)
+		}
+		creds = append(creds, [2]string{line[:idx], line[idx+1:]})
+	}
+	if len(creds) == 0 {
+		fatal(errInvalidArgument(), "凭证文件中没有凭证: %s", fn)
+	}
+	return creds
+}
+
+// newAnonymousClient returns clients sending unauthenticated requests.
+func newAnonymousClient(ctx *cli.Context) func() (cl *minio.Client, done func()) {
+	return newClientFn(ctx, getAnonymousClient)
+}
+
+// newClientFn returns a client selector for all hosts using getClient to create clients.
+// With more than one getClient, each call will use the next one for the selected host.
+func newClientFn(ctx *cli.Context, getClient ...func(ctx *cli.Context, host string) (*minio.Client, error)) func() (cl *minio.Client, done func()) {
+	hosts, weights, err := parseHostWeights(ctx.String("host"))
+	fatalIf(probe.NewError(err), "无法解析主机 host 参数")
+	if len(hosts) > 0 {
+		caps, err := parseHostCaps(ctx.String("host-max-concurrent"), hosts)
+		fatalIf(probe.NewError(err), "无效的 host-max-concurrent 值")
+		if caps != nil {
+			if weights != nil {
+				fatal(errInvalidArgument(), "主机权重不能与 --host-max-concurrent 同时使用")
+			}
+			return newCappedClientFn(ctx, hosts, caps, getClient)
+		}
+	}
+	weight := func(i int) int {
+		if weights == nil {
+			return 1
+		}
+		return weights[i]
+	}
 	switch len(hosts) {
 	case 0:
 		fatalIf(probe.NewError(errors.New("no host defined")), "无法创建 MinIO 客户端")
 	case 1:
-		cl, err := getClient(ctx, hosts[0])
-		fatalIf(probe.NewError(err), "无法创建 MinIO 客户端")
-
+		client := hostClients(ctx, hosts, getClient)
 		return func() (*minio.Client, func()) {
-			return cl, func() {}
+			return client(0), func() {}
 		}
 	}
+	client := hostClients(ctx, hosts, getClient)
 	hostSelect := hostSelectType(ctx.String("host-select"))
 	switch hostSelect {
 	case hostSelectTypeRoundrobin:
 		// Do round-robin.
+		// Hosts with a weight are added that number of times.
 		var current int
 		var mu sync.Mutex
-		clients := make([]*minio.Client, len(hosts))
+		idxs := make([]int, 0, len(hosts))
 		for i := range hosts {
-			cl, err := getClient(ctx, hosts[i])
-			fatalIf(probe.NewError(err), "无法创建 MinIO 客户端")
-			clients[i] = cl
+			for j := 0; j < weight(i); j++ {
+				idxs = append(idxs, i)
+			}
 		}
 		return func() (*minio.Client, func()) {
 			mu.Lock()
-			now := current % len(clients)
+			now := current % len(idxs)
 			current++
 			mu.Unlock()
-			return clients[now], func() {}
+			return client(idxs[now]), func() {}
 		}
 	case hostSelectTypeWeighed:
 		// Keep track of handed out clients.
 		// Select random between the clients that have the fewest handed out.
 		var mu sync.Mutex
-		clients := make([]*minio.Client, len(hosts))
-		for i := range hosts {
-			cl, err := getClient(ctx, hosts[i])
-			fatalIf(probe.NewError(err), "无法创建 MinIO 客户端")
-			clients[i] = cl
-		}
 		running := make([]int, len(hosts))
 		lastFinished := make([]time.Time, len(hosts))
 		{
@@ -103,17 +171,21 @@ func newClient(ctx *cli.Context) func() (cl *minio.Client, done func()) {
 				lastFinished[i] = t
 			}
 		}
+		// load is the number of running requests relative to the weight of the host.
+		load := func(i int) float64 {
+			return float64(running[i]) / float64(weight(i))
+		}
 		find := func() int {
-			min := math.MaxInt32
-			for _, n := range running {
-				if n < min {
-					min = n
+			min := math.MaxFloat64
+			for i := range running {
+				if l := load(i); l < min {
+					min = l
 				}
 			}
 			earliest := time.Now().Add(time.Second)
 			earliestIdx := 0
-			for i, n := range running {
-				if n == min {
+			for i := range running {
+				if load(i) == min {
 					if lastFinished[i].Before(earliest) {
 						earliest = lastFinished[i]
 						earliestIdx = i
@@ -127,7 +199,7 @@ func newClient(ctx *cli.Context) func() (cl *minio.Client, done func()) {
 			idx := find()
 			running[idx]++
 			mu.Unlock()
-			return clients[idx], func() {
+			return client(idx), func() {
 				mu.Lock()
 				lastFinished[idx] = time.Now()
 				running[idx]--
@@ -143,27 +215,158 @@ func newClient(ctx *cli.Context) func() (cl *minio.Client, done func()) {
 	return nil
 }
 
+// parseHostCaps parses the maximum number of concurrent requests for each host
+// given as 'host1:10,host2:50'. Hosts without a limit will have the value 0.
+// If no limits are given nil is returned.
+func parseHostCaps(s string, hosts []string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	caps := make([]int, len(hosts))
+	for _, hc := range strings.Split(s, ",") {
+		hc = strings.TrimSpace(hc)
+		idx := strings.LastIndex(hc, ":")
+		if idx <= 0 {
+			return nil, errors.New("格式应为 'host:并发数': " + hc)
+		}
+		n, err := strconv.Atoi(hc[idx+1:])
+		if err != nil || n <= 0 {
+			return nil, errors.New("无效的并发数: " + hc)
+		}
+		found := false
+		for i, host := range hosts {
+			if host == hc[:idx] {
+				caps[i] = n
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.New("主机不在 --host 中: " + hc[:idx])
+		}
+	}
+	return caps, nil
+}
+
+// newCappedClientFn returns a client selector that will never have more than caps[i]
+// requests in flight to hosts[i]. Hosts with a cap of 0 have no limit.
+// When all hosts are at their limit, the selector blocks until a request finishes.
+func newCappedClientFn(ctx *cli.Context, hosts []string, caps []int, getClient []func(ctx *cli.Context, host string) (*minio.Client, error)) func() (cl *minio.Client, done func()) {
+	client := hostClients(ctx, hosts, getClient)
+	hostSelect := hostSelectType(ctx.String("host-select"))
+	if hostSelect != hostSelectTypeRoundrobin && hostSelect != hostSelectTypeWeighed {
+		console.Fatalln("unknown host-select:", hostSelect)
+	}
+
+	var mu sync.Mutex
+	available := sync.NewCond(&mu)
+	running := make([]int, len(hosts))
+	lastFinished := make([]time.Time, len(hosts))
+	var next int
+	allowed := func(i int) bool {
+		return caps[i] <= 0 || running[i] < caps[i]
+	}
+	// find returns the host to use or -1 if all are at their limit.
+	find := func() int {
+		if hostSelect == hostSelectTypeRoundrobin {
+			for k := range hosts {
+				i := (next + k) % len(hosts)
+				if allowed(i) {
+					next = i + 1
+					return i
+				}
+			}
+			return -1
+		}
+		found := -1
+		for i := range hosts {
+			if !allowed(i) {
+				continue
+			}
+			if found < 0 || running[i] < running[found] ||
+				(running[i] == running[found] && lastFinished[i].Before(lastFinished[found])) {
+				found = i
+			}
+		}
+		return found
+	}
+	return func() (*minio.Client, func()) {
+		mu.Lock()
+		idx := find()
+		for idx < 0 {
+			available.Wait()
+			idx = find()
+		}
+		running[idx]++
+		mu.Unlock()
+		return client(idx), func() {
+			mu.Lock()
+			lastFinished[idx] = time.Now()
+			running[idx]--
+			mu.Unlock()
+			available.Signal()
+		}
+	}
+}
+
+// hostClients creates a client for each host with each getClient.
+// The returned function returns a client for host i,
+// rotating between the getClient functions on each call.
+func hostClients(ctx *cli.Context, hosts []string, getClient []func(ctx *cli.Context, host string) (*minio.Client, error)) func(i int) *minio.Client {
+	clients := make([][]*minio.Client, len(getClient))
+	for t, get := range getClient {
+		clients[t] = make([]*minio.Client, len(hosts))
+		for i, host := range hosts {
+			cl, err := get(ctx, host)
+			fatalIf(probe.NewError(err), "无法创建 MinIO 客户端")
+			clients[t][i] = cl
+		}
+	}
+	if len(clients) == 1 {
+		return func(i int) *minio.Client {
+			return clients[0][i]
+		}
+	}
+	var current uint64
+	return func(i int) *minio.Client {
+		n := atomic.AddUint64(&current, 1)
+		return clients[n%uint64(len(clients))][i]
+	}
+}
+
 // getClient creates a client with the specified host and the options set in the context.
 func getClient(ctx *cli.Context, host string) (*minio.Client, error) {
-	var creds *credentials.Credentials
+	return getClientCreds(ctx, host, staticCreds(ctx, ctx.String("access-key"), ctx.String("secret-key")))
+}
+
+// staticCreds returns credentials for the keys using the signature set in the context.
+func staticCreds(ctx *cli.Context, accessKey, secretKey string) *credentials.Credentials {
 	switch strings.ToUpper(ctx.String("signature")) {
 	case "S3V4":
 		// if Signature version '4' use NewV4 directly.
-		creds = credentials.NewStaticV4(ctx.String("access-key"), ctx.String("secret-key"), "")
+		return credentials.NewStaticV4(accessKey, secretKey, "")
 	case "S3V2":
 		// if Signature version '2' use NewV2 directly.
-		creds = credentials.NewStaticV2(ctx.String("access-key"), ctx.String("secret-key"), "")
+		return credentials.NewStaticV2(accessKey, secretKey, "")
 	default:
 		fatal(probe.NewError(errors.New("未知的签名方法，请提供 S3V2 或者 S3V4 签名")), strings.ToUpper(ctx.String("signature")))
 	}
+	return nil
+}
 
+// getAnonymousClient creates a client sending unsigned requests to the host.
+func getAnonymousClient(ctx *cli.Context, host string) (*minio.Client, error) {
+	return getClientCreds(ctx, host, credentials.NewStaticV4("", "", ""))
+}
+
+// getClientCreds creates a client with the specified host, credentials and the options set in the context.
+func getClientCreds(ctx *cli.Context, host string, creds *credentials.Credentials) (*minio.Client, error) {
 	cl, err := minio.New(host, &minio.Options{
 		Creds:        creds,
 		Secure:       ctx.Bool("tls"),
 		Region:       ctx.String("region"),
 		BucketLookup: minio.BucketLookupAuto,
 		CustomMD5:    md5simd.NewServer().NewHash,
-		Transport:    clientTransport(ctx),
+		Transport:    bench.NewRecorderTransport(clientTransport(ctx)),
 	})
 	if err != nil {
 		return nil, err
@@ -172,6 +375,38 @@ func getClient(ctx *cli.Context, host string) (*minio.Client, error) {
 	return cl, nil
 }
 
+// newSignedDo returns a function that signs raw requests with the credentials
+// set in the context and sends them using the client transport.
+// If --creds-file is set, each call will use the next set of credentials.
+func newSignedDo(ctx *cli.Context) func(req *http.Request) (*http.Response, error) {
+	tr := bench.NewRecorderTransport(clientTransport(ctx))
+	creds := readCredsFile(ctx)
+	if len(creds) == 0 {
+		creds = [][2]string{{ctx.String("access-key"), ctx.String("secret-key")}}
+	}
+	region := ctx.String("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+	v2 := strings.ToUpper(ctx.String("signature")) == "S3V2"
+	var current uint64
+	return func(req *http.Request) (*http.Response, error) {
+		c := creds[atomic.AddUint64(&current, 1)%uint64(len(creds))]
+		accessKey, secretKey := c[0], c[1]
+		if v2 {
+			req = signer.SignV2(*req, accessKey, secretKey, false)
+		} else {
+			req = signer.SignV4(*req, accessKey, secretKey, "", region)
+		}
+		return tr.RoundTrip(req)
+	}
+}
+
+// newRawDo returns a function that sends raw requests unsigned using the client transport.
+func newRawDo(ctx *cli.Context) func(req *http.Request) (*http.Response, error) {
+	return bench.NewRecorderTransport(clientTransport(ctx)).RoundTrip
+}
+
 func clientTransport(ctx *cli.Context) http.RoundTripper {
 	tr := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
@@ -179,7 +414,7 @@ func clientTransport(ctx *cli.Context) http.RoundTripper {
 			Timeout:   10 * time.Second,
 			KeepAlive: 10 * time.Second,
 		}).DialContext,
-		MaxIdleConnsPerHost:   ctx.Int("concurrent"),
+		MaxIdleConnsPerHost:   concurrency(ctx),
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   15 * time.Second,
 		ExpectContinueTimeout: 10 * time.Second,
@@ -191,6 +426,8 @@ func clientTransport(ctx *cli.Context) http.RoundTripper {
 		// Refer:
 		//    https://golang.org/src/net/http/transport.go?h=roundTrip#L1843
 		DisableCompression: true,
+		// Force a new connection for each request.
+		DisableKeepAlives: ctx.Bool("no-keepalive"),
 	}
 	if ctx.Bool("tls") {
 		// Keep TLS config.
@@ -204,35 +441,182 @@ func clientTransport(ctx *cli.Context) http.RoundTripper {
 		if ctx.Bool("insecure") {
 			tlsConfig.InsecureSkipVerify = true
 		}
+		setTLSCerts(ctx, tlsConfig)
 		tr.TLSClientConfig = tlsConfig
 
 		// Because we create a custom TLSClientConfig, we have to opt-in to HTTP/2.
 		// See https://github.com/golang/go/issues/14275
-		http2.ConfigureTransport(tr)
+		err := http2.ConfigureTransport(tr)
+		fatalIf(probe.NewError(err), "无法配置 HTTP/2 传输")
+		if ctx.Bool("http2") {
+			return withUserAgent(ctx, &http2FallbackTransport{rt: tr})
+		}
+	} else if ctx.Bool("http2") {
+		console.Fatal("http2 需要同时指定 --tls")
+	} else if ctx.String("cacert") != "" || ctx.String("client-cert") != "" || ctx.String("client-key") != "" {
+		console.Fatal("cacert, client-cert 和 client-key 需要同时指定 --tls")
 	}
-	return tr
+	return withUserAgent(ctx, tr)
+}
+
+// setTLSCerts will add the CA given by --cacert to the trusted root CAs
+// and the client certificate given by --client-cert and --client-key for mutual TLS.
+func setTLSCerts(ctx *cli.Context, cfg *tls.Config) {
+	if ca := ctx.String("cacert"); ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		fatalIf(probe.NewError(err), "无法读取 CA 证书文件 "+ca)
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			fatal(errInvalidArgument(), "CA 证书文件中没有找到有效的 PEM 证书: "+ca)
+		}
+	}
+	cert, key := ctx.String("client-cert"), ctx.String("client-key")
+	switch {
+	case cert == "" && key == "":
+		return
+	case cert == "" || key == "":
+		fatal(errInvalidArgument(), "双向 TLS 需要同时指定 --client-cert 和 --client-key")
+	}
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	fatalIf(probe.NewError(err), "无法加载客户端证书和私钥")
+	cfg.Certificates = []tls.Certificate{pair}
+}
+
+// withUserAgent will wrap the transport so all requests carry
+// the User-Agent given by --user-agent, if any.
+func withUserAgent(ctx *cli.Context, rt http.RoundTripper) http.RoundTripper {
+	ua := strings.TrimSpace(ctx.String("user-agent"))
+	if ua == "" {
+		return rt
+	}
+	return &userAgentTransport{rt: rt, userAgent: ua}
+}
+
+// userAgentTransport replaces the User-Agent header of all requests.
+type userAgentTransport struct {
+	rt        http.RoundTripper
+	userAgent string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.rt.RoundTrip(req)
+}
+
+// http2FallbackTransport will print a warning if a server
+// doesn't negotiate HTTP/2 when it has been requested.
+type http2FallbackTransport struct {
+	rt   http.RoundTripper
+	once sync.Once
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *http2FallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if resp != nil && resp.ProtoMajor < 2 {
+		t.once.Do(func() {
+			console.Errorf("警告: 服务器 %s 不支持 HTTP/2, 回退到 %s\n", req.URL.Host, resp.Proto)
+		})
+	}
+	return resp, err
 }
 
 // parseHosts will parse the host parameter given.
+// Host weights are ignored.
 func parseHosts(h string) []string {
+	hosts, _, err := parseHostWeights(h)
+	fatalIf(probe.NewError(err), "无法解析主机 host 参数")
+	return hosts
+}
+
+// parseHostWeights will parse the host parameter given.
+// Each host can have a weight added as 'host:port=weight'.
+// Hosts expanded from a pattern all get the weight of the pattern.
+// If no weights are specified, nil weights are returned,
+// otherwise hosts without a weight will have weight 1.
+func parseHostWeights(h string) (dst []string, weights []int, err error) {
 	hosts := strings.Split(h, ",")
-	dst := make([]string, 0, len(hosts))
+	dst = make([]string, 0, len(hosts))
+	var ws []int
+	weighted := false
 	for _, host := range hosts {
-		if !ellipses.HasEllipses(host) {
-			dst = append(dst, host)
-			continue
+		w := 1
+		if idx := strings.LastIndex(host, "="); idx >= 0 {
+			n, err := strconv.Atoi(host[idx+1:])
+			if err != nil || n <= 0 {
+				return nil, nil, errors.New("无效的主机权重, 权重必须是正整数: " + host)
+			}
+			host, w = host[:idx], n
+			weighted = true
 		}
-		patterns, perr := ellipses.FindEllipsesPatterns(host)
-		if perr != nil {
-			fatalIf(probe.NewError(perr), "无法解析主机 host 参数")
-
-			log.Fatal(perr.Error())
+		before := len(dst)
+		if !ellipses.HasEllipses(host) {
+			if strings.ContainsAny(host, "{}") {
+				return nil, nil, errors.New("范围格式应为 {a...b}: " + host)
+			}
+			expanded, err := expandPortRange(host)
+			if err != nil {
+				return nil, nil, err
+			}
+			dst = append(dst, expanded...)
+		} else {
+			patterns, err := ellipses.FindEllipsesPatterns(host)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, labels := range patterns.Expand() {
+				expanded, err := expandPortRange(strings.Join(labels, ""))
+				if err != nil {
+					return nil, nil, err
+				}
+				dst = append(dst, expanded...)
+			}
 		}
-		for _, p := range patterns {
-			dst = append(dst, p.Expand()...)
+		for range dst[before:] {
+			ws = append(ws, w)
 		}
 	}
-	return dst
+	if !weighted {
+		return dst, nil, nil
+	}
+	return dst, ws, nil
+}
+
+// maxPortRange is the maximum number of endpoints a single port range may expand to.
+const maxPortRange = 1024
+
+// expandPortRange will expand a host with a port range,
+// for instance "10.0.0.1:9000-9003" into one host per port.
+// Hosts without a port range are returned as is.
+func expandPortRange(host string) ([]string, error) {
+	idx := strings.LastIndex(host, ":")
+	if idx < 0 || !strings.Contains(host[idx+1:], "-") {
+		return []string{host}, nil
+	}
+	name, ports := host[:idx], host[idx+1:]
+	rng := strings.SplitN(ports, "-", 2)
+	from, err := strconv.Atoi(rng[0])
+	if err != nil {
+		return nil, fmt.Errorf("无法解析主机端口范围 %s: %v", host, err)
+	}
+	to, err := strconv.Atoi(rng[1])
+	if err != nil {
+		return nil, fmt.Errorf("无法解析主机端口范围 %s: %v", host, err)
+	}
+	switch {
+	case from <= 0 || to > 65535:
+		return nil, errors.New("主机端口超出有效范围 (1-65535): " + host)
+	case from > to:
+		return nil, errors.New("主机端口范围的起始端口大于结束端口: " + host)
+	case to-from+1 > maxPortRange:
+		return nil, fmt.Errorf("主机端口范围过大, 最多允许 %d 个端口: %s", maxPortRange, host)
+	}
+	dst := make([]string, 0, to-from+1)
+	for port := from; port <= to; port++ {
+		dst = append(dst, name+":"+strconv.Itoa(port))
+	}
+	return dst, nil
 }
 
 // mustGetSystemCertPool - return system CAs or empty pool in case of error (or windows)
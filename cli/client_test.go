@@ -0,0 +1,129 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseHostWeights(t *testing.T) {
+	tests := []struct {
+		in          string
+		wantHosts   []string
+		wantWeights []int
+		wantErr     bool
+	}{
+		{in: "a:9000", wantHosts: []string{"a:9000"}},
+		{in: "a:9000,b:9000", wantHosts: []string{"a:9000", "b:9000"}},
+		{in: "a:9000=2,b:9000", wantHosts: []string{"a:9000", "b:9000"}, wantWeights: []int{2, 1}},
+		{in: "h{1...3}:9000=2", wantHosts: []string{"h1:9000", "h2:9000", "h3:9000"}, wantWeights: []int{2, 2, 2}},
+		{in: "a:9000-9002", wantHosts: []string{"a:9000", "a:9001", "a:9002"}},
+		{in: "a:9000-9001=3,b:9000", wantHosts: []string{"a:9000", "a:9001", "b:9000"}, wantWeights: []int{3, 3, 1}},
+		{in: "h{1...2}:9000-9001", wantHosts: []string{"h1:9000", "h1:9001", "h2:9000", "h2:9001"}},
+		{in: "server{1...2}.rack{1...2}:9000=2", wantHosts: []string{"server1.rack1:9000", "server2.rack1:9000", "server1.rack2:9000", "server2.rack2:9000"}, wantWeights: []int{2, 2, 2, 2}},
+		{in: "a:9000=0", wantErr: true},
+		{in: "a:9000=-1", wantErr: true},
+		{in: "a:9000=x", wantErr: true},
+		{in: "h{1..3}:9000", wantErr: true},
+		{in: "a:9001-9000", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			hosts, weights, err := parseHostWeights(test.in)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(hosts, test.wantHosts) {
+				t.Errorf("got hosts %v, want %v", hosts, test.wantHosts)
+			}
+			if !reflect.DeepEqual(weights, test.wantWeights) {
+				t.Errorf("got weights %v, want %v", weights, test.wantWeights)
+			}
+		})
+	}
+}
+
+func TestExpandPortRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "a", want: []string{"a"}},
+		{in: "a:9000", want: []string{"a:9000"}},
+		{in: "a:9000-9000", want: []string{"a:9000"}},
+		{in: "a:9000-9002", want: []string{"a:9000", "a:9001", "a:9002"}},
+		{in: "[::1]:9000-9001", want: []string{"[::1]:9000", "[::1]:9001"}},
+		{in: "a:1-1024", want: nil},
+		{in: "a:1-1025", wantErr: true},
+		{in: "a:0-1", wantErr: true},
+		{in: "a:65535-65536", wantErr: true},
+		{in: "a:9001-9000", wantErr: true},
+		{in: "a:x-9000", wantErr: true},
+		{in: "a:9000-", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			got, err := expandPortRange(test.in)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if err != nil || test.want == nil {
+				return
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseHostCaps(t *testing.T) {
+	hosts := []string{"a:9000", "b:9000"}
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: " ", want: nil},
+		{in: "a:9000:10", want: []int{10, 0}},
+		{in: "a:9000:10,b:9000:20", want: []int{10, 20}},
+		{in: " a:9000:10 , b:9000:20 ", want: []int{10, 20}},
+		{in: "a:9000:0", wantErr: true},
+		{in: "a:9000:x", wantErr: true},
+		{in: "10", wantErr: true},
+		{in: ":10", wantErr: true},
+		{in: "c:9000:5", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			got, err := parseHostCaps(test.in, hosts)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
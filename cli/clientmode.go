@@ -28,7 +28,13 @@ import (
 )
 
 var (
-	clientFlags = []cli.Flag{}
+	clientFlags = []cli.Flag{
+		cli.DurationFlag{
+			Name:  "client-idle-timeout",
+			Value: 0,
+			Usage: "如果在此时间内没有收到服务器的命令, 则中止当前基准测试, 清理数据并重新等待连接. 0 表示禁用",
+		},
+	}
 )
 
 // Put command.
@@ -71,6 +77,7 @@ func mainClient(ctx *cli.Context) error {
 	default:
 		fatal(errInvalidArgument(), "参数太多")
 	}
+	clientIdleTimeout = ctx.Duration("client-idle-timeout")
 	http.HandleFunc("/ws", serveWs)
 	console.Infoln("正在监听", addr)
 	fatalIf(probe.NewError(http.ListenAndServe(addr, nil)), "无法启动客户端")
@@ -78,4 +85,7 @@ func mainClient(ctx *cli.Context) error {
 }
 
 func checkClientSyntax(ctx *cli.Context) {
+	if ctx.Duration("client-idle-timeout") < 0 {
+		fatal(errInvalidArgument(), "--client-idle-timeout 不能为负数")
+	}
 }
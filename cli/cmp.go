@@ -18,19 +18,28 @@
 package cli
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fatih/color"
-	"github.com/klauspost/compress/zstd"
 	"github.com/minio/cli"
 	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/minio/pkg/console"
 	"github.com/minio/warp/pkg/bench"
 )
 
-var cmpFlags = []cli.Flag{}
+var cmpFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "compare.out",
+		Value: "",
+		Usage: "将每个请求操作的比较结果以 CSV 格式输出到文件, 使用 '-' 输出到标准输出",
+	},
+}
 
 var cmpCmd = cli.Command{
 	Name:   "cmp",
@@ -43,6 +52,7 @@ var cmpCmd = cli.Command{
 
 使用:
   {{.HelpName}} [FLAGS] before-benchmark-data-file after-benchmark-data-file
+  {{.HelpName}} [FLAGS] benchmark-data-file1 benchmark-data-file2 benchmark-data-file3 ...
   -> see https://github.com/minio/warp#comparing-benchmarks
 
 参数:
@@ -55,26 +65,19 @@ func mainCmp(ctx *cli.Context) error {
 	checkAnalyze(ctx)
 	checkCmp(ctx)
 	args := ctx.Args()
-	var zstdDec, _ = zstd.NewReader(nil)
-	defer zstdDec.Close()
-	log := console.Printf
-	if globalQuiet {
-		log = nil
+	if len(args) == 2 {
+		printCompare(ctx, readBenchDataFile(ctx, args[0], true), readBenchDataFile(ctx, args[1], true))
+		return nil
 	}
-	readOps := func(s string) bench.Operations {
-		f, err := os.Open(s)
-		fatalIf(probe.NewError(err), "无法打开输入文件")
-		defer f.Close()
-		err = zstdDec.Reset(f)
-		fatalIf(probe.NewError(err), "无法读取输入文件")
-		ops, err := bench.OperationsFromCSV(zstdDec, true, ctx.Int("analyze.offset"), ctx.Int("analyze.limit"), log)
-		fatalIf(probe.NewError(err), "无法解析输入文件")
-		return ops
+	runs := make([]bench.Operations, len(args))
+	for i, arg := range args {
+		runs[i] = readBenchDataFile(ctx, arg, true)
 	}
-	printCompare(ctx, readOps(args[0]), readOps(args[1]))
+	printTrend(ctx, args, runs)
 	return nil
 }
 
+// printCompare prints the differences between before and after for each operation type.
 func printCompare(ctx *cli.Context, before, after bench.Operations) {
 	var wrSegs io.Writer
 
@@ -89,7 +92,6 @@ func printCompare(ctx *cli.Context, before, after bench.Operations) {
 			wrSegs = f
 		}
 	}
-	_ = wrSegs
 	isMultiOp := before.IsMixed()
 	if isMultiOp != after.IsMixed() {
 		console.Fatal("无法将多个请求操作与单个请求操作进行比较.")
@@ -98,6 +100,7 @@ func printCompare(ctx *cli.Context, before, after bench.Operations) {
 		start, end := ops.ActiveTimeRange(!isMultiOp)
 		return end.Sub(start).Round(time.Second)
 	}
+	var res []*bench.Comparison
 
 	for _, typ := range before.OpTypes() {
 		if wantOp := ctx.String("analyze.op"); wantOp != "" {
@@ -117,6 +120,7 @@ func printCompare(ctx *cli.Context, before, after bench.Operations) {
 			console.Println(err)
 			continue
 		}
+		res = append(res, cmp)
 
 		if len(before) != len(after) {
 			console.Println("请求操作:", len(before), "->", len(after))
@@ -136,6 +140,16 @@ func printCompare(ctx *cli.Context, before, after bench.Operations) {
 			console.Println("持续时间:", timeDur(before), "->", timeDur(after))
 		}
 		console.Println("* 平均值:", cmp.Average)
+		if cmp.Errors.Before > 0 || cmp.Errors.After > 0 {
+			if cmp.Errors.Increased() {
+				console.SetColor("Print", color.New(color.FgHiRed))
+			}
+			console.Println("* 错误:", cmp.Errors)
+			console.SetColor("Print", color.New(color.FgWhite))
+			if cmp.Errors.Increased() {
+				console.Println("  错误率增加了, 吞吐量只比较成功的请求操作.")
+			}
+		}
 		if cmp.TTFB != nil {
 			console.Println("首个字节:", cmp.TTFB)
 		}
@@ -146,10 +160,127 @@ func printCompare(ctx *cli.Context, before, after bench.Operations) {
 			console.Println("* 最慢:", cmp.Slowest)
 		}
 	}
+	if wrSegs != nil {
+		err := bench.ComparisonsCSV(wrSegs, res, !isMultiOp)
+		fatalIf(probe.NewError(err), "无法写入比较结果")
+	}
+}
+
+// trendRow is a single metric in a trend table.
+type trendRow struct {
+	name   string
+	value  func(p bench.TrendPoint) float64
+	format func(v float64) string
+}
+
+// printTrend prints a table of the results of each run, with the change from the previous run.
+func printTrend(ctx *cli.Context, names []string, runs []bench.Operations) {
+	isMultiOp := runs[0].IsMixed()
+	for _, ops := range runs[1:] {
+		if isMultiOp != ops.IsMixed() {
+			console.Fatal("无法将多个请求操作与单个请求操作进行比较.")
+		}
+	}
+	console.SetColor("Print", color.New(color.FgWhite))
+	for i, name := range names {
+		console.Printf("#%d: %s\n", i+1, name)
+	}
+	count := func(v float64) string { return strconv.Itoa(int(v)) }
+	perSec := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	dur := func(v float64) string { return time.Duration(v).Round(time.Microsecond).String() }
+	tput := func(v float64) string { return bench.Throughput(v).String() }
+	bps := func(seg func(p bench.TrendPoint) bench.Segment) func(p bench.TrendPoint) float64 {
+		return func(p bench.TrendPoint) float64 {
+			v, _, _ := seg(p).SpeedPerSec()
+			return v * (1 << 20)
+		}
+	}
+	objs := func(seg func(p bench.TrendPoint) bench.Segment) func(p bench.TrendPoint) float64 {
+		return func(p bench.TrendPoint) float64 {
+			_, _, v := seg(p).SpeedPerSec()
+			return v
+		}
+	}
+	average := func(p bench.TrendPoint) bench.Segment { return p.Average }
+	fastest := func(p bench.TrendPoint) bench.Segment { return p.Fastest }
+	median := func(p bench.TrendPoint) bench.Segment { return p.Median }
+	slowest := func(p bench.TrendPoint) bench.Segment { return p.Slowest }
+
+	for _, typ := range runs[0].OpTypes() {
+		if wantOp := ctx.String("analyze.op"); wantOp != "" {
+			if wantOp != typ {
+				continue
+			}
+		}
+		byOp := make([]bench.Operations, len(runs))
+		for i, ops := range runs {
+			byOp[i] = ops.FilterByOp(typ)
+		}
+		console.Println("-------------------")
+		console.SetColor("Print", color.New(color.FgHiWhite))
+		console.Println("请求操作:", typ)
+		console.SetColor("Print", color.New(color.FgWhite))
+
+		points, err := bench.Trend(byOp, analysisDur(ctx, byOp[0].Duration()), !isMultiOp)
+		if err != nil {
+			console.Println(err)
+			continue
+		}
+		rows := []trendRow{
+			{name: "请求操作", value: func(p bench.TrendPoint) float64 { return float64(p.Requests) }, format: count},
+			{name: "并发量", value: func(p bench.TrendPoint) float64 { return float64(p.Threads) }, format: count},
+		}
+		if points[0].Average.TotalBytes > 0 {
+			rows = append(rows,
+				trendRow{name: "平均值 吞吐量", value: bps(average), format: tput},
+				trendRow{name: "最快 吞吐量", value: bps(fastest), format: tput},
+				trendRow{name: "50% 中位数 吞吐量", value: bps(median), format: tput},
+				trendRow{name: "最慢 吞吐量", value: bps(slowest), format: tput},
+			)
+		}
+		rows = append(rows,
+			trendRow{name: "平均值 obj/s", value: objs(average), format: perSec},
+			trendRow{name: "最快 obj/s", value: objs(fastest), format: perSec},
+			trendRow{name: "50% 中位数 obj/s", value: objs(median), format: perSec},
+			trendRow{name: "最慢 obj/s", value: objs(slowest), format: perSec},
+		)
+		if points[0].TTFB.Average > 0 {
+			rows = append(rows, trendRow{name: "首个字节中位数", value: func(p bench.TrendPoint) float64 { return float64(p.TTFB.Median) }, format: dur})
+		}
+
+		var buf bytes.Buffer
+		tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+		fmt.Fprint(tw, "指标")
+		for i := range points {
+			fmt.Fprintf(tw, "\t#%d", i+1)
+		}
+		fmt.Fprintln(tw)
+		for _, row := range rows {
+			fmt.Fprint(tw, row.name)
+			for i, p := range points {
+				v := row.value(p)
+				fmt.Fprint(tw, "\t", row.format(v))
+				if i == 0 {
+					continue
+				}
+				if prev := row.value(points[i-1]); prev != 0 {
+					pct := 100 * (v - prev) / prev
+					if pct > 0 {
+						fmt.Fprintf(tw, " (+%.1f%%)", pct)
+					} else {
+						fmt.Fprintf(tw, " (%.1f%%)", pct)
+					}
+				}
+			}
+			fmt.Fprintln(tw)
+		}
+		tw.Flush()
+		console.Print(buf.String())
+	}
 }
 
 func checkCmp(ctx *cli.Context) {
-	if ctx.NArg() != 2 {
-		console.Fatal("必须提供两个数据源")
+	if ctx.NArg() < 2 {
+		console.Fatal("必须提供至少两个数据源")
 	}
 }
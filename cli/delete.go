@@ -25,11 +25,12 @@ import (
 
 var (
 	deleteFlags = []cli.Flag{
-		cli.IntFlag{
+		cli.StringFlag{
 			Name:  "objects",
-			Value: 25000,
-			Usage: "要上传的对象数.",
+			Value: "25000",
+			Usage: "要上传的对象数. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
 		},
+		objectsWorkingSetFlag,
 		cli.StringFlag{
 			Name:  "obj.size",
 			Value: "1KiB",
@@ -40,6 +41,10 @@ var (
 			Value: 100,
 			Usage: "每批的删除请求操作数.",
 		},
+		cli.BoolTFlag{
+			Name:  "delete.random",
+			Usage: "以随机顺序删除对象. 默认启用, 使用 --delete.random=false 按上传顺序删除对象.",
+		},
 	}
 )
 
@@ -70,19 +75,21 @@ func mainDelete(ctx *cli.Context) error {
 	b := bench.Delete{
 		Common: bench.Common{
 			Client:      newClient(ctx),
-			Concurrency: ctx.Int("concurrent"),
+			Concurrency: concurrency(ctx),
 			Source:      src,
 			Bucket:      ctx.String("bucket"),
 			Location:    "",
 			PutOpts:     putOpts(ctx),
 		},
-		CreateObjects: ctx.Int("objects"),
+		CreateObjects: objectCount(ctx),
 		BatchSize:     ctx.Int("batch"),
+		Sequential:    !ctx.BoolT("delete.random"),
 	}
 	return runBench(ctx, &b)
 }
 
 func checkDeleteSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, "obj.size", ctx.Int("batch")*concurrency(ctx)*4)
 	if ctx.NArg() > 0 {
 		console.Fatal("命令中没有附带参数")
 	}
@@ -91,8 +98,8 @@ func checkDeleteSyntax(ctx *cli.Context) {
 	if ctx.Int("batch") < 1 {
 		console.Fatal("批量大小必须大于等于 1")
 	}
-	wantO := ctx.Int("batch") * ctx.Int("concurrent") * 4
-	if ctx.Int("objects") < wantO {
+	wantO := ctx.Int("batch") * concurrency(ctx) * 4
+	if objectCount(ctx) < wantO {
 		console.Fatalf("对象太少: 请使用 --batch 和 --concurrent 参数进行设置, 有效的基准测试，至少需要 %d 个对象数. 可以使用 --objects=%d 来指定", wantO, wantO)
 	}
 }
@@ -0,0 +1,292 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+)
+
+var doctorCmd = cli.Command{
+	Name:   "doctor",
+	Usage:  "检查连接, 凭证和服务器功能等常见的配置问题",
+	Action: mainDoctor,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} [FLAGS]
+  -> see https://github.com/minio/warp#doctor
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// maxClockSkew is the maximum clock difference accepted by S3 servers.
+const maxClockSkew = 15 * time.Minute
+
+// doctorMultipartSize is the size of the object uploaded to check multipart support.
+// It must be bigger than doctorPartSize, so it is uploaded in two parts.
+const (
+	doctorPartSize      = 5 << 20
+	doctorMultipartSize = doctorPartSize + 1
+)
+
+// doctorResult is the result of a single check.
+type doctorResult struct {
+	name string
+	err  error
+	// hint describes how to fix a failed check.
+	hint string
+	// info is printed for passed checks.
+	info string
+	// skipped is set if the check wasn't run because a previous check failed.
+	skipped bool
+	// optional checks are reported as warnings if they fail.
+	optional bool
+}
+
+// mainDoctor is the entry point for doctor command.
+// Each check is run in order and a report with a hint for each failed check is printed.
+func mainDoctor(ctx *cli.Context) error {
+	checkDoctorSyntax(ctx)
+	bgCtx := context.Background()
+	hosts := parseHosts(ctx.String("host"))
+
+	var results []doctorResult
+	report := func(r doctorResult) {
+		results = append(results, r)
+		printDoctorResult(r)
+	}
+
+	reachable := true
+	for _, host := range hosts {
+		r, skew := doctorConnect(bgCtx, ctx, host)
+		report(r)
+		if r.err != nil {
+			reachable = false
+			continue
+		}
+		report(doctorClockSkew(host, skew))
+	}
+	if !reachable {
+		report(doctorResult{name: "认证", skipped: true})
+	} else {
+		cl, err := getClient(ctx, hosts[0])
+		if err != nil {
+			report(doctorResult{name: "认证", err: err, hint: "检查 --host 和 --signature 参数"})
+		} else {
+			doctorBucket(bgCtx, ctx, cl, report)
+		}
+		report(doctorAdmin(bgCtx, ctx))
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.err != nil && !r.optional {
+			failed++
+		}
+	}
+	if failed > 0 {
+		console.Fatalf("%d 项检查失败\n", failed)
+	}
+	console.Infoln("所有检查均已通过.")
+	return nil
+}
+
+func checkDoctorSyntax(ctx *cli.Context) {
+	if ctx.NArg() > 0 {
+		console.Fatal("命令中没有附带参数")
+	}
+	if hosts := parseHosts(ctx.String("host")); len(hosts) == 0 {
+		console.Fatal("需要指定 --host")
+	}
+}
+
+// printDoctorResult prints a single line for the result and the hint if it failed.
+func printDoctorResult(r doctorResult) {
+	switch {
+	case r.skipped:
+		console.Println("[跳过]", r.name)
+	case r.err != nil && r.optional:
+		console.Errorln("[警告]", r.name+":", r.err)
+		if r.hint != "" {
+			console.Errorln("       建议:", r.hint)
+		}
+	case r.err != nil:
+		console.Errorln("[失败]", r.name+":", r.err)
+		if r.hint != "" {
+			console.Errorln("       建议:", r.hint)
+		}
+	default:
+		if r.info != "" {
+			console.Infoln("[通过]", r.name+":", r.info)
+		} else {
+			console.Infoln("[通过]", r.name)
+		}
+	}
+}
+
+// doctorConnect sends an unauthenticated request to the host
+// and returns the difference between the server and local clock.
+func doctorConnect(ctx2 context.Context, ctx *cli.Context, host string) (doctorResult, time.Duration) {
+	r := doctorResult{name: "连接 " + host}
+	cl, err := getClient(ctx, host)
+	if err != nil {
+		r.err = err
+		r.hint = "检查 --host 的格式, 应为 host:port"
+		return r, 0
+	}
+	reqCtx, cancel := context.WithTimeout(ctx2, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, cl.EndpointURL().String(), nil)
+	if err != nil {
+		r.err = err
+		return r, 0
+	}
+	sent := time.Now()
+	resp, err := clientTransport(ctx).RoundTrip(req)
+	if err != nil {
+		r.err = err
+		msg := err.Error()
+		switch {
+		case strings.Contains(msg, "x509"):
+			r.hint = "服务器证书无法验证, 使用 --insecure 跳过证书验证, 或将 CA 添加到系统证书中"
+		case strings.Contains(msg, "server gave HTTP response to HTTPS client"):
+			r.hint = "服务器没有使用 TLS, 请去掉 --tls"
+		case strings.Contains(msg, "malformed HTTP response"):
+			r.hint = "服务器使用了 TLS, 请添加 --tls"
+		default:
+			r.hint = "检查主机地址和端口是否正确, 服务器是否正在运行, 以及防火墙设置"
+		}
+		return r, 0
+	}
+	resp.Body.Close()
+	r.info = resp.Status
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return r, 0
+	}
+	// Compare to the middle of the request.
+	local := sent.Add(time.Since(sent) / 2)
+	return r, date.Sub(local)
+}
+
+// doctorClockSkew checks that the difference between the server and local clock is acceptable.
+func doctorClockSkew(host string, skew time.Duration) doctorResult {
+	r := doctorResult{name: "时钟偏差 " + host, info: skew.Round(time.Second).String()}
+	if skew > maxClockSkew || skew < -maxClockSkew {
+		r.err = fmt.Errorf("时钟偏差 %v 超过了 %v", skew.Round(time.Second), maxClockSkew)
+		r.hint = "服务器会拒绝请求, 请使用 NTP 同步本机和服务器的时钟"
+	}
+	return r
+}
+
+// doctorBucket checks credentials, bucket create/delete permission,
+// multipart uploads and versioning using a temporary bucket.
+func doctorBucket(ctx2 context.Context, ctx *cli.Context, cl *minio.Client, report func(r doctorResult)) {
+	if _, err := cl.ListBuckets(ctx2); err != nil {
+		r := doctorResult{name: "认证", err: err}
+		switch minio.ToErrorResponse(err).Code {
+		case "InvalidAccessKeyId":
+			r.hint = "服务器不认识 --access-key"
+		case "SignatureDoesNotMatch":
+			r.hint = "检查 --secret-key 是否正确, 以及 --signature 和 --region 是否与服务器一致"
+		case "RequestTimeTooSkewed":
+			r.hint = "请使用 NTP 同步本机和服务器的时钟"
+		case "AccessDenied":
+			r.hint = "凭证有效, 但没有列出存储桶的权限"
+		default:
+			r.hint = "检查 --access-key, --secret-key 和 --signature"
+		}
+		report(r)
+		report(doctorResult{name: "创建存储桶", skipped: true})
+		return
+	}
+	report(doctorResult{name: "认证"})
+
+	bucket := fmt.Sprintf("%s-doctor-%d", appName, time.Now().UnixNano())
+	err := cl.MakeBucket(ctx2, bucket, minio.MakeBucketOptions{Region: ctx.String("region")})
+	if err != nil {
+		report(doctorResult{name: "创建存储桶", err: err, hint: "凭证没有创建存储桶的权限, 请为基准测试使用已存在的存储桶并添加 --no-bucket-create"})
+		return
+	}
+	report(doctorResult{name: "创建存储桶", info: bucket})
+
+	// Multipart upload
+	r := doctorResult{name: "分段上传"}
+	info, err := cl.PutObject(ctx2, bucket, "multipart", bytes.NewReader(make([]byte, doctorMultipartSize)), doctorMultipartSize, minio.PutObjectOptions{PartSize: doctorPartSize})
+	switch {
+	case err != nil:
+		r.err = err
+		r.hint = "服务器不支持分段上传, 请添加 --disable-multipart"
+	case info.Size != doctorMultipartSize:
+		r.err = fmt.Errorf("上传了 %d 字节, 需要的是 %d 字节", info.Size, doctorMultipartSize)
+	}
+	report(r)
+	if err == nil {
+		if err := cl.RemoveObject(ctx2, bucket, "multipart", minio.RemoveObjectOptions{}); err != nil {
+			console.Errorln("删除对象出错:", err)
+		}
+	} else if err := cl.RemoveIncompleteUpload(ctx2, bucket, "multipart"); err != nil {
+		// The bucket cannot be removed with the upload left behind.
+		console.Errorln("中止分段上传出错:", err)
+	}
+
+	// Versioning
+	r = doctorResult{name: "版本控制"}
+	if err := cl.EnableVersioning(ctx2, bucket); err != nil {
+		r.err = err
+	} else if cfg, err := cl.GetBucketVersioning(ctx2, bucket); err != nil {
+		r.err = err
+	} else if cfg.Status != "Enabled" {
+		r.err = fmt.Errorf("版本控制状态是 %q", cfg.Status)
+	}
+	if r.err != nil {
+		r.hint = "服务器不支持版本控制, 无法使用 versioned 基准测试和 --list.versions"
+	}
+	report(r)
+
+	r = doctorResult{name: "删除存储桶"}
+	if err := cl.RemoveBucket(ctx2, bucket); err != nil {
+		r.err = err
+		r.hint = fmt.Sprintf("凭证没有删除存储桶的权限, 请手动删除存储桶 %q", bucket)
+	}
+	report(r)
+}
+
+// doctorAdmin checks if the admin API can be used.
+// This is optional, since it is only available on MinIO servers.
+func doctorAdmin(ctx2 context.Context, ctx *cli.Context) doctorResult {
+	r := doctorResult{name: "管理 API", optional: true}
+	if _, err := newAdminClient(ctx).ServerInfo(ctx2); err != nil {
+		r.err = err
+		r.hint = "--serverprof 和 --collect-server-metrics 需要 MinIO 服务器和管理员权限, 不使用这些参数时可以忽略"
+	}
+	return r
+}
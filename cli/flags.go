@@ -20,9 +20,13 @@ package cli
 import (
 	"fmt"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/minio/cli"
 	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/bench"
 )
 
 // Collection of warp flags currently supported
@@ -53,6 +57,11 @@ var globalFlags = []cli.Flag{
 		Name:  "autocompletion",
 		Usage: "为 shell 安装自动补全",
 	},
+	cli.StringFlag{
+		Name:  "units",
+		Value: bench.UnitsAuto,
+		Usage: "吞吐量的输出单位. 可以是 'auto', 'si' (MB/s), 'iec' (MiB/s) 或 'bits' (Mbit/s).",
+	},
 }
 
 var profileFlags = []cli.Flag{
@@ -102,6 +111,11 @@ func setGlobalsFromContext(ctx *cli.Context) error {
 	json := ctx.IsSet("json")
 	noColor := ctx.IsSet("no-color")
 	setGlobals(quiet, debug, json, noColor)
+	if ctx.IsSet("units") {
+		if err := bench.SetThroughputUnits(ctx.String("units")); err != nil {
+			console.Fatal("无效的 units 参数: ", err)
+		}
+	}
 	return nil
 }
 
@@ -133,7 +147,7 @@ func commandLine(ctx *cli.Context) string {
 		}
 		name := flag.GetName()
 		switch name {
-		case "access-key", "secret-key":
+		case "access-key", "secret-key", "replica-access-key", "replica-secret-key":
 			val = "*REDACTED*"
 		}
 		s += " --" + flag.GetName() + "=" + val
@@ -141,11 +155,52 @@ func commandLine(ctx *cli.Context) string {
 	return s
 }
 
+// concurrencyMultipleComment returns the resolved concurrency for the benchmark data,
+// if concurrent was given as a multiple of the number of CPUs.
+func concurrencyMultipleComment(ctx *cli.Context) string {
+	c := ctx.String("concurrent")
+	if !strings.HasSuffix(c, "x") {
+		return ""
+	}
+	return fmt.Sprintf("\nconcurrent %s resolved to %d (%d CPUs)", c, concurrency(ctx), runtime.NumCPU())
+}
+
+// concurrency returns the value of the concurrent flag.
+// The value can be specified as a multiple of the number of CPUs, for example '4x'.
+func concurrency(ctx *cli.Context) int {
+	n, err := parseConcurrency(ctx.String("concurrent"), runtime.NumCPU())
+	if err != nil {
+		console.Fatal("无效的 concurrent 值: ", ctx.String("concurrent"))
+	}
+	return n
+}
+
+// parseConcurrency parses a concurrency value, which is either a positive number
+// or a multiple of cpus, for example '4x'. Multiples resolve to at least 1.
+func parseConcurrency(c string, cpus int) (int, error) {
+	if strings.HasSuffix(c, "x") {
+		mul, err := strconv.ParseFloat(strings.TrimSuffix(c, "x"), 64)
+		if err != nil || mul <= 0 {
+			return 0, fmt.Errorf("invalid multiple %q", c)
+		}
+		n := int(mul * float64(cpus))
+		if n < 1 {
+			n = 1
+		}
+		return n, nil
+	}
+	n, err := strconv.Atoi(c)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid value %q", c)
+	}
+	return n, nil
+}
+
 // Flags common across all I/O commands such as cp, mirror, stat, pipe etc.
 var ioFlags = []cli.Flag{
 	cli.StringFlag{
 		Name:   "host",
-		Usage:  "主机 host 地址，可以将多个主机 host 指定为用逗号分割的列表.",
+		Usage:  "主机 host 地址，可以将多个主机 host 指定为用逗号分割的列表. 可以使用 host:port=权重 为主机指定权重.",
 		EnvVar: appNameUC + "_HOST",
 		Value:  "127.0.0.1:9000",
 	},
@@ -161,11 +216,46 @@ var ioFlags = []cli.Flag{
 		EnvVar: appNameUC + "_SECRET_KEY",
 		Value:  "",
 	},
+	cli.StringFlag{
+		Name:   "creds-file",
+		Usage:  "包含多组凭证的文件, 每行一组 'access-key:secret-key'. 请求将轮流使用这些凭证以模拟多个租户, 并按租户记录吞吐量",
+		EnvVar: appNameUC + "_CREDS_FILE",
+	},
 	cli.BoolFlag{
 		Name:   "tls",
 		Usage:  "使用 TLS (HTTPS) 进行传输",
 		EnvVar: appNameUC + "_TLS",
 	},
+	cli.BoolFlag{
+		Name:   "http2",
+		Usage:  "要求使用 HTTP/2, 如果服务器回退到 HTTP/1.1 将打印警告. 需要 --tls",
+		EnvVar: appNameUC + "_HTTP2",
+	},
+	cli.BoolFlag{
+		Name:   "no-keepalive",
+		Usage:  "禁用连接复用, 每个请求都建立新的 TCP/TLS 连接. 会自动启用 --trace-phases 以记录建立连接的时间",
+		EnvVar: appNameUC + "_NO_KEEPALIVE",
+	},
+	cli.StringFlag{
+		Name:   "cacert",
+		Usage:  "用于验证服务器证书的 CA 证书 (PEM) 文件. 除系统 CA 外还会信任该 CA. 需要 --tls",
+		EnvVar: appNameUC + "_CACERT",
+	},
+	cli.StringFlag{
+		Name:   "client-cert",
+		Usage:  "用于双向 TLS 认证的客户端证书 (PEM) 文件. 需要同时指定 --client-key 和 --tls",
+		EnvVar: appNameUC + "_CLIENT_CERT",
+	},
+	cli.StringFlag{
+		Name:   "client-key",
+		Usage:  "用于双向 TLS 认证的客户端私钥 (PEM) 文件. 需要同时指定 --client-cert 和 --tls",
+		EnvVar: appNameUC + "_CLIENT_KEY",
+	},
+	cli.StringFlag{
+		Name:   "user-agent",
+		Usage:  "为所有请求设置自定义的 User-Agent. 默认为 'warp/<版本号>'",
+		EnvVar: appNameUC + "_USER_AGENT",
+	},
 	cli.StringFlag{
 		Name:   "region",
 		Usage:  "指定自定义的区域 (region)",
@@ -191,10 +281,15 @@ var ioFlags = []cli.Flag{
 		Value: string(hostSelectTypeWeighed),
 		Usage: fmt.Sprintf("主机 Host 的选择算法. 可以是 %q 或 %q", hostSelectTypeWeighed, hostSelectTypeRoundrobin),
 	},
-	cli.IntFlag{
+	cli.StringFlag{
+		Name:  "host-max-concurrent",
+		Value: "",
+		Usage: "限制每个主机同时处理的最大请求数, 例如 'host1:10,host2:50'. 所有主机都达到上限时请求将等待.",
+	},
+	cli.StringFlag{
 		Name:  "concurrent",
-		Value: 20,
-		Usage: "运行基准测试时的并发请求数",
+		Value: "20",
+		Usage: "运行基准测试时的并发请求数. 可以指定为 CPU 核数的倍数, 例如 '4x'",
 	},
 	cli.BoolFlag{
 		Name:  "noprefix",
@@ -211,6 +306,11 @@ var ioFlags = []cli.Flag{
 	cli.StringFlag{
 		Name:  "storage-class",
 		Value: "",
-		Usage: "指定自定义的存储类, 如: 'STANDARD' 或者 'REDUCED_REDUNDANCY'.",
+		Usage: "指定自定义的存储类, 如: 'STANDARD' 或者 'REDUCED_REDUNDANCY'. put 基准测试可以按权重使用多个存储类, 如: 'STANDARD:80,REDUCED_REDUNDANCY:20'.",
+	},
+	cli.StringFlag{
+		Name:  "run-tag",
+		Value: "",
+		Usage: "为所有上传的对象添加对象标签 'warp-run=<值>', 可以使用 'warp cleanup' 删除这些对象.",
 	},
 }
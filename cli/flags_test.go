@@ -0,0 +1,54 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import "testing"
+
+func TestParseConcurrency(t *testing.T) {
+	tests := []struct {
+		in      string
+		cpus    int
+		want    int
+		wantErr bool
+	}{
+		{in: "20", cpus: 8, want: 20},
+		{in: "4x", cpus: 8, want: 32},
+		{in: "0.5x", cpus: 8, want: 4},
+		{in: "1.5x", cpus: 3, want: 4},
+		{in: "0.1x", cpus: 4, want: 1},
+		{in: "abc", cpus: 8, wantErr: true},
+		{in: "", cpus: 8, wantErr: true},
+		{in: "0", cpus: 8, wantErr: true},
+		{in: "-1", cpus: 8, wantErr: true},
+		{in: "x", cpus: 8, wantErr: true},
+		{in: "0x", cpus: 8, wantErr: true},
+		{in: "-2x", cpus: 8, wantErr: true},
+		{in: "4X", cpus: 8, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			got, err := parseConcurrency(test.in, test.cpus)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
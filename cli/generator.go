@@ -19,11 +19,14 @@ package cli
 
 import (
 	"errors"
+	"strconv"
+	"strings"
 
 	humanize "github.com/dustin/go-humanize"
 	"github.com/minio/mc/pkg/probe"
 
 	"github.com/minio/cli"
+	"github.com/minio/minio/pkg/console"
 	"github.com/minio/warp/pkg/generator"
 )
 
@@ -33,10 +36,49 @@ var genFlags = []cli.Flag{
 		Value: "random",
 		Usage: "指定使用特定的数据生成器 (generator)",
 	},
+	cli.StringFlag{
+		Name:  "gen.filetypes",
+		Value: "",
+		Usage: "在随机数据前添加有效的文件头, 按权重选择文件类型, 例如 'jpeg:50,pdf:30,zip:20'. 可以是 jpeg, pdf 和 zip. 只支持 random 生成器.",
+	},
+	cli.IntFlag{
+		Name:  "gen.precompute",
+		Value: 0,
+		Usage: "在基准测试开始前生成该数量的对象数据并保存在内存中, 测试时轮流使用. 只支持 random 生成器. 0 表示不预先生成",
+	},
+	cli.StringFlag{
+		Name:  "gen.precompute.max",
+		Value: "1GiB",
+		Usage: "gen.precompute 允许使用的最大内存",
+	},
 	cli.BoolFlag{
 		Name:  "obj.randsize",
 		Usage: "随机化对象的大小，使其达到指定的大小",
 	},
+	cli.StringFlag{
+		Name:  "obj.sizes",
+		Value: "",
+		Usage: "按顺序轮流使用这些确切的对象大小, 用逗号分隔, 例如 '1KiB,4KiB,1MiB'. 将代替 obj.size",
+	},
+	cli.BoolFlag{
+		Name:  "obj.sizes.shuffle",
+		Usage: "从 obj.sizes 中随机选择每个对象的大小, 而不是按顺序使用",
+	},
+	cli.IntFlag{
+		Name:  "key-length",
+		Value: 0,
+		Usage: "生成的对象名 (key) 的字符长度, 包括前缀. 0 表示使用默认的命名方式",
+	},
+	cli.StringFlag{
+		Name:  "key-charset",
+		Value: "",
+		Usage: "生成对象名 (key) 使用的字符集. 为空表示使用默认的字符 [a-zA-Z0-9()]",
+	},
+	cli.IntFlag{
+		Name:  "key-depth",
+		Value: 0,
+		Usage: "生成的对象名 (key) 在前缀之后的路径段数, 例如 4 生成 'd3/d0/d7/对象名'. 每层使用 10 个目录名. 0 表示不添加目录",
+	},
 }
 
 func newGenSourceCSV(ctx *cli.Context) func() generator.Source {
@@ -53,6 +95,9 @@ func newGenSourceCSV(ctx *cli.Context) func() generator.Source {
 		generator.WithPrefixSize(prefixSize),
 		generator.WithSize(int64(size)),
 		generator.WithRandomSize(ctx.Bool("obj.randsize")),
+		generator.WithKeyLength(ctx.Int("key-length")),
+		generator.WithKeyCharset(ctx.String("key-charset")),
+		generator.WithKeyDepth(ctx.Int("key-depth")),
 	)
 	fatalIf(probe.NewError(err), "无法创建数据生成器 (generator)")
 	return src
@@ -60,6 +105,11 @@ func newGenSourceCSV(ctx *cli.Context) func() generator.Source {
 
 // newGenSource returns a new generator
 func newGenSource(ctx *cli.Context) func() generator.Source {
+	return newGenSourceSize(ctx, "obj.size")
+}
+
+// newGenSourceSize returns a new generator with the object size taken from the named flag.
+func newGenSourceSize(ctx *cli.Context, sizeFlag string) func() generator.Source {
 	prefixSize := 8
 	if ctx.Bool("noprefix") {
 		prefixSize = 0
@@ -70,24 +120,190 @@ func newGenSource(ctx *cli.Context) func() generator.Source {
 	case "random":
 		g = generator.WithRandomData()
 	case "csv":
+		if ctx.String("gen.filetypes") != "" {
+			fatal(errInvalidArgument(), "gen.filetypes 只支持 random 生成器")
+		}
 		g = generator.WithCSV().Size(25, 1000)
 	default:
 		err := errors.New("未知的生成器 (generator) 类型:" + ctx.String("generator"))
 		fatal(probe.NewError(err), "无效的 -generator 参数")
 		return nil
 	}
-	size, err := toSize(ctx.String("obj.size"))
-	fatalIf(probe.NewError(err), "指定的 obj.size 无效")
+	size, err := toSize(ctx.String(sizeFlag))
+	fatalIf(probe.NewError(err), "指定的 "+sizeFlag+" 无效")
+	var sizes []int64
+	if sizeFlag == "obj.size" {
+		// Overrides of obj.size, like put.size, are not affected by obj.sizes.
+		sizes = parseObjSizes(ctx)
+		for _, sz := range sizes {
+			if uint64(sz) > size {
+				size = uint64(sz)
+			}
+		}
+	}
+	fileTypes, err := generator.ParseFileTypes(ctx.String("gen.filetypes"))
+	fatalIf(probe.NewError(err), "无效的 gen.filetypes 参数")
+	precompute := ctx.Int("gen.precompute")
+	if precompute > 0 {
+		checkPrecompute(ctx, precompute, size)
+	}
 	src, err := generator.NewFn(g.Apply(),
+		generator.WithPrecompute(precompute),
+		generator.WithFileTypes(fileTypes),
 		generator.WithPrefixSize(prefixSize),
 		generator.WithSize(int64(size)),
 		generator.WithRandomSize(ctx.Bool("obj.randsize")),
+		generator.WithSizes(sizes, ctx.Bool("obj.sizes.shuffle")),
+		generator.WithKeyLength(ctx.Int("key-length")),
+		generator.WithKeyCharset(ctx.String("key-charset")),
+		generator.WithKeyDepth(ctx.Int("key-depth")),
 	)
 	fatalIf(probe.NewError(err), "无法创建数据生成器 (generator)")
 	return src
 }
 
+// maxObjSize is the maximum size of an S3 object.
+const maxObjSize = 5 << 40
+
+// parseObjSizes returns the sizes given in --obj.sizes.
+// If no sizes are given nil is returned.
+func parseObjSizes(ctx *cli.Context) []int64 {
+	list := ctx.String("obj.sizes")
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	if ctx.Bool("obj.randsize") {
+		fatal(errInvalidArgument(), "obj.sizes 不能与 obj.randsize 同时使用")
+	}
+	var sizes []int64
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		sz, err := toSize(s)
+		fatalIf(probe.NewError(err), "obj.sizes 中的大小无效: %s", s)
+		if sz == 0 || sz > maxObjSize {
+			fatal(errInvalidArgument(), "obj.sizes 中的大小 %s 必须大于 0 并且不超过 %s", s, humanize.IBytes(maxObjSize))
+		}
+		sizes = append(sizes, int64(sz))
+	}
+	return sizes
+}
+
+// precomputeWarnFraction is the fraction of --gen.precompute.max
+// above which a warning about memory use is printed.
+const precomputeWarnFraction = 0.5
+
+// checkPrecompute verifies that n precomputed objects of up to size bytes
+// stay below --gen.precompute.max.
+func checkPrecompute(ctx *cli.Context, n int, size uint64) {
+	if ctx.String("obj.generator") != "random" {
+		fatal(errInvalidArgument(), "gen.precompute 只支持 random 生成器")
+	}
+	if ctx.String("gen.filetypes") != "" {
+		fatal(errInvalidArgument(), "gen.precompute 不能与 gen.filetypes 同时使用")
+	}
+	maxMem, err := toSize(ctx.String("gen.precompute.max"))
+	fatalIf(probe.NewError(err), "指定的 gen.precompute.max 无效")
+	total := uint64(n) * size
+	if total > maxMem {
+		fatal(errInvalidArgument(), "gen.precompute 需要 %s 内存, 超过了 gen.precompute.max 允许的 %s", humanize.IBytes(total), humanize.IBytes(maxMem))
+	}
+	console.Infof("gen.precompute: 将预先生成 %d 个对象, 最多使用 %s 内存\n", n, humanize.IBytes(total))
+	if float64(total) > float64(maxMem)*precomputeWarnFraction {
+		console.Errorf("警告: gen.precompute 将使用 %s 内存, 接近 gen.precompute.max 的限制\n", humanize.IBytes(total))
+	}
+}
+
 // toSize converts a size indication to bytes.
 func toSize(size string) (uint64, error) {
 	return humanize.ParseBytes(size)
 }
+
+// objectsAuto is the value of --objects that selects the number of objects automatically.
+const objectsAuto = "auto"
+
+// objectsWorkingSetFlag is the target size of the uploaded objects for --objects=auto.
+var objectsWorkingSetFlag = cli.StringFlag{
+	Name:  "objects.working-set",
+	Value: "1GiB",
+	Usage: "使用 --objects=auto 时, 上传对象的目标总大小.",
+}
+
+// Limits of the number of objects selected by --objects=auto.
+const (
+	autoObjectsMin       = 100
+	autoObjectsPerThread = 10
+	autoObjectsPerSecond = 100
+	autoObjectsMax       = 1000000
+	autoObjectsWarnSize  = 10 << 30
+)
+
+// resolveObjects replaces --objects=auto with a number of objects
+// estimated from the object size, the benchmark duration and --objects.working-set.
+// sizeFlag is the flag containing the size of the uploaded objects.
+// At least minObjects objects are selected, even if it exceeds the estimate.
+func resolveObjects(ctx *cli.Context, sizeFlag string, minObjects int) {
+	if !strings.EqualFold(ctx.String("objects"), objectsAuto) {
+		objectCount(ctx)
+		return
+	}
+	size, err := toSize(ctx.String(sizeFlag))
+	fatalIf(probe.NewError(err), "指定的 "+sizeFlag+" 无效")
+	if sizes := parseObjSizes(ctx); sizeFlag == "obj.size" && len(sizes) > 0 {
+		// Use the average of the listed sizes.
+		var total int64
+		for _, sz := range sizes {
+			total += sz
+		}
+		size = uint64(total / int64(len(sizes)))
+	}
+	ws, err := toSize(ctx.String(objectsWorkingSetFlag.Name))
+	fatalIf(probe.NewError(err), "指定的 objects.working-set 无效")
+	if ctx.Bool("obj.randsize") {
+		// Random sizes are on average half the size.
+		size /= 2
+	}
+	if size == 0 {
+		size = 1
+	}
+	n := int(ws / size)
+
+	// Have enough objects for all threads to work on different objects,
+	// but don't spend longer uploading than running the benchmark.
+	conc := concurrency(ctx)
+	if minN := conc * autoObjectsPerThread; n < minN {
+		n = minN
+	}
+	if n < autoObjectsMin {
+		n = autoObjectsMin
+	}
+	if maxN := int(ctx.Duration("duration").Seconds() * autoObjectsPerSecond); maxN > 0 && n > maxN {
+		n = maxN
+	}
+	if n > autoObjectsMax {
+		n = autoObjectsMax
+	}
+	if n < minObjects {
+		console.Errorf("警告: --objects=auto 估计的 %d 个对象少于此基准测试需要的 %d 个, 将上传 %d 个对象\n", n, minObjects, minObjects)
+		n = minObjects
+	}
+	// Round up to an equal number of objects per thread.
+	n = (n + conc - 1) / conc * conc
+
+	total := uint64(n) * size
+	console.Infof("--objects=auto: 将上传 %d 个对象, 共 %s\n", n, humanize.IBytes(total))
+	if total > autoObjectsWarnSize {
+		console.Errorf("警告: 将上传 %s 的数据, 请确认服务器有足够的空间或指定 --objects\n", humanize.IBytes(total))
+	}
+	err = ctx.Set("objects", strconv.Itoa(n))
+	fatalIf(probe.NewError(err), "无法设置 objects")
+}
+
+// objectCount returns the value of --objects.
+// resolveObjects must be called first if 'auto' is allowed.
+func objectCount(ctx *cli.Context) int {
+	n, err := strconv.Atoi(ctx.String("objects"))
+	if err != nil {
+		console.Fatal("无效的 objects 值, 必须是数字或 'auto': ", ctx.String("objects"))
+	}
+	return n
+}
@@ -26,11 +26,12 @@ import (
 
 var (
 	getFlags = []cli.Flag{
-		cli.IntFlag{
+		cli.StringFlag{
 			Name:  "objects",
-			Value: 2500,
-			Usage: "要上传的对象数.",
+			Value: "2500",
+			Usage: "要上传的对象数. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
 		},
+		objectsWorkingSetFlag,
 		cli.StringFlag{
 			Name:  "obj.size",
 			Value: "10MiB",
@@ -40,6 +41,14 @@ var (
 			Name:  "range",
 			Usage: "进行分片 GET 请求操作时. offset 和 length 的值将是随机的.",
 		},
+		cli.BoolFlag{
+			Name:  "get.verify-size",
+			Usage: "检查服务器返回的 Content-Length 与期望的对象大小以及实际读取的字节数是否一致.",
+		},
+		cli.BoolFlag{
+			Name:  "get.partition",
+			Usage: "将按名称排序的对象分成互不重叠的范围分配给每个工作线程, 每个线程始终只读取自己的对象. 用于测试缓存命中.",
+		},
 	}
 )
 
@@ -48,7 +57,7 @@ var getCmd = cli.Command{
 	Usage:  "获取对象 (get) 请求操作的基准测试",
 	Action: mainGet,
 	Before: setGlobalsFromContext,
-	Flags:  combineFlags(globalFlags, ioFlags, getFlags, genFlags, benchFlags, analyzeFlags),
+	Flags:  combineFlags(globalFlags, ioFlags, getFlags, readBucketFlags, genFlags, benchFlags, analyzeFlags),
 	CustomHelpTemplate: `名称:
   {{.HelpName}} - {{.Usage}}
 
@@ -69,23 +78,30 @@ func mainGet(ctx *cli.Context) error {
 	b := bench.Get{
 		Common: bench.Common{
 			Client:      newClient(ctx),
-			Concurrency: ctx.Int("concurrent"),
+			Concurrency: concurrency(ctx),
 			Source:      src,
 			Bucket:      ctx.String("bucket"),
 			Location:    "",
 			PutOpts:     putOpts(ctx),
 		},
 		RandomRanges:  ctx.Bool("range"),
-		CreateObjects: ctx.Int("objects"),
+		CreateObjects: objectCount(ctx),
 		GetOpts:       minio.GetObjectOptions{ServerSideEncryption: sse},
+		VerifySize:    ctx.Bool("get.verify-size"),
+		Partition:     ctx.Bool("get.partition"),
 	}
+	setReadBucket(ctx, &b.Common)
 	return runBench(ctx, &b)
 }
 
 func checkGetSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, "obj.size", 0)
 	if ctx.NArg() > 0 {
 		console.Fatal("命令中没有附带参数")
 	}
+	if ctx.Bool("get.partition") && objectCount(ctx) < concurrency(ctx) {
+		console.Fatalf("使用 --get.partition 时对象数量 (%d) 不能少于并发数 (%d)\n", objectCount(ctx), concurrency(ctx))
+	}
 
 	checkAnalyze(ctx)
 	checkBenchmark(ctx)
@@ -25,16 +25,22 @@ import (
 
 var (
 	listFlags = []cli.Flag{
-		cli.IntFlag{
+		cli.StringFlag{
 			Name:  "objects",
-			Value: 10000,
-			Usage: "要上传的对象数. 四舍五入使其具有相等的并发对象数.",
+			Value: "10000",
+			Usage: "要上传的对象数. 四舍五入使其具有相等的并发对象数. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
 		},
+		objectsWorkingSetFlag,
 		cli.StringFlag{
 			Name:  "obj.size",
 			Value: "1KB",
 			Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
 		},
+		cli.IntFlag{
+			Name:  "list.versions",
+			Value: 0,
+			Usage: "在启用版本控制的存储桶中为每个对象上传该数量的版本, 并列出对象版本. 0 表示列出对象.",
+		},
 	}
 )
 
@@ -64,22 +70,27 @@ func mainList(ctx *cli.Context) error {
 	b := bench.List{
 		Common: bench.Common{
 			Client:      newClient(ctx),
-			Concurrency: ctx.Int("concurrent"),
+			Concurrency: concurrency(ctx),
 			Source:      src,
 			Bucket:      ctx.String("bucket"),
 			Location:    "",
 			PutOpts:     putOpts(ctx),
 		},
-		CreateObjects: ctx.Int("objects"),
+		CreateObjects: objectCount(ctx),
 		NoPrefix:      ctx.Bool("noprefix"),
+		Versions:      ctx.Int("list.versions"),
 	}
 	return runBench(ctx, &b)
 }
 
 func checkListSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, "obj.size", 0)
 	if ctx.NArg() > 0 {
 		console.Fatal("命令中没有附带参数")
 	}
+	if ctx.Int("list.versions") < 0 {
+		console.Fatal("list.versions 的值不能是负数")
+	}
 
 	checkAnalyze(ctx)
 	checkBenchmark(ctx)
@@ -18,9 +18,12 @@
 package cli
 
 import (
+	"container/heap"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/klauspost/compress/zstd"
@@ -36,6 +39,68 @@ var mergeFlags = []cli.Flag{
 		Value: "",
 		Usage: "将合并的数据输出到该文件. 默认会生成唯一的文件名.",
 	},
+	benchDataCompressionFlag,
+	benchDataRawFlag,
+	cli.BoolFlag{
+		Name:  "merge.dedup",
+		Usage: "删除输入文件中完全重复的请求操作.",
+	},
+}
+
+// mergeOpKey identifies an operation across merged files.
+// Threads are not included since they are offset for each input.
+type mergeOpKey struct {
+	clientID, opType, file string
+	start, end, size       int64
+}
+
+// mergeKey returns the key identifying op across merged files.
+func mergeKey(op bench.Operation) mergeOpKey {
+	return mergeOpKey{
+		clientID: op.ClientID,
+		opType:   op.OpType,
+		file:     op.File,
+		start:    op.Start.UnixNano(),
+		end:      op.End.UnixNano(),
+		size:     op.Size,
+	}
+}
+
+// mergeRange tracks the active time range of an operation type
+// the same way as bench.Operations.ActiveTimeRange with all threads,
+// without keeping the operations.
+type mergeRange struct {
+	firstEnded  map[uint16]time.Time
+	lastStarted map[uint16]time.Time
+}
+
+func (m *mergeRange) add(op bench.Operation) {
+	if m.firstEnded == nil {
+		m.firstEnded = make(map[uint16]time.Time)
+		m.lastStarted = make(map[uint16]time.Time)
+	}
+	if ended, ok := m.firstEnded[op.Thread]; !ok || ended.After(op.End) {
+		m.firstEnded[op.Thread] = op.End
+	}
+	if started, ok := m.lastStarted[op.Thread]; !ok || started.Before(op.Start) {
+		m.lastStarted[op.Thread] = op.Start
+	}
+}
+
+// overlaps returns whether all threads were active at the same time.
+func (m *mergeRange) overlaps() bool {
+	var start, end time.Time
+	for _, ended := range m.firstEnded {
+		if ended.After(start) {
+			start = ended
+		}
+	}
+	for _, started := range m.lastStarted {
+		if end.IsZero() || end.After(started) {
+			end = started
+		}
+	}
+	return start.Before(end)
 }
 
 var mergeCmd = cli.Command{
@@ -56,60 +121,233 @@ var mergeCmd = cli.Command{
   {{end}}`,
 }
 
-// mainAnalyze is the entry point for analyze command.
+// mergeSpan is the time range of the operations of a client.
+type mergeSpan struct {
+	start, end time.Time
+}
+
+// mergeInput is what is known about an input before it is merged.
+type mergeInput struct {
+	// threads is the number of threads used by the input.
+	threads uint16
+	// clients is the time range of the operations of each client.
+	clients map[string]mergeSpan
+}
+
+// scanMergeInput reads all operations of an input without keeping them.
+func scanMergeInput(r io.Reader, offset, limit int, log func(msg string, v ...interface{})) (mergeInput, error) {
+	in := mergeInput{clients: make(map[string]mergeSpan)}
+	err := bench.StreamOperationsFromCSV(r, false, offset, limit, log, func(op bench.Operation) error {
+		if op.Thread >= in.threads {
+			in.threads = op.Thread + 1
+		}
+		span, ok := in.clients[op.ClientID]
+		if !ok || op.Start.Before(span.start) {
+			span.start = op.Start
+		}
+		if !ok || op.End.After(span.end) {
+			span.end = op.End
+		}
+		in.clients[op.ClientID] = span
+		return nil
+	})
+	return in, err
+}
+
+// sharedClient returns a client with operations in the same time range in both inputs.
+// Client IDs are unique for each run, so both inputs then contain the same operations,
+// for example when an input has already been merged into the other.
+func (m mergeInput) sharedClient(other mergeInput) (string, bool) {
+	for id, a := range m.clients {
+		b, ok := other.clients[id]
+		if ok && !a.end.Before(b.start) && !b.end.Before(a.start) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// mergeStream is an input being merged.
+// Operations are read in the background and the next one is kept pending.
+type mergeStream struct {
+	ops    chan bench.Operation
+	err    error
+	offset uint16
+	next   bench.Operation
+}
+
+// pull makes the next operation of the input pending.
+// Returns false when the input has no more operations.
+func (s *mergeStream) pull() bool {
+	op, ok := <-s.ops
+	if !ok {
+		return false
+	}
+	op.Thread += s.offset
+	s.next = op
+	return true
+}
+
+// mergeHeap orders inputs by the start time of their pending operation.
+type mergeHeap []*mergeStream
+
+func (h mergeHeap) Len() int           { return len(h) }
+func (h mergeHeap) Less(i, j int) bool { return h[i].next.Start.Before(h[j].next.Start) }
+func (h mergeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*mergeStream)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	s := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return s
+}
+
+// mainMerge is the entry point for merge command.
+// Each input is sorted by start time, so operations are merged from
+// all inputs at once, keeping only one pending operation per input.
+// Inputs don't have to fit in memory.
 func mainMerge(ctx *cli.Context) error {
 	checkMerge(ctx)
 	args := ctx.Args()
 	if len(args) <= 1 {
 		console.Fatal("必须提供两个或多个基准测试的数据文件")
 	}
-	var zstdDec, _ = zstd.NewReader(nil)
-	defer zstdDec.Close()
-	var allOps bench.Operations
-	threads := uint16(0)
+	// Inputs are read concurrently, so each gets its own decoder.
+	open := func(name string) (io.Reader, func()) {
+		f, err := os.Open(name)
+		fatalIf(probe.NewError(err), "无法打开输入文件")
+		dec, _ := zstd.NewReader(nil)
+		input, err := benchDataReader(dec, f)
+		fatalIf(probe.NewError(err), "无法解压缩输入文件")
+		return input, func() {
+			dec.Close()
+			f.Close()
+		}
+	}
+	aOffset, aLimit := ctx.Int("analyze.offset"), ctx.Int("analyze.limit")
 	log := console.Printf
 	if globalQuiet {
 		log = nil
 	}
-	for _, arg := range args {
-		f, err := os.Open(arg)
-		fatalIf(probe.NewError(err), "无法打开输入文件")
-		defer f.Close()
-		err = zstdDec.Reset(f)
-		fatalIf(probe.NewError(err), "无法解压缩输入文件")
-		ops, err := bench.OperationsFromCSV(zstdDec, false, ctx.Int("analyze.offset"), ctx.Int("analyze.limit"), log)
+
+	// Collect the columns and threads of all inputs,
+	// so columns can be written and threads offset before any operation.
+	var phases bool
+	var headers []string
+	seenHeaders := make(map[string]struct{})
+	inputs := make([]mergeInput, len(args))
+	offsets := make([]uint16, len(args))
+	threads := uint16(0)
+	for i, arg := range args {
+		input, done := open(arg)
+		p, h, err := bench.CSVColumns(input)
+		done()
 		fatalIf(probe.NewError(err), "无法解析输入文件")
+		phases = phases || p
+		for _, name := range h {
+			if _, ok := seenHeaders[name]; !ok {
+				seenHeaders[name] = struct{}{}
+				headers = append(headers, name)
+			}
+		}
 
-		threads = ops.OffsetThreads(threads)
-		allOps = append(allOps, ops...)
+		input, done = open(arg)
+		inputs[i], err = scanMergeInput(input, aOffset, aLimit, log)
+		done()
+		fatalIf(probe.NewError(err), "无法解析输入文件")
+		offsets[i] = threads
+		threads += inputs[i].threads
 	}
-	if len(allOps) == 0 {
-		return errors.New("基准测试文件中没有任何数据")
+	sort.Strings(headers)
+
+	dedup := ctx.Bool("merge.dedup")
+	if !dedup {
+		for i := range inputs {
+			for j := 0; j < i; j++ {
+				if id, ok := inputs[i].sharedClient(inputs[j]); ok {
+					console.Errorf("警告: %q 与 %q 包含客户端 %s 相同时间范围的请求操作, 可能合并了相同的数据, 结果将被重复计算. 使用 --merge.dedup 删除重复项.\n", args[i], args[j], id)
+				}
+			}
+		}
 	}
+
 	fileName := ctx.String("benchdata")
 	if fileName == "" {
 		fileName = fmt.Sprintf("%s-%s-%s", appName, ctx.Command.Name, time.Now().Format("2006-01-02[150405]"))
 	}
-	allOps.SortByStartTime()
-	f, err := os.Create(fileName + ".csv.zst")
-	if err != nil {
-		console.Error("无法写入基准测试数据:", err)
-	} else {
-		func() {
-			defer f.Close()
-			enc, err := zstd.NewWriter(f, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
-			fatalIf(probe.NewError(err), "无法压缩基准测试数据到输出")
-
-			defer enc.Close()
-			err = allOps.CSV(enc, commandLine(ctx))
-			fatalIf(probe.NewError(err), "无法写入基准测试数据到输出")
-
-			console.Infof("基准测试数据写入到了 %q\n", fileName+".csv.zst")
+	outName := benchDataFileName(ctx, fileName)
+	out, err := createBenchDataFile(ctx, outName)
+	fatalIf(probe.NewError(err), "无法写入基准测试数据")
+	cw, err := bench.NewCSVWriter(out, phases, headers)
+	fatalIf(probe.NewError(err), "无法写入基准测试数据到输出")
+
+	streams := make(mergeHeap, 0, len(args))
+	for i, arg := range args {
+		input, done := open(arg)
+		s := &mergeStream{ops: make(chan bench.Operation, 1000), offset: offsets[i]}
+		go func() {
+			defer done()
+			defer close(s.ops)
+			s.err = bench.StreamOperationsFromCSV(input, false, aOffset, aLimit, nil, func(op bench.Operation) error {
+				s.ops <- op
+				return nil
+			})
 		}()
+		if s.pull() {
+			streams = append(streams, s)
+		} else {
+			fatalIf(probe.NewError(s.err), "无法合并输入文件")
+		}
 	}
-	for typ, ops := range allOps.ByOp() {
-		start, end := ops.ActiveTimeRange(true)
-		if !start.Before(end) {
+	heap.Init(&streams)
+
+	seen := make(map[mergeOpKey]struct{})
+	ranges := make(map[string]*mergeRange)
+	var dupes, written int
+	for len(streams) > 0 {
+		s := streams[0]
+		op := s.next
+		if s.pull() {
+			heap.Fix(&streams, 0)
+		} else {
+			fatalIf(probe.NewError(s.err), "无法合并输入文件")
+			heap.Pop(&streams)
+		}
+		if dedup {
+			key := mergeKey(op)
+			if _, ok := seen[key]; ok {
+				dupes++
+				continue
+			}
+			seen[key] = struct{}{}
+		}
+		r := ranges[op.OpType]
+		if r == nil {
+			r = &mergeRange{}
+			ranges[op.OpType] = r
+		}
+		r.add(op)
+		written++
+		err := cw.Write(op)
+		fatalIf(probe.NewError(err), "无法写入基准测试数据到输出")
+	}
+	err = cw.Close(commandLine(ctx))
+	fatalIf(probe.NewError(err), "无法写入基准测试数据到输出")
+	err = out.Close()
+	fatalIf(probe.NewError(err), "无法写入基准测试数据到输出")
+	if written == 0 {
+		os.Remove(outName)
+		return errors.New("基准测试文件中没有任何数据")
+	}
+	console.Infof("基准测试数据写入到了 %q\n", outName)
+
+	if dupes > 0 {
+		console.Infof("已删除 %d 个重复的请求操作.\n", dupes)
+	}
+	for typ, r := range ranges {
+		if !r.overlaps() {
 			console.Errorf("类型 %v 中没有重叠项", typ)
 		}
 	}
@@ -117,4 +355,7 @@ func mainMerge(ctx *cli.Context) error {
 }
 
 func checkMerge(ctx *cli.Context) {
+	if _, err := benchDataEncoderLevel(ctx); err != nil {
+		fatalIf(probe.NewError(err), "无效的 benchdata.compression 参数")
+	}
 }
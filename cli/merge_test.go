@@ -0,0 +1,168 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+func TestMergeKey(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	base := bench.Operation{
+		OpType:   "GET",
+		Thread:   1,
+		ClientID: "client1",
+		File:     "obj1",
+		Size:     1000,
+		Start:    start,
+		End:      start.Add(time.Second),
+	}
+	tests := []struct {
+		name     string
+		modify   func(op *bench.Operation)
+		wantSame bool
+	}{
+		{name: "same", modify: func(op *bench.Operation) {}, wantSame: true},
+		{name: "thread", modify: func(op *bench.Operation) { op.Thread = 100 }, wantSame: true},
+		{name: "endpoint", modify: func(op *bench.Operation) { op.Endpoint = "other" }, wantSame: true},
+		{name: "client", modify: func(op *bench.Operation) { op.ClientID = "client2" }},
+		{name: "op", modify: func(op *bench.Operation) { op.OpType = "PUT" }},
+		{name: "file", modify: func(op *bench.Operation) { op.File = "obj2" }},
+		{name: "size", modify: func(op *bench.Operation) { op.Size++ }},
+		{name: "start", modify: func(op *bench.Operation) { op.Start = op.Start.Add(time.Nanosecond) }},
+		{name: "end", modify: func(op *bench.Operation) { op.End = op.End.Add(time.Nanosecond) }},
+		// Fields must be separated, so values can't move between them.
+		{name: "shifted", modify: func(op *bench.Operation) { op.ClientID, op.OpType = "client1G", "ET" }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op := base
+			test.modify(&op)
+			if same := mergeKey(op) == mergeKey(base); same != test.wantSame {
+				t.Errorf("same key: %v, want %v", same, test.wantSame)
+			}
+		})
+	}
+}
+
+func TestMergeRange(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+	op := func(thread uint16, from, to int) bench.Operation {
+		return bench.Operation{Thread: thread, Start: at(from), End: at(to)}
+	}
+	tests := []struct {
+		name string
+		ops  []bench.Operation
+		want bool
+	}{
+		{name: "empty", want: false},
+		{name: "single-op", ops: []bench.Operation{op(0, 0, 1)}, want: false},
+		{name: "single-thread", ops: []bench.Operation{op(0, 0, 1), op(0, 1, 2), op(0, 2, 3)}, want: true},
+		{name: "overlapping", ops: []bench.Operation{op(0, 0, 1), op(0, 5, 6), op(1, 0, 2), op(1, 4, 6)}, want: true},
+		{name: "after-each-other", ops: []bench.Operation{op(0, 0, 1), op(0, 1, 2), op(1, 3, 4), op(1, 4, 5)}, want: false},
+		{name: "one-op-thread", ops: []bench.Operation{op(0, 0, 1), op(0, 5, 6), op(1, 0, 6)}, want: false},
+		// Order of operations doesn't matter.
+		{name: "unordered", ops: []bench.Operation{op(1, 4, 6), op(0, 5, 6), op(1, 0, 2), op(0, 0, 1)}, want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var r mergeRange
+			for _, op := range test.ops {
+				r.add(op)
+			}
+			if got := r.overlaps(); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeInput_sharedClient(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	op := func(client string, thread uint16, from, to int) bench.Operation {
+		return bench.Operation{
+			OpType:   "GET",
+			Thread:   thread,
+			ClientID: client,
+			File:     "obj",
+			Start:    start.Add(time.Duration(from) * time.Second),
+			End:      start.Add(time.Duration(to) * time.Second),
+		}
+	}
+	a := []bench.Operation{op("a", 0, 0, 1), op("a", 1, 0, 2), op("a", 0, 1, 3)}
+	b := []bench.Operation{op("b", 0, 0, 1), op("b", 0, 1, 2)}
+	// merged is a and b merged with the threads of b offset.
+	var merged []bench.Operation
+	merged = append(merged, a...)
+	for _, o := range b {
+		o.Thread += 2
+		merged = append(merged, o)
+	}
+	// later is a run of client a after the operations in a.
+	later := []bench.Operation{op("a", 0, 4, 5), op("a", 0, 5, 6)}
+
+	scan := func(t *testing.T, ops []bench.Operation) mergeInput {
+		t.Helper()
+		var buf bytes.Buffer
+		w, err := bench.NewCSVWriter(&buf, false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range ops {
+			if err := w.Write(o); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(""); err != nil {
+			t.Fatal(err)
+		}
+		in, err := scanMergeInput(&buf, 0, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return in
+	}
+	tests := []struct {
+		name       string
+		x, y       []bench.Operation
+		wantClient string
+		wantShared bool
+	}{
+		{name: "different-clients", x: a, y: b},
+		{name: "same-file", x: a, y: a, wantClient: "a", wantShared: true},
+		{name: "merged-first", x: b, y: merged, wantClient: "b", wantShared: true},
+		{name: "merged-second", x: merged, y: b, wantClient: "b", wantShared: true},
+		{name: "later", x: a, y: later},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, shared := scan(t, test.x).sharedClient(scan(t, test.y))
+			if shared != test.wantShared || client != test.wantClient {
+				t.Errorf("got %q, %v, want %q, %v", client, shared, test.wantClient, test.wantShared)
+			}
+		})
+	}
+	if got := scan(t, merged).threads; got != 3 {
+		t.Errorf("got %d threads, want 3", got)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/bench"
+)
+
+var (
+	metaFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "meta.ops",
+			Value: "location,versioning,listbuckets",
+			Usage: "要执行的元数据请求, 用逗号分隔. 可以是 'location', 'versioning', 'listbuckets' 和 'exists'.",
+		},
+	}
+)
+
+var metaCmd = cli.Command{
+	Name:   "meta",
+	Usage:  "存储桶位置, 版本控制和列出存储桶等快速元数据请求操作的基准测试",
+	Action: mainMeta,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags, metaFlags, benchFlags, analyzeFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} [FLAGS]
+  -> see https://github.com/minio/warp#meta
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// mainMeta is the entry point for meta command.
+func mainMeta(ctx *cli.Context) error {
+	checkMetaSyntax(ctx)
+	ops, _ := bench.ParseMetaOps(ctx.String("meta.ops"))
+	b := bench.Meta{
+		Common: bench.Common{
+			Client:      newClient(ctx),
+			Concurrency: concurrency(ctx),
+			Bucket:      ctx.String("bucket"),
+			Location:    "",
+			PutOpts:     putOpts(ctx),
+		},
+		Ops: ops,
+		Do:  newSignedDo(ctx),
+	}
+	return runBench(ctx, &b)
+}
+
+func checkMetaSyntax(ctx *cli.Context) {
+	if ctx.NArg() > 0 {
+		console.Fatal("命令中没有附带参数")
+	}
+	if _, err := bench.ParseMetaOps(ctx.String("meta.ops")); err != nil {
+		fatalIf(probe.NewError(err), "无效的 meta.ops 参数")
+	}
+
+	checkAnalyze(ctx)
+	checkBenchmark(ctx)
+}
@@ -0,0 +1,103 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package cli
+
+import (
+	"github.com/minio/cli"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/bench"
+)
+
+var (
+	metaUpdateFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "objects",
+			Value: "2500",
+			Usage: "要上传的对象数. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
+		},
+		objectsWorkingSetFlag,
+		cli.StringFlag{
+			Name:  "obj.size",
+			Value: "10KiB",
+			Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
+		},
+		cli.StringFlag{
+			Name:  "meta-update.content-type",
+			Value: "",
+			Usage: "更新元数据时设置的 Content-Type. 默认不修改.",
+		},
+		cli.StringFlag{
+			Name:  "meta-update.storage-class",
+			Value: "",
+			Usage: "更新元数据时设置的存储类, 如: 'STANDARD'. 默认不修改.",
+		},
+	}
+)
+
+var metaUpdateCmd = cli.Command{
+	Name:   "meta-update",
+	Usage:  "通过将对象复制到自身来更新元数据 (metadata update) 请求操作的基准测试",
+	Action: mainMetaUpdate,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags, metaUpdateFlags, genFlags, benchFlags, analyzeFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} [FLAGS]
+  -> see https://github.com/minio/warp#meta-update
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// mainMetaUpdate is the entry point for meta-update command.
+func mainMetaUpdate(ctx *cli.Context) error {
+	checkMetaUpdateSyntax(ctx)
+	src := newGenSource(ctx)
+
+	b := bench.Copy{
+		Common: bench.Common{
+			Client:      newClient(ctx),
+			Concurrency: concurrency(ctx),
+			Source:      src,
+			Bucket:      ctx.String("bucket"),
+			Location:    "",
+			PutOpts:     putOpts(ctx),
+		},
+		CreateObjects: objectCount(ctx),
+		ReplaceMeta:   true,
+		MetaUpdate:    true,
+		ContentType:   ctx.String("meta-update.content-type"),
+		StorageClass:  ctx.String("meta-update.storage-class"),
+	}
+	return runBench(ctx, &b)
+}
+
+func checkMetaUpdateSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, "obj.size", 0)
+	if ctx.NArg() > 0 {
+		console.Fatal("命令中没有附带参数")
+	}
+	if objectCount(ctx) <= 0 {
+		console.Fatal("objects 的值必须大于 0")
+	}
+
+	checkAnalyze(ctx)
+	checkBenchmark(ctx)
+}
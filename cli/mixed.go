@@ -29,16 +29,27 @@ import (
 
 var (
 	mixedFlags = []cli.Flag{
-		cli.IntFlag{
+		cli.StringFlag{
 			Name:  "objects",
-			Value: 2500,
-			Usage: "要上传的对象数.",
+			Value: "2500",
+			Usage: "要上传的对象数. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
 		},
+		objectsWorkingSetFlag,
 		cli.StringFlag{
 			Name:  "obj.size",
 			Value: "10MiB",
 			Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
 		},
+		cli.StringFlag{
+			Name:  "get.size",
+			Value: "",
+			Usage: "预先上传用于 GET/STAT/DELETE 请求的对象大小. 默认使用 obj.size.",
+		},
+		cli.StringFlag{
+			Name:  "put.size",
+			Value: "",
+			Usage: "PUT 请求上传的对象大小. 默认使用 obj.size.",
+		},
 		cli.Float64Flag{
 			Name:  "get-distrib",
 			Usage: "GET 请求操作权重量.",
@@ -59,6 +70,11 @@ var (
 			Usage: "DELETE 请求操作权重量. 须小于等于 PUT 请求权重量.",
 			Value: 10,
 		},
+		cli.StringFlag{
+			Name:  "mixed.order",
+			Value: bench.MixedOrderRandom,
+			Usage: "请求操作的顺序. 'random' 随机交错, 'weighted-shuffle' 随机交错并在每 20 个请求中保持权重比例, 'bursty' 同类请求成批发送.",
+		},
 	}
 )
 
@@ -83,7 +99,8 @@ var mixedCmd = cli.Command{
 // mainMixed is the entry point for mixed command.
 func mainMixed(ctx *cli.Context) error {
 	checkMixedSyntax(ctx)
-	src := newGenSource(ctx)
+	src := newGenSourceSize(ctx, mixedSizeFlag(ctx, "get.size"))
+	putSrc := newGenSourceSize(ctx, mixedSizeFlag(ctx, "put.size"))
 	sse := newSSE(ctx)
 	dist := bench.MixedDistribution{
 		Distribution: map[string]float64{
@@ -92,32 +109,49 @@ func mainMixed(ctx *cli.Context) error {
 			http.MethodPut:    ctx.Float64("put-distrib"),
 			http.MethodDelete: ctx.Float64("delete-distrib"),
 		},
+		Order: ctx.String("mixed.order"),
 	}
-	err := dist.Generate(ctx.Int("objects") * 2)
+	err := dist.Generate(objectCount(ctx) * 2)
 	fatalIf(probe.NewError(err), "无效的请求分配比例")
 	b := bench.Mixed{
 		Common: bench.Common{
 			Client:      newClient(ctx),
-			Concurrency: ctx.Int("concurrent"),
+			Concurrency: concurrency(ctx),
 			Source:      src,
 			Bucket:      ctx.String("bucket"),
 			Location:    "",
 			PutOpts:     putOpts(ctx),
 		},
-		CreateObjects: ctx.Int("objects"),
+		CreateObjects: objectCount(ctx),
 		GetOpts:       minio.GetObjectOptions{ServerSideEncryption: sse},
 		StatOpts: minio.StatObjectOptions{
 			ServerSideEncryption: sse,
 		},
-		Dist: &dist,
+		Dist:      &dist,
+		PutSource: putSrc,
 	}
 	return runBench(ctx, &b)
 }
 
+// mixedSizeFlag returns the flag to use for object size.
+// If the override flag isn't set, obj.size is used.
+func mixedSizeFlag(ctx *cli.Context, override string) string {
+	if ctx.String(override) != "" {
+		return override
+	}
+	return "obj.size"
+}
+
 func checkMixedSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, mixedSizeFlag(ctx, "get.size"), 0)
 	if ctx.NArg() > 0 {
 		console.Fatal("命令中没有附带参数")
 	}
+	switch ctx.String("mixed.order") {
+	case bench.MixedOrderRandom, bench.MixedOrderWeightedShuffle, bench.MixedOrderBursty:
+	default:
+		console.Fatalf("未知的 mixed.order %q, 可以是 'random', 'weighted-shuffle' 或 'bursty'\n", ctx.String("mixed.order"))
+	}
 
 	checkAnalyze(ctx)
 	checkBenchmark(ctx)
@@ -0,0 +1,88 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package cli
+
+import (
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/bench"
+)
+
+var postFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "obj.size",
+		Value: "10MiB",
+		Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
+	},
+	cli.DurationFlag{
+		Name:  "post.policy-expiry",
+		Value: 15 * time.Minute,
+		Usage: "每个 POST 策略的有效期. 有效期过半后将重新生成策略.",
+	},
+}
+
+// Post command.
+var postCmd = cli.Command{
+	Name:   "post",
+	Usage:  "使用 POST 策略上传对象 (浏览器表单上传) 请求操作的基准测试",
+	Action: mainPost,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags, postFlags, genFlags, benchFlags, analyzeFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} [FLAGS]
+  -> see https://github.com/minio/warp#post
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// mainPost is the entry point for post command.
+func mainPost(ctx *cli.Context) error {
+	checkPostSyntax(ctx)
+	src := newGenSource(ctx)
+	b := bench.Post{
+		Common: bench.Common{
+			Client:      newClient(ctx),
+			Concurrency: concurrency(ctx),
+			Source:      src,
+			Bucket:      ctx.String("bucket"),
+			Location:    "",
+			PutOpts:     putOpts(ctx),
+		},
+		PolicyExpiry: ctx.Duration("post.policy-expiry"),
+		Do:           newRawDo(ctx),
+	}
+	return runBench(ctx, &b)
+}
+
+func checkPostSyntax(ctx *cli.Context) {
+	if ctx.NArg() > 0 {
+		console.Fatal("命令中没有附带参数")
+	}
+	if ctx.Duration("post.policy-expiry") < time.Minute {
+		console.Fatal("post.policy-expiry 不能小于 1 分钟")
+	}
+
+	checkAnalyze(ctx)
+	checkBenchmark(ctx)
+}
@@ -0,0 +1,105 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package cli
+
+import (
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/bench"
+)
+
+var presignFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "objects",
+		Value: "2500",
+		Usage: "要上传的对象数. 每个对象生成一个预签名 GET URL. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
+	},
+	objectsWorkingSetFlag,
+	cli.StringFlag{
+		Name:  "obj.size",
+		Value: "10MiB",
+		Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
+	},
+	cli.DurationFlag{
+		Name:  "presign.expiry",
+		Value: time.Hour,
+		Usage: "每个预签名 URL 的有效期. 有效期过半后将重新生成所有 URL. 最长为 7 天.",
+	},
+	cli.IntFlag{
+		Name:  "presign.put",
+		Value: 0,
+		Usage: "使用预签名 PUT URL 上传的请求百分比 (0-100). 其余请求使用预签名 GET URL 下载.",
+	},
+}
+
+// Presign command.
+var presignCmd = cli.Command{
+	Name:   "presign",
+	Usage:  "使用预签名 URL 下载和上传对象请求操作的基准测试",
+	Action: mainPresign,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags, presignFlags, genFlags, benchFlags, analyzeFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} [FLAGS]
+  -> see https://github.com/minio/warp#presign
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// mainPresign is the entry point for presign command.
+func mainPresign(ctx *cli.Context) error {
+	checkPresignSyntax(ctx)
+	src := newGenSource(ctx)
+	b := bench.Presign{
+		Common: bench.Common{
+			Client:      newClient(ctx),
+			Concurrency: concurrency(ctx),
+			Source:      src,
+			Bucket:      ctx.String("bucket"),
+			Location:    "",
+			PutOpts:     putOpts(ctx),
+		},
+		CreateObjects: objectCount(ctx),
+		Expiry:        ctx.Duration("presign.expiry"),
+		PutPct:        ctx.Int("presign.put"),
+		Do:            newRawDo(ctx),
+	}
+	return runBench(ctx, &b)
+}
+
+func checkPresignSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, "obj.size", 0)
+	if ctx.NArg() > 0 {
+		console.Fatal("命令中没有附带参数")
+	}
+	if d := ctx.Duration("presign.expiry"); d < time.Minute || d > 7*24*time.Hour {
+		console.Fatal("presign.expiry 必须在 1 分钟到 7 天之间")
+	}
+	if p := ctx.Int("presign.put"); p < 0 || p > 100 {
+		console.Fatal("presign.put 必须在 0 到 100 之间")
+	}
+
+	checkAnalyze(ctx)
+	checkBenchmark(ctx)
+}
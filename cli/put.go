@@ -18,6 +18,10 @@
 package cli
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/minio/cli"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio/pkg/console"
@@ -31,6 +35,44 @@ var (
 			Value: "10MiB",
 			Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
 		},
+		putExpiresFlag,
+		putChecksumFlag,
+		cli.BoolFlag{
+			Name:  "put.if-none-match",
+			Usage: "上传时发送 'If-None-Match: *' 头. 每个线程每隔一次上传会覆盖之前上传的对象, 返回 412 的上传单独记录为 PUT-412.",
+		},
+		cli.StringFlag{
+			Name:  "multipart.threshold",
+			Value: "",
+			Usage: "大于等于该大小的对象使用分片上传, 较小的对象使用单个 PUT, 例如 '16MiB'. 最小为 5MiB. 默认由客户端决定.",
+		},
+	}
+
+	putChecksumFlag = cli.StringFlag{
+		Name:  "put.checksum",
+		Value: "",
+		Usage: "上传时发送的校验和算法. 值可以是 'crc32c', 'crc32', 'sha1' 和 'sha256'. 为空表示不发送.",
+	}
+
+	putExpiresFlag = cli.DurationFlag{
+		Name:  "put.expires",
+		Value: 0,
+		Usage: "为上传的对象设置 Expires 头, 值为从上传开始后的持续时间, 例如 '24h'. 0 表示不设置.",
+	}
+
+	lifecycleFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "obj.size",
+			Value: "10MiB",
+			Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
+		},
+		cli.IntFlag{
+			Name:  "lifecycle.days",
+			Value: 1,
+			Usage: "存储桶生命周期规则中对象过期的天数.",
+		},
+		putExpiresFlag,
+		putChecksumFlag,
 	}
 )
 
@@ -53,6 +95,25 @@ var putCmd = cli.Command{
   {{end}}`,
 }
 
+// Lifecycle command.
+var lifecycleCmd = cli.Command{
+	Name:   "lifecycle",
+	Usage:  "在设置了生命周期过期规则的存储桶中上传对象 (put) 请求操作的基准测试",
+	Action: mainLifecycle,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags, lifecycleFlags, genFlags, benchFlags, analyzeFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} [FLAGS]
+  -> see https://github.com/minio/warp#lifecycle
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
 // mainPut is the entry point for cp command.
 func mainPut(ctx *cli.Context) error {
 	checkPutSyntax(ctx)
@@ -60,24 +121,119 @@ func mainPut(ctx *cli.Context) error {
 	b := bench.Put{
 		Common: bench.Common{
 			Client:      newClient(ctx),
-			Concurrency: ctx.Int("concurrent"),
+			Concurrency: concurrency(ctx),
 			Source:      src,
 			Bucket:      ctx.String("bucket"),
 			Location:    "",
 			PutOpts:     putOpts(ctx),
 		},
+		Checksum:           ctx.String("put.checksum"),
+		Do:                 newSignedDo(ctx),
+		MultipartThreshold: multipartThreshold(ctx),
+		IfNoneMatch:        ctx.Bool("put.if-none-match"),
+		Expires:            putExpires(ctx),
+	}
+	if classes := storageClasses(ctx); len(classes) > 1 {
+		b.StorageClasses = classes
 	}
 	return runBench(ctx, &b)
 }
 
+// multipartThreshold returns the value of --multipart.threshold or 0 if not set.
+func multipartThreshold(ctx *cli.Context) int64 {
+	s := ctx.String("multipart.threshold")
+	if s == "" {
+		return 0
+	}
+	sz, err := toSize(s)
+	if err != nil {
+		console.Fatal("无效的 multipart.threshold 参数: ", err)
+	}
+	if sz < 5<<20 {
+		console.Fatal("multipart.threshold 不能小于 5MiB")
+	}
+	return int64(sz)
+}
+
+// mainLifecycle is the entry point for lifecycle command.
+func mainLifecycle(ctx *cli.Context) error {
+	checkPutSyntax(ctx)
+	if ctx.Int("lifecycle.days") <= 0 {
+		console.Fatal("lifecycle.days 的值必须大于 0")
+	}
+	src := newGenSource(ctx)
+	b := bench.Lifecycle{
+		Put: bench.Put{
+			Common: bench.Common{
+				Client:      newClient(ctx),
+				Concurrency: concurrency(ctx),
+				Source:      src,
+				Bucket:      ctx.String("bucket"),
+				Location:    "",
+				PutOpts:     putOpts(ctx),
+			},
+			Expires:  putExpires(ctx),
+			Checksum: ctx.String("put.checksum"),
+			Do:       newSignedDo(ctx),
+		},
+		ExpireDays: ctx.Int("lifecycle.days"),
+	}
+	return runBench(ctx, &b)
+}
+
+// putExpires returns the Expires time of uploaded objects set by --put.expires.
+// The zero time is returned if not set.
+func putExpires(ctx *cli.Context) time.Time {
+	exp := ctx.Duration("put.expires")
+	if exp <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(exp)
+}
+
 // putOpts retrieves put options from the context.
 func putOpts(ctx *cli.Context) minio.PutObjectOptions {
-	return minio.PutObjectOptions{
+	opts := minio.PutObjectOptions{
 		ServerSideEncryption: newSSE(ctx),
 		DisableMultipart:     ctx.Bool("disable-multipart"),
 		SendContentMd5:       ctx.Bool("md5"),
-		StorageClass:         ctx.String("storage-class"),
 	}
+	switch classes := storageClasses(ctx); {
+	case len(classes) == 1:
+		opts.StorageClass = classes[0].Class
+	case len(classes) > 1 && ctx.Command.Name != "put":
+		console.Fatal("带权重的多个存储类仅支持 put 基准测试")
+	}
+	if tag := ctx.String("run-tag"); tag != "" {
+		opts.UserTags = map[string]string{runTagKey: tag}
+	}
+	return opts
+}
+
+// storageClasses parses --storage-class.
+// It is either a single storage class or a comma separated list of 'CLASS:weight'.
+// Returns nil if not set.
+func storageClasses(ctx *cli.Context) []bench.WeightedStorageClass {
+	s := ctx.String("storage-class")
+	if s == "" {
+		return nil
+	}
+	if !strings.ContainsAny(s, ":,") {
+		return []bench.WeightedStorageClass{{Class: s, Weight: 1}}
+	}
+	var classes []bench.WeightedStorageClass
+	for _, part := range strings.Split(s, ",") {
+		idx := strings.LastIndexByte(part, ':')
+		if idx <= 0 {
+			console.Fatal("无效的 storage-class 参数, 应为 'CLASS:权重': ", part)
+		}
+		weight, err := strconv.Atoi(part[idx+1:])
+		if err != nil || weight <= 0 {
+			console.Fatal("storage-class 的权重必须是正整数: ", part)
+		}
+		classes = append(classes, bench.WeightedStorageClass{Class: strings.TrimSpace(part[:idx]), Weight: weight})
+	}
+	return classes
 }
 
 func checkPutSyntax(ctx *cli.Context) {
@@ -85,6 +241,22 @@ func checkPutSyntax(ctx *cli.Context) {
 		console.Fatal("命令中没有附带参数")
 	}
 
+	if ctx.Duration("put.expires") < 0 {
+		console.Fatal("put.expires 的值不能是负数")
+	}
+	if err := bench.CheckChecksumAlgorithm(ctx.String("put.checksum")); err != nil {
+		console.Fatal("无效的 put.checksum 参数: ", err)
+	}
+	if ctx.String("put.checksum") != "" && ctx.Bool("md5") {
+		console.Fatal("put.checksum 不能与 md5 同时使用")
+	}
+	if ctx.String("multipart.threshold") != "" {
+		if ctx.Bool("disable-multipart") {
+			console.Fatal("multipart.threshold 不能与 disable-multipart 同时使用")
+		}
+		multipartThreshold(ctx)
+	}
+
 	checkAnalyze(ctx)
 	checkBenchmark(ctx)
 }
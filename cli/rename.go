@@ -0,0 +1,91 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"github.com/minio/cli"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/bench"
+)
+
+var (
+	renameFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "objects",
+			Value: "1000",
+			Usage: "要上传并重命名的对象数. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
+		},
+		objectsWorkingSetFlag,
+		cli.StringFlag{
+			Name:  "obj.size",
+			Value: "1KiB",
+			Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
+		},
+	}
+)
+
+var renameCmd = cli.Command{
+	Name:   "rename",
+	Usage:  "重命名对象 (RenameObject) 请求操作的基准测试",
+	Action: mainRename,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags, renameFlags, genFlags, benchFlags, analyzeFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} [FLAGS]
+  -> see https://github.com/minio/warp#rename
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// mainRename is the entry point for rename command.
+func mainRename(ctx *cli.Context) error {
+	checkRenameSyntax(ctx)
+	src := newGenSource(ctx)
+
+	b := bench.Rename{
+		Common: bench.Common{
+			Client:      newClient(ctx),
+			Concurrency: concurrency(ctx),
+			Source:      src,
+			Bucket:      ctx.String("bucket"),
+			Location:    "",
+			PutOpts:     putOpts(ctx),
+		},
+		CreateObjects: objectCount(ctx),
+		Do:            newSignedDo(ctx),
+	}
+	return runBench(ctx, &b)
+}
+
+func checkRenameSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, "obj.size", 0)
+	if ctx.NArg() > 0 {
+		console.Fatal("命令中没有附带参数")
+	}
+	if objectCount(ctx) <= 0 {
+		console.Fatal("objects 的值必须大于 0")
+	}
+
+	checkAnalyze(ctx)
+	checkBenchmark(ctx)
+}
@@ -0,0 +1,71 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package cli
+
+import (
+	"github.com/minio/cli"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/bench"
+)
+
+var replayCmd = cli.Command{
+	Name:   "replay",
+	Usage:  "按原有的顺序和时间间隔重放基准测试数据文件中的操作",
+	Action: mainReplay,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags, benchFlags, analyzeFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} [FLAGS] benchmark-data-file
+  -> see https://github.com/minio/warp#replay
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// mainReplay is the entry point for replay command.
+func mainReplay(ctx *cli.Context) error {
+	checkReplaySyntax(ctx)
+	ops := readBenchDataFile(ctx, ctx.Args().First(), false)
+	if len(ops) == 0 {
+		console.Fatal("输入文件中没有操作")
+	}
+
+	b := bench.Replay{
+		Common: bench.Common{
+			Client:      newClient(ctx),
+			Concurrency: concurrency(ctx),
+			Bucket:      ctx.String("bucket"),
+			Location:    "",
+			PutOpts:     putOpts(ctx),
+		},
+		Ops: ops,
+	}
+	return runBench(ctx, &b)
+}
+
+func checkReplaySyntax(ctx *cli.Context) {
+	if ctx.NArg() != 1 {
+		console.Fatal("需要一个基准测试数据文件作为参数")
+	}
+
+	checkAnalyze(ctx)
+	checkBenchmark(ctx)
+}
@@ -0,0 +1,157 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"sync"
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/bench"
+)
+
+var (
+	replicateFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "obj.size",
+			Value: "1MiB",
+			Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
+		},
+		cli.StringFlag{
+			Name:   "replica-host",
+			Usage:  "副本服务器的主机. 可以指定多个主机, 用逗号分隔.",
+			EnvVar: appNameUC + "_REPLICA_HOST",
+		},
+		cli.StringFlag{
+			Name:  "replica-bucket",
+			Usage: "副本服务器上的存储桶. 默认与 --bucket 相同.",
+		},
+		cli.StringFlag{
+			Name:   "replica-access-key",
+			Usage:  "副本服务器的访问密钥 (access key). 默认与 --access-key 相同.",
+			EnvVar: appNameUC + "_REPLICA_ACCESS_KEY",
+		},
+		cli.StringFlag{
+			Name:   "replica-secret-key",
+			Usage:  "副本服务器的密钥 (secret key). 默认与 --secret-key 相同.",
+			EnvVar: appNameUC + "_REPLICA_SECRET_KEY",
+		},
+		cli.DurationFlag{
+			Name:  "replica.timeout",
+			Value: time.Minute,
+			Usage: "等待对象出现在副本上的最长时间. 超过的对象记录为 REPLICATE-TIMEOUT.",
+		},
+		cli.DurationFlag{
+			Name:  "replica.poll",
+			Value: 100 * time.Millisecond,
+			Usage: "检查副本上对象的时间间隔.",
+		},
+	}
+)
+
+var replicateCmd = cli.Command{
+	Name:   "replicate",
+	Usage:  "上传对象并测量对象复制到副本的延迟 (replication lag) 的基准测试",
+	Action: mainReplicate,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags, replicateFlags, genFlags, benchFlags, analyzeFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} [FLAGS]
+  -> see https://github.com/minio/warp#replicate
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// mainReplicate is the entry point for replicate command.
+func mainReplicate(ctx *cli.Context) error {
+	checkReplicateSyntax(ctx)
+	src := newGenSource(ctx)
+	replicaBucket := ctx.String("replica-bucket")
+	if replicaBucket == "" {
+		replicaBucket = ctx.String("bucket")
+	}
+	b := bench.Replicate{
+		Common: bench.Common{
+			Client:      newClient(ctx),
+			Concurrency: concurrency(ctx),
+			Source:      src,
+			Bucket:      ctx.String("bucket"),
+			Location:    "",
+			PutOpts:     putOpts(ctx),
+		},
+		ReplicaClient: newReplicaClient(ctx),
+		ReplicaBucket: replicaBucket,
+		Timeout:       ctx.Duration("replica.timeout"),
+		PollInterval:  ctx.Duration("replica.poll"),
+	}
+	return runBench(ctx, &b)
+}
+
+// newReplicaClient returns clients for the replica hosts selected in round-robin order.
+func newReplicaClient(ctx *cli.Context) func() (cl *minio.Client, done func()) {
+	accessKey, secretKey := ctx.String("replica-access-key"), ctx.String("replica-secret-key")
+	if accessKey == "" {
+		accessKey = ctx.String("access-key")
+	}
+	if secretKey == "" {
+		secretKey = ctx.String("secret-key")
+	}
+	hosts := parseHosts(ctx.String("replica-host"))
+	clients := make([]*minio.Client, len(hosts))
+	for i, host := range hosts {
+		cl, err := getClientCreds(ctx, host, credentials.NewStaticV4(accessKey, secretKey, ""))
+		fatalIf(probe.NewError(err), "无法创建副本的 MinIO 客户端")
+		clients[i] = cl
+	}
+	var mu sync.Mutex
+	var current int
+	return func() (*minio.Client, func()) {
+		mu.Lock()
+		now := current % len(clients)
+		current++
+		mu.Unlock()
+		return clients[now], func() {}
+	}
+}
+
+func checkReplicateSyntax(ctx *cli.Context) {
+	if ctx.NArg() > 0 {
+		console.Fatal("命令中没有附带参数")
+	}
+	if len(parseHosts(ctx.String("replica-host"))) == 0 {
+		console.Fatal("必须使用 --replica-host 指定副本服务器")
+	}
+	if ctx.Duration("replica.timeout") <= 0 {
+		console.Fatal("replica.timeout 的值必须大于 0")
+	}
+	if ctx.Duration("replica.poll") <= 0 {
+		console.Fatal("replica.poll 的值必须大于 0")
+	}
+
+	checkAnalyze(ctx)
+	checkBenchmark(ctx)
+}
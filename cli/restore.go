@@ -0,0 +1,122 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/bench"
+)
+
+var (
+	restoreFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "objects",
+			Value: "1000",
+			Usage: "要上传并恢复的对象数. 每个对象只会恢复一次. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
+		},
+		objectsWorkingSetFlag,
+		cli.StringFlag{
+			Name:  "obj.size",
+			Value: "1KB",
+			Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
+		},
+		cli.IntFlag{
+			Name:  "restore.days",
+			Value: 1,
+			Usage: "恢复的对象副本保留的天数.",
+		},
+		cli.StringFlag{
+			Name:  "restore.tier",
+			Value: "Standard",
+			Usage: "恢复使用的检索层级. 值可以是 'Standard', 'Bulk' 和 'Expedited'.",
+		},
+		cli.DurationFlag{
+			Name:  "restore.poll",
+			Value: 5 * time.Second,
+			Usage: "检查恢复的对象是否可用的时间间隔.",
+		},
+	}
+)
+
+var restoreCmd = cli.Command{
+	Name:   "restore",
+	Usage:  "从归档层恢复对象 (restore) 请求操作的基准测试",
+	Action: mainRestore,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags, restoreFlags, genFlags, benchFlags, analyzeFlags),
+	CustomHelpTemplate: `名称:
+  {{.HelpName}} - {{.Usage}}
+
+使用:
+  {{.HelpName}} [FLAGS]
+  -> see https://github.com/minio/warp#restore
+
+参数:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// mainRestore is the entry point for restore command.
+func mainRestore(ctx *cli.Context) error {
+	checkRestoreSyntax(ctx)
+	src := newGenSource(ctx)
+
+	b := bench.Restore{
+		Common: bench.Common{
+			Client:      newClient(ctx),
+			Concurrency: concurrency(ctx),
+			Source:      src,
+			Bucket:      ctx.String("bucket"),
+			Location:    "",
+			PutOpts:     putOpts(ctx),
+		},
+		CreateObjects: objectCount(ctx),
+		RestoreDays:   ctx.Int("restore.days"),
+		RestoreTier:   ctx.String("restore.tier"),
+		PollInterval:  ctx.Duration("restore.poll"),
+		Do:            newSignedDo(ctx),
+	}
+	return runBench(ctx, &b)
+}
+
+func checkRestoreSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, "obj.size", 0)
+	if ctx.NArg() > 0 {
+		console.Fatal("命令中没有附带参数")
+	}
+	if ctx.Int("restore.days") <= 0 {
+		console.Fatal("restore.days 的值必须大于 0")
+	}
+	if ctx.Duration("restore.poll") <= 0 {
+		console.Fatal("restore.poll 的值必须大于 0")
+	}
+	if ctx.String("storage-class") == "" {
+		console.Fatal("restore 需要使用 --storage-class 指定归档存储类, 上传的对象必须在归档层中")
+	}
+	switch ctx.String("restore.tier") {
+	case "Standard", "Bulk", "Expedited":
+	default:
+		console.Fatal("无法识别 restore.tier 的值: " + ctx.String("restore.tier"))
+	}
+
+	checkAnalyze(ctx)
+	checkBenchmark(ctx)
+}
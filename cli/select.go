@@ -18,18 +18,25 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
 	"github.com/minio/warp/pkg/bench"
 )
 
 var (
 	selectFlags = []cli.Flag{
-		cli.IntFlag{
+		cli.StringFlag{
 			Name:  "objects",
-			Value: 2500,
-			Usage: "要上传的对象数.",
+			Value: "2500",
+			Usage: "要上传的对象数. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
 		},
+		objectsWorkingSetFlag,
 		cli.StringFlag{
 			Name:  "obj.size",
 			Value: "10MiB",
@@ -40,6 +47,21 @@ var (
 			Value: "select * from s3object",
 			Usage: "select 查询的表达式",
 		},
+		cli.StringFlag{
+			Name:  "select.format",
+			Value: "csv",
+			Usage: "上传对象的格式. 值可以是 'csv' 和 'json' (JSON Lines).",
+		},
+		cli.StringFlag{
+			Name:  "select.compression",
+			Value: "none",
+			Usage: "上传对象的压缩格式. 值可以是 'none' 和 'gzip'.",
+		},
+		cli.StringFlag{
+			Name:  "select.scan-range",
+			Value: "",
+			Usage: "只扫描每个对象的指定字节范围, 格式为 'start-end', 例如 '0-1MiB'. 不能超过对象大小.",
+		},
 	}
 )
 
@@ -48,7 +70,7 @@ var selectCmd = cli.Command{
 	Usage:  "选择对象 (select) 请求操作的基准测试",
 	Action: mainSelect,
 	Before: setGlobalsFromContext,
-	Flags:  combineFlags(globalFlags, ioFlags, selectFlags, genFlags, benchFlags, analyzeFlags),
+	Flags:  combineFlags(globalFlags, ioFlags, selectFlags, readBucketFlags, genFlags, benchFlags, analyzeFlags),
 	CustomHelpTemplate: `名称:
   {{.HelpName}} - {{.Usage}}
 
@@ -69,26 +91,19 @@ func mainSelect(ctx *cli.Context) error {
 	b := bench.Select{
 		Common: bench.Common{
 			Client:      newClient(ctx),
-			Concurrency: ctx.Int("concurrent"),
+			Concurrency: concurrency(ctx),
 			Source:      src,
 			Bucket:      ctx.String("bucket"),
 			Location:    "",
 			PutOpts:     putOpts(ctx),
 		},
-		CreateObjects: ctx.Int("objects"),
+		CreateObjects: objectCount(ctx),
 		SelectOpts: minio.SelectObjectOptions{
 			Expression:     ctx.String("query"),
 			ExpressionType: minio.QueryExpressionTypeSQL,
 			// Set any encryption headers
 			ServerSideEncryption: sse,
-			// TODO: support all variations including, json/parquet
-			InputSerialization: minio.SelectObjectInputSerialization{
-				CSV: &minio.CSVInputOptions{
-					RecordDelimiter: "\n",
-					FieldDelimiter:  ",",
-					FileHeaderInfo:  minio.CSVFileHeaderInfoUse,
-				},
-			},
+			InputSerialization:   selectInput(ctx),
 			OutputSerialization: minio.SelectObjectOutputSerialization{
 				CSV: &minio.CSVOutputOptions{
 					RecordDelimiter: "\n",
@@ -97,10 +112,113 @@ func mainSelect(ctx *cli.Context) error {
 			},
 		},
 	}
+	if ctx.String("select.scan-range") != "" {
+		sr, err := parseScanRange(ctx.String("select.scan-range"))
+		fatalIf(probe.NewError(err), "无效的 select.scan-range")
+		b.ScanRange = sr
+		b.Do = newSignedDo(ctx)
+	}
+	setReadBucket(ctx, &b.Common)
 	return runBench(ctx, &b)
 }
 
+// parseScanRange parses a scan range in the form 'start-end'.
+// Start and end can have size suffixes. End is inclusive.
+func parseScanRange(s string) (*bench.SelectScanRange, error) {
+	split := strings.Split(s, "-")
+	if len(split) != 2 {
+		return nil, errors.New("scan range must be in the form 'start-end'")
+	}
+	start, err := toSize(strings.TrimSpace(split[0]))
+	if err != nil {
+		return nil, err
+	}
+	end, err := toSize(strings.TrimSpace(split[1]))
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, errors.New("scan range end must not be before start")
+	}
+	return &bench.SelectScanRange{Start: int64(start), End: int64(end)}, nil
+}
+
+// selectInput returns the serialization of the Select input.
+func selectInput(ctx *cli.Context) minio.SelectObjectInputSerialization {
+	in := minio.SelectObjectInputSerialization{CompressionType: minio.SelectCompressionNONE}
+	if strings.ToLower(ctx.String("select.compression")) == "gzip" {
+		in.CompressionType = minio.SelectCompressionGZIP
+	}
+	if strings.ToLower(ctx.String("select.format")) == "json" {
+		in.JSON = &minio.JSONInputOptions{Type: minio.JSONLinesType}
+		return in
+	}
+	in.CSV = &minio.CSVInputOptions{
+		RecordDelimiter: "\n",
+		FieldDelimiter:  ",",
+		FileHeaderInfo:  minio.CSVFileHeaderInfoUse,
+	}
+	return in
+}
+
+// selectCompressions contains the compressions S3 Select accepts for each input format.
+var selectCompressions = map[string][]string{
+	"csv":     {"none", "gzip", "bzip2"},
+	"json":    {"none", "gzip", "bzip2"},
+	"parquet": {"none"},
+}
+
+// checkSelectInput returns an error if objects of the format and compression
+// cannot be queried or cannot be generated.
+func checkSelectInput(format, compression string) error {
+	format, compression = strings.ToLower(format), strings.ToLower(compression)
+	if compression == "" {
+		compression = "none"
+	}
+	valid, ok := selectCompressions[format]
+	if !ok {
+		return fmt.Errorf("无法识别 select.format 的值: %s", format)
+	}
+	switch compression {
+	case "none", "gzip", "bzip2":
+	default:
+		return fmt.Errorf("无法识别 select.compression 的值: %s", compression)
+	}
+	supported := false
+	for _, c := range valid {
+		supported = supported || c == compression
+	}
+	if !supported {
+		return fmt.Errorf("S3 Select 不支持 %s 压缩的 %s 对象, select.compression 可以是: %s", compression, format, strings.Join(valid, ", "))
+	}
+	switch {
+	case format == "parquet":
+		return errors.New("无法生成 parquet 格式的数据, select.format 可以是 'csv' 或 'json'")
+	case compression == "bzip2":
+		// The standard library can decompress, but not compress bzip2.
+		return errors.New("无法生成 bzip2 压缩的数据, select.compression 可以是 'none' 或 'gzip'")
+	}
+	return nil
+}
+
 func checkSelectSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, "obj.size", 0)
+	if err := checkSelectInput(ctx.String("select.format"), ctx.String("select.compression")); err != nil {
+		console.Fatal(err)
+	}
+	if sr := ctx.String("select.scan-range"); sr != "" {
+		r, err := parseScanRange(sr)
+		if err != nil {
+			console.Fatal("无效的 select.scan-range: " + err.Error())
+		}
+		if strings.ToLower(ctx.String("select.compression")) == "gzip" {
+			console.Fatal("select.scan-range 不支持压缩的对象")
+		}
+		// Random sizes are checked for each object when preparing.
+		if sz, err := toSize(ctx.String("obj.size")); err == nil && uint64(r.End) >= sz {
+			console.Fatalf("select.scan-range %s 超出了对象大小 %s\n", sr, ctx.String("obj.size"))
+		}
+	}
 	checkAnalyze(ctx)
 	checkBenchmark(ctx)
 }
@@ -0,0 +1,124 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/minio/pkg/madmin"
+)
+
+// serverMetricsSnapshot is the server information at a point in time.
+type serverMetricsSnapshot struct {
+	Time  time.Time           `json:"time"`
+	Info  *madmin.InfoMessage `json:"info,omitempty"`
+	Error string              `json:"error,omitempty"`
+}
+
+// serverMetrics collects server information while a benchmark is running.
+type serverMetrics struct {
+	client   *madmin.AdminClient
+	interval time.Duration
+
+	mu        sync.Mutex
+	snapshots []serverMetricsSnapshot
+	wg        sync.WaitGroup
+	cancel    context.CancelFunc
+}
+
+// startServerMetrics will snapshot the server information every --collect-server-metrics.interval
+// once start is closed, if --collect-server-metrics is set.
+// The admin client of the server profiles is used if they are running.
+func startServerMetrics(ctx2 context.Context, ctx *cli.Context, prof *runningProfiles, start <-chan struct{}) *serverMetrics {
+	if !ctx.Bool("collect-server-metrics") {
+		return nil
+	}
+	sm := serverMetrics{interval: ctx.Duration("collect-server-metrics.interval")}
+	if prof != nil && prof.client != nil {
+		sm.client = prof.client
+	} else {
+		sm.client = newAdminClient(ctx)
+	}
+	ctx2, sm.cancel = context.WithCancel(ctx2)
+	sm.wg.Add(1)
+	go func() {
+		defer sm.wg.Done()
+		select {
+		case <-start:
+		case <-ctx2.Done():
+			return
+		}
+		t := time.NewTicker(sm.interval)
+		defer t.Stop()
+		for {
+			sm.snapshot(ctx2)
+			select {
+			case <-t.C:
+			case <-ctx2.Done():
+				return
+			}
+		}
+	}()
+	return &sm
+}
+
+// snapshot adds the current server information.
+func (sm *serverMetrics) snapshot(ctx context.Context) {
+	s := serverMetricsSnapshot{Time: time.Now()}
+	info, err := sm.client.ServerInfo(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		s.Error = err.Error()
+	} else {
+		s.Info = &info
+	}
+	sm.mu.Lock()
+	sm.snapshots = append(sm.snapshots, s)
+	sm.mu.Unlock()
+}
+
+// stop will stop collecting and write the snapshots to fileName as JSON, one snapshot per line.
+func (sm *serverMetrics) stop(fileName string) {
+	if sm == nil {
+		return
+	}
+	sm.cancel()
+	sm.wg.Wait()
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		console.Error("无法写入服务器指标:", err)
+		return
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, s := range sm.snapshots {
+		if err := enc.Encode(s); err != nil {
+			console.Error("无法写入服务器指标:", err)
+			return
+		}
+	}
+	console.Infof("已收集 %d 个服务器指标快照, 写入到了 %s\n", len(sm.snapshots), fileName)
+}
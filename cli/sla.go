@@ -0,0 +1,215 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/aggregate"
+)
+
+// slaCheck is a single latency requirement for a size class,
+// for example '<=1MiB:p99<50ms'.
+type slaCheck struct {
+	spec string
+
+	// Size class. sizeOp is empty if all sizes match.
+	sizeOp string
+	size   int64
+
+	metric  string
+	limitOp string
+	limit   time.Duration
+}
+
+// slaMetrics are the supported latency metrics.
+var slaMetrics = []string{"avg", "p50", "p90", "p99", "max"}
+
+// parseSLA parses comma separated SLA expressions.
+func parseSLA(s string) ([]slaCheck, error) {
+	var res []slaCheck
+	for _, spec := range strings.Split(s, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		var c slaCheck
+		c.spec = spec
+		fields := strings.SplitN(spec, ":", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%q: 格式应为 '<大小类>:<指标><限制>', 例如 '<=1MiB:p99<50ms'", spec)
+		}
+		sizeClass, cond := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+		if sizeClass != "*" {
+			c.sizeOp, sizeClass = splitCompareOp(sizeClass)
+			if c.sizeOp == "" {
+				return nil, fmt.Errorf("%q: 大小类必须以 <, <=, > 或 >= 开头, 或者使用 '*' 匹配所有大小", spec)
+			}
+			sz, err := toSize(sizeClass)
+			if err != nil {
+				return nil, fmt.Errorf("%q: 无效的大小: %v", spec, err)
+			}
+			c.size = int64(sz)
+		}
+		idx := strings.Index(cond, "<")
+		if idx <= 0 {
+			return nil, fmt.Errorf("%q: 条件格式应为 '<指标><限制>' 或 '<指标><=<限制>', 例如 'p99<50ms'", spec)
+		}
+		c.metric = strings.ToLower(strings.TrimSpace(cond[:idx]))
+		if !slaKnownMetric(c.metric) {
+			return nil, fmt.Errorf("%q: 未知的指标 %q, 可以是 %s", spec, c.metric, strings.Join(slaMetrics, ", "))
+		}
+		var limit string
+		c.limitOp, limit = splitCompareOp(cond[idx:])
+		d, err := time.ParseDuration(strings.TrimSpace(limit))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%q: 无效的延迟限制 %q", spec, limit)
+		}
+		c.limit = d
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+// splitCompareOp splits a leading comparison operator from s.
+func splitCompareOp(s string) (op, rest string) {
+	for _, op := range []string{"<=", ">=", "<", ">"} {
+		if strings.HasPrefix(s, op) {
+			return op, strings.TrimSpace(s[len(op):])
+		}
+	}
+	return "", s
+}
+
+func slaKnownMetric(m string) bool {
+	for _, known := range slaMetrics {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSize returns whether a size class with the average object size matches.
+func (c slaCheck) matchSize(size int64) bool {
+	return slaCompare(c.sizeOp, size, c.size)
+}
+
+// passes returns whether the duration in microseconds fulfills the requirement.
+func (c slaCheck) passes(micros int) bool {
+	return slaCompare(c.limitOp, int64(micros)*int64(time.Microsecond), int64(c.limit))
+}
+
+func slaCompare(op string, a, b int64) bool {
+	switch op {
+	case "":
+		return true
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+// slaSizeClass contains the durations of a single size class in microseconds.
+type slaSizeClass struct {
+	name      string
+	size      int64
+	durations map[string]int
+}
+
+// slaSizeClasses returns the size classes of the operation.
+func slaSizeClasses(op aggregate.Operation) []slaSizeClass {
+	if r := op.SingleSizedRequests; r != nil && !r.Skipped {
+		return []slaSizeClass{{
+			name: fmt.Sprintf("%d 字节", r.ObjSize),
+			size: r.ObjSize,
+			durations: map[string]int{
+				"avg": r.DurAvgMicros,
+				"p50": r.DurMedianMicros,
+				"p90": r.Dur90Micros,
+				"p99": r.Dur99Micros,
+				"max": r.SlowestMicros,
+			},
+		}}
+	}
+	var res []slaSizeClass
+	if r := op.MultiSizedRequests; r != nil && !r.Skipped {
+		for _, s := range r.BySize {
+			res = append(res, slaSizeClass{
+				name: fmt.Sprintf("%s -> %s", s.MinSizeString, s.MaxSizeString),
+				size: int64(s.AvgObjSize),
+				durations: map[string]int{
+					"avg": s.AvgDurationMicros,
+					"p50": s.DurMedianMicros,
+					"p90": s.Dur90Micros,
+					"p99": s.Dur99Micros,
+					"max": s.SlowestMicros,
+				},
+			})
+		}
+	}
+	return res
+}
+
+// checkSLA evaluates --sla against the aggregated operations.
+// Violations are printed and warp exits with an error.
+func checkSLA(ctx *cli.Context, aggr aggregate.Aggregated) {
+	checks, err := parseSLA(ctx.String("sla"))
+	fatalIf(probe.NewError(err), "无效的 sla 参数")
+	if len(checks) == 0 {
+		return
+	}
+	var failed, evaluated int
+	for _, op := range aggr.Operations {
+		for _, sc := range slaSizeClasses(op) {
+			for _, c := range checks {
+				if !c.matchSize(sc.size) {
+					continue
+				}
+				evaluated++
+				got := sc.durations[c.metric]
+				if c.passes(got) {
+					continue
+				}
+				failed++
+				console.Errorf("SLA 未通过: %s, 大小类 %s (平均 %d 字节): %s 为 %v, 要求 %s\n",
+					op.Type, sc.name, sc.size, c.metric, aggregate.MicrosToDuration(got), c.spec)
+			}
+		}
+	}
+	if evaluated == 0 {
+		console.Errorln("警告: 没有与 SLA 匹配的大小类")
+	}
+	if failed > 0 {
+		fatalIf(errDummy(), "%d 项 SLA 检查未通过", failed)
+	}
+	if evaluated > 0 && !globalQuiet && !globalJSON {
+		console.Printf("所有 %d 项 SLA 检查均已通过.\n", evaluated)
+	}
+}
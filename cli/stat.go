@@ -26,16 +26,22 @@ import (
 
 var (
 	statFlags = []cli.Flag{
-		cli.IntFlag{
+		cli.StringFlag{
 			Name:  "objects",
-			Value: 10000,
-			Usage: "要上传的对象数. 四舍五入使其具有相等的并发对象数.",
+			Value: "10000",
+			Usage: "要上传的对象数. 四舍五入使其具有相等的并发对象数. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
 		},
+		objectsWorkingSetFlag,
 		cli.StringFlag{
 			Name:  "obj.size",
 			Value: "1KB",
 			Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
 		},
+		cli.StringFlag{
+			Name:  "stat.method",
+			Value: bench.StatMethodHead,
+			Usage: "读取对象元数据使用的 API. 可以是 'head' (HeadObject) 或 'attributes' (GetObjectAttributes).",
+		},
 	}
 )
 
@@ -44,7 +50,7 @@ var statCmd = cli.Command{
 	Usage:  "获取对象元数据信息 (stat) 请求操作的基准测试",
 	Action: mainStat,
 	Before: setGlobalsFromContext,
-	Flags:  combineFlags(globalFlags, ioFlags, statFlags, genFlags, benchFlags, analyzeFlags),
+	Flags:  combineFlags(globalFlags, ioFlags, statFlags, readBucketFlags, genFlags, benchFlags, analyzeFlags),
 	CustomHelpTemplate: `名称:
   {{.HelpName}} - {{.Usage}}
 
@@ -66,24 +72,39 @@ func mainStat(ctx *cli.Context) error {
 	b := bench.Stat{
 		Common: bench.Common{
 			Client:      newClient(ctx),
-			Concurrency: ctx.Int("concurrent"),
+			Concurrency: concurrency(ctx),
 			Source:      src,
 			Bucket:      ctx.String("bucket"),
 			Location:    "",
 			PutOpts:     putOpts(ctx),
 		},
-		CreateObjects: ctx.Int("objects"),
+		CreateObjects: objectCount(ctx),
 		StatOpts: minio.StatObjectOptions{
 			ServerSideEncryption: sse,
 		},
+		Method: ctx.String("stat.method"),
 	}
+	if b.Method == bench.StatMethodAttributes {
+		b.Do = newSignedDo(ctx)
+	}
+	setReadBucket(ctx, &b.Common)
 	return runBench(ctx, &b)
 }
 
 func checkStatSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, "obj.size", 0)
 	if ctx.NArg() > 0 {
 		console.Fatal("命令中没有附带参数")
 	}
+	switch ctx.String("stat.method") {
+	case bench.StatMethodHead:
+	case bench.StatMethodAttributes:
+		if ctx.Bool("anonymous") {
+			console.Fatal("--stat.method=attributes 不能与 --anonymous 同时使用")
+		}
+	default:
+		console.Fatalf("未知的 stat.method %q, 可以是 'head' 或 'attributes'\n", ctx.String("stat.method"))
+	}
 
 	checkAnalyze(ctx)
 	checkBenchmark(ctx)
@@ -29,16 +29,22 @@ import (
 
 var (
 	versionedFlags = []cli.Flag{
-		cli.IntFlag{
+		cli.StringFlag{
 			Name:  "objects",
-			Value: 250,
-			Usage: "要上传的对象数.",
+			Value: "250",
+			Usage: "要上传的对象数. 使用 'auto' 根据对象大小, duration 和 objects.working-set 估算.",
 		},
+		objectsWorkingSetFlag,
 		cli.StringFlag{
 			Name:  "obj.size",
 			Value: "10MiB",
 			Usage: "生成每个对象的大小. 可以是数字或 10KiB/MiB/GiB. 数字必须是 2^n 倍.",
 		},
+		cli.IntFlag{
+			Name:  "versions-per-object",
+			Value: 1,
+			Usage: "准备阶段为每个对象创建的版本数.",
+		},
 		cli.Float64Flag{
 			Name:  "get-distrib",
 			Usage: "GET 请求操作权重量.",
@@ -93,19 +99,20 @@ func mainVersioned(ctx *cli.Context) error {
 			http.MethodDelete: ctx.Float64("delete-distrib"),
 		},
 	}
-	err := dist.Generate(ctx.Int("objects") * 2)
+	err := dist.Generate(objectCount(ctx) * 2)
 	fatalIf(probe.NewError(err), "无效的请求操作分配比例")
 	b := bench.Versioned{
 		Common: bench.Common{
 			Client:      newClient(ctx),
-			Concurrency: ctx.Int("concurrent"),
+			Concurrency: concurrency(ctx),
 			Source:      src,
 			Bucket:      ctx.String("bucket"),
 			Location:    "",
 			PutOpts:     putOpts(ctx),
 		},
-		CreateObjects: ctx.Int("objects"),
-		GetOpts:       minio.GetObjectOptions{ServerSideEncryption: sse},
+		CreateObjects:     objectCount(ctx),
+		VersionsPerObject: ctx.Int("versions-per-object"),
+		GetOpts:           minio.GetObjectOptions{ServerSideEncryption: sse},
 		StatOpts: minio.StatObjectOptions{
 			ServerSideEncryption: sse,
 		},
@@ -115,9 +122,13 @@ func mainVersioned(ctx *cli.Context) error {
 }
 
 func checkVersionedSyntax(ctx *cli.Context) {
+	resolveObjects(ctx, "obj.size", 0)
 	if ctx.NArg() > 0 {
 		console.Fatal("命令中没有附带参数")
 	}
+	if ctx.Int("versions-per-object") < 1 {
+		console.Fatal("--versions-per-object 必须至少为 1")
+	}
 
 	checkAnalyze(ctx)
 	checkBenchmark(ctx)
@@ -20,4 +20,5 @@ require (
 	golang.org/x/crypto v0.0.0-20210220033148-5ea612d1eb83 // indirect
 	golang.org/x/net v0.0.0-20201010224723-4f7140c49acb
 	golang.org/x/sys v0.0.0-20210220050731-9a76102bfb43 // indirect
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -61,12 +61,37 @@ type Operation struct {
 	MultiSizedRequests *MultiSizedRequests `json:"multi_sized_requests,omitempty"`
 	// Total errors recorded.
 	Errors int `json:"errors"`
+	// Operations canceled when the benchmark was stopped.
+	// These are not counted as errors.
+	Canceled int `json:"canceled,omitempty"`
+	// Errors caused by exceeding the operation timeout.
+	TimedOut int `json:"timed_out,omitempty"`
+	// The operation timeout, if any operations timed out.
+	TimeoutMillis int `json:"timeout_millis,omitempty"`
 	// Subset of errors.
 	FirstErrors []string `json:"first_errors"`
+	// Request IDs of the slowest requests, if recorded.
+	SlowestRequests []string `json:"slowest_requests,omitempty"`
+	// Time spent in each request phase, if recorded.
+	RequestPhases *RequestPhases `json:"request_phases,omitempty"`
+	// Captured response headers, if any.
+	Headers []HeaderValues `json:"headers,omitempty"`
+	// Number of operations for each HTTP protocol, if recorded.
+	Protocols map[string]int `json:"protocols,omitempty"`
+	// Requests in flight over time.
+	InFlight *InFlight `json:"in_flight,omitempty"`
+	// Throttled operations, if any.
+	Throttle *Throttle `json:"throttle,omitempty"`
+	// Requests slower than the latency SLO, if set.
+	SLOViolations *SLOViolations `json:"slo_violations,omitempty"`
 	// Throughput information.
 	Throughput Throughput `json:"throughput"`
 	// Throughput by host.
 	ThroughputByHost map[string]Throughput `json:"throughput_by_host"`
+	// Throughput by tenant, if several tenants were recorded.
+	ThroughputByTenant map[string]Throughput `json:"throughput_by_tenant,omitempty"`
+	// Throughput by storage class, if several storage classes were used.
+	ThroughputByStorageClass map[string]Throughput `json:"throughput_by_storage_class,omitempty"`
 }
 
 // SegmentDurFn accepts a total time and should return the duration used for each segment.
@@ -76,10 +101,22 @@ type Options struct {
 	Prefiltered bool
 	DurFunc     SegmentDurFn
 	SkipDur     time.Duration
+	// WallClockAligned will align segments to whole multiples of the segment duration.
+	WallClockAligned bool
+	// SLO will count requests slower than this, if > 0.
+	SLO time.Duration
 }
 
 // Aggregate returns statistics when only a single operation was running concurrently.
 func Aggregate(o bench.Operations, opts Options) Aggregated {
+	// Operations canceled at shutdown are only counted.
+	canceled := make(map[string]int)
+	if c := o.FilterCanceled(true); len(c) > 0 {
+		for _, op := range c {
+			canceled[op.OpType]++
+		}
+		o = o.FilterCanceled(false)
+	}
 	o.SortByStartTime()
 	types := o.OpTypes()
 	a := Aggregated{
@@ -119,10 +156,11 @@ func Aggregate(o bench.Operations, opts Options) Aggregated {
 
 		segmentDur := opts.DurFunc(total.Duration())
 		segs := ops.Segment(bench.SegmentOptions{
-			From:           time.Time{},
-			PerSegDuration: segmentDur,
-			AllThreads:     false,
-			MultiOp:        true,
+			From:             time.Time{},
+			PerSegDuration:   segmentDur,
+			WallClockAligned: opts.WallClockAligned,
+			AllThreads:       false,
+			MultiOp:          true,
 		})
 		if len(segs) > 1 {
 			a.MixedServerStats.Segmented = &ThroughputSegmented{
@@ -170,6 +208,7 @@ func Aggregate(o bench.Operations, opts Options) Aggregated {
 				wg.Done()
 			}()
 			a.Type = typ
+			a.Canceled = canceled[typ]
 			ops := o.FilterByOp(typ)
 			if opts.SkipDur > 0 {
 				start, end := ops.TimeRange()
@@ -179,6 +218,12 @@ func Aggregate(o bench.Operations, opts Options) Aggregated {
 			errs := ops.FilterErrors()
 			if len(errs) > 0 {
 				a.Errors = len(errs)
+				for _, err := range errs {
+					if err.Timeout > 0 {
+						a.TimedOut++
+						a.TimeoutMillis = durToMillis(err.Timeout)
+					}
+				}
 				for _, err := range errs {
 					if len(a.FirstErrors) >= 10 {
 						break
@@ -189,10 +234,11 @@ func Aggregate(o bench.Operations, opts Options) Aggregated {
 
 			segmentDur := opts.DurFunc(ops.Duration())
 			segs := ops.Segment(bench.SegmentOptions{
-				From:           time.Time{},
-				PerSegDuration: segmentDur,
-				AllThreads:     !opts.Prefiltered,
-				MultiOp:        false,
+				From:             time.Time{},
+				PerSegDuration:   segmentDur,
+				WallClockAligned: opts.WallClockAligned,
+				AllThreads:       !opts.Prefiltered,
+				MultiOp:          false,
 			})
 			a.N = len(ops)
 			if len(segs) <= 1 {
@@ -216,10 +262,26 @@ func Aggregate(o bench.Operations, opts Options) Aggregated {
 				SegmentDurationMillis: durToMillis(segmentDur),
 			}
 			a.Throughput.Segmented.fill(segs, total)
+			a.InFlight = inFlight(allOps, a.Throughput.Segmented.Segments, segmentDur)
+			a.Throttle = throttling(allOps, a.Throughput.Segmented.Segments, segmentDur)
+			if opts.SLO > 0 {
+				a.SLOViolations = sloViolations(ops, opts.SLO)
+			}
 			a.ObjectsPerOperation = ops.FirstObjPerOp()
 			a.Concurrency = ops.Threads()
 			a.Clients = ops.Clients()
 			a.Hosts = ops.Hosts()
+			a.RequestPhases = requestPhases(ops)
+			a.Headers = capturedHeaders(allOps)
+			if protos := allOps.Protocols(); len(protos) > 0 {
+				a.Protocols = protos
+			}
+			for _, op := range ops.Slowest(5) {
+				if op.RequestID == "" {
+					continue
+				}
+				a.SlowestRequests = append(a.SlowestRequests, fmt.Sprintf("%s, %v: %s", op.Endpoint, op.Duration().Round(time.Millisecond), op.RequestID))
+			}
 
 			if !ops.MultipleSizes() {
 				a.SingleSizedRequests = RequestAnalysisSingleSized(ops, !opts.Prefiltered)
@@ -236,30 +298,9 @@ func Aggregate(o bench.Operations, opts Options) Aggregated {
 				go func(ep string) {
 					defer epWg.Done()
 					// Use all ops to include errors.
-					ops := allOps.FilterByEndpoint(ep)
-
-					segs := ops.Segment(bench.SegmentOptions{
-						From:           time.Time{},
-						PerSegDuration: segmentDur,
-						AllThreads:     false,
-					})
-
-					var host Throughput
-					errs := ops.FilterErrors()
-					if len(errs) > 0 {
-						ops = ops.FilterSuccessful()
-						if len(ops) == 0 {
-							return
-						}
-					}
-					total := ops.Total(false)
-					total.Errors = len(errs)
-					host.fill(total)
-					if len(segs) > 1 {
-						host.Segmented = &ThroughputSegmented{
-							SegmentDurationMillis: durToMillis(segmentDur),
-						}
-						host.Segmented.fill(segs, total)
+					host, ok := partThroughput(allOps.FilterByEndpoint(ep), segmentDur, opts.WallClockAligned)
+					if !ok {
+						return
 					}
 					epMu.Lock()
 					a.ThroughputByHost[ep] = host
@@ -267,9 +308,58 @@ func Aggregate(o bench.Operations, opts Options) Aggregated {
 				}(ep)
 			}
 			epWg.Wait()
+
+			if tenants := allOps.Tenants(); len(tenants) > 1 {
+				a.ThroughputByTenant = make(map[string]Throughput, len(tenants))
+				for _, tenant := range tenants {
+					if t, ok := partThroughput(allOps.FilterByTenant(tenant), segmentDur, opts.WallClockAligned); ok {
+						a.ThroughputByTenant[tenant] = t
+					}
+				}
+			}
+
+			if classes := allOps.StorageClasses(); len(classes) > 1 {
+				a.ThroughputByStorageClass = make(map[string]Throughput, len(classes))
+				for _, class := range classes {
+					if t, ok := partThroughput(allOps.FilterByStorageClass(class), segmentDur, opts.WallClockAligned); ok {
+						a.ThroughputByStorageClass[class] = t
+					}
+				}
+			}
 		}(i)
 	}
 	wg.Wait()
 	a.Operations = res
 	return a
 }
+
+// partThroughput returns the throughput of a part of the operations, for instance a single host.
+// Errors are counted but not included in the throughput.
+// If there are no successful operations false is returned.
+func partThroughput(ops bench.Operations, segmentDur time.Duration, wallClock bool) (Throughput, bool) {
+	segs := ops.Segment(bench.SegmentOptions{
+		From:             time.Time{},
+		PerSegDuration:   segmentDur,
+		WallClockAligned: wallClock,
+		AllThreads:       false,
+	})
+
+	var t Throughput
+	errs := ops.FilterErrors()
+	if len(errs) > 0 {
+		ops = ops.FilterSuccessful()
+		if len(ops) == 0 {
+			return t, false
+		}
+	}
+	total := ops.Total(false)
+	total.Errors = len(errs)
+	t.fill(total)
+	if len(segs) > 1 {
+		t.Segmented = &ThroughputSegmented{
+			SegmentDurationMillis: durToMillis(segmentDur),
+		}
+		t.Segmented.fill(segs, total)
+	}
+	return t, true
+}
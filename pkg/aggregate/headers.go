@@ -0,0 +1,116 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package aggregate
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+// maxHeaderValues is the maximum number of distinct values reported for each header.
+const maxHeaderValues = 10
+
+// HeaderValues contains statistics for a captured response header.
+type HeaderValues struct {
+	Name string `json:"name"`
+	// N is the number of operations with the header set.
+	N int `json:"n"`
+	// Distinct is the number of distinct values.
+	Distinct int `json:"distinct"`
+	// Most common values, most common first.
+	Values []HeaderValueCount `json:"values"`
+	// Numeric is populated if all values are numbers.
+	Numeric *HeaderNumeric `json:"numeric,omitempty"`
+}
+
+// HeaderValueCount is the number of operations with a specific header value.
+type HeaderValueCount struct {
+	Value string `json:"value"`
+	N     int    `json:"n"`
+}
+
+// HeaderNumeric contains the distribution of numeric header values.
+type HeaderNumeric struct {
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P90    float64 `json:"90th"`
+	P99    float64 `json:"99th"`
+	Max    float64 `json:"max"`
+}
+
+// capturedHeaders returns statistics for all captured headers of the operations.
+// If no headers were captured nil is returned.
+func capturedHeaders(ops bench.Operations) []HeaderValues {
+	names := ops.HeaderNames()
+	if len(names) == 0 {
+		return nil
+	}
+	res := make([]HeaderValues, 0, len(names))
+	for _, name := range names {
+		h := HeaderValues{Name: name}
+		counts := make(map[string]int)
+		var nums []float64
+		numeric := true
+		for _, op := range ops {
+			v, ok := op.Headers[name]
+			if !ok {
+				continue
+			}
+			h.N++
+			counts[v]++
+			if numeric {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					numeric = false
+					continue
+				}
+				nums = append(nums, f)
+			}
+		}
+		h.Distinct = len(counts)
+		for v, n := range counts {
+			h.Values = append(h.Values, HeaderValueCount{Value: v, N: n})
+		}
+		sort.Slice(h.Values, func(i, j int) bool {
+			if h.Values[i].N != h.Values[j].N {
+				return h.Values[i].N > h.Values[j].N
+			}
+			return h.Values[i].Value < h.Values[j].Value
+		})
+		if len(h.Values) > maxHeaderValues {
+			h.Values = h.Values[:maxHeaderValues]
+		}
+		if numeric && len(nums) > 0 {
+			sort.Float64s(nums)
+			pct := func(p float64) float64 {
+				return nums[int(p*float64(len(nums)-1))]
+			}
+			h.Numeric = &HeaderNumeric{
+				Min:    nums[0],
+				Median: pct(0.5),
+				P90:    pct(0.9),
+				P99:    pct(0.99),
+				Max:    nums[len(nums)-1],
+			}
+		}
+		res = append(res, h)
+	}
+	return res
+}
@@ -0,0 +1,77 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package aggregate
+
+import (
+	"math"
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+// InFlight contains the number of concurrently outstanding requests.
+type InFlight struct {
+	// Average number of requests in flight.
+	Average float64 `json:"average"`
+	// Lowest segment average.
+	Min float64 `json:"min"`
+	// Highest segment average.
+	Max float64 `json:"max"`
+	// Average number of requests in flight for each segment, ordered by time.
+	Segments []float64 `json:"segments"`
+}
+
+// inFlight returns the average number of requests in flight in each segment.
+// Segments are expected to be sorted by time.
+func inFlight(ops bench.Operations, segs []SegmentSmall, segDur time.Duration) *InFlight {
+	if len(segs) == 0 || segDur <= 0 {
+		return nil
+	}
+	from := segs[0].Start
+	to := from.Add(segDur * time.Duration(len(segs)))
+	busy := make([]time.Duration, len(segs))
+	for _, op := range ops {
+		start, end := op.Start, op.End
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		// Distribute the operation time into the segments it overlaps.
+		for start.Before(end) {
+			idx := int(start.Sub(from) / segDur)
+			segEnd := from.Add(segDur * time.Duration(idx+1))
+			if segEnd.After(end) {
+				segEnd = end
+			}
+			busy[idx] += segEnd.Sub(start)
+			start = segEnd
+		}
+	}
+	res := InFlight{Min: math.MaxFloat64, Segments: make([]float64, len(segs))}
+	var total float64
+	for i, b := range busy {
+		v := float64(b) / float64(segDur)
+		total += v
+		res.Segments[i] = math.Round(v*100) / 100
+		res.Min = math.Min(res.Min, res.Segments[i])
+		res.Max = math.Max(res.Max, res.Segments[i])
+	}
+	res.Average = math.Round(total/float64(len(segs))*100) / 100
+	return &res
+}
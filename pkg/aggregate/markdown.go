@@ -0,0 +1,85 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package aggregate
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+// Markdown writes the key results of each operation type as a GitHub flavored Markdown table.
+// Mixed benchmarks get an additional row with the totals.
+// Latencies of operations with different sizes are calculated across all sizes.
+// Values that are not available are written as '-'.
+func (a Aggregated) Markdown(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("| Operation | Requests | Obj/s | Throughput | p50 | p99 | Errors |\n")
+	sb.WriteString("|-----------|---------:|------:|-----------:|----:|----:|-------:|\n")
+	for _, op := range a.Operations {
+		p50, p99 := "-", "-"
+		if r := op.SingleSizedRequests; r != nil && !r.Skipped {
+			p50 = markdownDur(r.DurMedianMicros, r.DurMedianMillis)
+			p99 = markdownDur(r.Dur99Micros, r.Dur99Millis)
+		} else if r := op.MultiSizedRequests; r != nil && !r.Skipped {
+			p50 = markdownDur(r.DurMedianMicros, r.DurMedianMillis)
+			p99 = markdownDur(r.Dur99Micros, r.Dur99Millis)
+		}
+		if op.Skipped {
+			fmt.Fprintf(&sb, "| %s | %d | - | - | %s | %s | %d |\n", op.Type, op.N, p50, p99, op.Errors)
+			continue
+		}
+		fmt.Fprintf(&sb, "| %s | %d | %s | %s | %s | %s | %d |\n", op.Type, op.N, markdownOPS(op.Throughput.AverageOPS), markdownBPS(op.Throughput.AverageBPS), p50, p99, op.Errors)
+	}
+	if a.Mixed && a.MixedServerStats != nil {
+		t := a.MixedServerStats
+		n := 0
+		for _, op := range a.Operations {
+			n += op.N
+		}
+		fmt.Fprintf(&sb, "| **Total** | %d | %s | %s | - | - | %d |\n", n, markdownOPS(t.AverageOPS), markdownBPS(t.AverageBPS), t.Errors)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// markdownDur returns a request duration, using microseconds if recorded.
+func markdownDur(micros, millis int) string {
+	if micros > 0 {
+		return (time.Duration(micros) * time.Microsecond).Round(10 * time.Microsecond).String()
+	}
+	return (time.Duration(millis) * time.Millisecond).String()
+}
+
+// markdownOPS returns objects per second, or '-' if none.
+func markdownOPS(ops float64) string {
+	if ops <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", ops)
+}
+
+// markdownBPS returns the throughput, or '-' if no data was transferred.
+func markdownBPS(bps float64) string {
+	if bps <= 0 {
+		return "-"
+	}
+	return bench.Throughput(bps).String()
+}
@@ -0,0 +1,99 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package aggregate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+// PhaseLatency contains latency statistics for a single phase of requests.
+type PhaseLatency struct {
+	AverageMillis float64 `json:"average_millis"`
+	MedianMillis  float64 `json:"median_millis"`
+	P90Millis     float64 `json:"90th_millis"`
+	P99Millis     float64 `json:"99th_millis"`
+}
+
+// String returns a human printable version of the phase latency.
+func (p PhaseLatency) String() string {
+	return fmt.Sprintf("Avg: %v, 50%%: %v, 90%%: %v, 99%%: %v",
+		millisToDur(p.AverageMillis), millisToDur(p.MedianMillis), millisToDur(p.P90Millis), millisToDur(p.P99Millis))
+}
+
+// RequestPhases contains latency statistics for each phase of requests.
+// Operations that reused a connection will have no time spent on DNS, connect and TLS,
+// so these are included with 0 values.
+type RequestPhases struct {
+	DNS     PhaseLatency `json:"dns"`
+	Connect PhaseLatency `json:"connect"`
+	TLS     PhaseLatency `json:"tls"`
+	Server  PhaseLatency `json:"server"`
+}
+
+// requestPhases returns phase statistics for operations with phase timings.
+// If no operations have phase timings nil is returned.
+func requestPhases(ops bench.Operations) *RequestPhases {
+	var dns, connect, tls, server []time.Duration
+	for _, op := range ops {
+		if op.Phases == nil {
+			continue
+		}
+		dns = append(dns, op.Phases.DNS)
+		connect = append(connect, op.Phases.Connect)
+		tls = append(tls, op.Phases.TLS)
+		server = append(server, op.Phases.Server)
+	}
+	if len(server) == 0 {
+		return nil
+	}
+	return &RequestPhases{
+		DNS:     phaseLatency(dns),
+		Connect: phaseLatency(connect),
+		TLS:     phaseLatency(tls),
+		Server:  phaseLatency(server),
+	}
+}
+
+func phaseLatency(d []time.Duration) PhaseLatency {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	var total time.Duration
+	for _, v := range d {
+		total += v
+	}
+	pct := func(p float64) float64 {
+		return durToMillisF(d[int(p*float64(len(d)-1))])
+	}
+	return PhaseLatency{
+		AverageMillis: durToMillisF(total / time.Duration(len(d))),
+		MedianMillis:  pct(0.5),
+		P90Millis:     pct(0.9),
+		P99Millis:     pct(0.99),
+	}
+}
+
+func durToMillisF(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func millisToDur(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond)).Round(time.Microsecond)
+}
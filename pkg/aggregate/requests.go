@@ -44,6 +44,13 @@ type SingleSizedRequests struct {
 	FastestMillis int `json:"fastest_millis"`
 	// Slowest request time.
 	SlowestMillis int `json:"slowest_millis"`
+	// Request times in microseconds, for sub-millisecond precision.
+	DurAvgMicros    int `json:"dur_avg_micros"`
+	DurMedianMicros int `json:"dur_median_micros"`
+	Dur90Micros     int `json:"dur_90_micros"`
+	Dur99Micros     int `json:"dur_99_micros"`
+	FastestMicros   int `json:"fastest_micros"`
+	SlowestMicros   int `json:"slowest_micros"`
 	// Time to first byte if applicable.
 	FirstByte *TTFB `json:"first_byte,omitempty"`
 	// FirstAccess is filled if the same object is accessed multiple times.
@@ -64,6 +71,12 @@ func (a *SingleSizedRequests) fill(ops bench.Operations) {
 	a.Dur99Millis = durToMillis(ops.Median(0.99).Duration())
 	a.SlowestMillis = durToMillis(ops.Median(1).Duration())
 	a.FastestMillis = durToMillis(ops.Median(0).Duration())
+	a.DurAvgMicros = durToMicros(ops.AvgDuration())
+	a.DurMedianMicros = durToMicros(ops.Median(0.5).Duration())
+	a.Dur90Micros = durToMicros(ops.Median(0.9).Duration())
+	a.Dur99Micros = durToMicros(ops.Median(0.99).Duration())
+	a.SlowestMicros = durToMicros(ops.Median(1).Duration())
+	a.FastestMicros = durToMicros(ops.Median(0).Duration())
 	a.FirstByte = TtfbFromBench(ops.TTFB(start, end))
 }
 
@@ -90,6 +103,19 @@ type RequestSizeRange struct {
 	AvgObjSize        int `json:"avg_obj_size"`
 	AvgDurationMillis int `json:"avg_duration_millis"`
 
+	// Request duration percentiles.
+	DurMedianMillis int `json:"dur_median_millis"`
+	Dur90Millis     int `json:"dur_90_millis"`
+	Dur99Millis     int `json:"dur_99_millis"`
+	SlowestMillis   int `json:"slowest_millis"`
+
+	// Request duration in microseconds, for sub-millisecond precision.
+	AvgDurationMicros int `json:"avg_duration_micros"`
+	DurMedianMicros   int `json:"dur_median_micros"`
+	Dur90Micros       int `json:"dur_90_micros"`
+	Dur99Micros       int `json:"dur_99_micros"`
+	SlowestMicros     int `json:"slowest_micros"`
+
 	// Stats:
 	BpsAverage float64 `json:"bps_average"`
 	BpsMedian  float64 `json:"bps_median"`
@@ -120,6 +146,16 @@ func (r *RequestSizeRange) fill(s bench.SizeSegment) {
 	r.Bps99 = s.Ops.Median(0.99).BytesPerSec().Float()
 	r.BpsFastest = s.Ops.Median(0.0).BytesPerSec().Float()
 	r.BpsSlowest = s.Ops.Median(1).BytesPerSec().Float()
+	s.Ops.SortByDuration()
+	r.DurMedianMillis = durToMillis(s.Ops.Median(0.5).Duration())
+	r.Dur90Millis = durToMillis(s.Ops.Median(0.9).Duration())
+	r.Dur99Millis = durToMillis(s.Ops.Median(0.99).Duration())
+	r.SlowestMillis = durToMillis(s.Ops.Median(1).Duration())
+	r.AvgDurationMicros = durToMicros(s.Ops.AvgDuration())
+	r.DurMedianMicros = durToMicros(s.Ops.Median(0.5).Duration())
+	r.Dur90Micros = durToMicros(s.Ops.Median(0.9).Duration())
+	r.Dur99Micros = durToMicros(s.Ops.Median(0.99).Duration())
+	r.SlowestMicros = durToMicros(s.Ops.Median(1).Duration())
 }
 
 func (r *RequestSizeRange) fillFirst(s bench.SizeSegment) {
@@ -143,6 +179,12 @@ type MultiSizedRequests struct {
 	// Average object size
 	AvgObjSize int64 `json:"avg_obj_size"`
 
+	// Request duration percentiles of all sizes.
+	DurMedianMillis int `json:"dur_median_millis"`
+	Dur99Millis     int `json:"dur_99_millis"`
+	DurMedianMicros int `json:"dur_median_micros"`
+	Dur99Micros     int `json:"dur_99_micros"`
+
 	// BySize contains request times separated by sizes
 	BySize []RequestSizeRange `json:"by_size"`
 
@@ -175,6 +217,11 @@ func (a *MultiSizedRequests) fill(ops bench.Operations) {
 		}(i)
 	}
 	wg.Wait()
+	ops.SortByDuration()
+	a.DurMedianMillis = durToMillis(ops.Median(0.5).Duration())
+	a.Dur99Millis = durToMillis(ops.Median(0.99).Duration())
+	a.DurMedianMicros = durToMicros(ops.Median(0.5).Duration())
+	a.Dur99Micros = durToMicros(ops.Median(0.99).Duration())
 }
 
 // RequestAnalysisSingleSized performs analysis where all objects have equal size.
@@ -270,3 +317,20 @@ func RequestAnalysisHostsMultiSized(o bench.Operations) map[string]RequestSizeRa
 func durToMillis(d time.Duration) int {
 	return int(d.Round(time.Millisecond) / time.Millisecond)
 }
+
+// durToMicros converts a duration to microseconds.
+// Rounded to nearest.
+func durToMicros(d time.Duration) int {
+	return int(d.Round(time.Microsecond) / time.Microsecond)
+}
+
+// MicrosToDuration converts microseconds to a duration for display.
+// Durations of 100ms or more are rounded to milliseconds,
+// shorter durations keep microsecond precision.
+func MicrosToDuration(us int) time.Duration {
+	d := time.Duration(us) * time.Microsecond
+	if d >= 100*time.Millisecond {
+		return d.Round(time.Millisecond)
+	}
+	return d
+}
@@ -0,0 +1,57 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package aggregate
+
+import (
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+// SLOViolations contains the number of requests slower than the latency SLO.
+type SLOViolations struct {
+	// The latency threshold.
+	ThresholdMicros int `json:"threshold_micros"`
+	// Successful requests.
+	Requests int `json:"requests"`
+	// Requests that took longer than the threshold.
+	Violations int `json:"violations"`
+}
+
+// Percent returns the violations as a percentage of the requests.
+func (s SLOViolations) Percent() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return 100 * float64(s.Violations) / float64(s.Requests)
+}
+
+// sloViolations counts the successful operations that took longer than slo.
+func sloViolations(ops bench.Operations, slo time.Duration) *SLOViolations {
+	res := SLOViolations{ThresholdMicros: durToMicros(slo)}
+	for _, op := range ops {
+		if op.Err != "" {
+			continue
+		}
+		res.Requests++
+		if op.Duration() > slo {
+			res.Violations++
+		}
+	}
+	return &res
+}
@@ -0,0 +1,89 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package aggregate
+
+import (
+	"sort"
+	"time"
+
+	"github.com/minio/warp/pkg/bench"
+)
+
+// Throttle contains information about operations the server throttled.
+type Throttle struct {
+	// Operations with at least one throttled request.
+	Operations int `json:"operations"`
+	// Number of segments with throttled operations.
+	Segments int `json:"segments"`
+	// Total number of segments.
+	TotalSegments int `json:"total_segments"`
+	// Sustained is set when throttling occurred in most segments.
+	// The throughput is then likely limited by the throttling and not the server capacity.
+	Sustained bool `json:"sustained"`
+	// Median throughput of the throttled segments.
+	BPS float64 `json:"bytes_per_sec"`
+	OPS float64 `json:"obj_per_sec"`
+}
+
+// String returns a human readable description of the throughput ceiling.
+func (t Throttle) String() string {
+	return BPSorOPS(t.BPS, t.OPS)
+}
+
+// throttling returns information about throttled operations in the segments.
+// An operation is counted in the segment it ended in.
+// Segments are expected to be sorted by time.
+// If no operations were throttled nil is returned.
+func throttling(ops bench.Operations, segs []SegmentSmall, segDur time.Duration) *Throttle {
+	if len(segs) == 0 || segDur <= 0 {
+		return nil
+	}
+	from := segs[0].Start
+	throttled := make([]bool, len(segs))
+	var res Throttle
+	for _, op := range ops {
+		if !op.Throttled {
+			continue
+		}
+		res.Operations++
+		idx := int(op.End.Sub(from) / segDur)
+		if idx >= 0 && idx < len(segs) {
+			throttled[idx] = true
+		}
+	}
+	if res.Operations == 0 {
+		return nil
+	}
+	res.TotalSegments = len(segs)
+	var bps, objs []float64
+	for i, t := range throttled {
+		if !t {
+			continue
+		}
+		res.Segments++
+		bps = append(bps, segs[i].BPS)
+		objs = append(objs, segs[i].OPS)
+	}
+	res.Sustained = res.Segments > 1 && res.Segments*2 >= res.TotalSegments
+	if res.Segments > 0 {
+		sort.Float64s(bps)
+		sort.Float64s(objs)
+		res.BPS, res.OPS = bps[len(bps)/2], objs[len(objs)/2]
+	}
+	return &res
+}
@@ -39,6 +39,9 @@ type Throughput struct {
 	AverageBPS float64 `json:"average_bps"`
 	// Average operations per second.
 	AverageOPS float64 `json:"average_ops"`
+	// Average requests per second.
+	// Differs from AverageOPS when each request operates on several objects.
+	AverageRPS float64 `json:"average_rps"`
 	// Number of full operations
 	Operations int `json:"operations"`
 	// Time segmented throughput summary.
@@ -59,7 +62,7 @@ func (t Throughput) StringDuration() string {
 func (t Throughput) StringDetails(details bool) string {
 	speed := ""
 	if t.AverageBPS > 0 {
-		speed = fmt.Sprintf("%.02f MiB/s, ", t.AverageBPS/(1<<20))
+		speed = bench.Throughput(t.AverageBPS).StringLong() + ", "
 	}
 	errs := ""
 	if t.Errors > 0 {
@@ -69,8 +72,22 @@ func (t Throughput) StringDetails(details bool) string {
 		speed, t.AverageOPS, errs)
 }
 
+// Normalized returns the average throughput divided by n, for instance per host.
+// Errors and the segmented throughput are not included.
+func (t Throughput) Normalized(n int) Throughput {
+	if n <= 0 {
+		n = 1
+	}
+	t.AverageBPS /= float64(n)
+	t.AverageOPS /= float64(n)
+	t.AverageRPS /= float64(n)
+	t.Errors = 0
+	t.Segmented = nil
+	return t
+}
+
 func (t *Throughput) fill(total bench.Segment) {
-	mib, _, objs := total.SpeedPerSec()
+	mib, reqs, objs := total.SpeedPerSec()
 	*t = Throughput{
 		Operations:            total.FullOps,
 		MeasureDurationMillis: durToMillis(total.EndsBefore.Sub(total.Start)),
@@ -78,6 +95,7 @@ func (t *Throughput) fill(total bench.Segment) {
 		EndTime:               total.EndsBefore,
 		AverageBPS:            math.Round(mib*(1<<20)*10) / 10,
 		AverageOPS:            math.Round(objs*100) / 100,
+		AverageRPS:            math.Round(reqs*100) / 100,
 		Errors:                total.Errors,
 	}
 }
@@ -107,6 +125,24 @@ type ThroughputSegmented struct {
 	SlowestStart time.Time `json:"slowest_start"`
 	SlowestBPS   float64   `json:"slowest_bps"`
 	SlowestOPS   float64   `json:"slowest_ops"`
+	// Percentiles of the segment throughput, slowest first.
+	Percentiles []SegmentPercentile `json:"percentiles,omitempty"`
+
+	// Standard deviation of segment throughput, in the unit of SortedBy.
+	StdDev float64 `json:"std_dev"`
+	// Coefficient of variation of segment throughput (StdDev / mean).
+	// Lower values indicate more stable throughput.
+	CoV float64 `json:"coefficient_of_variation"`
+}
+
+// segmentPercentiles are the percentiles of segment throughput that are calculated.
+var segmentPercentiles = []int{1, 5, 95, 99}
+
+// SegmentPercentile is the segment at a percentile of segment throughput.
+// For instance at the 5th percentile 5% of the segments were slower.
+type SegmentPercentile struct {
+	Percentile int `json:"percentile"`
+	SegmentSmall
 }
 
 // BPSorOPS returns bytes per second if non zero otherwise operations per second as human readable string.
@@ -131,6 +167,12 @@ type SegmentSmall struct {
 
 	// Start time of the segment.
 	Start time.Time `json:"start"`
+
+	// Slowest and fastest speeds of the segments merged by Downsample.
+	MinBPS float64 `json:"min_bytes_per_sec,omitempty"`
+	MaxBPS float64 `json:"max_bytes_per_sec,omitempty"`
+	MinOPS float64 `json:"min_obj_per_sec,omitempty"`
+	MaxOPS float64 `json:"max_obj_per_sec,omitempty"`
 }
 
 // cloneBenchSegments clones benchmark segments to the simpler representation.
@@ -203,4 +245,126 @@ func (a *ThroughputSegmented) fill(segs bench.Segments, total bench.Segment) {
 		SlowestBPS:            bps(slow),
 		SlowestOPS:            ops(slow),
 	}
+	for _, p := range segmentPercentiles {
+		seg := segs.Median(float64(p) / 100)
+		a.Percentiles = append(a.Percentiles, SegmentPercentile{
+			Percentile:   p,
+			SegmentSmall: SegmentSmall{BPS: bps(seg), OPS: ops(seg), Start: seg.Start},
+		})
+	}
+	a.fillVariation()
+}
+
+// Downsample merges adjacent segments, so at most n segments remain.
+// Speeds of merged segments are averaged and the slowest and fastest
+// speeds are kept. Other statistics are not changed.
+func (a *ThroughputSegmented) Downsample(n int) {
+	if a == nil || n <= 0 || len(a.Segments) <= n {
+		return
+	}
+	per := (len(a.Segments) + n - 1) / n
+	res := make([]SegmentSmall, 0, n)
+	for segs := a.Segments; len(segs) > 0; {
+		group := segs
+		if len(group) > per {
+			group = group[:per]
+		}
+		segs = segs[len(group):]
+		m := SegmentSmall{Start: group[0].Start, MinBPS: math.MaxFloat64, MinOPS: math.MaxFloat64}
+		for _, seg := range group {
+			m.BPS += seg.BPS
+			m.OPS += seg.OPS
+			m.Errors += seg.Errors
+			m.MinBPS = math.Min(m.MinBPS, seg.minBPS())
+			m.MaxBPS = math.Max(m.MaxBPS, seg.maxBPS())
+			m.MinOPS = math.Min(m.MinOPS, seg.minOPS())
+			m.MaxOPS = math.Max(m.MaxOPS, seg.maxOPS())
+		}
+		m.BPS = math.Round(m.BPS / float64(len(group)))
+		m.OPS = math.Round(m.OPS*100/float64(len(group))) / 100
+		res = append(res, m)
+	}
+	a.Segments = res
+	a.SegmentDurationMillis *= per
+}
+
+func (s SegmentSmall) minBPS() float64 {
+	if s.MinBPS > 0 || s.MaxBPS > 0 {
+		return s.MinBPS
+	}
+	return s.BPS
+}
+
+func (s SegmentSmall) maxBPS() float64 {
+	if s.MaxBPS > 0 {
+		return s.MaxBPS
+	}
+	return s.BPS
+}
+
+func (s SegmentSmall) minOPS() float64 {
+	if s.MinOPS > 0 || s.MaxOPS > 0 {
+		return s.MinOPS
+	}
+	return s.OPS
+}
+
+func (s SegmentSmall) maxOPS() float64 {
+	if s.MaxOPS > 0 {
+		return s.MaxOPS
+	}
+	return s.OPS
+}
+
+// DownsampleSegments downsamples all time segments to at most n segments.
+// See ThroughputSegmented.Downsample.
+func (a *Aggregated) DownsampleSegments(n int) {
+	if a.MixedServerStats != nil {
+		a.MixedServerStats.Segmented.Downsample(n)
+	}
+	for _, t := range a.MixedThroughputByHost {
+		t.Segmented.Downsample(n)
+	}
+	for _, op := range a.Operations {
+		op.Throughput.Segmented.Downsample(n)
+		for _, t := range op.ThroughputByHost {
+			t.Segmented.Downsample(n)
+		}
+		for _, t := range op.ThroughputByTenant {
+			t.Segmented.Downsample(n)
+		}
+		for _, t := range op.ThroughputByStorageClass {
+			t.Segmented.Downsample(n)
+		}
+	}
+}
+
+// fillVariation calculates the standard deviation and coefficient of variation
+// of the segment throughput.
+func (a *ThroughputSegmented) fillVariation() {
+	if len(a.Segments) < 2 {
+		return
+	}
+	val := func(s SegmentSmall) float64 {
+		if a.SortedBy == "bps" {
+			return s.BPS
+		}
+		return s.OPS
+	}
+	var sum float64
+	for _, seg := range a.Segments {
+		sum += val(seg)
+	}
+	mean := sum / float64(len(a.Segments))
+	if mean <= 0 {
+		return
+	}
+	var sq float64
+	for _, seg := range a.Segments {
+		d := val(seg) - mean
+		sq += d * d
+	}
+	stdDev := math.Sqrt(sq / float64(len(a.Segments)))
+	a.StdDev = math.Round(stdDev*100) / 100
+	a.CoV = math.Round(stdDev/mean*10000) / 10000
 }
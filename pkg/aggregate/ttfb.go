@@ -19,6 +19,7 @@ package aggregate
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/minio/warp/pkg/bench"
@@ -30,18 +31,62 @@ type TTFB struct {
 	MedianMillis  int `json:"median_millis"`
 	FastestMillis int `json:"fastest_millis"`
 	SlowestMillis int `json:"slowest_millis"`
+	P90Millis     int `json:"p90_millis"`
+	P99Millis     int `json:"p99_millis"`
+
+	// Times to first byte in microseconds, for sub-millisecond precision.
+	AverageMicros int `json:"average_micros"`
+	MedianMicros  int `json:"median_micros"`
+	FastestMicros int `json:"fastest_micros"`
+	SlowestMicros int `json:"slowest_micros"`
+	P90Micros     int `json:"p90_micros"`
+	P99Micros     int `json:"p99_micros"`
+
+	// Histogram of time to first byte.
+	Histogram []TTFBBucket `json:"histogram,omitempty"`
+}
+
+// TTFBBucket contains the number of requests with a time to first byte
+// below UpToMillis and at least the limit of the previous bucket.
+type TTFBBucket struct {
+	UpToMillis int `json:"up_to_millis"`
+	Count      int `json:"count"`
 }
 
 // String returns a human printable version of the time to first byte.
 func (t TTFB) String() string {
-	if t.AverageMillis == 0 {
+	if t.AverageMicros == 0 {
 		return ""
 	}
-	return fmt.Sprintf("Avg: %v, Median: %v, Best: %v, Worst: %v",
-		time.Duration(t.AverageMillis)*time.Millisecond,
-		time.Duration(t.MedianMillis)*time.Millisecond,
-		time.Duration(t.FastestMillis)*time.Millisecond,
-		time.Duration(t.SlowestMillis)*time.Millisecond)
+	return fmt.Sprintf("Avg: %v, Median: %v, 90%%: %v, 99%%: %v, Best: %v, Worst: %v",
+		MicrosToDuration(t.AverageMicros),
+		MicrosToDuration(t.MedianMicros),
+		MicrosToDuration(t.P90Micros),
+		MicrosToDuration(t.P99Micros),
+		MicrosToDuration(t.FastestMicros),
+		MicrosToDuration(t.SlowestMicros))
+}
+
+// HistogramString returns a human printable histogram of the time to first byte.
+// Each line is prefixed with indent.
+func (t TTFB) HistogramString(indent string) string {
+	var total, maxCount int
+	for _, b := range t.Histogram {
+		total += b.Count
+		if b.Count > maxCount {
+			maxCount = b.Count
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+	const barWidth = 40
+	var sb strings.Builder
+	for _, b := range t.Histogram {
+		bar := strings.Repeat("#", (b.Count*barWidth+maxCount-1)/maxCount)
+		fmt.Fprintf(&sb, "%s< %-8v %6.2f%% %s\n", indent, time.Duration(b.UpToMillis)*time.Millisecond, 100*float64(b.Count)/float64(total), bar)
+	}
+	return sb.String()
 }
 
 // TtfbFromBench converts from bench.TTFB
@@ -54,5 +99,25 @@ func TtfbFromBench(t bench.TTFB) *TTFB {
 		MedianMillis:  durToMillis(t.Median),
 		FastestMillis: durToMillis(t.Best),
 		SlowestMillis: durToMillis(t.Worst),
+		P90Millis:     durToMillis(t.P90),
+		P99Millis:     durToMillis(t.P99),
+		AverageMicros: durToMicros(t.Average),
+		MedianMicros:  durToMicros(t.Median),
+		FastestMicros: durToMicros(t.Best),
+		SlowestMicros: durToMicros(t.Worst),
+		P90Micros:     durToMicros(t.P90),
+		P99Micros:     durToMicros(t.P99),
+		Histogram:     ttfbHistogram(t.Histogram),
+	}
+}
+
+func ttfbHistogram(h []bench.TTFBBucket) []TTFBBucket {
+	if len(h) == 0 {
+		return nil
+	}
+	res := make([]TTFBBucket, len(h))
+	for i, b := range h {
+		res[i] = TTFBBucket{UpToMillis: durToMillis(b.UpTo), Count: b.Count}
 	}
+	return res
 }
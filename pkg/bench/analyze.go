@@ -32,6 +32,16 @@ type SegmentOptions struct {
 	PerSegDuration time.Duration
 	AllThreads     bool
 	MultiOp        bool
+
+	// WallClockAligned will start segments at whole multiples of PerSegDuration,
+	// so segments from different clients line up.
+	// The first segment will start at the first boundary after the start of the operations.
+	WallClockAligned bool
+
+	// Slide will start a new segment every Slide, so segments overlap, if > 0 and less than PerSegDuration.
+	// Since an operation is counted in every segment it overlaps,
+	// overlapping segments should only be used for plotting and not for statistics.
+	Slide time.Duration
 }
 
 // A Segment represents totals of operations in a specific time segment
@@ -49,6 +59,18 @@ type Segment struct {
 	Errors     int       `json:"errors"`
 	Start      time.Time `json:"start"`
 	EndsBefore time.Time `json:"ends_before"`
+
+	// Min and Max contain the slowest and fastest speeds
+	// of the segments merged by Downsample.
+	Min *SegmentSpeed `json:"min,omitempty"`
+	Max *SegmentSpeed `json:"max,omitempty"`
+}
+
+// SegmentSpeed contains the speeds of a segment.
+type SegmentSpeed struct {
+	MiBPerSec      float64 `json:"mib_per_sec"`
+	OpsEndedPerSec float64 `json:"ops_ended_per_sec"`
+	ObjsPerSec     float64 `json:"objs_per_sec"`
 }
 
 // TTFB contains time to first byte stats.
@@ -57,6 +79,40 @@ type TTFB struct {
 	Worst   time.Duration
 	Best    time.Duration
 	Median  time.Duration
+	P90     time.Duration
+	P99     time.Duration
+
+	// Histogram of the time to first byte.
+	Histogram []TTFBBucket
+}
+
+// TTFBBucket contains the number of operations with time to first byte
+// less than UpTo and at least the UpTo of the previous bucket.
+type TTFBBucket struct {
+	UpTo  time.Duration
+	Count int
+}
+
+// ttfbHistogram returns a histogram of the sorted operations.
+// Bucket limits are doubled for each bucket, starting at 1ms.
+func ttfbHistogram(sorted Operations) []TTFBBucket {
+	var res []TTFBBucket
+	upTo := time.Millisecond
+	bucket := TTFBBucket{UpTo: upTo}
+	for _, op := range sorted {
+		ttfb := op.TTFB()
+		for ttfb >= bucket.UpTo {
+			res = append(res, bucket)
+			bucket = TTFBBucket{UpTo: bucket.UpTo * 2}
+		}
+		bucket.Count++
+	}
+	res = append(res, bucket)
+	// Remove empty leading buckets.
+	for len(res) > 1 && res[0].Count == 0 {
+		res = res[1:]
+	}
+	return res
 }
 
 // Segments is a slice of segment elements.
@@ -102,6 +158,10 @@ func (o Operations) TTFB(start, end time.Time) TTFB {
 		Worst:   0,
 		Best:    time.Minute * 10000,
 		Median:  filtered[len(filtered)/2].TTFB(),
+		P90:     filtered[(len(filtered)*90)/100].TTFB(),
+		P99:     filtered[(len(filtered)*99)/100].TTFB(),
+
+		Histogram: ttfbHistogram(filtered),
 	}
 	for _, op := range filtered {
 		ttfb := op.TTFB()
@@ -145,6 +205,17 @@ func (o Operations) Segment(so SegmentOptions) Segments {
 	if start.After(so.From) {
 		so.From = start
 	}
+	if so.WallClockAligned {
+		aligned := so.From.Truncate(so.PerSegDuration)
+		if aligned.Before(so.From) {
+			aligned = aligned.Add(so.PerSegDuration)
+		}
+		so.From = aligned
+	}
+	step := so.PerSegDuration
+	if so.Slide > 0 && so.Slide < step {
+		step = so.Slide
+	}
 	var segments []Segment
 	segStart := so.From
 	host := ""
@@ -183,7 +254,7 @@ func (o Operations) Segment(so SegmentOptions) Segments {
 			}
 		}
 		segments = append(segments, s)
-		segStart = segStart.Add(so.PerSegDuration)
+		segStart = segStart.Add(step)
 	}
 	return segments
 }
@@ -197,6 +268,12 @@ func (s Segment) SpeedPerSec() (mib, ops, objs float64) {
 	return
 }
 
+// AchievedOpsPerSec returns the number of operations started per second in the segment.
+// This is the rate requests were sent at, regardless of when they finished.
+func (s Segment) AchievedOpsPerSec() float64 {
+	return float64(s.OpsStarted) / (float64(s.EndsBefore.Sub(s.Start)) / float64(time.Second))
+}
+
 // Print segments to a supplied writer.
 func (s Segments) Print(w io.Writer) error {
 	for i, seg := range s {
@@ -212,7 +289,7 @@ func (s Segments) Print(w io.Writer) error {
 func (s Segments) CSV(w io.Writer) error {
 	cw := csv.NewWriter(w)
 	cw.Comma = '\t'
-	err := cw.Write([]string{
+	header := []string{
 		"index",
 		"op",
 		"host",
@@ -229,7 +306,14 @@ func (s Segments) CSV(w io.Writer) error {
 		"objs_per_sec",
 		"start_time",
 		"end_time",
-	})
+		"achieved_ops_per_sec",
+		"achieved_bytes_per_sec",
+	}
+	envelope := len(s) > 0 && s[0].Min != nil
+	if envelope {
+		header = append(header, "min_mb_per_sec", "max_mb_per_sec", "min_objs_per_sec", "max_objs_per_sec")
+	}
+	err := cw.Write(header)
 	if err != nil {
 		return err
 	}
@@ -243,10 +327,33 @@ func (s Segments) CSV(w io.Writer) error {
 	return nil
 }
 
+// GrafanaSeries is a time series in the format used by the Grafana JSON datasource.
+// Each datapoint is a value and a Unix timestamp in milliseconds.
+type GrafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// Grafana returns the segments as a Grafana time series named target.
+// Values are MiB/s if bytes is true, otherwise objects/s.
+// The timestamp of each datapoint is the start of the segment.
+func (s Segments) Grafana(target string, bytes bool) GrafanaSeries {
+	res := GrafanaSeries{Target: target, Datapoints: make([][2]float64, 0, len(s))}
+	for _, seg := range s {
+		mib, _, objs := seg.SpeedPerSec()
+		v := objs
+		if bytes {
+			v = mib
+		}
+		res.Datapoints = append(res.Datapoints, [2]float64{v, float64(seg.Start.UnixNano() / int64(time.Millisecond))})
+	}
+	return res
+}
+
 // CSV writes a CSV representation of the segment to the supplied writer.
 func (s Segment) CSV(w *csv.Writer, idx int) error {
 	mib, ops, objs := s.SpeedPerSec()
-	return w.Write([]string{
+	fields := []string{
 		fmt.Sprint(idx),
 		s.OpType,
 		s.Host,
@@ -263,7 +370,13 @@ func (s Segment) CSV(w *csv.Writer, idx int) error {
 		fmt.Sprint(objs),
 		fmt.Sprint(s.Start),
 		fmt.Sprint(s.EndsBefore),
-	})
+		fmt.Sprint(s.AchievedOpsPerSec()),
+		fmt.Sprint(mib * (1 << 20)),
+	}
+	if s.Min != nil && s.Max != nil {
+		fields = append(fields, fmt.Sprint(s.Min.MiBPerSec), fmt.Sprint(s.Max.MiBPerSec), fmt.Sprint(s.Min.ObjsPerSec), fmt.Sprint(s.Max.ObjsPerSec))
+	}
+	return w.Write(fields)
 }
 
 // String returns a string representation of the segment
@@ -276,7 +389,7 @@ func (s Segment) String() string {
 	mib, _, objs := s.SpeedPerSec()
 	speed := ""
 	if mib > 0 {
-		speed = fmt.Sprintf("%.02f MiB/s, ", mib)
+		speed = Throughput(mib*(1<<20)).StringLong() + ", "
 	}
 	return fmt.Sprintf("%s%.02f obj/s (%v, starting %v)",
 		speed, objs, s.EndsBefore.Sub(s.Start).Round(time.Millisecond), s.Start.Format("15:04:05 MST"))
@@ -287,7 +400,7 @@ func (s Segment) ShortString() string {
 	mib, _, objs := s.SpeedPerSec()
 	speed := ""
 	if mib > 0 {
-		speed = fmt.Sprintf("%.02f MiB/s, ", mib)
+		speed = Throughput(mib*(1<<20)).StringLong() + ", "
 	}
 	return fmt.Sprintf("%s%.02f obj/s (%v)",
 		speed, objs, s.EndsBefore.Sub(s.Start).Round(time.Millisecond))
@@ -331,6 +444,65 @@ func (s Segments) SortByTime() {
 	})
 }
 
+// Downsample merges adjacent segments, so at most n segments are returned.
+// Segments must be sorted by time. Merged segments contain the totals
+// of the merged segments, so the speed is the average speed.
+// The slowest and fastest speeds of the merged segments are kept in Min and Max.
+// If n <= 0 or there are no more than n segments, s is returned unmodified.
+func (s Segments) Downsample(n int) Segments {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	per := (len(s) + n - 1) / n
+	res := make(Segments, 0, n)
+	for len(s) > 0 {
+		group := s
+		if len(group) > per {
+			group = group[:per]
+		}
+		s = s[len(group):]
+		res = append(res, group.merge())
+	}
+	return res
+}
+
+// merge returns a single segment with the totals of all segments.
+func (s Segments) merge() Segment {
+	res := s[0]
+	res.Min, res.Max = nil, nil
+	for i, seg := range s {
+		mib, ops, objs := seg.SpeedPerSec()
+		speed := SegmentSpeed{MiBPerSec: mib, OpsEndedPerSec: ops, ObjsPerSec: objs}
+		lo, hi := speed, speed
+		if seg.Min != nil {
+			lo = *seg.Min
+		}
+		if seg.Max != nil {
+			hi = *seg.Max
+		}
+		if i == 0 {
+			res.Min, res.Max = &lo, &hi
+			continue
+		}
+		res.Min.MiBPerSec = math.Min(res.Min.MiBPerSec, lo.MiBPerSec)
+		res.Min.OpsEndedPerSec = math.Min(res.Min.OpsEndedPerSec, lo.OpsEndedPerSec)
+		res.Min.ObjsPerSec = math.Min(res.Min.ObjsPerSec, lo.ObjsPerSec)
+		res.Max.MiBPerSec = math.Max(res.Max.MiBPerSec, hi.MiBPerSec)
+		res.Max.OpsEndedPerSec = math.Max(res.Max.OpsEndedPerSec, hi.OpsEndedPerSec)
+		res.Max.ObjsPerSec = math.Max(res.Max.ObjsPerSec, hi.ObjsPerSec)
+
+		res.TotalBytes += seg.TotalBytes
+		res.FullOps += seg.FullOps
+		res.PartialOps += seg.PartialOps
+		res.OpsStarted += seg.OpsStarted
+		res.OpsEnded += seg.OpsEnded
+		res.Objects += seg.Objects
+		res.Errors += seg.Errors
+		res.EndsBefore = seg.EndsBefore
+	}
+	return res
+}
+
 // Median returns the m part median.
 // m is clamped to the range 0 -> 1.
 func (s Segments) Median(m float64) Segment {
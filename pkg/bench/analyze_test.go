@@ -70,3 +70,67 @@ func TestOperations_Segment(t *testing.T) {
 		t.Log(buf.String())
 	}
 }
+
+func TestOperations_SegmentSlide(t *testing.T) {
+	// One MiB per second on a single thread for 20 seconds.
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ops Operations
+	for i := 0; i < 20; i++ {
+		ops = append(ops, Operation{
+			OpType:   "GET",
+			Size:     1 << 20,
+			ObjPerOp: 1,
+			Start:    start.Add(time.Duration(i) * time.Second),
+			End:      start.Add(time.Duration(i+1) * time.Second),
+		})
+	}
+	const perSeg = 4 * time.Second
+	segment := func(slide time.Duration) Segments {
+		return ops.Segment(SegmentOptions{PerSegDuration: perSeg, Slide: slide})
+	}
+	plain := segment(0)
+	if len(plain) == 0 {
+		t.Fatal("no segments")
+	}
+	tests := []struct {
+		name     string
+		slide    time.Duration
+		wantStep time.Duration
+	}{
+		{name: "none", slide: 0, wantStep: perSeg},
+		{name: "negative", slide: -time.Second, wantStep: perSeg},
+		{name: "one-second", slide: time.Second, wantStep: time.Second},
+		{name: "half", slide: perSeg / 2, wantStep: perSeg / 2},
+		{name: "equal", slide: perSeg, wantStep: perSeg},
+		{name: "above", slide: 2 * perSeg, wantStep: perSeg},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			segs := segment(test.slide)
+			if len(segs) == 0 {
+				t.Fatal("no segments")
+			}
+			if !segs[0].Start.Equal(plain[0].Start) {
+				t.Errorf("first segment starts at %v, want %v", segs[0].Start, plain[0].Start)
+			}
+			// Segments must cover the same time as without sliding.
+			last, plainLast := segs[len(segs)-1].Start, plain[len(plain)-1].Start
+			if last.Before(plainLast) || !last.Before(plainLast.Add(perSeg)) {
+				t.Errorf("last segment starts at %v, want within %v of %v", last, perSeg, plainLast)
+			}
+			for i, seg := range segs {
+				if got := seg.EndsBefore.Sub(seg.Start); got != perSeg {
+					t.Errorf("segment %d is %v, want %v", i, got, perSeg)
+				}
+				if i > 0 {
+					if got := seg.Start.Sub(segs[i-1].Start); got != test.wantStep {
+						t.Errorf("segment %d starts %v after the previous, want %v", i, got, test.wantStep)
+					}
+				}
+				if mib, _, _ := seg.SpeedPerSec(); mib < 0.99 || mib > 1.01 {
+					t.Errorf("segment %d has %.2f MiB/s, want 1", i, mib)
+				}
+			}
+		})
+	}
+}
@@ -19,9 +19,12 @@ package bench
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/minio/minio-go/v7"
@@ -53,6 +56,20 @@ type Common struct {
 	Bucket      string
 	Location    string
 
+	// PrepareConcurrency is the number of workers uploading objects in Prepare.
+	// If 0, Concurrency is used.
+	PrepareConcurrency int
+
+	// Jitter will randomly change the number of active requests if set.
+	Jitter *ConcurrencyJitter
+
+	// HealthCheck will check availability of each endpoint while benchmarking if set.
+	HealthCheck *HealthCheck
+
+	// BufferMemory limits the memory used by upload buffers if set.
+	// Workers wait until their buffers fit within the limit.
+	BufferMemory *BufferLimiter
+
 	// Running in client mode.
 	ClientMode bool
 	// Clear bucket before benchmark
@@ -60,16 +77,76 @@ type Common struct {
 	PrepareProgress chan float64
 	// Does destination support versioning?
 	Versioned bool
+	// NoBucketCreate will assume the bucket exists and never attempt to create it.
+	NoBucketCreate bool
+	// ObjectLocking will enable object locking when creating the bucket.
+	ObjectLocking bool
+
+	// PrepareRetries is the number of times failed uploads are retried while preparing.
+	PrepareRetries int
+	// prepareRetried is the number of uploads that were retried while preparing.
+	prepareRetried int64
+
+	// ExactSizes is set if object sizes are selected from an exact list of sizes.
+	// Benchmark operations are marked with ExactSize.
+	ExactSizes bool
+
+	// IgnoreCleanupErrors will not log errors deleting objects when cleaning up.
+	// Objects that could not be deleted are still counted, see CleanupFailed.
+	IgnoreCleanupErrors bool
+	// cleanupFailed is the number of objects that could not be deleted when cleaning up.
+	cleanupFailed int64
 
 	// Auto termination is set when this is > 0.
 	AutoTermDur   time.Duration
 	AutoTermScale float64
+	// AutoTermMetric is the metric that must be stable. Defaults to throughput.
+	AutoTermMetric AutoTermMetric
+
+	// NoObjectContention will make sure that each object is only
+	// operated on by one worker at the time.
+	NoObjectContention bool
+	objectLocks        objectLocks
+
+	// FailFast is called with the first failed operation of the benchmark, if set.
+	// Operations canceled when the benchmark is stopped are not considered failed.
+	FailFast func(op Operation)
 
 	// Default Put options.
 	PutOpts minio.PutObjectOptions
 
 	// Error should log an error similar to fmt.Print(data...)
 	Error func(data ...interface{})
+
+	// Spans will receive all operations if set.
+	Spans *SpanExporter
+
+	// TracePhases will record the time spent in each phase of requests.
+	TracePhases bool
+
+	// CaptureHeaders are response headers that will be recorded on each operation.
+	// Names should be lower case.
+	CaptureHeaders []string
+
+	// Timeouts contains the maximum duration of single operations by operation type.
+	// Operations exceeding the timeout fail and have the timeout recorded.
+	Timeouts map[string]time.Duration
+
+	// RecordTenants will record the access key that signed the requests of each operation.
+	RecordTenants bool
+
+	// Live will receive all operations if set.
+	Live *LiveStats
+
+	// ReadBucket is the bucket read by the benchmark if different from Bucket.
+	// Objects are prepared in Bucket.
+	ReadBucket string
+	// ReadBucketWait is the maximum time to wait for prepared objects to appear in ReadBucket.
+	ReadBucketWait time.Duration
+
+	// AnonymousClient is used for benchmarked requests if set.
+	// Objects are still prepared and cleaned up using Client.
+	AnonymousClient func() (cl *minio.Client, done func())
 }
 
 const (
@@ -85,13 +162,158 @@ func (c *Common) GetCommon() *Common {
 	return c
 }
 
+// CleanupFailed returns the number of objects that could not be deleted when cleaning up.
+func (c *Common) CleanupFailed() int {
+	return int(atomic.LoadInt64(&c.cleanupFailed))
+}
+
+// PrepareRetried returns the number of uploads that had to be retried while preparing.
+func (c *Common) PrepareRetried() int {
+	return int(atomic.LoadInt64(&c.prepareRetried))
+}
+
+// prepareUpload will call upload until it succeeds or PrepareRetries retries have been made.
+// If r is not nil, it is rewound before each retry.
+func (c *Common) prepareUpload(ctx context.Context, r io.Seeker, upload func() error) error {
+	err := upload()
+	for retry := 1; err != nil && retry <= c.PrepareRetries; retry++ {
+		if ctx.Err() != nil {
+			return err
+		}
+		if retry == 1 {
+			atomic.AddInt64(&c.prepareRetried, 1)
+		}
+		c.Error(fmt.Sprintf("upload error, retrying (%d/%d): %v", retry, c.PrepareRetries, err))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(retry) * time.Second):
+		}
+		if r != nil {
+			if _, serr := r.Seek(0, io.SeekStart); serr != nil {
+				return err
+			}
+		}
+		err = upload()
+	}
+	return err
+}
+
 func (c *Common) ErrorF(format string, data ...interface{}) {
 	c.Error(fmt.Sprintf(format, data...))
 }
 
+// readBucket returns the bucket the benchmark should read from.
+func (c *Common) readBucket() string {
+	if c.ReadBucket == "" {
+		return c.Bucket
+	}
+	return c.ReadBucket
+}
+
+// readClient returns a client for the benchmarked read requests.
+func (c *Common) readClient() (*minio.Client, func()) {
+	if c.AnonymousClient != nil {
+		return c.AnonymousClient()
+	}
+	return c.Client()
+}
+
+// waitForReadBucket waits until all objects are present in the read bucket.
+// If the objects are not available within ReadBucketWait an error is returned.
+func (c *Common) waitForReadBucket(ctx context.Context, objs generator.Objects) error {
+	if c.readBucket() == c.Bucket {
+		return nil
+	}
+	cl, done := c.Client()
+	defer done()
+	x, err := cl.BucketExists(ctx, c.ReadBucket)
+	if err != nil {
+		return err
+	}
+	if !x {
+		return fmt.Errorf("read bucket %q does not exist", c.ReadBucket)
+	}
+	console.Infof("\r正在等待 %d 个对象出现在桶 %q 中...", len(objs), c.ReadBucket)
+	deadline := time.Now().Add(c.ReadBucketWait)
+	missing := objs
+	for {
+		var stillMissing generator.Objects
+		for _, obj := range missing {
+			_, err := cl.StatObject(ctx, c.ReadBucket, obj.Name, minio.StatObjectOptions{VersionID: obj.VersionID})
+			if err != nil {
+				stillMissing = append(stillMissing, obj)
+			}
+		}
+		missing = stillMissing
+		c.prepareProgress(float64(len(objs)-len(missing)) / float64(len(objs)))
+		if len(missing) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d of %d objects not found in bucket %q after %v", len(missing), len(objs), c.ReadBucket, c.ReadBucketWait)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// cleanupReadBucket deletes the prefixes from the read bucket if it differs from Bucket.
+func (c *Common) cleanupReadBucket(ctx context.Context, prefixes ...string) {
+	if c.readBucket() == c.Bucket {
+		return
+	}
+	c.deleteAllInBucket(ctx, c.ReadBucket, prefixes...)
+}
+
+// opContext returns the context for a single operation of the type opType.
+// If a timeout is set for the operation type it is applied to the returned context.
+// The returned function must be called when the operation is done.
+// It will record the timeout on op if the operation failed because the timeout was exceeded.
+func (c *Common) opContext(ctx context.Context, opType string) (context.Context, func(op *Operation)) {
+	timeout := c.Timeouts[opType]
+	if timeout <= 0 {
+		return ctx, func(*Operation) {}
+	}
+	opCtx, cancel := context.WithTimeout(ctx, timeout)
+	return opCtx, func(op *Operation) {
+		if op.Err != "" && errors.Is(opCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			op.Timeout = timeout
+		}
+		cancel()
+	}
+}
+
+// newCollector returns a collector for the benchmark.
+func (c *Common) newCollector() *Collector {
+	col := newCollector(c.Spans, c.Live)
+	col.autoTermMetric = c.AutoTermMetric
+	col.failFast = c.FailFast
+	col.exactSizes = c.ExactSizes
+	return col
+}
+
 // createEmptyBucket will create an empty bucket
 // or delete all content if it already exists.
 func (c *Common) createEmptyBucket(ctx context.Context) error {
+	if err := c.createBucket(ctx); err != nil {
+		return err
+	}
+	if c.Clear {
+		console.Infof("\r正在清理桶数据 %q...", c.Bucket)
+		if n := c.deleteAll(ctx, c.Bucket); n > 0 {
+			console.Errorf("\r警告: 桶 %q 中有 %d 个对象无法删除, 继续运行基准测试.\n", c.Bucket, n)
+		}
+	}
+	return nil
+}
+
+// createBucket will create the bucket if it does not exist.
+// Existing content is kept.
+func (c *Common) createBucket(ctx context.Context) error {
 	cl, done := c.Client()
 	defer done()
 	x, err := cl.BucketExists(ctx, c.Bucket)
@@ -99,10 +321,14 @@ func (c *Common) createEmptyBucket(ctx context.Context) error {
 		return err
 	}
 
+	if !x && c.NoBucketCreate {
+		return fmt.Errorf("bucket %q does not exist and bucket creation is disabled", c.Bucket)
+	}
 	if !x {
 		console.Infof("\r正在创建桶 %q...", c.Bucket)
 		err := cl.MakeBucket(ctx, c.Bucket, minio.MakeBucketOptions{
-			Region: c.Location,
+			Region:        c.Location,
+			ObjectLocking: c.ObjectLocking,
 		})
 
 		// In client mode someone else may have created it first.
@@ -122,43 +348,59 @@ func (c *Common) createEmptyBucket(ctx context.Context) error {
 	if bvc, err := cl.GetBucketVersioning(ctx, c.Bucket); err == nil {
 		c.Versioned = bvc.Status == "Enabled"
 	}
-
-	if c.Clear {
-		console.Infof("\r正在清理桶数据 %q...", c.Bucket)
-		c.deleteAllInBucket(ctx)
-	}
 	return nil
 }
 
+// cleanupErrorsLogged is the maximum number of errors logged when deleting objects.
+// Further errors are only counted.
+const cleanupErrorsLogged = 10
+
 // deleteAllInBucket will delete all content in a bucket.
 // If no prefixes are specified everything in bucket is deleted.
-func (c *Common) deleteAllInBucket(ctx context.Context, prefixes ...string) {
+// Objects that could not be deleted are added to CleanupFailed.
+func (c *Common) deleteAllInBucket(ctx context.Context, bucket string, prefixes ...string) {
+	atomic.AddInt64(&c.cleanupFailed, int64(c.deleteAll(ctx, bucket, prefixes...)))
+}
+
+// deleteAll will delete all content in a bucket and return the number
+// of objects that could not be deleted or listed.
+// If no prefixes are specified everything in bucket is deleted.
+func (c *Common) deleteAll(ctx context.Context, bucket string, prefixes ...string) int {
+	return c.deleteAllWith(ctx, c.Client, bucket, prefixes...)
+}
+
+// deleteAllWith will delete all content in a bucket using clients from client
+// and return the number of objects that could not be deleted or listed.
+func (c *Common) deleteAllWith(ctx context.Context, client func() (cl *minio.Client, done func()), bucket string, prefixes ...string) int {
 	if len(prefixes) == 0 {
 		prefixes = []string{""}
 	}
+	var failed int64
+	logErr := func(err error) {
+		if atomic.AddInt64(&failed, 1) <= cleanupErrorsLogged && !c.IgnoreCleanupErrors {
+			c.Error(err)
+		}
+	}
 	var wg sync.WaitGroup
 	wg.Add(len(prefixes))
 	for _, prefix := range prefixes {
 		go func(prefix string) {
 			defer wg.Done()
 
-			doneCh := make(chan struct{})
-			defer close(doneCh)
-			cl, done := c.Client()
+			cl, done := client()
 			defer done()
 			remove := make(chan minio.ObjectInfo, 1000)
-			errCh := cl.RemoveObjects(ctx, c.Bucket, remove, minio.RemoveObjectsOptions{})
+			errCh := cl.RemoveObjects(ctx, bucket, remove, minio.RemoveObjectsOptions{})
 			defer func() {
 				// Signal we are done
 				close(remove)
 				// Wait for deletes to finish
-				err := <-errCh
-				if err.Err != nil {
-					c.Error(err.Err)
+				for err := range errCh {
+					logErr(err.Err)
 				}
 			}()
 
-			objects := cl.ListObjects(ctx, c.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true, WithVersions: c.Versioned})
+			objects := cl.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true, WithVersions: c.Versioned})
 			for {
 				select {
 				case obj, ok := <-objects:
@@ -166,7 +408,7 @@ func (c *Common) deleteAllInBucket(ctx context.Context, prefixes ...string) {
 						return
 					}
 					if obj.Err != nil {
-						c.Error(obj.Err)
+						logErr(obj.Err)
 						continue
 					}
 				sendNext:
@@ -178,17 +420,28 @@ func (c *Common) deleteAllInBucket(ctx context.Context, prefixes ...string) {
 						}:
 							break sendNext
 						case err := <-errCh:
-							c.Error(err)
+							logErr(err.Err)
 						}
 					}
 				case err := <-errCh:
-					c.Error(err)
+					logErr(err.Err)
 				}
 			}
 		}(prefix)
 	}
 	wg.Wait()
+	if n := atomic.LoadInt64(&failed); n > cleanupErrorsLogged && !c.IgnoreCleanupErrors {
+		c.ErrorF("%d more errors deleting objects", n-cleanupErrorsLogged)
+	}
+	return int(failed)
+}
 
+// prepareConcurrency returns the number of workers to use in Prepare.
+func (c *Common) prepareConcurrency() int {
+	if c.PrepareConcurrency > 0 {
+		return c.PrepareConcurrency
+	}
+	return c.Concurrency
 }
 
 // prepareProgress updates preparation progess with the value 0->1.
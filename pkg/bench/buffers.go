@@ -0,0 +1,129 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package bench
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Defaults used by the client when uploading.
+const (
+	clientPartSize   = 128 << 20
+	clientNumThreads = 4
+)
+
+// BufferLimiter limits the total memory used by upload buffers.
+type BufferLimiter struct {
+	max int64
+
+	mu   sync.Mutex
+	used int64
+	// freed is closed and replaced when memory is released.
+	freed chan struct{}
+}
+
+// NewBufferLimiter returns a limiter allowing max bytes of buffers to be used concurrently.
+func NewBufferLimiter(max int64) *BufferLimiter {
+	return &BufferLimiter{max: max, freed: make(chan struct{})}
+}
+
+// acquire blocks until n bytes are available and returns a function that releases them.
+// Requests bigger than the limit are reduced to the limit, so they can run alone.
+// If ctx is canceled nothing is reserved.
+func (b *BufferLimiter) acquire(ctx context.Context, n int64) (release func()) {
+	if n > b.max {
+		n = b.max
+	}
+	if n <= 0 {
+		return func() {}
+	}
+	for {
+		b.mu.Lock()
+		if b.used+n <= b.max {
+			b.used += n
+			b.mu.Unlock()
+			return func() {
+				b.mu.Lock()
+				b.used -= n
+				close(b.freed)
+				b.freed = make(chan struct{})
+				b.mu.Unlock()
+			}
+		}
+		freed := b.freed
+		b.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return func() {}
+		}
+	}
+}
+
+// uploadBufferSize returns the memory the client buffers when uploading size bytes from r with opts.
+// Single part uploads are streamed, unless a Content-MD5 must be sent.
+// Multipart uploads buffer a part, or a part per thread if r can be read in parallel.
+func uploadBufferSize(r io.Reader, size int64, opts minio.PutObjectOptions) int64 {
+	partSize := int64(opts.PartSize)
+	if partSize == 0 {
+		partSize = clientPartSize
+	}
+	if singlePartUpload(size, opts) {
+		if opts.SendContentMd5 {
+			return size
+		}
+		return 0
+	}
+	if _, ok := r.(io.ReaderAt); ok && !opts.SendContentMd5 {
+		threads := int64(opts.NumThreads)
+		if threads == 0 {
+			threads = clientNumThreads
+		}
+		return partSize * threads
+	}
+	return partSize
+}
+
+// singlePartUpload returns whether the client will upload size bytes with opts using a single PUT.
+func singlePartUpload(size int64, opts minio.PutObjectOptions) bool {
+	partSize := int64(opts.PartSize)
+	if partSize == 0 {
+		partSize = clientPartSize
+	}
+	return size < partSize || opts.DisableMultipart
+}
+
+// reserveUpload blocks until the buffers for uploading size bytes from r with opts
+// are within BufferMemory and returns a function that releases them.
+func (c *Common) reserveUpload(ctx context.Context, r io.Reader, size int64, opts minio.PutObjectOptions) (release func()) {
+	if c.BufferMemory == nil {
+		return func() {}
+	}
+	return c.BufferMemory.acquire(ctx, uploadBufferSize(r, size, opts))
+}
+
+// putObject uploads an object when the buffers are within BufferMemory.
+// This should only be used when the upload isn't timed, since waiting is included.
+func (c *Common) putObject(ctx context.Context, client *minio.Client, bucket, object string, r io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	release := c.reserveUpload(ctx, r, size, opts)
+	defer release()
+	return client.PutObject(ctx, bucket, object, r, size, opts)
+}
@@ -0,0 +1,113 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestUploadBufferSize(t *testing.T) {
+	const mib = 1 << 20
+	// stream cannot be read in parallel.
+	stream := struct{ io.Reader }{strings.NewReader("")}
+	readerAt := strings.NewReader("")
+	tests := []struct {
+		name string
+		r    io.Reader
+		size int64
+		opts minio.PutObjectOptions
+		want int64
+	}{
+		{name: "single-part", r: stream, size: 10 * mib, want: 0},
+		{name: "single-part-md5", r: stream, size: 10 * mib, opts: minio.PutObjectOptions{SendContentMd5: true}, want: 10 * mib},
+		{name: "disable-multipart", r: stream, size: 1024 * mib, opts: minio.PutObjectOptions{DisableMultipart: true}, want: 0},
+		{name: "multipart-stream", r: stream, size: 1024 * mib, want: clientPartSize},
+		{name: "multipart-readerat", r: readerAt, size: 1024 * mib, want: clientPartSize * clientNumThreads},
+		{name: "multipart-readerat-threads", r: readerAt, size: 1024 * mib, opts: minio.PutObjectOptions{NumThreads: 2}, want: clientPartSize * 2},
+		{name: "multipart-readerat-md5", r: readerAt, size: 1024 * mib, opts: minio.PutObjectOptions{SendContentMd5: true}, want: clientPartSize},
+		{name: "part-size", r: stream, size: 100 * mib, opts: minio.PutObjectOptions{PartSize: 16 * mib}, want: 16 * mib},
+		{name: "below-part-size", r: stream, size: 15 * mib, opts: minio.PutObjectOptions{PartSize: 16 * mib}, want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := uploadBufferSize(test.r, test.size, test.opts); got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBufferLimiter_acquire(t *testing.T) {
+	tests := []struct {
+		name string
+		max  int64
+		// held is acquired before n.
+		held int64
+		n    int64
+		// wantUsed is the memory used after acquiring n while held is still acquired.
+		// If n doesn't fit, acquiring must wait and nothing is reserved when canceled.
+		wantUsed int64
+	}{
+		{name: "zero", max: 100, held: 100, n: 0, wantUsed: 100},
+		{name: "fits", max: 100, held: 40, n: 60, wantUsed: 100},
+		{name: "exceeds", max: 100, held: 50, n: 60, wantUsed: 50},
+		{name: "above-max", max: 100, held: 0, n: 1000, wantUsed: 100},
+		{name: "above-max-held", max: 100, held: 1, n: 1000, wantUsed: 1},
+	}
+	used := func(b *BufferLimiter) int64 {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.used
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := NewBufferLimiter(test.max)
+			releaseHeld := b.acquire(context.Background(), test.held)
+
+			// With a canceled context acquire only reserves memory that is available.
+			canceled, cancel := context.WithCancel(context.Background())
+			cancel()
+			release := b.acquire(canceled, test.n)
+			if got := used(b); got != test.wantUsed {
+				t.Fatalf("used %d, want %d", got, test.wantUsed)
+			}
+			release()
+
+			done := make(chan struct{})
+			go func() {
+				b.acquire(context.Background(), test.n)()
+				close(done)
+			}()
+			releaseHeld()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("acquire did not return after release")
+			}
+			if got := used(b); got != 0 {
+				t.Errorf("%d bytes still used after release", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package bench
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// ChecksumAlgorithms contains the supported checksum algorithms for uploads.
+var ChecksumAlgorithms = []string{"crc32c", "crc32", "sha1", "sha256"}
+
+// CheckChecksumAlgorithm returns an error if the algorithm isn't supported.
+// An empty algorithm is valid and disables checksums.
+func CheckChecksumAlgorithm(alg string) error {
+	if alg == "" {
+		return nil
+	}
+	for _, a := range ChecksumAlgorithms {
+		if a == alg {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown checksum algorithm %q, must be one of %v", alg, ChecksumAlgorithms)
+}
+
+func newChecksumHash(alg string) hash.Hash {
+	switch alg {
+	case "crc32c":
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case "crc32":
+		return crc32.NewIEEE()
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	}
+	return nil
+}
+
+// checksumObject calculates the checksum of the content of r using alg
+// and rewinds r to the start.
+// The returned header and value can be sent with the upload.
+func checksumObject(alg string, r io.ReadSeeker) (header, value string, err error) {
+	h := newChecksumHash(alg)
+	if h == nil {
+		return "", "", fmt.Errorf("unknown checksum algorithm %q", alg)
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+	return "x-amz-checksum-" + alg, base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
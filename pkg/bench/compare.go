@@ -18,9 +18,12 @@
 package bench
 
 import (
+	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"strconv"
 	"time"
 )
 
@@ -30,12 +33,47 @@ type Comparison struct {
 
 	TTFB *TTFBCmp
 
+	// Errors compares the number of failed operations.
+	Errors ErrorCmp
+
 	Average CmpSegment
 	Fastest CmpSegment
 	Median  CmpSegment
 	Slowest CmpSegment
 }
 
+// ErrorCmp is a comparison of failed operations between two benchmarks.
+type ErrorCmp struct {
+	Before, After int
+	// Percentage of operations that failed.
+	BeforeRate, AfterRate float64
+}
+
+// newErrorCmp compares the failed operations of before and after.
+func newErrorCmp(before, after Operations) ErrorCmp {
+	rate := func(ops Operations, errs int) float64 {
+		if len(ops) == 0 {
+			return 0
+		}
+		return 100 * float64(errs) / float64(len(ops))
+	}
+	c := ErrorCmp{Before: len(before.Errors()), After: len(after.Errors())}
+	c.BeforeRate, c.AfterRate = rate(before, c.Before), rate(after, c.After)
+	return c
+}
+
+// Increased returns whether the rate of failed operations increased.
+func (c ErrorCmp) Increased() bool {
+	return c.AfterRate > c.BeforeRate
+}
+
+// String returns a string representation of the error comparison.
+func (c ErrorCmp) String() string {
+	return fmt.Sprintf("%d -> %d (%s%d), 错误率 %.02f%% -> %.02f%% (%s%.02f%%)",
+		c.Before, c.After, plusPositiveF(float64(c.After-c.Before)), c.After-c.Before,
+		c.BeforeRate, c.AfterRate, plusPositiveF(c.AfterRate-c.BeforeRate), c.AfterRate-c.BeforeRate)
+}
+
 // CmpSegment is s comparisons between two segments.
 type CmpSegment struct {
 	Before, After    *Segment
@@ -66,9 +104,9 @@ func (c CmpSegment) String() string {
 	mibA, _, objsA := c.After.SpeedPerSec()
 
 	if c.ThroughputPerSec != 0 {
-		speed = fmt.Sprintf("%s%.02f%% (%s%.1f MiB/s) 吞吐量, ",
+		speed = fmt.Sprintf("%s%.02f%% (%s%s) 吞吐量, ",
 			plusPositiveF(c.ThroughputPerSec), c.ThroughputPerSec,
-			plusPositiveF(c.ThroughputPerSec), mibA-mibB,
+			plusPositiveF(c.ThroughputPerSec), Throughput((mibA-mibB)*(1<<20)).stringDelta(),
 		)
 	}
 	return fmt.Sprintf("%s%s%.02f%% (%s%.1f) obj/s",
@@ -103,6 +141,8 @@ func (t TTFB) Compare(after TTFB) *TTFBCmp {
 			Worst:   after.Worst - t.Worst,
 			Best:    after.Best - t.Best,
 			Median:  after.Median - t.Median,
+			P90:     after.P90 - t.P90,
+			P99:     after.P99 - t.P99,
 		},
 		Before: t,
 		After:  after,
@@ -152,32 +192,21 @@ func Compare(before, after Operations, analysis time.Duration, allThreads bool)
 	if analysis <= 0 {
 		return nil, fmt.Errorf("invalid analysis duration: %v", analysis)
 	}
-	if len(before.Errors()) > 0 || len(after.Errors()) > 0 {
-		return nil, fmt.Errorf("errors recorded in benchmark run. before: %v, after %d", len(before.Errors()), len(after.Errors()))
-	}
 	res.Op = before.FirstOpType()
-	segment := func(ops Operations) (Segments, error) {
-		segs := ops.Segment(SegmentOptions{
-			From:           time.Time{},
-			PerSegDuration: analysis,
-			AllThreads:     allThreads,
-		})
-		if len(segs) <= 1 {
-			return nil, errors.New("too few samples")
+	res.Errors = newErrorCmp(before, after)
+	if before.HasError() || after.HasError() {
+		// Compare successful operations only.
+		before, after = before.FilterSuccessful(), after.FilterSuccessful()
+		allThreads = false
+		if len(before) == 0 || len(after) == 0 {
+			return nil, fmt.Errorf("no successful operations. before: %d errors, after %d errors", res.Errors.Before, res.Errors.After)
 		}
-		totals := ops.Total(allThreads)
-		if totals.TotalBytes > 0 {
-			segs.SortByThroughput()
-		} else {
-			segs.SortByObjsPerSec()
-		}
-		return segs, nil
 	}
-	bs, err := segment(before)
+	bs, err := sortedSegments(before, analysis, allThreads)
 	if err != nil {
 		return nil, fmt.Errorf("segmenting before: %w", err)
 	}
-	as, err := segment(after)
+	as, err := sortedSegments(after, analysis, allThreads)
 	if err != nil {
 		return nil, fmt.Errorf("segmenting after: %w", err)
 	}
@@ -193,3 +222,139 @@ func Compare(before, after Operations, analysis time.Duration, allThreads bool)
 	res.TTFB = beforeTTFB.Compare(afterTTFB)
 	return &res, nil
 }
+
+// sortedSegments returns the operations split into segments of the analysis duration,
+// sorted slowest first.
+func sortedSegments(ops Operations, analysis time.Duration, allThreads bool) (Segments, error) {
+	segs := ops.Segment(SegmentOptions{
+		From:           time.Time{},
+		PerSegDuration: analysis,
+		AllThreads:     allThreads,
+	})
+	if len(segs) <= 1 {
+		return nil, errors.New("too few samples")
+	}
+	totals := ops.Total(allThreads)
+	if totals.TotalBytes > 0 {
+		segs.SortByThroughput()
+	} else {
+		segs.SortByObjsPerSec()
+	}
+	return segs, nil
+}
+
+// TrendPoint contains the results of a single benchmark run in a trend.
+type TrendPoint struct {
+	Requests int
+	Threads  int
+
+	TTFB TTFB
+
+	Average Segment
+	Fastest Segment
+	Median  Segment
+	Slowest Segment
+}
+
+// Trend returns the results of a sequence of benchmark runs of a single operation type.
+func Trend(runs []Operations, analysis time.Duration, allThreads bool) ([]TrendPoint, error) {
+	if analysis <= 0 {
+		return nil, fmt.Errorf("invalid analysis duration: %v", analysis)
+	}
+	res := make([]TrendPoint, len(runs))
+	for i, ops := range runs {
+		if i > 0 && ops.FirstOpType() != runs[0].FirstOpType() {
+			return nil, fmt.Errorf("different operation types. run 1: %v, run %d: %v", runs[0].FirstOpType(), i+1, ops.FirstOpType())
+		}
+		if errs := ops.Errors(); len(errs) > 0 {
+			return nil, fmt.Errorf("errors recorded in benchmark run %d: %v", i+1, len(errs))
+		}
+		segs, err := sortedSegments(ops, analysis, allThreads)
+		if err != nil {
+			return nil, fmt.Errorf("segmenting run %d: %w", i+1, err)
+		}
+		res[i] = TrendPoint{
+			Requests: len(ops),
+			Threads:  ops.Threads(),
+			TTFB:     ops.TTFB(ops.TimeRange()),
+			Average:  ops.Total(allThreads),
+			Fastest:  segs.Median(1),
+			Median:   segs.Median(0.5),
+			Slowest:  segs.Median(0),
+		}
+	}
+	return res, nil
+}
+
+type namedCmpSegment struct {
+	name string
+	seg  CmpSegment
+}
+
+// ComparisonsCSV writes the comparisons to w as CSV with a row for each metric of each operation type.
+// The columns are op, metric, before, after, delta and pct, where pct is the change in percent of before.
+// pct is empty if before is 0.
+// If segments is false the fastest, median and slowest segments are not written.
+func ComparisonsCSV(w io.Writer, cmps []*Comparison, segments bool) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"op", "metric", "before", "after", "delta", "pct"}); err != nil {
+		return err
+	}
+	format := func(v float64) string {
+		return strconv.FormatFloat(math.Round(v*10000)/10000, 'f', -1, 64)
+	}
+	for _, c := range cmps {
+		row := func(metric string, before, after float64) error {
+			pct := ""
+			if before != 0 {
+				pct = format(100 * (after - before) / before)
+			}
+			return cw.Write([]string{c.Op, metric, format(before), format(after), format(after - before), pct})
+		}
+		segs := []namedCmpSegment{{"average", c.Average}}
+		if segments {
+			segs = append(segs, namedCmpSegment{"fastest", c.Fastest}, namedCmpSegment{"median", c.Median}, namedCmpSegment{"slowest", c.Slowest})
+		}
+		for _, s := range segs {
+			if s.seg.Before == nil || s.seg.After == nil {
+				continue
+			}
+			mibB, _, objsB := s.seg.Before.SpeedPerSec()
+			mibA, _, objsA := s.seg.After.SpeedPerSec()
+			if mibB > 0 || mibA > 0 {
+				if err := row(s.name+"_mib_per_sec", mibB, mibA); err != nil {
+					return err
+				}
+			}
+			if err := row(s.name+"_obj_per_sec", objsB, objsA); err != nil {
+				return err
+			}
+		}
+		if err := row("errors", float64(c.Errors.Before), float64(c.Errors.After)); err != nil {
+			return err
+		}
+		if err := row("error_rate_pct", c.Errors.BeforeRate, c.Errors.AfterRate); err != nil {
+			return err
+		}
+		if t := c.TTFB; t != nil {
+			ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+			for _, m := range []struct {
+				name          string
+				before, after time.Duration
+			}{
+				{"ttfb_average_ms", t.Before.Average, t.After.Average},
+				{"ttfb_median_ms", t.Before.Median, t.After.Median},
+				{"ttfb_best_ms", t.Before.Best, t.After.Best},
+				{"ttfb_worst_ms", t.Before.Worst, t.After.Worst},
+				{"ttfb_p90_ms", t.Before.P90, t.After.P90},
+				{"ttfb_p99_ms", t.Before.P99, t.After.P99},
+			} {
+				if err := row(m.name, ms(m.before), ms(m.after)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,126 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestComparisonsCSV(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// seg returns a one second segment with the given bytes and objects.
+	seg := func(bytes int64, objs float64) *Segment {
+		return &Segment{TotalBytes: bytes, Objects: objs, OpsEnded: int(objs), Start: start, EndsBefore: start.Add(time.Second)}
+	}
+	cmp := func(before, after *Segment) CmpSegment {
+		return CmpSegment{Before: before, After: after}
+	}
+	tests := []struct {
+		name     string
+		cmps     []*Comparison
+		segments bool
+		want     [][]string
+	}{
+		{
+			name: "throughput",
+			cmps: []*Comparison{{
+				Op:      "GET",
+				Average: cmp(seg(1<<20, 10), seg(2<<20, 15)),
+				Errors:  ErrorCmp{Before: 0, After: 2, BeforeRate: 0, AfterRate: 12.5},
+			}},
+			want: [][]string{
+				{"GET", "average_mib_per_sec", "1", "2", "1", "100"},
+				{"GET", "average_obj_per_sec", "10", "15", "5", "50"},
+				{"GET", "errors", "0", "2", "2", ""},
+				{"GET", "error_rate_pct", "0", "12.5", "12.5", ""},
+			},
+		},
+		{
+			name: "no-bytes",
+			cmps: []*Comparison{{
+				Op:      "DELETE",
+				Average: cmp(seg(0, 100), seg(0, 80)),
+				Errors:  ErrorCmp{Before: 4, After: 2, BeforeRate: 4, AfterRate: 2},
+			}},
+			want: [][]string{
+				{"DELETE", "average_obj_per_sec", "100", "80", "-20", "-20"},
+				{"DELETE", "errors", "4", "2", "-2", "-50"},
+				{"DELETE", "error_rate_pct", "4", "2", "-2", "-50"},
+			},
+		},
+		{
+			name: "segments",
+			cmps: []*Comparison{{
+				Op:      "STAT",
+				Average: cmp(seg(0, 3), seg(0, 3)),
+				Fastest: cmp(seg(0, 4), seg(0, 5)),
+				Median:  cmp(seg(0, 3), seg(0, 3)),
+				// Missing segments are skipped.
+				Slowest: cmp(nil, seg(0, 1)),
+			}},
+			segments: true,
+			want: [][]string{
+				{"STAT", "average_obj_per_sec", "3", "3", "0", "0"},
+				{"STAT", "fastest_obj_per_sec", "4", "5", "1", "25"},
+				{"STAT", "median_obj_per_sec", "3", "3", "0", "0"},
+				{"STAT", "errors", "0", "0", "0", ""},
+				{"STAT", "error_rate_pct", "0", "0", "0", ""},
+			},
+		},
+		{
+			name: "ttfb",
+			cmps: []*Comparison{{
+				Op: "GET",
+				TTFB: &TTFBCmp{
+					Before: TTFB{Average: 10 * time.Millisecond, Median: 10 * time.Millisecond, Best: time.Millisecond, Worst: 20 * time.Millisecond, P90: 15 * time.Millisecond, P99: 19 * time.Millisecond},
+					After:  TTFB{Average: 5 * time.Millisecond, Median: 5 * time.Millisecond, Best: time.Millisecond, Worst: 10 * time.Millisecond, P90: 1500 * time.Microsecond, P99: 9 * time.Millisecond},
+				},
+			}},
+			want: [][]string{
+				{"GET", "errors", "0", "0", "0", ""},
+				{"GET", "error_rate_pct", "0", "0", "0", ""},
+				{"GET", "ttfb_average_ms", "10", "5", "-5", "-50"},
+				{"GET", "ttfb_median_ms", "10", "5", "-5", "-50"},
+				{"GET", "ttfb_best_ms", "1", "1", "0", "0"},
+				{"GET", "ttfb_worst_ms", "20", "10", "-10", "-50"},
+				{"GET", "ttfb_p90_ms", "15", "1.5", "-13.5", "-90"},
+				{"GET", "ttfb_p99_ms", "19", "9", "-10", "-52.6316"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ComparisonsCSV(&buf, test.cmps, test.segments); err != nil {
+				t.Fatal(err)
+			}
+			got, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := append([][]string{{"op", "metric", "before", "after", "delta", "pct"}}, test.want...)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
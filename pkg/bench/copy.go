@@ -0,0 +1,261 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/generator"
+)
+
+// Copy benchmarks server side copy speed.
+type Copy struct {
+	CreateObjects int
+	Collector     *Collector
+	objects       generator.Objects
+
+	// ReplaceMeta will copy objects onto themselves, replacing the metadata.
+	// Otherwise objects are copied to a new object.
+	ReplaceMeta bool
+	// MetaUpdate will record metadata replacements as META-UPDATE operations
+	// and check that the server supports them before the benchmark.
+	// Otherwise they are recorded as COPY-META operations.
+	MetaUpdate bool
+	// ContentType and StorageClass are set when replacing metadata, if not empty.
+	ContentType  string
+	StorageClass string
+	Common
+}
+
+// opTypeMetaUpdate is the operation type of metadata updates by copying objects onto themselves.
+const opTypeMetaUpdate = "META-UPDATE"
+
+// Prepare will create an empty bucket or delete any content already there
+// and upload a number of objects.
+func (g *Copy) Prepare(ctx context.Context) error {
+	if err := g.createEmptyBucket(ctx); err != nil {
+		return err
+	}
+	src := g.Source()
+	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
+	var wg sync.WaitGroup
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
+	obj := make(chan struct{}, g.CreateObjects)
+	for i := 0; i < g.CreateObjects; i++ {
+		obj <- struct{}{}
+	}
+	close(obj)
+	var groupErr error
+	var mu sync.Mutex
+	for i := 0; i < g.prepareConcurrency(); i++ {
+		go func(i int) {
+			defer wg.Done()
+			src := g.Source()
+			for range obj {
+				opts := g.PutOpts
+				rcv := g.Collector.Receiver()
+				done := ctx.Done()
+
+				select {
+				case <-done:
+					return
+				default:
+				}
+				obj := src.Object()
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   http.MethodPut,
+					Thread:   uint16(i),
+					Size:     obj.Size,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				opts.ContentType = obj.ContentType
+				var res minio.UploadInfo
+				err := g.prepareUpload(ctx, obj.Reader, func() (err error) {
+					op.Start = time.Now()
+					res, err = g.putObject(ctx, client, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+					return err
+				})
+				op.End = time.Now()
+				cldone()
+				if err != nil {
+					err := fmt.Errorf("upload error: %w", err)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+
+				obj.VersionID = res.VersionID
+				if res.Size != obj.Size {
+					err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				mu.Lock()
+				obj.Reader = nil
+				g.objects = append(g.objects, *obj)
+				g.prepareProgress(float64(len(g.objects)) / float64(g.CreateObjects))
+				mu.Unlock()
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	if groupErr != nil {
+		return groupErr
+	}
+	if g.MetaUpdate {
+		return g.checkMetaUpdate(ctx)
+	}
+	return nil
+}
+
+// checkMetaUpdate checks that the server supports updating metadata by copying an object onto itself.
+func (g *Copy) checkMetaUpdate(ctx context.Context) error {
+	if len(g.objects) == 0 {
+		return nil
+	}
+	client, cldone := g.Client()
+	defer cldone()
+	obj := g.objects[0]
+	_, err := client.CopyObject(ctx, g.metaUpdateDst(obj.Name, "check"), minio.CopySrcOptions{Bucket: g.Bucket, Object: obj.Name})
+	if err != nil {
+		return fmt.Errorf("server does not support updating metadata by copying objects onto themselves: %w", err)
+	}
+	return nil
+}
+
+// metaUpdateDst returns the destination for updating the metadata of an object in place.
+func (g *Copy) metaUpdateDst(object, value string) minio.CopyDestOptions {
+	meta := map[string]string{"Warp-Update": value}
+	if g.ContentType != "" {
+		meta["Content-Type"] = g.ContentType
+	}
+	if g.StorageClass != "" {
+		meta["X-Amz-Storage-Class"] = g.StorageClass
+	}
+	return minio.CopyDestOptions{
+		Bucket:          g.Bucket,
+		Object:          object,
+		ReplaceMetadata: true,
+		UserMetadata:    meta,
+	}
+}
+
+// Start will execute the main benchmark.
+// Operations should begin executing when the start channel is closed.
+func (g *Copy) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(g.Concurrency)
+	c := g.Collector
+	opType := "COPY"
+	switch {
+	case g.MetaUpdate:
+		opType = opTypeMetaUpdate
+	case g.ReplaceMeta:
+		opType = "COPY-META"
+	}
+	if g.AutoTermDur > 0 {
+		ctx = c.AutoTerm(ctx, opType, g.AutoTermScale, autoTermCheck, autoTermSamples, g.AutoTermDur)
+	}
+	// Non-terminating context.
+	nonTerm := context.Background()
+
+	for i := 0; i < g.Concurrency; i++ {
+		go func(i int) {
+			rng := rand.New(rand.NewSource(int64(i)))
+			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
+			defer wg.Done()
+			done := ctx.Done()
+
+			<-wait
+			for n := 0; ; n++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				obj := g.objects[rng.Intn(len(g.objects))]
+				unlock := g.lockObject(obj.Name)
+				client, cldone := g.Client()
+				src := minio.CopySrcOptions{
+					Bucket: g.Bucket,
+					Object: obj.Name,
+				}
+				dst := minio.CopyDestOptions{
+					Bucket: g.Bucket,
+					Object: obj.Name + ".copy",
+				}
+				op := Operation{
+					OpType:   opType,
+					Thread:   uint16(i),
+					Size:     obj.Size,
+					File:     dst.Object,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				if g.ReplaceMeta {
+					// Update metadata in place. No object data is copied.
+					dst = g.metaUpdateDst(obj.Name, fmt.Sprintf("%d-%d", i, n))
+					op.File = obj.Name
+					op.Size = 0
+				} else {
+					src.VersionID = obj.VersionID
+				}
+				op.Start = time.Now()
+				_, err := client.CopyObject(reqCtx, dst, src)
+				op.End = time.Now()
+				if err != nil {
+					g.Error("CopyObject 出错: ", err)
+					op.Err = err.Error()
+				}
+				rec.fill(&op)
+				rcv <- op
+				cldone()
+				unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return c.Close(), nil
+}
+
+// Cleanup deletes everything uploaded and copied to the bucket.
+func (g *Copy) Cleanup(ctx context.Context) {
+	g.deleteAllInBucket(ctx, g.Bucket, g.objects.Prefixes()...)
+}
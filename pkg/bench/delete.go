@@ -34,8 +34,11 @@ import (
 type Delete struct {
 	CreateObjects int
 	BatchSize     int
-	Collector     *Collector
-	objects       generator.Objects
+	// Sequential will delete objects in the order they were uploaded
+	// instead of in random order.
+	Sequential bool
+	Collector  *Collector
+	objects    generator.Objects
 
 	Common
 }
@@ -49,8 +52,8 @@ func (d *Delete) Prepare(ctx context.Context) error {
 	src := d.Source()
 	console.Info("\r正在上传 ", d.CreateObjects, " 个对象: ", src.String())
 	var wg sync.WaitGroup
-	wg.Add(d.Concurrency)
-	d.Collector = NewCollector()
+	wg.Add(d.prepareConcurrency())
+	d.Collector = d.newCollector()
 	obj := make(chan struct{}, d.CreateObjects)
 	for i := 0; i < d.CreateObjects; i++ {
 		obj <- struct{}{}
@@ -58,7 +61,7 @@ func (d *Delete) Prepare(ctx context.Context) error {
 	close(obj)
 	var mu sync.Mutex
 	var groupErr error
-	for i := 0; i < d.Concurrency; i++ {
+	for i := 0; i < d.prepareConcurrency(); i++ {
 		go func(i int) {
 			defer wg.Done()
 			src := d.Source()
@@ -83,9 +86,14 @@ func (d *Delete) Prepare(ctx context.Context) error {
 					Endpoint: client.EndpointURL().String(),
 				}
 				opts.ContentType = obj.ContentType
-				op.Start = time.Now()
-				res, err := client.PutObject(ctx, d.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+				var res minio.UploadInfo
+				err := d.prepareUpload(ctx, obj.Reader, func() (err error) {
+					op.Start = time.Now()
+					res, err = d.putObject(ctx, client, d.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+					return err
+				})
 				op.End = time.Now()
+				cldone()
 				if err != nil {
 					err := fmt.Errorf("upload error: %w", err)
 					d.Error(err)
@@ -108,7 +116,6 @@ func (d *Delete) Prepare(ctx context.Context) error {
 					mu.Unlock()
 					return
 				}
-				cldone()
 				mu.Lock()
 				obj.Reader = nil
 				d.objects = append(d.objects, *obj)
@@ -120,8 +127,11 @@ func (d *Delete) Prepare(ctx context.Context) error {
 	}
 	wg.Wait()
 
+	if d.Sequential {
+		return groupErr
+	}
 	// Shuffle objects.
-	// Benchmark will pick from slice in order.
+	// Benchmark will pick from slice in order, so each object is deleted once.
 	a := d.objects
 	rand.Shuffle(len(a), func(i, j int) {
 		a[i], a[j] = a[j], a[i]
@@ -145,6 +155,7 @@ func (d *Delete) Start(ctx context.Context, wait chan struct{}) (Operations, err
 	for i := 0; i < d.Concurrency; i++ {
 		go func(i int) {
 			rcv := c.Receiver()
+			reqCtx, rec := d.newRecorder(nonTerm, cancelOnStop(ctx))
 			defer wg.Done()
 			done := ctx.Done()
 
@@ -187,7 +198,8 @@ func (d *Delete) Start(ctx context.Context, wait chan struct{}) (Operations, err
 				}
 				op.Start = time.Now()
 				// RemoveObjectsWithContext will split any batches > 1000 into separate requests.
-				errCh := client.RemoveObjects(nonTerm, d.Bucket, objects, minio.RemoveObjectsOptions{})
+				opCtx, opDone := d.opContext(reqCtx, http.MethodDelete)
+				errCh := client.RemoveObjects(opCtx, d.Bucket, objects, minio.RemoveObjectsOptions{})
 
 				// Wait for errCh to close.
 				for {
@@ -202,6 +214,8 @@ func (d *Delete) Start(ctx context.Context, wait chan struct{}) (Operations, err
 				}
 				op.End = time.Now()
 				cldone()
+				opDone(&op)
+				rec.fill(&op)
 				rcv <- op
 			}
 		}(i)
@@ -213,6 +227,6 @@ func (d *Delete) Start(ctx context.Context, wait chan struct{}) (Operations, err
 // Cleanup deletes everything uploaded to the bucket.
 func (d *Delete) Cleanup(ctx context.Context) {
 	if len(d.objects) > 0 {
-		d.deleteAllInBucket(ctx, d.objects.Prefixes()...)
+		d.deleteAllInBucket(ctx, d.Bucket, d.objects.Prefixes()...)
 	}
 }
@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
@@ -39,6 +40,14 @@ type Get struct {
 	Collector     *Collector
 	objects       generator.Objects
 
+	// VerifySize will check the Content-Length returned by the server
+	// against the expected size and the number of bytes read.
+	VerifySize bool
+
+	// Partition will give each worker a disjoint range of the objects sorted by name,
+	// so each worker always reads the same objects.
+	Partition bool
+
 	// Default Get options.
 	GetOpts minio.GetObjectOptions
 	Common
@@ -53,8 +62,8 @@ func (g *Get) Prepare(ctx context.Context) error {
 	src := g.Source()
 	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
 	var wg sync.WaitGroup
-	wg.Add(g.Concurrency)
-	g.Collector = NewCollector()
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
 	obj := make(chan struct{}, g.CreateObjects)
 	for i := 0; i < g.CreateObjects; i++ {
 		obj <- struct{}{}
@@ -63,7 +72,7 @@ func (g *Get) Prepare(ctx context.Context) error {
 	var groupErr error
 	var mu sync.Mutex
 
-	for i := 0; i < g.Concurrency; i++ {
+	for i := 0; i < g.prepareConcurrency(); i++ {
 		go func(i int) {
 			defer wg.Done()
 			src := g.Source()
@@ -88,9 +97,14 @@ func (g *Get) Prepare(ctx context.Context) error {
 					Endpoint: client.EndpointURL().String(),
 				}
 				opts.ContentType = obj.ContentType
-				op.Start = time.Now()
-				res, err := client.PutObject(ctx, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+				var res minio.UploadInfo
+				err := g.prepareUpload(ctx, obj.Reader, func() (err error) {
+					op.Start = time.Now()
+					res, err = g.putObject(ctx, client, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+					return err
+				})
 				op.End = time.Now()
+				cldone()
 				if err != nil {
 					err := fmt.Errorf("upload error: %w", err)
 					g.Error(err)
@@ -112,7 +126,6 @@ func (g *Get) Prepare(ctx context.Context) error {
 					mu.Unlock()
 					return
 				}
-				cldone()
 				mu.Lock()
 				obj.Reader = nil
 				g.objects = append(g.objects, *obj)
@@ -123,7 +136,13 @@ func (g *Get) Prepare(ctx context.Context) error {
 		}(i)
 	}
 	wg.Wait()
-	return groupErr
+	if groupErr != nil {
+		return groupErr
+	}
+	if g.Partition && len(g.objects) < g.Concurrency {
+		return fmt.Errorf("cannot partition %d objects between %d workers", len(g.objects), g.Concurrency)
+	}
+	return g.waitForReadBucket(ctx, g.objects)
 }
 
 type firstByteRecorder struct {
@@ -157,13 +176,26 @@ func (g *Get) Start(ctx context.Context, wait chan struct{}) (Operations, error)
 	// Non-terminating context.
 	nonTerm := context.Background()
 
+	objects := g.objects
+	if g.Partition {
+		objects = append(generator.Objects(nil), objects...)
+		sort.Slice(objects, func(i, j int) bool {
+			return objects[i].Name < objects[j].Name
+		})
+	}
+
 	for i := 0; i < g.Concurrency; i++ {
 		go func(i int) {
 			rng := rand.New(rand.NewSource(int64(i)))
 			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
 			defer wg.Done()
 			opts := g.GetOpts
 			done := ctx.Done()
+			objects := objects
+			if g.Partition {
+				objects = objects[i*len(objects)/g.Concurrency : (i+1)*len(objects)/g.Concurrency]
+			}
 
 			<-wait
 			for {
@@ -173,8 +205,9 @@ func (g *Get) Start(ctx context.Context, wait chan struct{}) (Operations, error)
 				default:
 				}
 				fbr := firstByteRecorder{}
-				obj := g.objects[rng.Intn(len(g.objects))]
-				client, cldone := g.Client()
+				obj := objects[rng.Intn(len(objects))]
+				unlock := g.lockObject(obj.Name)
+				client, cldone := g.readClient()
 				op := Operation{
 					OpType:   http.MethodGet,
 					Thread:   uint16(i),
@@ -194,13 +227,17 @@ func (g *Get) Start(ctx context.Context, wait chan struct{}) (Operations, error)
 				op.Start = time.Now()
 				var err error
 				opts.VersionID = obj.VersionID
-				o, err := client.GetObject(nonTerm, g.Bucket, obj.Name, opts)
+				opCtx, opDone := g.opContext(reqCtx, http.MethodGet)
+				o, err := client.GetObject(opCtx, g.readBucket(), obj.Name, opts)
 				if err != nil {
 					g.Error("下载出错:", err)
 					op.Err = err.Error()
 					op.End = time.Now()
+					opDone(&op)
+					rec.fill(&op)
 					rcv <- op
 					cldone()
+					unlock()
 					continue
 				}
 				fbr.r = o
@@ -211,12 +248,21 @@ func (g *Get) Start(ctx context.Context, wait chan struct{}) (Operations, error)
 				}
 				op.FirstByte = fbr.t
 				op.End = time.Now()
+				if g.VerifySize && op.Err == "" {
+					op.Err = verifyContentLength(o, op.Size, n)
+					if op.Err != "" {
+						g.Error(op.Err)
+					}
+				}
 				if n != op.Size && op.Err == "" {
 					op.Err = fmt.Sprint("不符合期望的下载大小. 需要的是:", op.Size, ", 实际上是:", n)
 					g.Error(op.Err)
 				}
+				opDone(&op)
+				rec.fill(&op)
 				rcv <- op
 				cldone()
+				unlock()
 				o.Close()
 			}
 		}(i)
@@ -225,7 +271,24 @@ func (g *Get) Start(ctx context.Context, wait chan struct{}) (Operations, error)
 	return c.Close(), nil
 }
 
+// verifyContentLength returns an error message if the Content-Length reported by the server
+// doesn't match the expected size or the number of bytes read.
+func verifyContentLength(o *minio.Object, want, read int64) string {
+	st, err := o.Stat()
+	if err != nil {
+		return fmt.Sprint("无法获取 Content-Length: ", err)
+	}
+	if st.Size != want {
+		return fmt.Sprint("服务器返回的 Content-Length 不符合期望. 需要的是:", want, ", 实际上是:", st.Size)
+	}
+	if read != st.Size {
+		return fmt.Sprint("读取的数据被截断. Content-Length:", st.Size, ", 实际读取:", read)
+	}
+	return ""
+}
+
 // Cleanup deletes everything uploaded to the bucket.
 func (g *Get) Cleanup(ctx context.Context) {
-	g.deleteAllInBucket(ctx, g.objects.Prefixes()...)
+	g.deleteAllInBucket(ctx, g.Bucket, g.objects.Prefixes()...)
+	g.cleanupReadBucket(ctx, g.objects.Prefixes()...)
 }
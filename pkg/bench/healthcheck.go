@@ -0,0 +1,127 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// HealthCheck will check that the bucket is available on each endpoint every Interval
+// while the benchmark is running.
+type HealthCheck struct {
+	Interval time.Duration
+	// Clients contains a client for each endpoint.
+	Clients []*minio.Client
+}
+
+// AvailabilityGap is a period where health checks of an endpoint failed.
+type AvailabilityGap struct {
+	Endpoint string
+	// Start is the time of the first failed check.
+	Start time.Time
+	// End is the time of the next successful check,
+	// or when health checks stopped if the endpoint didn't recover.
+	End time.Time
+	// Err is the error of the first failed check.
+	Err string
+}
+
+// Duration returns the duration of the gap.
+func (g AvailabilityGap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+// StartHealthCheck will check availability of all endpoints when start is closed,
+// if HealthCheck is set. Each check is a HEAD request on the bucket.
+// Checks stop when ctx is canceled or the returned function is called.
+// The returned function returns the recorded availability gaps sorted by start time.
+func (c *Common) StartHealthCheck(ctx context.Context, start <-chan struct{}) (stop func() []AvailabilityGap) {
+	if c.HealthCheck == nil || len(c.HealthCheck.Clients) == 0 {
+		return func() []AvailabilityGap { return nil }
+	}
+	h := *c.HealthCheck
+	ctx, cancel := context.WithCancel(ctx)
+	var mu sync.Mutex
+	var gaps []AvailabilityGap
+	var wg sync.WaitGroup
+	wg.Add(len(h.Clients))
+	for _, cl := range h.Clients {
+		go func(cl *minio.Client) {
+			defer wg.Done()
+			select {
+			case <-start:
+			case <-ctx.Done():
+				return
+			}
+			ticker := time.NewTicker(h.Interval)
+			defer ticker.Stop()
+			var gap *AvailabilityGap
+			endGap := func(t time.Time) {
+				if gap == nil {
+					return
+				}
+				gap.End = t
+				mu.Lock()
+				gaps = append(gaps, *gap)
+				mu.Unlock()
+				gap = nil
+			}
+			for {
+				checkCtx, checkCancel := context.WithTimeout(ctx, h.Interval)
+				now := time.Now()
+				found, err := cl.BucketExists(checkCtx, c.Bucket)
+				checkCancel()
+				if err == nil && !found {
+					err = errors.New("bucket does not exist")
+				}
+				if ctx.Err() != nil {
+					// Stopped while checking.
+					endGap(time.Now())
+					return
+				}
+				if err == nil {
+					endGap(now)
+				} else if gap == nil {
+					gap = &AvailabilityGap{Endpoint: cl.EndpointURL().String(), Start: now, Err: err.Error()}
+				}
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					endGap(time.Now())
+					return
+				}
+			}
+		}(cl)
+	}
+	return func() []AvailabilityGap {
+		cancel()
+		wg.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		sort.Slice(gaps, func(i, j int) bool {
+			return gaps[i].Start.Before(gaps[j].Start)
+		})
+		return gaps
+	}
+}
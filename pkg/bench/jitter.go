@@ -0,0 +1,180 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// ConcurrencyJitter will randomly change the number of active requests
+// between Min and Max every Interval.
+type ConcurrencyJitter struct {
+	Min, Max int
+	Interval time.Duration
+}
+
+// ConcurrencyChange records a change in the number of active requests.
+type ConcurrencyChange struct {
+	Time        time.Time
+	Concurrency int
+}
+
+// ParseConcurrencyJitter parses a jitter specification in the form 'min:max@interval',
+// for example '10:50@5s'.
+func ParseConcurrencyJitter(s string) (*ConcurrencyJitter, error) {
+	at := strings.Split(s, "@")
+	if len(at) != 2 {
+		return nil, fmt.Errorf("expected 'min:max@interval', got %q", s)
+	}
+	minMax := strings.Split(at[0], ":")
+	if len(minMax) != 2 {
+		return nil, fmt.Errorf("expected 'min:max@interval', got %q", s)
+	}
+	var j ConcurrencyJitter
+	var err error
+	if j.Min, err = strconv.Atoi(minMax[0]); err != nil {
+		return nil, err
+	}
+	if j.Max, err = strconv.Atoi(minMax[1]); err != nil {
+		return nil, err
+	}
+	if j.Interval, err = time.ParseDuration(at[1]); err != nil {
+		return nil, err
+	}
+	if j.Min < 1 || j.Max < j.Min {
+		return nil, fmt.Errorf("min must be at least 1 and max at least min, got %d:%d", j.Min, j.Max)
+	}
+	if j.Interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %v", j.Interval)
+	}
+	return &j, nil
+}
+
+// concurrencyLimiter limits the number of concurrent requests to a limit that can be changed.
+type concurrencyLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	limit  int
+}
+
+func newConcurrencyLimiter(limit int) *concurrencyLimiter {
+	l := &concurrencyLimiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *concurrencyLimiter) acquire() {
+	l.mu.Lock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+	l.mu.Unlock()
+}
+
+func (l *concurrencyLimiter) release() {
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+func (l *concurrencyLimiter) setLimit(n int) {
+	l.mu.Lock()
+	l.limit = n
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// StartJitter will change the number of active requests randomly when start is closed,
+// if Jitter is set. Requests are limited when clients are requested.
+// Jitter stops when ctx is canceled or the returned function is called.
+// The returned function returns the recorded concurrency timeline.
+// Must be called before the benchmark is started.
+func (c *Common) StartJitter(ctx context.Context, start <-chan struct{}) (stop func() []ConcurrencyChange) {
+	if c.Jitter == nil {
+		return func() []ConcurrencyChange { return nil }
+	}
+	j := *c.Jitter
+	l := newConcurrencyLimiter(c.Concurrency)
+	limit := func(fn func() (*minio.Client, func())) func() (*minio.Client, func()) {
+		return func() (*minio.Client, func()) {
+			l.acquire()
+			cl, done := fn()
+			return cl, func() {
+				done()
+				l.release()
+			}
+		}
+	}
+	orgClient, orgAnon := c.Client, c.AnonymousClient
+	c.Client = limit(orgClient)
+	if orgAnon != nil {
+		c.AnonymousClient = limit(orgAnon)
+	}
+
+	var mu sync.Mutex
+	var timeline []ConcurrencyChange
+	stopCh := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		// Remove limits when done, so no requests are blocked.
+		defer l.setLimit(c.Concurrency)
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		select {
+		case <-start:
+		case <-ctx.Done():
+			return
+		case <-stopCh:
+			return
+		}
+		ticker := time.NewTicker(j.Interval)
+		defer ticker.Stop()
+		for {
+			n := j.Min + rng.Intn(j.Max-j.Min+1)
+			l.setLimit(n)
+			mu.Lock()
+			timeline = append(timeline, ConcurrencyChange{Time: time.Now(), Concurrency: n})
+			mu.Unlock()
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return func() []ConcurrencyChange {
+		close(stopCh)
+		<-finished
+		c.Client, c.AnonymousClient = orgClient, orgAnon
+		mu.Lock()
+		defer mu.Unlock()
+		return timeline
+	}
+}
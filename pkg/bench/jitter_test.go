@@ -0,0 +1,55 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseConcurrencyJitter(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ConcurrencyJitter
+		wantErr bool
+	}{
+		{in: "10:50@5s", want: ConcurrencyJitter{Min: 10, Max: 50, Interval: 5 * time.Second}},
+		{in: "1:1@100ms", want: ConcurrencyJitter{Min: 1, Max: 1, Interval: 100 * time.Millisecond}},
+		{in: "10:50", wantErr: true},
+		{in: "10@5s", wantErr: true},
+		{in: "10:50@5s@1s", wantErr: true},
+		{in: "a:50@5s", wantErr: true},
+		{in: "10:b@5s", wantErr: true},
+		{in: "10:50@soon", wantErr: true},
+		{in: "0:10@5s", wantErr: true},
+		{in: "50:10@5s", wantErr: true},
+		{in: "10:50@0s", wantErr: true},
+		{in: "10:50@-1s", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			got, err := ParseConcurrencyJitter(test.in)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error: %v", err, test.wantErr)
+			}
+			if err == nil && *got != test.want {
+				t.Errorf("got %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+}
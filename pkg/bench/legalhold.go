@@ -0,0 +1,247 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/generator"
+)
+
+// LegalHold benchmarks setting and clearing legal hold on objects.
+type LegalHold struct {
+	CreateObjects int
+	Collector     *Collector
+	objects       generator.Objects
+
+	Common
+}
+
+// Operation types of LegalHold.
+const (
+	opTypeLegalHoldSet   = "LEGALHOLD-SET"
+	opTypeLegalHoldClear = "LEGALHOLD-CLEAR"
+)
+
+// Prepare will create a bucket with object locking or delete any content already there
+// and upload a number of objects.
+func (g *LegalHold) Prepare(ctx context.Context) error {
+	g.ObjectLocking = true
+	if err := g.createEmptyBucket(ctx); err != nil {
+		return err
+	}
+	cl, done := g.Client()
+	enabled, _, _, _, err := cl.GetObjectLockConfig(ctx, g.Bucket)
+	done()
+	if err != nil {
+		return fmt.Errorf("bucket %q must have object locking enabled: %w", g.Bucket, err)
+	}
+	if enabled != "Enabled" {
+		return fmt.Errorf("bucket %q must have object locking enabled", g.Bucket)
+	}
+	// Object locking always enables versioning.
+	g.Versioned = true
+
+	src := g.Source()
+	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
+	var wg sync.WaitGroup
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
+	obj := make(chan struct{}, g.CreateObjects)
+	for i := 0; i < g.CreateObjects; i++ {
+		obj <- struct{}{}
+	}
+	close(obj)
+	var groupErr error
+	var mu sync.Mutex
+	for i := 0; i < g.prepareConcurrency(); i++ {
+		go func(i int) {
+			defer wg.Done()
+			src := g.Source()
+			for range obj {
+				opts := g.PutOpts
+				rcv := g.Collector.Receiver()
+				done := ctx.Done()
+
+				select {
+				case <-done:
+					return
+				default:
+				}
+				obj := src.Object()
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   http.MethodPut,
+					Thread:   uint16(i),
+					Size:     obj.Size,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				opts.ContentType = obj.ContentType
+				var res minio.UploadInfo
+				err := g.prepareUpload(ctx, obj.Reader, func() (err error) {
+					op.Start = time.Now()
+					res, err = g.putObject(ctx, client, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+					return err
+				})
+				op.End = time.Now()
+				cldone()
+				if err != nil {
+					err := fmt.Errorf("upload error: %w", err)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+
+				obj.VersionID = res.VersionID
+				if res.Size != obj.Size {
+					err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				mu.Lock()
+				obj.Reader = nil
+				g.objects = append(g.objects, *obj)
+				g.prepareProgress(float64(len(g.objects)) / float64(g.CreateObjects))
+				mu.Unlock()
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	return groupErr
+}
+
+// Start will execute the main benchmark.
+// Operations should begin executing when the start channel is closed.
+// Each thread toggles legal hold on its own share of the objects,
+// so every object alternates between being set and cleared.
+func (g *LegalHold) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(g.Concurrency)
+	c := g.Collector
+	if g.AutoTermDur > 0 {
+		ctx = c.AutoTerm(ctx, opTypeLegalHoldSet, g.AutoTermScale, autoTermCheck, autoTermSamples, g.AutoTermDur)
+	}
+	// Non-terminating context.
+	nonTerm := context.Background()
+
+	for i := 0; i < g.Concurrency; i++ {
+		go func(i int) {
+			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
+			defer wg.Done()
+			done := ctx.Done()
+			var objs generator.Objects
+			for j := i; j < len(g.objects); j += g.Concurrency {
+				objs = append(objs, g.objects[j])
+			}
+			if len(objs) == 0 {
+				return
+			}
+			held := make([]bool, len(objs))
+
+			<-wait
+			for n := 0; ; n++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				idx := n % len(objs)
+				obj := objs[idx]
+				status, opType := minio.LegalHoldEnabled, opTypeLegalHoldSet
+				if held[idx] {
+					status, opType = minio.LegalHoldDisabled, opTypeLegalHoldClear
+				}
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   opType,
+					Thread:   uint16(i),
+					Size:     0,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				op.Start = time.Now()
+				err := client.PutObjectLegalHold(reqCtx, g.Bucket, obj.Name, minio.PutObjectLegalHoldOptions{
+					VersionID: obj.VersionID,
+					Status:    &status,
+				})
+				op.End = time.Now()
+				cldone()
+				if err != nil {
+					g.Error("PutObjectLegalHold 出错: ", err)
+					op.Err = err.Error()
+				} else {
+					held[idx] = !held[idx]
+				}
+				rec.fill(&op)
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	return c.Close(), nil
+}
+
+// Cleanup clears legal hold on all objects and deletes everything uploaded to the bucket.
+func (g *LegalHold) Cleanup(ctx context.Context) {
+	objs := make(chan generator.Object, len(g.objects))
+	for _, obj := range g.objects {
+		objs <- obj
+	}
+	close(objs)
+	var wg sync.WaitGroup
+	wg.Add(g.Concurrency)
+	for i := 0; i < g.Concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			status := minio.LegalHoldDisabled
+			for obj := range objs {
+				client, cldone := g.Client()
+				err := client.PutObjectLegalHold(ctx, g.Bucket, obj.Name, minio.PutObjectLegalHoldOptions{
+					VersionID: obj.VersionID,
+					Status:    &status,
+				})
+				cldone()
+				if err != nil {
+					g.Error("清除 legal hold 出错: ", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	g.deleteAllInBucket(ctx, g.Bucket, g.objects.Prefixes()...)
+}
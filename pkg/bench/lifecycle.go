@@ -0,0 +1,84 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/minio/minio/pkg/console"
+)
+
+// Lifecycle benchmarks upload speed to a bucket with an expiration lifecycle rule.
+type Lifecycle struct {
+	Put
+
+	// ExpireDays is the number of days after which objects expire.
+	ExpireDays int
+
+	// previous is the lifecycle configuration of the bucket before the benchmark.
+	previous *lifecycle.Configuration
+}
+
+// lifecycleRuleID is the ID of the lifecycle rule added by the benchmark.
+const lifecycleRuleID = "warp-benchmark-expiry"
+
+// Prepare will create an empty bucket and apply the lifecycle configuration.
+// Any existing lifecycle configuration is kept and restored on cleanup.
+func (l *Lifecycle) Prepare(ctx context.Context) error {
+	if err := l.Put.Prepare(ctx); err != nil {
+		return err
+	}
+	cl, done := l.Client()
+	defer done()
+	if cfg, err := cl.GetBucketLifecycle(ctx, l.Bucket); err == nil && !cfg.Empty() {
+		l.previous = cfg
+	}
+	cfg := lifecycle.NewConfiguration()
+	if l.previous != nil {
+		cfg.Rules = append(cfg.Rules, l.previous.Rules...)
+	}
+	cfg.Rules = append(cfg.Rules, lifecycle.Rule{
+		ID:         lifecycleRuleID,
+		Status:     "Enabled",
+		RuleFilter: lifecycle.Filter{Prefix: ""},
+		Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(l.ExpireDays)},
+	})
+	if err := cl.SetBucketLifecycle(ctx, l.Bucket, cfg); err != nil {
+		return fmt.Errorf("set bucket lifecycle: %w", err)
+	}
+	console.Infof("\r已设置存储桶生命周期规则, 对象在 %d 天后过期.\n", l.ExpireDays)
+	return nil
+}
+
+// Cleanup deletes everything uploaded to the bucket and
+// restores the lifecycle configuration from before the benchmark.
+func (l *Lifecycle) Cleanup(ctx context.Context) {
+	l.Put.Cleanup(ctx)
+	cl, done := l.Client()
+	defer done()
+	cfg := l.previous
+	if cfg == nil {
+		// An empty configuration removes the lifecycle configuration.
+		cfg = lifecycle.NewConfiguration()
+	}
+	if err := cl.SetBucketLifecycle(ctx, l.Bucket, cfg); err != nil {
+		l.Error("恢复存储桶生命周期配置出错: ", err)
+	}
+}
@@ -35,8 +35,13 @@ import (
 type List struct {
 	CreateObjects int
 	NoPrefix      bool
-	Collector     *Collector
-	objects       []generator.Objects
+	// Versions will upload this number of versions of each object
+	// to a versioned bucket and list object versions if > 0.
+	Versions  int
+	Collector *Collector
+	objects   []generator.Objects
+	// enabledVersioning is set if versioning was enabled by Prepare.
+	enabledVersioning bool
 
 	Common
 }
@@ -47,8 +52,25 @@ func (d *List) Prepare(ctx context.Context) error {
 	if err := d.createEmptyBucket(ctx); err != nil {
 		return err
 	}
+	if d.Versions > 0 && !d.Versioned {
+		cl, done := d.Client()
+		err := cl.EnableVersioning(ctx, d.Bucket)
+		done()
+		if err != nil {
+			return err
+		}
+		d.Versioned = true
+		d.enabledVersioning = true
+	}
+	versions := d.Versions
+	if versions < 1 {
+		versions = 1
+	}
 	src := d.Source()
 	objPerPrefix := d.CreateObjects / d.Concurrency
+	if d.Versions > 0 {
+		console.Info("\r每个对象将上传 ", versions, " 个版本")
+	}
 	if d.NoPrefix {
 		console.Info("\r正在上传 ", objPerPrefix*d.Concurrency, " 个对象: ", src.String(), ", 没有前缀")
 	} else {
@@ -56,7 +78,7 @@ func (d *List) Prepare(ctx context.Context) error {
 	}
 	var wg sync.WaitGroup
 	wg.Add(d.Concurrency)
-	d.Collector = NewCollector()
+	d.Collector = d.newCollector()
 	d.objects = make([]generator.Objects, d.Concurrency)
 	var mu sync.Mutex
 	objsCreated := 0
@@ -86,48 +108,62 @@ func (d *List) Prepare(ctx context.Context) error {
 					break
 				}
 				exists[obj.Name] = struct{}{}
-				client, cldone := d.Client()
-				op := Operation{
-					OpType:   http.MethodPut,
-					Thread:   uint16(i),
-					Size:     obj.Size,
-					File:     obj.Name,
-					ObjPerOp: 1,
-					Endpoint: client.EndpointURL().String(),
-				}
-				opts.ContentType = obj.ContentType
-				op.Start = time.Now()
-				res, err := client.PutObject(ctx, d.Bucket, obj.Name, obj.Reader, obj.Size, opts)
-				op.End = time.Now()
-				if err != nil {
-					err := fmt.Errorf("upload error: %w", err)
-					d.Error(err)
-					mu.Lock()
-					if groupErr == nil {
-						groupErr = err
+				name, prefix := obj.Name, obj.Prefix
+				for v := 0; v < versions; v++ {
+					if v > 0 {
+						// Upload a new version of the same key.
+						obj = src.Object()
+						obj.Name, obj.Prefix = name, prefix
+					}
+					client, cldone := d.Client()
+					op := Operation{
+						OpType:   http.MethodPut,
+						Thread:   uint16(i),
+						Size:     obj.Size,
+						File:     obj.Name,
+						ObjPerOp: 1,
+						Endpoint: client.EndpointURL().String(),
+					}
+					opts.ContentType = obj.ContentType
+					var res minio.UploadInfo
+					err := d.prepareUpload(ctx, obj.Reader, func() (err error) {
+						op.Start = time.Now()
+						res, err = d.putObject(ctx, client, d.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+						return err
+					})
+					op.End = time.Now()
+					cldone()
+					if err != nil {
+						err := fmt.Errorf("upload error: %w", err)
+						d.Error(err)
+						mu.Lock()
+						if groupErr == nil {
+							groupErr = err
+						}
+						mu.Unlock()
+						return
+					}
+					obj.VersionID = res.VersionID
+					if res.Size != obj.Size {
+						err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
+						d.Error(err)
+						mu.Lock()
+						if groupErr == nil {
+							groupErr = err
+						}
+						mu.Unlock()
+						return
 					}
-					mu.Unlock()
-					return
-				}
-				obj.VersionID = res.VersionID
-				if res.Size != obj.Size {
-					err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
-					d.Error(err)
 					mu.Lock()
-					if groupErr == nil {
-						groupErr = err
+					obj.Reader = nil
+					if v == 0 {
+						d.objects[i] = append(d.objects[i], *obj)
+						objsCreated++
+						d.prepareProgress(float64(objsCreated) / float64(objPerPrefix*d.Concurrency))
 					}
 					mu.Unlock()
-					return
+					rcv <- op
 				}
-				cldone()
-				mu.Lock()
-				obj.Reader = nil
-				d.objects[i] = append(d.objects[i], *obj)
-				objsCreated++
-				d.prepareProgress(float64(objsCreated) / float64(objPerPrefix*d.Concurrency))
-				mu.Unlock()
-				rcv <- op
 			}
 		}(i)
 	}
@@ -149,7 +185,7 @@ func (d *List) Start(ctx context.Context, wait chan struct{}) (Operations, error
 	wg.Add(d.Concurrency)
 	c := d.Collector
 	if d.AutoTermDur > 0 {
-		ctx = c.AutoTerm(ctx, "LIST", d.AutoTermScale, autoTermCheck, autoTermSamples, d.AutoTermDur)
+		ctx = c.AutoTerm(ctx, d.opType(), d.AutoTermScale, autoTermCheck, autoTermSamples, d.AutoTermDur)
 	}
 	// Non-terminating context.
 	nonTerm := context.Background()
@@ -157,6 +193,7 @@ func (d *List) Start(ctx context.Context, wait chan struct{}) (Operations, error
 	for i := 0; i < d.Concurrency; i++ {
 		go func(i int) {
 			rcv := c.Receiver()
+			reqCtx, rec := d.newRecorder(nonTerm, cancelOnStop(ctx))
 			defer wg.Done()
 			done := ctx.Done()
 			objs := d.objects[i]
@@ -164,6 +201,10 @@ func (d *List) Start(ctx context.Context, wait chan struct{}) (Operations, error
 			if d.NoPrefix {
 				wantN *= d.Concurrency
 			}
+			if d.Versions > 0 {
+				// Each version is returned as an entry.
+				wantN *= d.Versions
+			}
 
 			<-wait
 			for {
@@ -174,10 +215,10 @@ func (d *List) Start(ctx context.Context, wait chan struct{}) (Operations, error
 				}
 
 				prefix := objs[0].Prefix
-				client, cldone := d.Client()
+				client, cldone := d.readClient()
 				op := Operation{
 					File:     prefix,
-					OpType:   "LIST",
+					OpType:   d.opType(),
 					Thread:   uint16(i),
 					Size:     0,
 					Endpoint: client.EndpointURL().String(),
@@ -185,7 +226,7 @@ func (d *List) Start(ctx context.Context, wait chan struct{}) (Operations, error
 				op.Start = time.Now()
 
 				// List all objects with prefix
-				listCh := client.ListObjects(nonTerm, d.Bucket, minio.ListObjectsOptions{WithMetadata: true, Prefix: objs[0].Prefix, Recursive: true})
+				listCh := client.ListObjects(reqCtx, d.Bucket, minio.ListObjectsOptions{WithMetadata: true, WithVersions: d.Versions > 0, Prefix: objs[0].Prefix, Recursive: true})
 
 				// Wait for errCh to close.
 				for {
@@ -198,6 +239,7 @@ func (d *List) Start(ctx context.Context, wait chan struct{}) (Operations, error
 						op.Err = err.Err.Error()
 					}
 					op.ObjPerOp++
+					op.Size += listEntrySize(err)
 					if op.FirstByte == nil {
 						now := time.Now()
 						op.FirstByte = &now
@@ -210,6 +252,7 @@ func (d *List) Start(ctx context.Context, wait chan struct{}) (Operations, error
 				}
 				op.End = time.Now()
 				cldone()
+				rec.fill(&op)
 				rcv <- op
 			}
 		}(i)
@@ -218,7 +261,44 @@ func (d *List) Start(ctx context.Context, wait chan struct{}) (Operations, error
 	return c.Close(), nil
 }
 
+// opType returns the operation type of list operations.
+func (d *List) opType() string {
+	if d.Versions > 0 {
+		return "LIST-VERSIONS"
+	}
+	return "LIST"
+}
+
+// listEntryOverhead is the approximate size of the XML of a single
+// list entry, excluding the key, etag and metadata.
+const listEntryOverhead = 250
+
+// listEntrySize returns the approximate number of bytes a single object
+// adds to a list response.
+func listEntrySize(obj minio.ObjectInfo) int64 {
+	if obj.Err != nil {
+		return 0
+	}
+	n := listEntryOverhead + len(obj.Key) + len(obj.ETag) + len(obj.StorageClass)
+	for k, v := range obj.UserMetadata {
+		n += 2*len(k) + len(v) + 5
+	}
+	return int64(n)
+}
+
 // Cleanup deletes everything uploaded to the bucket.
+// If versioning was enabled by Prepare it is suspended again.
 func (d *List) Cleanup(ctx context.Context) {
-	d.deleteAllInBucket(ctx, generator.MergeObjectPrefixes(d.objects)...)
+	d.deleteAllInBucket(ctx, d.Bucket, generator.MergeObjectPrefixes(d.objects)...)
+	if !d.enabledVersioning {
+		return
+	}
+	cl, done := d.Client()
+	defer done()
+	if err := cl.SuspendVersioning(ctx, d.Bucket); err != nil {
+		d.Error("SuspendVersioning 出错: ", err)
+		return
+	}
+	d.Versioned = false
+	d.enabledVersioning = false
 }
@@ -0,0 +1,119 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LiveStats keeps running counts of finished operations by type.
+// It is safe for concurrent use.
+type LiveStats struct {
+	mu    sync.Mutex
+	since time.Time
+	byOp  map[string]*LiveOpStats
+	slo   time.Duration
+}
+
+// LiveOpStats contains the operations of a single type finished since the last snapshot.
+type LiveOpStats struct {
+	OpType  string
+	Ops     int
+	Objects int
+	Errors  int
+	Bytes   int64
+	// Operations slower than the SLO.
+	SLOViolations int
+	// Duration since the last snapshot.
+	Duration time.Duration
+}
+
+// NewLiveStats returns new live stats.
+func NewLiveStats() *LiveStats {
+	return &LiveStats{since: time.Now(), byOp: make(map[string]*LiveOpStats)}
+}
+
+// SetSLO will count successful operations taking longer than slo.
+// 0 disables counting.
+func (l *LiveStats) SetSLO(slo time.Duration) {
+	l.mu.Lock()
+	l.slo = slo
+	l.mu.Unlock()
+}
+
+// add an operation. Canceled operations are ignored.
+func (l *LiveStats) add(op Operation) {
+	if l == nil || op.Canceled {
+		return
+	}
+	l.mu.Lock()
+	s := l.byOp[op.OpType]
+	if s == nil {
+		s = &LiveOpStats{OpType: op.OpType}
+		l.byOp[op.OpType] = s
+	}
+	s.Ops++
+	if op.Err != "" {
+		s.Errors++
+	} else {
+		s.Objects += op.ObjPerOp
+		s.Bytes += op.Size
+		if l.slo > 0 && op.Duration() > l.slo {
+			s.SLOViolations++
+		}
+	}
+	l.mu.Unlock()
+}
+
+// Snapshot returns the operations finished since the previous snapshot sorted by type
+// and resets the counts.
+func (l *LiveStats) Snapshot() []LiveOpStats {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	dur := now.Sub(l.since)
+	res := make([]LiveOpStats, 0, len(l.byOp))
+	for _, s := range l.byOp {
+		s.Duration = dur
+		res = append(res, *s)
+		*s = LiveOpStats{OpType: s.OpType}
+	}
+	l.since = now
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].OpType < res[j].OpType
+	})
+	return res
+}
+
+// ObjsPerSec returns the number of successful objects per second.
+func (s LiveOpStats) ObjsPerSec() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Objects) / s.Duration.Seconds()
+}
+
+// BytesPerSec returns the successful bytes per second.
+func (s LiveOpStats) BytesPerSec() Throughput {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return Throughput(float64(s.Bytes) / s.Duration.Seconds())
+}
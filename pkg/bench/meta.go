@@ -0,0 +1,187 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metadata calls that can be benchmarked by Meta.
+const (
+	MetaOpLocation     = "location"
+	MetaOpVersioning   = "versioning"
+	MetaOpListBuckets  = "listbuckets"
+	MetaOpBucketExists = "exists"
+)
+
+// metaOpTypes contains the recorded operation type for each metadata call.
+var metaOpTypes = map[string]string{
+	MetaOpLocation:     "LOCATION",
+	MetaOpVersioning:   "VERSIONING",
+	MetaOpListBuckets:  "LISTBUCKETS",
+	MetaOpBucketExists: "BUCKETEXISTS",
+}
+
+// emptySHA256 is the hex encoded SHA256 of an empty body.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// ParseMetaOps parses a comma separated list of metadata calls,
+// for example 'location,versioning,listbuckets'.
+func ParseMetaOps(s string) ([]string, error) {
+	var res []string
+	for _, op := range strings.Split(s, ",") {
+		op = strings.ToLower(strings.TrimSpace(op))
+		if op == "" {
+			continue
+		}
+		if _, ok := metaOpTypes[op]; !ok {
+			valid := make([]string, 0, len(metaOpTypes))
+			for k := range metaOpTypes {
+				valid = append(valid, k)
+			}
+			sort.Strings(valid)
+			return nil, fmt.Errorf("unknown metadata call %q, valid values are %v", op, valid)
+		}
+		res = append(res, op)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no metadata calls specified")
+	}
+	return res, nil
+}
+
+// Meta benchmarks fast bucket metadata calls.
+type Meta struct {
+	Common
+
+	// Ops are the metadata calls to make.
+	// Each thread cycles through these in order.
+	Ops []string
+
+	// Do will sign and send a raw request.
+	// Used for GetBucketLocation, since the client caches the location.
+	Do func(req *http.Request) (*http.Response, error)
+}
+
+// Prepare will create the bucket if it does not exist.
+// Existing content is kept, since no objects are used.
+func (g *Meta) Prepare(ctx context.Context) error {
+	return g.createBucket(ctx)
+}
+
+// Start will execute the main benchmark.
+// Operations should begin executing when the start channel is closed.
+func (g *Meta) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(g.Concurrency)
+	c := g.newCollector()
+	if g.AutoTermDur > 0 {
+		ctx = c.AutoTerm(ctx, "", g.AutoTermScale, autoTermCheck, autoTermSamples, g.AutoTermDur)
+	}
+	// Non-terminating context.
+	nonTerm := context.Background()
+
+	for i := 0; i < g.Concurrency; i++ {
+		go func(i int) {
+			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
+			defer wg.Done()
+			done := ctx.Done()
+			// Start each thread at a different call.
+			next := i
+
+			<-wait
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				call := g.Ops[next%len(g.Ops)]
+				next++
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   metaOpTypes[call],
+					Thread:   uint16(i),
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				op.Start = time.Now()
+				var err error
+				switch call {
+				case MetaOpLocation:
+					err = g.getLocation(reqCtx, *client.EndpointURL())
+				case MetaOpVersioning:
+					_, err = client.GetBucketVersioning(reqCtx, g.Bucket)
+				case MetaOpListBuckets:
+					_, err = client.ListBuckets(reqCtx)
+				case MetaOpBucketExists:
+					var found bool
+					found, err = client.BucketExists(reqCtx, g.Bucket)
+					if err == nil && !found {
+						err = fmt.Errorf("bucket %q not found", g.Bucket)
+					}
+				}
+				op.End = time.Now()
+				if err != nil {
+					g.Error(op.OpType, " 出错: ", err)
+					op.Err = err.Error()
+				}
+				cldone()
+				rec.fill(&op)
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	return c.Close(), nil
+}
+
+// getLocation sends a GetBucketLocation request to the endpoint.
+func (g *Meta) getLocation(ctx context.Context, u url.URL) error {
+	u.Path = "/" + g.Bucket + "/"
+	u.RawQuery = "location="
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	resp, err := g.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return err
+}
+
+// Cleanup does nothing, since no objects are uploaded.
+func (g *Meta) Cleanup(ctx context.Context) {
+}
@@ -42,17 +42,39 @@ type Mixed struct {
 
 	GetOpts  minio.GetObjectOptions
 	StatOpts minio.StatObjectOptions
+
+	// PutSource is used for objects uploaded by PUT operations.
+	// If nil, the common source is used.
+	PutSource func() generator.Source
 	Common
 }
 
+const (
+	// MixedOrderRandom will interleave operations randomly.
+	MixedOrderRandom = "random"
+	// MixedOrderWeightedShuffle will interleave operations randomly,
+	// but keep the proportions of operation types in every block of mixedShuffleBlock operations.
+	MixedOrderWeightedShuffle = "weighted-shuffle"
+	// MixedOrderBursty will send operations of the same type in bursts of up to mixedBurstLen operations.
+	MixedOrderBursty = "bursty"
+
+	mixedShuffleBlock = 20
+	mixedBurstLen     = 50
+)
+
 // MixedDistribution keeps track of operation distribution
 // and currently available objects.
 type MixedDistribution struct {
 	// Operation -> distribution.
 	Distribution map[string]float64
-	ops          []string
-	objects      map[string]generator.Object
-	rng          *rand.Rand
+	// Order is the order of operations, MixedOrderRandom if empty.
+	Order string
+
+	ops     []string
+	objects map[string]generator.Object
+	rng     *rand.Rand
+	// deletesAhead is the most deletes exceeding puts at any point in ops.
+	deletesAhead int
 
 	current int
 	mu      sync.Mutex
@@ -71,16 +93,78 @@ func (m *MixedDistribution) Generate(allocObjs int) error {
 
 	const genOps = 1000
 	m.ops = make([]string, 0, genOps)
-	for op, dist := range m.Distribution {
-		add := int(0.5 + dist*genOps)
-		for i := 0; i < add; i++ {
-			m.ops = append(m.ops, op)
-		}
+	ops := make([]string, 0, len(m.Distribution))
+	for op := range m.Distribution {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	counts := make(map[string]int, len(ops))
+	for _, op := range ops {
+		counts[op] = int(0.5 + m.Distribution[op]*genOps)
 	}
 	m.rng = rand.New(rand.NewSource(0xabad1dea))
-	sort.Slice(m.ops, func(i, j int) bool {
-		return m.rng.Int63()&1 == 0
-	})
+	switch m.Order {
+	case "", MixedOrderRandom:
+		for _, op := range ops {
+			for i := 0; i < counts[op]; i++ {
+				m.ops = append(m.ops, op)
+			}
+		}
+		sort.Slice(m.ops, func(i, j int) bool {
+			return m.rng.Int63()&1 == 0
+		})
+	case MixedOrderWeightedShuffle:
+		// Spread each operation type evenly over blocks and shuffle each block,
+		// so every block contains operations in the requested proportions.
+		const blocks = genOps / mixedShuffleBlock
+		blockOps := make([][]string, blocks)
+		for _, op := range ops {
+			n := counts[op]
+			for i := 0; i < n; i++ {
+				b := i * blocks / n
+				blockOps[b] = append(blockOps[b], op)
+			}
+		}
+		for _, b := range blockOps {
+			m.rng.Shuffle(len(b), func(i, j int) { b[i], b[j] = b[j], b[i] })
+			m.ops = append(m.ops, b...)
+		}
+	case MixedOrderBursty:
+		// Split each operation type into bursts and shuffle the order of the bursts.
+		var bursts [][]string
+		for _, op := range ops {
+			for n := counts[op]; n > 0; n -= mixedBurstLen {
+				l := n
+				if l > mixedBurstLen {
+					l = mixedBurstLen
+				}
+				burst := make([]string, l)
+				for i := range burst {
+					burst[i] = op
+				}
+				bursts = append(bursts, burst)
+			}
+		}
+		m.rng.Shuffle(len(bursts), func(i, j int) { bursts[i], bursts[j] = bursts[j], bursts[i] })
+		for _, b := range bursts {
+			m.ops = append(m.ops, b...)
+		}
+	default:
+		return fmt.Errorf("unknown mixed order %q", m.Order)
+	}
+
+	ahead := 0
+	for _, op := range m.ops {
+		switch op {
+		case http.MethodPut:
+			ahead--
+		case http.MethodDelete:
+			ahead++
+		}
+		if ahead > m.deletesAhead {
+			m.deletesAhead = ahead
+		}
+	}
 	return nil
 }
 
@@ -155,14 +239,17 @@ func (g *Mixed) Prepare(ctx context.Context) error {
 	if g.CreateObjects <= g.Concurrency {
 		return errors.New("initial number of objects should be at least matching concurrency")
 	}
+	if g.CreateObjects <= g.Concurrency+g.Dist.deletesAhead {
+		return fmt.Errorf("initial number of objects should be more than concurrency + %d, since up to %d more objects than uploaded may be deleted", g.Dist.deletesAhead, g.Dist.deletesAhead)
+	}
 	if err := g.createEmptyBucket(ctx); err != nil {
 		return err
 	}
 	src := g.Source()
 	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
 	var wg sync.WaitGroup
-	wg.Add(g.Concurrency)
-	g.Collector = NewCollector()
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
 	obj := make(chan struct{}, g.CreateObjects)
 	for i := 0; i < g.CreateObjects; i++ {
 		obj <- struct{}{}
@@ -170,7 +257,7 @@ func (g *Mixed) Prepare(ctx context.Context) error {
 	close(obj)
 	var groupErr error
 	var mu sync.Mutex
-	for i := 0; i < g.Concurrency; i++ {
+	for i := 0; i < g.prepareConcurrency(); i++ {
 		go func(i int) {
 			defer wg.Done()
 			src := g.Source()
@@ -186,7 +273,12 @@ func (g *Mixed) Prepare(ctx context.Context) error {
 				obj := src.Object()
 				client, clDone := g.Client()
 				opts.ContentType = obj.ContentType
-				res, err := client.PutObject(ctx, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+				var res minio.UploadInfo
+				err := g.prepareUpload(ctx, obj.Reader, func() (err error) {
+					res, err = g.putObject(ctx, client, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+					return err
+				})
+				clDone()
 				if err != nil {
 					err := fmt.Errorf("upload error: %w", err)
 					g.Error(err)
@@ -208,7 +300,6 @@ func (g *Mixed) Prepare(ctx context.Context) error {
 					mu.Unlock()
 					return
 				}
-				clDone()
 				obj.Reader = nil
 				g.Dist.addObj(*obj)
 				g.prepareProgress(float64(len(g.Dist.objects)) / float64(g.CreateObjects))
@@ -234,9 +325,13 @@ func (g *Mixed) Start(ctx context.Context, wait chan struct{}) (Operations, erro
 	for i := 0; i < g.Concurrency; i++ {
 		go func(i int) {
 			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
 			defer wg.Done()
 			done := ctx.Done()
 			src := g.Source()
+			if g.PutSource != nil {
+				src = g.PutSource()
+			}
 			putOpts := g.PutOpts
 			statOpts := g.StatOpts
 			getOpts := g.GetOpts
@@ -265,12 +360,15 @@ func (g *Mixed) Start(ctx context.Context, wait chan struct{}) (Operations, erro
 					op.Start = time.Now()
 					var err error
 					getOpts.VersionID = obj.VersionID
-					o, err := client.GetObject(nonTerm, g.Bucket, obj.Name, getOpts)
+					opCtx, opDone := g.opContext(reqCtx, http.MethodGet)
+					o, err := client.GetObject(opCtx, g.Bucket, obj.Name, getOpts)
 					fbr.r = o
 					if err != nil {
 						g.Error("下载出错:", err)
 						op.Err = err.Error()
 						op.End = time.Now()
+						opDone(&op)
+						rec.fill(&op)
 						rcv <- op
 						clDone()
 						objDone()
@@ -287,6 +385,8 @@ func (g *Mixed) Start(ctx context.Context, wait chan struct{}) (Operations, erro
 						op.Err = fmt.Sprint("不符合期望的下载大小. 需要的是:", obj.Size, ", 实际上是:", n)
 						g.Error(op.Err)
 					}
+					opDone(&op)
+					rec.fill(&op)
 					rcv <- op
 					objDone()
 					clDone()
@@ -304,9 +404,12 @@ func (g *Mixed) Start(ctx context.Context, wait chan struct{}) (Operations, erro
 						ObjPerOp: 1,
 						Endpoint: client.EndpointURL().String(),
 					}
+					release := g.reserveUpload(reqCtx, obj.Reader, obj.Size, putOpts)
 					op.Start = time.Now()
-					res, err := client.PutObject(nonTerm, g.Bucket, obj.Name, obj.Reader, obj.Size, putOpts)
+					opCtx, opDone := g.opContext(reqCtx, http.MethodPut)
+					res, err := client.PutObject(opCtx, g.Bucket, obj.Name, obj.Reader, obj.Size, putOpts)
 					op.End = time.Now()
+					release()
 					if err != nil {
 						g.Error("下载出错:", err)
 						op.Err = err.Error()
@@ -324,6 +427,8 @@ func (g *Mixed) Start(ctx context.Context, wait chan struct{}) (Operations, erro
 					if op.Err == "" {
 						g.Dist.addObj(*obj)
 					}
+					opDone(&op)
+					rec.fill(&op)
 					rcv <- op
 				case http.MethodDelete:
 					client, clDone := g.Client()
@@ -337,13 +442,16 @@ func (g *Mixed) Start(ctx context.Context, wait chan struct{}) (Operations, erro
 						Endpoint: client.EndpointURL().String(),
 					}
 					op.Start = time.Now()
-					err := client.RemoveObject(nonTerm, g.Bucket, obj.Name, minio.RemoveObjectOptions{VersionID: obj.VersionID})
+					opCtx, opDone := g.opContext(reqCtx, http.MethodDelete)
+					err := client.RemoveObject(opCtx, g.Bucket, obj.Name, minio.RemoveObjectOptions{VersionID: obj.VersionID})
 					op.End = time.Now()
 					clDone()
 					if err != nil {
 						g.Error("删除出错: ", err)
 						op.Err = err.Error()
 					}
+					opDone(&op)
+					rec.fill(&op)
 					rcv <- op
 				case "STAT":
 					obj, objDone := g.Dist.randomObj()
@@ -358,7 +466,8 @@ func (g *Mixed) Start(ctx context.Context, wait chan struct{}) (Operations, erro
 					}
 					op.Start = time.Now()
 					var err error
-					objI, err := client.StatObject(nonTerm, g.Bucket, obj.Name, statOpts)
+					opCtx, opDone := g.opContext(reqCtx, "STAT")
+					objI, err := client.StatObject(opCtx, g.Bucket, obj.Name, statOpts)
 					if err != nil {
 						g.Error("stat 错误: ", err)
 						op.Err = err.Error()
@@ -368,6 +477,8 @@ func (g *Mixed) Start(ctx context.Context, wait chan struct{}) (Operations, erro
 						op.Err = fmt.Sprint("不符合期望的 stat 大小. 需要的是:", obj.Size, ", 实际上是:", objI.Size)
 						g.Error(op.Err)
 					}
+					opDone(&op)
+					rec.fill(&op)
 					rcv <- op
 					objDone()
 					clDone()
@@ -383,5 +494,5 @@ func (g *Mixed) Start(ctx context.Context, wait chan struct{}) (Operations, erro
 
 // Cleanup deletes everything uploaded to the bucket.
 func (g *Mixed) Cleanup(ctx context.Context) {
-	g.deleteAllInBucket(ctx, g.Dist.Objects().Prefixes()...)
+	g.deleteAllInBucket(ctx, g.Bucket, g.Dist.Objects().Prefixes()...)
 }
@@ -0,0 +1,68 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import "sync"
+
+// objectLocks allows only one worker at the time to operate on each object.
+type objectLocks struct {
+	mu    sync.Mutex
+	locks map[string]*objectLock
+}
+
+type objectLock struct {
+	sync.Mutex
+	// refs is the number of workers holding or waiting for the lock.
+	refs int
+}
+
+// lock will block until no other worker holds the lock for name.
+// The returned function will release the lock.
+func (o *objectLocks) lock(name string) (unlock func()) {
+	o.mu.Lock()
+	if o.locks == nil {
+		o.locks = make(map[string]*objectLock)
+	}
+	l := o.locks[name]
+	if l == nil {
+		l = &objectLock{}
+		o.locks[name] = l
+	}
+	l.refs++
+	o.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		o.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(o.locks, name)
+		}
+		o.mu.Unlock()
+	}
+}
+
+// lockObject will block until no other worker is operating on the named object,
+// if NoObjectContention is set. The returned function must be called when the operation is done.
+func (c *Common) lockObject(name string) (unlock func()) {
+	if !c.NoObjectContention {
+		return func() {}
+	}
+	return c.objectLocks.lock(name)
+}
@@ -48,6 +48,36 @@ type Operation struct {
 	Thread    uint16     `json:"thread"`
 	ClientID  string     `json:"client_id"`
 	Endpoint  string     `json:"endpoint"`
+	// Tenant is the access key that issued the operation, if recorded.
+	Tenant    string        `json:"tenant,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	Proto     string        `json:"proto,omitempty"`
+	Phases    *PhaseTimings `json:"phases,omitempty"`
+	// Prepare is set on operations made while preparing the benchmark.
+	Prepare bool `json:"prepare,omitempty"`
+	// Timeout is set to the operation timeout if the operation failed because it was exceeded.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Throttled is set if the server responded with 429 or 503 to a request of the operation.
+	// The operation may still have succeeded after retrying.
+	Throttled bool `json:"throttled,omitempty"`
+	// Canceled is set on operations that failed because the benchmark was stopped.
+	Canceled bool `json:"canceled,omitempty"`
+	// Headers contains captured response headers, keyed by lower case name.
+	Headers map[string]string `json:"headers,omitempty"`
+	// StorageClass is the storage class of the upload, if recorded.
+	StorageClass string `json:"storage_class,omitempty"`
+	// ExactSize is set if the object size was selected from an exact list of sizes.
+	ExactSize bool `json:"exact_size,omitempty"`
+}
+
+// PhaseTimings contains the time spent in each phase of the requests of an operation.
+// If several requests were made for an operation, the times are added.
+type PhaseTimings struct {
+	DNS     time.Duration `json:"dns"`
+	Connect time.Duration `json:"connect"`
+	TLS     time.Duration `json:"tls"`
+	// Server is the time from the request was written until the first response byte.
+	Server time.Duration `json:"server"`
 }
 
 type Collector struct {
@@ -57,17 +87,46 @@ type Collector struct {
 	opsMu sync.Mutex
 	rcv   chan Operation
 	rcvWg sync.WaitGroup
+	spans *SpanExporter
+	live  *LiveStats
+
+	// autoTermMetric is the metric checked by AutoTerm.
+	autoTermMetric AutoTermMetric
+
+	// failFast is called once with the first failed benchmark operation.
+	failFast func(op Operation)
+	failed   bool
+
+	// exactSizes marks all received operations with ExactSize.
+	exactSizes bool
 }
 
 func NewCollector() *Collector {
+	return newCollector(nil, nil)
+}
+
+// newCollector returns a collector that will also export
+// all received operations to spans and add them to live, if not nil.
+func newCollector(spans *SpanExporter, live *LiveStats) *Collector {
 	r := &Collector{
-		ops: make(Operations, 0, 10000),
-		rcv: make(chan Operation, 1000),
+		ops:   make(Operations, 0, 10000),
+		rcv:   make(chan Operation, 1000),
+		spans: spans,
+		live:  live,
 	}
 	r.rcvWg.Add(1)
 	go func() {
 		defer r.rcvWg.Done()
 		for op := range r.rcv {
+			if r.exactSizes {
+				op.ExactSize = true
+			}
+			r.spans.Export(op)
+			r.live.add(op)
+			if r.failFast != nil && !r.failed && op.Err != "" && !op.Canceled {
+				r.failed = true
+				r.failFast(op)
+			}
 			r.opsMu.Lock()
 			r.ops = append(r.ops, op)
 			r.opsMu.Unlock()
@@ -76,10 +135,23 @@ func NewCollector() *Collector {
 	return r
 }
 
+// AutoTermMetric selects the metric that must be stable for automatic termination.
+type AutoTermMetric string
+
+const (
+	// AutoTermThroughput requires throughput to be stable.
+	AutoTermThroughput AutoTermMetric = "throughput"
+	// AutoTermLatency requires the 99th percentile latency to be stable.
+	AutoTermLatency AutoTermMetric = "latency"
+	// AutoTermBoth requires both throughput and latency to be stable.
+	AutoTermBoth AutoTermMetric = "both"
+)
+
 // AutoTerm will check if throughput is within 'threshold' (0 -> ) for wantSamples,
 // when the current operations are split into 'splitInto' segments.
 // The minimum duration for the calculation can be set as well.
 // Segment splitting may cause less than this duration to be used.
+// Depending on the metric of the collector the 99th percentile latency is checked as well.
 func (c *Collector) AutoTerm(ctx context.Context, op string, threshold float64, wantSamples, splitInto int, minDur time.Duration) context.Context {
 	if wantSamples >= splitInto {
 		panic("wantSamples >= splitInto")
@@ -118,31 +190,58 @@ func (c *Collector) AutoTerm(ctx context.Context, op string, threshold float64,
 			if len(segs) < wantSamples {
 				continue
 			}
+			segDur := end.Sub(start) / time.Duration(splitInto)
+			checkThroughput := c.autoTermMetric != AutoTermLatency
+			checkLatency := c.autoTermMetric == AutoTermLatency || c.autoTermMetric == AutoTermBoth
+
 			// Use last segment as our base.
 			mb, _, objs := segs[len(segs)-1].SpeedPerSec()
 			// Only use the segments we are interested in.
 			segs = segs[len(segs)-wantSamples : len(segs)-1]
-			for _, seg := range segs {
-				segMB, _, segObjs := seg.SpeedPerSec()
-				if mb > 0 {
-					if math.Abs(mb-segMB) > threshold*mb {
+			if checkThroughput {
+				for _, seg := range segs {
+					segMB, _, segObjs := seg.SpeedPerSec()
+					if mb > 0 {
+						if math.Abs(mb-segMB) > threshold*mb {
+							continue checkloop
+						}
+						continue
+					}
+					if math.Abs(objs-segObjs) > threshold*objs {
 						continue checkloop
 					}
+				}
+			}
+			var p99 time.Duration
+			if checkLatency {
+				// Compare the 99th percentile latency of the last segment
+				// with the same number of segments as above.
+				p99 = ops.latencyPercentile(end.Add(-segDur), end, 0.99)
+				if p99 <= 0 {
 					continue
 				}
-				if math.Abs(objs-segObjs) > threshold*objs {
-					continue checkloop
+				for i := 1; i < wantSamples; i++ {
+					segEnd := end.Add(-time.Duration(i) * segDur)
+					segP99 := ops.latencyPercentile(segEnd.Add(-segDur), segEnd, 0.99)
+					if math.Abs(float64(p99-segP99)) > threshold*float64(p99) {
+						continue checkloop
+					}
 				}
 			}
 			// All checks passed.
-			if mb > 0 {
-				console.Printf("\r吞吐量 %0.01fMiB/s within %f%% for %v. 结果已稳定，停止了基准测试.\n",
-					mb, threshold*100,
-					segs[0].Duration().Round(time.Millisecond)*time.Duration(len(segs)+1))
-			} else {
-				console.Printf("\r吞吐量 %0.01f objects/s within %f%% for %v. 结果已稳定，停止了基准测试.\n",
-					objs, threshold*100,
-					segs[0].Duration().Round(time.Millisecond)*time.Duration(len(segs)+1))
+			stableFor := segs[0].Duration().Round(time.Millisecond) * time.Duration(len(segs)+1)
+			if checkThroughput {
+				if mb > 0 {
+					console.Printf("\r吞吐量 %s within %f%% for %v. 结果已稳定，停止了基准测试.\n",
+						Throughput(mb*(1<<20)), threshold*100, stableFor)
+				} else {
+					console.Printf("\r吞吐量 %0.01f objects/s within %f%% for %v. 结果已稳定，停止了基准测试.\n",
+						objs, threshold*100, stableFor)
+				}
+			}
+			if checkLatency {
+				console.Printf("\r99%% 延迟 %v within %f%% for %v. 结果已稳定，停止了基准测试.\n",
+					p99.Round(time.Microsecond), threshold*100, stableFor)
 			}
 			return
 		}
@@ -150,6 +249,75 @@ func (c *Collector) AutoTerm(ctx context.Context, op string, threshold float64,
 	return ctx
 }
 
+// latencyPercentile returns the latency percentile of successful operations
+// ending within the time range. If there are no operations 0 is returned.
+func (o Operations) latencyPercentile(from, to time.Time, pct float64) time.Duration {
+	var durs []time.Duration
+	for _, op := range o {
+		if op.Err != "" || op.End.Before(from) || !op.End.Before(to) {
+			continue
+		}
+		durs = append(durs, op.Duration())
+	}
+	if len(durs) == 0 {
+		return 0
+	}
+	sort.Slice(durs, func(i, j int) bool { return durs[i] < durs[j] })
+	return durs[int(pct*float64(len(durs)-1))]
+}
+
+// SteadyState returns the time range where throughput is stable,
+// excluding ramp-up and the tail of the benchmark.
+// The operations are split into autoterm samples segments and the longest run of consecutive segments
+// with a throughput within 'threshold' (0 -> 1) of the median segment is returned.
+// ok is false if too few segments are stable.
+func (o Operations) SteadyState(threshold float64) (start, end time.Time, ok bool) {
+	from, to := o.ActiveTimeRange(!o.IsMixed())
+	segs := o.Segment(SegmentOptions{
+		From:           from,
+		PerSegDuration: to.Sub(from) / autoTermSamples,
+		AllThreads:     !o.IsMixed(),
+		MultiOp:        o.IsMixed(),
+	})
+	if len(segs) < autoTermCheck {
+		return start, end, false
+	}
+	useBytes := o.Total(false).TotalBytes > 0
+	speed := func(s Segment) float64 {
+		mb, _, objs := s.SpeedPerSec()
+		if useBytes {
+			return mb
+		}
+		return objs
+	}
+	speeds := make([]float64, len(segs))
+	for i, seg := range segs {
+		speeds[i] = speed(seg)
+	}
+	sorted := append([]float64{}, speeds...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	if median <= 0 {
+		return start, end, false
+	}
+
+	// Find the longest run of stable segments.
+	bestFrom, bestN, runFrom := 0, 0, 0
+	for i, v := range speeds {
+		if math.Abs(v-median) > threshold*median {
+			runFrom = i + 1
+			continue
+		}
+		if n := i - runFrom + 1; n > bestN {
+			bestFrom, bestN = runFrom, n
+		}
+	}
+	if bestN < autoTermCheck {
+		return start, end, false
+	}
+	return segs[bestFrom].Start, segs[bestFrom+bestN-1].EndsBefore, true
+}
+
 func (c *Collector) Receiver() chan<- Operation {
 	return c.rcv
 }
@@ -168,7 +336,36 @@ func (o Operation) Duration() time.Duration {
 // Throughput is the throughput as bytes/second.
 type Throughput float64
 
+// Units of throughput output.
+const (
+	// UnitsAuto uses binary units and keeps smaller units for longer than UnitsIEC.
+	UnitsAuto = "auto"
+	// UnitsSI uses decimal units, like MB/s.
+	UnitsSI = "si"
+	// UnitsIEC uses binary units, like MiB/s.
+	UnitsIEC = "iec"
+	// UnitsBits uses decimal bits per second, like Mbit/s.
+	UnitsBits = "bits"
+)
+
+// throughputUnits are the units used for printing throughput.
+var throughputUnits = UnitsAuto
+
+// SetThroughputUnits sets the units used when printing throughput.
+// Must be one of UnitsAuto, UnitsSI, UnitsIEC or UnitsBits.
+func SetThroughputUnits(units string) error {
+	switch units {
+	case UnitsAuto, UnitsSI, UnitsIEC, UnitsBits:
+		throughputUnits = units
+		return nil
+	}
+	return fmt.Errorf("unknown units %q, must be one of %s, %s, %s or %s", units, UnitsAuto, UnitsSI, UnitsIEC, UnitsBits)
+}
+
 func (t Throughput) String() string {
+	if throughputUnits != UnitsAuto {
+		return t.scaled(1)
+	}
 	if t < 2<<10 {
 		return fmt.Sprintf("%.1fB/s", float64(t))
 	}
@@ -184,6 +381,46 @@ func (t Throughput) String() string {
 	return fmt.Sprintf("%.2fTiB/s", float64(t/1024/1024/1024))
 }
 
+// StringLong returns the throughput with two decimals.
+// With UnitsAuto the throughput is always in MiB/s.
+func (t Throughput) StringLong() string {
+	if throughputUnits == UnitsAuto {
+		return fmt.Sprintf("%.02f MiB/s", float64(t)/(1<<20))
+	}
+	return t.scaled(2)
+}
+
+// stringDelta returns a throughput difference with one decimal.
+// With UnitsAuto the difference is always in MiB/s.
+func (t Throughput) stringDelta() string {
+	if throughputUnits == UnitsAuto {
+		return fmt.Sprintf("%.1f MiB/s", float64(t)/(1<<20))
+	}
+	return t.scaled(1)
+}
+
+// scaled returns the throughput in the biggest unit of throughputUnits
+// where the value is at least 1.
+func (t Throughput) scaled(decimals int) string {
+	v := float64(t)
+	base := 1000.0
+	units := []string{"B/s", "kB/s", "MB/s", "GB/s", "TB/s"}
+	switch throughputUnits {
+	case UnitsIEC:
+		base = 1024
+		units = []string{"B/s", "KiB/s", "MiB/s", "GiB/s", "TiB/s"}
+	case UnitsBits:
+		v *= 8
+		units = []string{"bit/s", "kbit/s", "Mbit/s", "Gbit/s", "Tbit/s"}
+	}
+	i := 0
+	for i < len(units)-1 && math.Abs(v) >= base {
+		v /= base
+		i++
+	}
+	return fmt.Sprintf("%.*f %s", decimals, v, units[i])
+}
+
 // Float returns a rounded (to 0.1) float value of the throughput.
 func (t Throughput) Float() float64 {
 	return math.Round(float64(t)*10) / 10
@@ -309,6 +546,69 @@ func (o Operations) SortByDuration() {
 	})
 }
 
+// Slowest returns up to n of the slowest operations, slowest first.
+// The operations are not modified.
+func (o Operations) Slowest(n int) Operations {
+	sorted := make(Operations, len(o))
+	copy(sorted, o)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Duration() > sorted[j].Duration()
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// Protocols returns the number of operations for each recorded protocol.
+// Operations without a recorded protocol are ignored.
+func (o Operations) Protocols() map[string]int {
+	res := make(map[string]int, 2)
+	for _, op := range o {
+		if op.Proto != "" {
+			res[op.Proto]++
+		}
+	}
+	return res
+}
+
+// internProto returns a shared string for common protocols to reduce memory usage.
+func internProto(s string) string {
+	switch s {
+	case "HTTP/1.1":
+		return "HTTP/1.1"
+	case "HTTP/2.0":
+		return "HTTP/2.0"
+	}
+	return s
+}
+
+// HasPhases returns whether any operation has phase timings.
+func (o Operations) HasPhases() bool {
+	for _, op := range o {
+		if op.Phases != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// HeaderNames returns the sorted names of all captured headers.
+func (o Operations) HeaderNames() []string {
+	found := make(map[string]struct{})
+	for _, op := range o {
+		for k := range op.Headers {
+			found[k] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(found))
+	for k := range found {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // SortByThroughput will sort the operations by throughput.
 // Fastest operations first.
 func (o Operations) SortByThroughput() {
@@ -381,6 +681,19 @@ func (o Operations) FilterByOp(opType string) Operations {
 	return dst
 }
 
+// FilterBySize returns operations with a size of at least min and at most max bytes.
+// If max is 0 there is no upper limit.
+func (o Operations) FilterBySize(min, max int64) Operations {
+	dst := make(Operations, 0, len(o))
+	for _, o := range o {
+		if o.Size < min || (max > 0 && o.Size > max) {
+			continue
+		}
+		dst = append(dst, o)
+	}
+	return dst
+}
+
 // SetClientID will set the client ID for all operations.
 func (o Operations) SetClientID(id string) {
 	for i := range o {
@@ -388,6 +701,36 @@ func (o Operations) SetClientID(id string) {
 	}
 }
 
+// SetPrepare marks all operations started before t as prepare operations.
+func (o Operations) SetPrepare(t time.Time) {
+	for i := range o {
+		o[i].Prepare = o[i].Start.Before(t)
+	}
+}
+
+// FilterCanceled returns operations that were either canceled at shutdown or not.
+func (o Operations) FilterCanceled(canceled bool) Operations {
+	dst := make(Operations, 0, len(o))
+	for _, op := range o {
+		if op.Canceled == canceled {
+			dst = append(dst, op)
+		}
+	}
+	return dst
+}
+
+// FilterByPrepare returns operations that are either prepare operations or not.
+// Always returns a copy.
+func (o Operations) FilterByPrepare(prepare bool) Operations {
+	dst := make(Operations, 0, len(o))
+	for _, o := range o {
+		if o.Prepare == prepare {
+			dst = append(dst, o)
+		}
+	}
+	return dst
+}
+
 // FilterByEndpoint returns operations run against a specific endpoint.
 // Always returns a copy.
 func (o Operations) FilterByEndpoint(endpoint string) Operations {
@@ -400,6 +743,30 @@ func (o Operations) FilterByEndpoint(endpoint string) Operations {
 	return dst
 }
 
+// FilterByTenant returns operations issued by a specific tenant.
+// Always returns a copy.
+func (o Operations) FilterByTenant(tenant string) Operations {
+	dst := make(Operations, 0, len(o))
+	for _, o := range o {
+		if o.Tenant == tenant {
+			dst = append(dst, o)
+		}
+	}
+	return dst
+}
+
+// FilterByStorageClass returns operations with a specific storage class.
+// Always returns a copy.
+func (o Operations) FilterByStorageClass(class string) Operations {
+	dst := make(Operations, 0, len(o))
+	for _, o := range o {
+		if o.StorageClass == class {
+			dst = append(dst, o)
+		}
+	}
+	return dst
+}
+
 // ByOp separates the operations by op.
 func (o Operations) ByOp() map[string]Operations {
 	dst := make(map[string]Operations, 1)
@@ -645,12 +1012,70 @@ func (o Operations) SingleSizeSegment() SizeSegment {
 	}
 }
 
+// maxDiscreteSizes is the maximum number of distinct object sizes
+// that SplitSizes will return separately.
+const maxDiscreteSizes = 10
+
+// discreteSizes returns the distinct sizes of the operations sorted by size,
+// if all operations have ExactSize set.
+// If there are more than maxDiscreteSizes nil is returned.
+func (o Operations) discreteSizes() []int64 {
+	seen := make(map[int64]struct{}, maxDiscreteSizes+1)
+	for _, op := range o {
+		if !op.ExactSize {
+			return nil
+		}
+		seen[op.Size] = struct{}{}
+		if len(seen) > maxDiscreteSizes {
+			return nil
+		}
+	}
+	sizes := make([]int64, 0, len(seen))
+	for sz := range seen {
+		sizes = append(sizes, sz)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+	return sizes
+}
+
 // SplitSizes will return log10 separated data.
+// If the sizes were selected from a short list of exact sizes,
+// each size is returned separately instead.
 // Specify the share of requests that must be in a segment to return it.
+// Exact sizes with fewer requests are combined with the next size.
 func (o Operations) SplitSizes(minShare float64) []SizeSegment {
 	if !o.MultipleSizes() {
 		return []SizeSegment{o.SingleSizeSegment()}
 	}
+	wantN := int(float64(len(o)) * minShare)
+	if sizes := o.discreteSizes(); sizes != nil {
+		var res []SizeSegment
+		var seg SizeSegment
+		for _, sz := range sizes {
+			if len(seg.Ops) == 0 {
+				seg.Smallest = sz
+			}
+			seg.Biggest = sz
+			for _, op := range o {
+				if op.Size == sz {
+					seg.Ops = append(seg.Ops, op)
+				}
+			}
+			if len(seg.Ops) >= wantN {
+				res = append(res, seg)
+				seg = SizeSegment{}
+			}
+		}
+		if len(seg.Ops) > 0 {
+			if len(res) == 0 {
+				return []SizeSegment{seg}
+			}
+			last := &res[len(res)-1]
+			last.Biggest = seg.Biggest
+			last.Ops = append(last.Ops, seg.Ops...)
+		}
+		return res
+	}
 	var res []SizeSegment
 	minSz, maxSz := o.MinMaxSize()
 	if minSz == 0 {
@@ -659,7 +1084,6 @@ func (o Operations) SplitSizes(minShare float64) []SizeSegment {
 	minLog := int(math.Log10(float64(minSz)))
 	maxLog := int(math.Log10(float64(maxSz)))
 	cLog := minLog
-	wantN := int(float64(len(o)) * minShare)
 	seg := SizeSegment{
 		Smallest:      log10ToLog2Size[cLog],
 		SmallestLog10: cLog,
@@ -837,6 +1261,40 @@ func (o Operations) Clients() int {
 	return len(clients)
 }
 
+// Tenants returns the recorded tenants as a sorted slice.
+// Operations without a tenant are not included.
+func (o Operations) Tenants() []string {
+	tenants := make(map[string]struct{})
+	for _, op := range o {
+		if op.Tenant != "" {
+			tenants[op.Tenant] = struct{}{}
+		}
+	}
+	dst := make([]string, 0, len(tenants))
+	for k := range tenants {
+		dst = append(dst, k)
+	}
+	sort.Strings(dst)
+	return dst
+}
+
+// StorageClasses returns the recorded storage classes as a sorted slice.
+// Operations without a storage class are not included.
+func (o Operations) StorageClasses() []string {
+	classes := make(map[string]struct{})
+	for _, op := range o {
+		if op.StorageClass != "" {
+			classes[op.StorageClass] = struct{}{}
+		}
+	}
+	dst := make([]string, 0, len(classes))
+	for k := range classes {
+		dst = append(dst, k)
+	}
+	sort.Strings(dst)
+	return dst
+}
+
 // Endpoints returns the endpoints as a sorted slice.
 func (o Operations) Endpoints() []string {
 	if len(o) == 0 {
@@ -855,13 +1313,14 @@ func (o Operations) Endpoints() []string {
 }
 
 // Errors returns the errors found.
+// Operations canceled when the benchmark was stopped are not included.
 func (o Operations) Errors() []string {
 	if len(o) == 0 {
 		return nil
 	}
 	errs := []string{}
 	for _, op := range o {
-		if len(op.Err) != 0 {
+		if len(op.Err) != 0 && !op.Canceled {
 			errs = append(errs, op.Err)
 		}
 	}
@@ -913,14 +1372,15 @@ func (o Operations) FilterFirst() Operations {
 	return ok
 }
 
-// Errors returns the errors found.
+// FilterErrors returns the failed operations.
+// Operations canceled when the benchmark was stopped are not included.
 func (o Operations) FilterErrors() Operations {
 	if len(o) == 0 {
 		return nil
 	}
 	errs := Operations{}
 	for _, op := range o {
-		if len(op.Err) != 0 {
+		if len(op.Err) != 0 && !op.Canceled {
 			errs = append(errs, op)
 		}
 	}
@@ -930,48 +1390,169 @@ func (o Operations) FilterErrors() Operations {
 // CSV will write the operations to w as CSV.
 // The comment, if any, is written at the end of the file, each line prefixed with '# '.
 func (o Operations) CSV(w io.Writer, comment string) error {
+	cw, err := NewCSVWriter(w, o.HasPhases(), o.HeaderNames())
+	if err != nil {
+		return err
+	}
+	for _, op := range o {
+		if err := cw.Write(op); err != nil {
+			return err
+		}
+	}
+	return cw.Close(comment)
+}
+
+// CSVWriter writes operations as CSV one at a time.
+type CSVWriter struct {
+	bw      *bufio.Writer
+	phases  bool
+	headers []string
+	idx     int
+}
+
+// NewCSVWriter writes the CSV header to w and returns a writer for operations.
+// Phase columns are written if phases is set and captured headers are written as a column each.
+// Close must be called when all operations have been written.
+func NewCSVWriter(w io.Writer, phases bool, headers []string) (*CSVWriter, error) {
 	bw := bufio.NewWriter(w)
-	_, err := bw.WriteString("idx\tthread\top\tclient_id\tn_objects\tbytes\tendpoint\tfile\terror\tstart\tfirst_byte\tend\tduration_ns\n")
+	header := "idx\tthread\top\tclient_id\tn_objects\tbytes\tendpoint\tfile\terror\tstart\tfirst_byte\tend\tduration_ns\trequest_id\tproto\tprepare\tcanceled\ttenant\tthrottled\ttimeout_ns\tstorage_class\texact_size"
+	if phases {
+		// Phase columns are only written when recorded.
+		header += "\tdns_ns\tconnect_ns\ttls_ns\tserver_ns"
+	}
+	for _, h := range headers {
+		header += "\t" + csvHeaderPrefix + h
+	}
+	_, err := bw.WriteString(header + "\n")
+	if err != nil {
+		return nil, err
+	}
+	return &CSVWriter{bw: bw, phases: phases, headers: headers}, nil
+}
+
+// Write a single operation.
+func (c *CSVWriter) Write(op Operation) error {
+	bw := c.bw
+	var ttfb string
+	if op.FirstByte != nil {
+		ttfb = op.FirstByte.Format(time.RFC3339Nano)
+	}
+	prepare := ""
+	if op.Prepare {
+		prepare = "1"
+	}
+	canceled := ""
+	if op.Canceled {
+		canceled = "1"
+	}
+	throttled := ""
+	if op.Throttled {
+		throttled = "1"
+	}
+	exactSize := ""
+	if op.ExactSize {
+		exactSize = "1"
+	}
+	timeout := ""
+	if op.Timeout > 0 {
+		timeout = strconv.FormatInt(int64(op.Timeout), 10)
+	}
+	_, err := fmt.Fprintf(bw, "%d\t%d\t%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s", c.idx, op.Thread, op.OpType, op.ClientID, op.ObjPerOp, op.Size, csvEscapeString(op.Endpoint), op.File, csvEscapeString(op.Err), op.Start.Format(time.RFC3339Nano), ttfb, op.End.Format(time.RFC3339Nano), op.End.Sub(op.Start)/time.Nanosecond, op.RequestID, op.Proto, prepare, canceled, op.Tenant, throttled, timeout, op.StorageClass, exactSize)
 	if err != nil {
 		return err
 	}
-	for i, op := range o {
-		var ttfb string
-		if op.FirstByte != nil {
-			ttfb = op.FirstByte.Format(time.RFC3339Nano)
+	c.idx++
+	if c.phases {
+		var p PhaseTimings
+		if op.Phases != nil {
+			p = *op.Phases
+		}
+		_, err = fmt.Fprintf(bw, "\t%d\t%d\t%d\t%d", p.DNS, p.Connect, p.TLS, p.Server)
+		if err != nil {
+			return err
 		}
-		_, err := fmt.Fprintf(bw, "%d\t%d\t%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n", i, op.Thread, op.OpType, op.ClientID, op.ObjPerOp, op.Size, csvEscapeString(op.Endpoint), op.File, csvEscapeString(op.Err), op.Start.Format(time.RFC3339Nano), ttfb, op.End.Format(time.RFC3339Nano), op.End.Sub(op.Start)/time.Nanosecond)
+	}
+	for _, h := range c.headers {
+		_, err = bw.WriteString("\t" + csvEscapeString(op.Headers[h]))
 		if err != nil {
 			return err
 		}
 	}
+	return bw.WriteByte('\n')
+}
+
+// Close writes the comment, if any, at the end of the file, each line prefixed with '# '
+// and flushes the output. The underlying writer is not closed.
+func (c *CSVWriter) Close(comment string) error {
 	if len(comment) > 0 {
 		lines := strings.Split(comment, "\n")
 		for _, txt := range lines {
-			_, err := bw.WriteString("# " + txt + "\n")
+			_, err := c.bw.WriteString("# " + txt + "\n")
 			if err != nil {
 				return err
 			}
 		}
 	}
+	return c.bw.Flush()
+}
 
-	return bw.Flush()
+// CSVColumns reads the header of CSV operations from r and returns
+// whether phase columns are present and the names of captured headers.
+func CSVColumns(r io.Reader) (phases bool, headers []string, err error) {
+	cr := csv.NewReader(r)
+	cr.Comma = '\t'
+	cr.Comment = '#'
+	header, err := cr.Read()
+	if err != nil {
+		return false, nil, err
+	}
+	for _, s := range header {
+		if s == "server_ns" {
+			phases = true
+		}
+		if strings.HasPrefix(s, csvHeaderPrefix) {
+			headers = append(headers, strings.TrimPrefix(s, csvHeaderPrefix))
+		}
+	}
+	return phases, headers, nil
 }
 
+// csvHeaderPrefix is the prefix of columns containing captured headers.
+const csvHeaderPrefix = "header:"
+
 // OperationsFromCSV will load operations from CSV.
 func OperationsFromCSV(r io.Reader, analyzeOnly bool, offset, limit int, log func(msg string, v ...interface{})) (Operations, error) {
 	var ops Operations
+	err := StreamOperationsFromCSV(r, analyzeOnly, offset, limit, log, func(op Operation) error {
+		ops = append(ops, op)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// StreamOperationsFromCSV will read operations from CSV and call fn with each operation.
+// Operations are not kept, so this can be used to process files that don't fit in memory.
+// If fn returns an error reading is stopped and the error is returned.
+func StreamOperationsFromCSV(r io.Reader, analyzeOnly bool, offset, limit int, log func(msg string, v ...interface{}), fn func(op Operation) error) error {
+	var n int
 	cr := csv.NewReader(r)
 	cr.Comma = '\t'
 	cr.ReuseRecord = true
 	cr.Comment = '#'
 	header, err := cr.Read()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	fieldIdx := make(map[string]int)
+	headerIdx := make(map[string]int)
 	for i, s := range header {
 		fieldIdx[s] = i
+		if strings.HasPrefix(s, csvHeaderPrefix) {
+			headerIdx[strings.TrimPrefix(s, csvHeaderPrefix)] = i
+		}
 	}
 	var clientMap = make(map[string]string, 16)
 	cb := byte('a')
@@ -1008,7 +1589,7 @@ func OperationsFromCSV(r io.Reader, analyzeOnly bool, offset, limit int, log fun
 			break
 		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if len(values) == 0 {
 			continue
@@ -1019,63 +1600,140 @@ func OperationsFromCSV(r io.Reader, analyzeOnly bool, offset, limit int, log fun
 		}
 		start, err := time.Parse(time.RFC3339Nano, values[fieldIdx["start"]])
 		if err != nil {
-			return nil, err
+			return err
 		}
 		var ttfb *time.Time
 		if fb := values[fieldIdx["first_byte"]]; fb != "" {
 			t, err := time.Parse(time.RFC3339Nano, fb)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			ttfb = &t
 		}
 		end, err := time.Parse(time.RFC3339Nano, values[fieldIdx["end"]])
 		if err != nil {
-			return nil, err
+			return err
 		}
 		size, err := strconv.ParseInt(values[fieldIdx["bytes"]], 10, 64)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		thread, err := strconv.ParseUint(values[fieldIdx["thread"]], 10, 16)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		objs, err := strconv.ParseInt(values[fieldIdx["n_objects"]], 10, 64)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		var endpoint, clientID string
+		var endpoint, clientID, requestID, proto, tenant, storageClass string
 		if idx, ok := fieldIdx["endpoint"]; ok {
 			endpoint = values[idx]
 		}
+		if idx, ok := fieldIdx["request_id"]; ok {
+			requestID = values[idx]
+		}
+		if idx, ok := fieldIdx["proto"]; ok {
+			proto = internProto(values[idx])
+		}
+		if idx, ok := fieldIdx["tenant"]; ok {
+			tenant = values[idx]
+		}
+		if idx, ok := fieldIdx["storage_class"]; ok {
+			storageClass = values[idx]
+		}
+		var prepare, canceled, throttled, exactSize bool
+		if idx, ok := fieldIdx["prepare"]; ok {
+			prepare = values[idx] == "1"
+		}
+		if idx, ok := fieldIdx["canceled"]; ok {
+			canceled = values[idx] == "1"
+		}
+		if idx, ok := fieldIdx["throttled"]; ok {
+			throttled = values[idx] == "1"
+		}
+		if idx, ok := fieldIdx["exact_size"]; ok {
+			exactSize = values[idx] == "1"
+		}
+		var timeout time.Duration
+		if idx, ok := fieldIdx["timeout_ns"]; ok && values[idx] != "" {
+			v, err := strconv.ParseInt(values[idx], 10, 64)
+			if err != nil {
+				return err
+			}
+			timeout = time.Duration(v)
+		}
+		var phases *PhaseTimings
+		if _, ok := fieldIdx["server_ns"]; ok {
+			var p PhaseTimings
+			for _, f := range []struct {
+				name string
+				dst  *time.Duration
+			}{{"dns_ns", &p.DNS}, {"connect_ns", &p.Connect}, {"tls_ns", &p.TLS}, {"server_ns", &p.Server}} {
+				idx, ok := fieldIdx[f.name]
+				if !ok {
+					continue
+				}
+				v, err := strconv.ParseInt(values[idx], 10, 64)
+				if err != nil {
+					return err
+				}
+				*f.dst = time.Duration(v)
+			}
+			phases = &p
+		}
+		var headers map[string]string
+		for name, idx := range headerIdx {
+			if values[idx] == "" {
+				continue
+			}
+			if headers == nil {
+				headers = make(map[string]string, len(headerIdx))
+			}
+			headers[name] = values[idx]
+		}
 		if idx, ok := fieldIdx["client_id"]; ok {
 			clientID = values[idx]
 		}
 		file := fileMap(values[fieldIdx["file"]])
 
-		ops = append(ops, Operation{
-			OpType:    values[fieldIdx["op"]],
-			ObjPerOp:  int(objs),
-			Start:     start,
-			FirstByte: ttfb,
-			End:       end,
-			Err:       values[fieldIdx["error"]],
-			Size:      size,
-			File:      file,
-			Thread:    uint16(thread),
-			Endpoint:  endpoint,
-			ClientID:  getClient(clientID),
+		err = fn(Operation{
+			OpType:       values[fieldIdx["op"]],
+			ObjPerOp:     int(objs),
+			Start:        start,
+			FirstByte:    ttfb,
+			End:          end,
+			Err:          values[fieldIdx["error"]],
+			Size:         size,
+			File:         file,
+			Thread:       uint16(thread),
+			Endpoint:     endpoint,
+			ClientID:     getClient(clientID),
+			RequestID:    requestID,
+			Proto:        proto,
+			Phases:       phases,
+			Prepare:      prepare,
+			Canceled:     canceled,
+			Headers:      headers,
+			Tenant:       tenant,
+			Throttled:    throttled,
+			Timeout:      timeout,
+			StorageClass: storageClass,
+			ExactSize:    exactSize,
 		})
-		if log != nil && len(ops)%1000000 == 0 {
-			log("\r%d 请求操作已加载 ...", len(ops))
+		if err != nil {
+			return err
+		}
+		n++
+		if log != nil && n%1000000 == 0 {
+			log("\r%d 请求操作已加载 ...", n)
 		}
-		if limit > 0 && len(ops) >= limit {
+		if limit > 0 && n >= limit {
 			break
 		}
 	}
 	if log != nil {
-		log("\r%d 请求操作已加载完成!\n", len(ops))
+		log("\r%d 请求操作已加载完成!\n", n)
 	}
-	return ops, nil
+	return nil
 }
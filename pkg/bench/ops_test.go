@@ -0,0 +1,127 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCSVWriter_roundTrip(t *testing.T) {
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		op   Operation
+	}{
+		{name: "plain", op: Operation{OpType: "GET", Thread: 1, Size: 100, File: "obj", ObjPerOp: 1, Endpoint: "http://host:9000"}},
+		{name: "throttled", op: Operation{OpType: "GET", Thread: 3, Size: 0, File: "obj3", ObjPerOp: 1, Err: "SlowDown", Throttled: true}},
+		{name: "timeout", op: Operation{OpType: "PUT", Thread: 4, Size: 0, File: "obj4", ObjPerOp: 1, Err: "context deadline exceeded", Timeout: 1500 * time.Millisecond}},
+		{name: "storage class", op: Operation{OpType: "PUT", Thread: 5, Size: 10, File: "obj5", ObjPerOp: 1, StorageClass: "REDUCED_REDUNDANCY"}},
+		{name: "exact size", op: Operation{OpType: "PUT", Thread: 6, Size: 4096, File: "obj6", ObjPerOp: 1, ExactSize: true}},
+		{name: "tenant", op: Operation{OpType: "PUT", Thread: 2, Size: 10, File: "obj2", ObjPerOp: 1, Tenant: "tenant-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.op
+			want.Start = start
+			want.End = start.Add(time.Second)
+			var buf bytes.Buffer
+			w, err := NewCSVWriter(&buf, false, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write(want); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(""); err != nil {
+				t.Fatal(err)
+			}
+			ops, err := OperationsFromCSV(&buf, false, 0, 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ops) != 1 {
+				t.Fatalf("got %d operations, want 1", len(ops))
+			}
+			got := ops[0]
+			if got.OpType != want.OpType || got.Thread != want.Thread || got.Size != want.Size || got.File != want.File || got.Endpoint != want.Endpoint {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+			if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) {
+				t.Errorf("got times %v-%v, want %v-%v", got.Start, got.End, want.Start, want.End)
+			}
+			if got.Throttled != want.Throttled || got.Err != want.Err {
+				t.Errorf("got throttled %v (%q), want %v (%q)", got.Throttled, got.Err, want.Throttled, want.Err)
+			}
+			if got.Timeout != want.Timeout {
+				t.Errorf("got timeout %v, want %v", got.Timeout, want.Timeout)
+			}
+			if got.StorageClass != want.StorageClass {
+				t.Errorf("got storage class %q, want %q", got.StorageClass, want.StorageClass)
+			}
+			if got.Tenant != want.Tenant {
+				t.Errorf("got tenant %q, want %q", got.Tenant, want.Tenant)
+			}
+			if got.ExactSize != want.ExactSize {
+				t.Errorf("got exact size %v, want %v", got.ExactSize, want.ExactSize)
+			}
+		})
+	}
+}
+
+func TestOperations_SplitSizes(t *testing.T) {
+	ops := func(exact bool, sizes ...int64) Operations {
+		var o Operations
+		for _, sz := range sizes {
+			o = append(o, Operation{OpType: "PUT", Size: sz, ExactSize: exact})
+		}
+		return o
+	}
+	type seg struct{ smallest, biggest int64 }
+	tests := []struct {
+		name     string
+		ops      Operations
+		minShare float64
+		want     []seg
+	}{
+		{name: "single", ops: ops(true, 10, 10), want: []seg{{10, 10}}},
+		{name: "exact", ops: ops(true, 10, 20, 20, 30), want: []seg{{10, 10}, {20, 20}, {30, 30}}},
+		{name: "exact min share", ops: ops(true, 10, 20, 20, 20, 20, 30, 30, 30, 30), minShare: 0.25, want: []seg{{10, 20}, {30, 30}}},
+		{name: "exact failed", ops: append(ops(true, 10, 20), Operation{OpType: "PUT", Size: 30, ExactSize: true, Err: "failed"}), want: []seg{{10, 10}, {20, 20}, {30, 30}}},
+		{name: "not exact", ops: ops(false, 10, 20, 30), want: []seg{{10, 100}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.ops.SplitSizes(tt.minShare)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d segments, want %d", len(got), len(tt.want))
+			}
+			n := 0
+			for i, s := range got {
+				if s.Smallest != tt.want[i].smallest || s.Biggest != tt.want[i].biggest {
+					t.Errorf("segment %d: got %d-%d, want %d-%d", i, s.Smallest, s.Biggest, tt.want[i].smallest, tt.want[i].biggest)
+				}
+				n += len(s.Ops)
+			}
+			if n != len(tt.ops) {
+				t.Errorf("got %d operations in segments, want %d", n, len(tt.ops))
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// spanBatchSize is the number of spans sent in each export request.
+	spanBatchSize = 512
+
+	// spanQueueSize is the number of batches that can be waiting to be sent.
+	// Batches are dropped when the queue is full.
+	spanQueueSize = 16
+)
+
+// SpanExporter exports operations as OpenTelemetry spans
+// to an OTLP/HTTP endpoint using the JSON encoding.
+// All spans are children of a root span covering the entire run.
+type SpanExporter struct {
+	// Error is called when exporting fails.
+	Error func(data ...interface{})
+
+	url        string
+	sampleRate float64
+	client     *http.Client
+	traceID    string
+	rootID     string
+	rootName   string
+	rootStart  time.Time
+
+	queue chan otlpRequest
+	done  chan struct{}
+	// failed is only accessed by the sender.
+	failed bool
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	batch   []otlpSpan
+	closed  bool
+	dropped int
+}
+
+// NewSpanExporter returns an exporter that will send spans to the OTLP/HTTP endpoint.
+// If no path is given in the endpoint "/v1/traces" is used.
+// Only sampleRate (0 -> 1) of the operations will be exported.
+func NewSpanExporter(endpoint, runName string, sampleRate float64) *SpanExporter {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	if idx := strings.Index(endpoint, "://"); !strings.Contains(endpoint[idx+3:], "/") {
+		endpoint += "/v1/traces"
+	}
+	var seed [8]byte
+	crand.Read(seed[:])
+	e := &SpanExporter{
+		url:        endpoint,
+		sampleRate: sampleRate,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		rootName:   runName,
+		rootStart:  time.Now(),
+		rng:        rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:])))),
+		queue:      make(chan otlpRequest, spanQueueSize),
+		done:       make(chan struct{}),
+	}
+	e.traceID = e.randomID(16)
+	e.rootID = e.randomID(8)
+	go e.sender()
+	return e
+}
+
+// Export will export the operation as a span, if it is sampled.
+func (e *SpanExporter) Export(op Operation) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	if e.sampleRate < 1 && e.rng.Float64() >= e.sampleRate {
+		return
+	}
+	attrs := []otlpAttribute{
+		stringAttr("warp.op", op.OpType),
+		intAttr("warp.size", op.Size),
+		stringAttr("warp.host", op.Endpoint),
+		intAttr("warp.duration_ns", int64(op.Duration())),
+		intAttr("warp.thread", int64(op.Thread)),
+		intAttr("warp.objects", int64(op.ObjPerOp)),
+	}
+	if op.File != "" {
+		attrs = append(attrs, stringAttr("warp.file", op.File))
+	}
+	if op.RequestID != "" {
+		attrs = append(attrs, stringAttr("warp.request_id", op.RequestID))
+	}
+	span := otlpSpan{
+		TraceID:      e.traceID,
+		SpanID:       e.randomID(8),
+		ParentSpanID: e.rootID,
+		Name:         op.OpType,
+		Kind:         otlpSpanKindClient,
+		Start:        strconv.FormatInt(op.Start.UnixNano(), 10),
+		End:          strconv.FormatInt(op.End.UnixNano(), 10),
+		Attributes:   attrs,
+	}
+	if op.Err != "" {
+		span.Status = &otlpStatus{Code: otlpStatusError, Message: op.Err}
+	}
+	e.batch = append(e.batch, span)
+	if len(e.batch) >= spanBatchSize {
+		e.sendLocked(false)
+	}
+}
+
+// Close will end the root span, send all remaining spans
+// and wait for queued batches to be sent.
+// Calling Close more than once has no effect.
+func (e *SpanExporter) Close() {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	e.closed = true
+	e.batch = append(e.batch, otlpSpan{
+		TraceID:    e.traceID,
+		SpanID:     e.rootID,
+		Name:       e.rootName,
+		Kind:       otlpSpanKindInternal,
+		Start:      strconv.FormatInt(e.rootStart.UnixNano(), 10),
+		End:        strconv.FormatInt(time.Now().UnixNano(), 10),
+		Attributes: []otlpAttribute{stringAttr("warp.sample_rate", strconv.FormatFloat(e.sampleRate, 'g', -1, 64))},
+	})
+	// The last batch contains the root span, so wait for room in the queue.
+	e.sendLocked(true)
+	dropped := e.dropped
+	e.mu.Unlock()
+	close(e.queue)
+	<-e.done
+	if dropped > 0 && e.Error != nil {
+		e.Error(fmt.Sprintf("导出 OpenTelemetry span 跟不上, 丢弃了 %d 批 span", dropped))
+	}
+}
+
+// sendLocked queues the current batch for sending.
+// If wait is false the batch is dropped when the queue is full.
+// e.mu must be held by the caller.
+func (e *SpanExporter) sendLocked(wait bool) {
+	if len(e.batch) == 0 {
+		return
+	}
+	req := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{stringAttr("service.name", "warp")}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/minio/warp"},
+			Spans: e.batch,
+		}},
+	}}}
+	e.batch = nil
+	if wait {
+		e.queue <- req
+		return
+	}
+	select {
+	case e.queue <- req:
+	default:
+		e.dropped++
+	}
+}
+
+// sender sends queued batches until the queue is closed.
+func (e *SpanExporter) sender() {
+	defer close(e.done)
+	for req := range e.queue {
+		err := e.send(req)
+		if err == nil || e.failed {
+			continue
+		}
+		// Only report the first error.
+		e.failed = true
+		if e.Error != nil {
+			e.Error("导出 OpenTelemetry span 出错:", err)
+		}
+	}
+}
+
+func (e *SpanExporter) send(req otlpRequest) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status from %s: %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+// randomID returns a random hex encoded id of n bytes.
+// e.mu must be held by the caller, unless called on creation.
+func (e *SpanExporter) randomID(n int) string {
+	b := make([]byte, n)
+	e.rng.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// OTLP JSON encoding of trace data.
+// See https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+const (
+	otlpSpanKindInternal = 1
+	otlpSpanKindClient   = 3
+	otlpStatusError      = 2
+)
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Status       *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func stringAttr(k, v string) otlpAttribute {
+	return otlpAttribute{Key: k, Value: otlpValue{StringValue: &v}}
+}
+
+func intAttr(k string, v int64) otlpAttribute {
+	s := strconv.FormatInt(v, 10)
+	return otlpAttribute{Key: k, Value: otlpValue{IntValue: &s}}
+}
@@ -0,0 +1,333 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Parquet writes the operations to w as an Apache Parquet file.
+// The file contains a single row group with one row per operation.
+// Columns are uncompressed and plain encoded.
+// Durations are in microseconds. If time to first byte wasn't recorded it is 0.
+func (o Operations) Parquet(w io.Writer) error {
+	cols := []parquetColumn{
+		{name: "op", typ: parquetByteArray, converted: parquetUTF8, str: func(op Operation) string { return op.OpType }},
+		{name: "thread", typ: parquetInt32, converted: parquetNoConversion, i64: func(op Operation) int64 { return int64(op.Thread) }},
+		{name: "client_id", typ: parquetByteArray, converted: parquetUTF8, str: func(op Operation) string { return op.ClientID }},
+		{name: "endpoint", typ: parquetByteArray, converted: parquetUTF8, str: func(op Operation) string { return op.Endpoint }},
+		{name: "file", typ: parquetByteArray, converted: parquetUTF8, str: func(op Operation) string { return op.File }},
+		{name: "objects", typ: parquetInt32, converted: parquetNoConversion, i64: func(op Operation) int64 { return int64(op.ObjPerOp) }},
+		{name: "bytes", typ: parquetInt64, converted: parquetNoConversion, i64: func(op Operation) int64 { return op.Size }},
+		{name: "start", typ: parquetInt64, converted: parquetTimestampMicros, i64: func(op Operation) int64 { return op.Start.UnixNano() / int64(time.Microsecond) }},
+		{name: "end", typ: parquetInt64, converted: parquetTimestampMicros, i64: func(op Operation) int64 { return op.End.UnixNano() / int64(time.Microsecond) }},
+		{name: "duration_us", typ: parquetInt64, converted: parquetNoConversion, i64: func(op Operation) int64 { return int64(op.Duration() / time.Microsecond) }},
+		{name: "ttfb_us", typ: parquetInt64, converted: parquetNoConversion, i64: func(op Operation) int64 {
+			if op.FirstByte == nil {
+				return 0
+			}
+			return int64(op.FirstByte.Sub(op.Start) / time.Microsecond)
+		}},
+		{name: "error", typ: parquetByteArray, converted: parquetUTF8, str: func(op Operation) string { return op.Err }},
+		{name: "request_id", typ: parquetByteArray, converted: parquetUTF8, str: func(op Operation) string { return op.RequestID }},
+	}
+
+	bw := bufio.NewWriter(w)
+	pw := &countWriter{w: bw}
+	if _, err := pw.Write([]byte(parquetMagic)); err != nil {
+		return err
+	}
+	var total int64
+	for i := range cols {
+		if err := cols[i].write(pw, o); err != nil {
+			return err
+		}
+		total += cols[i].size
+	}
+
+	// File metadata.
+	var t thriftWriter
+	t.field(1, thriftI32)
+	t.varint(1) // version
+	t.field(2, thriftList)
+	t.listHeader(len(cols)+1, thriftStruct)
+	t.begin()
+	t.field(4, thriftBinary)
+	t.binary("schema")
+	t.field(5, thriftI32)
+	t.varint(int64(len(cols)))
+	t.end()
+	for _, c := range cols {
+		t.begin()
+		t.field(1, thriftI32)
+		t.varint(int64(c.typ))
+		t.field(3, thriftI32)
+		t.varint(parquetRequired)
+		t.field(4, thriftBinary)
+		t.binary(c.name)
+		if c.converted != parquetNoConversion {
+			t.field(6, thriftI32)
+			t.varint(int64(c.converted))
+		}
+		t.end()
+	}
+	t.field(3, thriftI64)
+	t.varint(int64(len(o)))
+	t.field(4, thriftList)
+	t.listHeader(1, thriftStruct)
+	t.begin()
+	t.field(1, thriftList)
+	t.listHeader(len(cols), thriftStruct)
+	for _, c := range cols {
+		t.begin()
+		t.field(2, thriftI64)
+		t.varint(c.offset)
+		t.field(3, thriftStruct)
+		t.begin()
+		t.field(1, thriftI32)
+		t.varint(int64(c.typ))
+		t.field(2, thriftList)
+		t.listHeader(1, thriftI32)
+		t.varint(parquetPlain)
+		t.field(3, thriftList)
+		t.listHeader(1, thriftBinary)
+		t.binary(c.name)
+		t.field(4, thriftI32)
+		t.varint(parquetUncompressed)
+		t.field(5, thriftI64)
+		t.varint(int64(len(o)))
+		t.field(6, thriftI64)
+		t.varint(c.size)
+		t.field(7, thriftI64)
+		t.varint(c.size)
+		t.field(9, thriftI64)
+		t.varint(c.offset)
+		t.end()
+		t.end()
+	}
+	t.field(2, thriftI64)
+	t.varint(total)
+	t.field(3, thriftI64)
+	t.varint(int64(len(o)))
+	t.end()
+	t.field(6, thriftBinary)
+	t.binary("warp")
+	t.stop()
+
+	if _, err := pw.Write(t.buf.Bytes()); err != nil {
+		return err
+	}
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(t.buf.Len()))
+	if _, err := pw.Write(length[:]); err != nil {
+		return err
+	}
+	if _, err := pw.Write([]byte(parquetMagic)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+const parquetMagic = "PAR1"
+
+// parquetPageRows is the maximum number of rows in each data page.
+const parquetPageRows = 64 << 10
+
+// Parquet physical types.
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetByteArray = 6
+)
+
+// Parquet converted types.
+const (
+	parquetNoConversion    = -1
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+)
+
+// Other Parquet enum values used.
+const (
+	parquetRequired     = 0
+	parquetPlain        = 0
+	parquetRLE          = 3
+	parquetUncompressed = 0
+	parquetDataPage     = 0
+)
+
+// parquetColumn is a column written by Operations.Parquet.
+// Either str or i64 must be set.
+type parquetColumn struct {
+	name      string
+	typ       int
+	converted int
+	str       func(op Operation) string
+	i64       func(op Operation) int64
+
+	// Set when written.
+	offset int64
+	size   int64
+}
+
+// write the column chunk to w.
+func (c *parquetColumn) write(w *countWriter, ops Operations) error {
+	c.offset = w.n
+	var page bytes.Buffer
+	for start := 0; start < len(ops) || start == 0; start += parquetPageRows {
+		end := start + parquetPageRows
+		if end > len(ops) {
+			end = len(ops)
+		}
+		page.Reset()
+		var tmp [8]byte
+		for _, op := range ops[start:end] {
+			switch c.typ {
+			case parquetByteArray:
+				s := c.str(op)
+				binary.LittleEndian.PutUint32(tmp[:4], uint32(len(s)))
+				page.Write(tmp[:4])
+				page.WriteString(s)
+			case parquetInt32:
+				binary.LittleEndian.PutUint32(tmp[:4], uint32(c.i64(op)))
+				page.Write(tmp[:4])
+			case parquetInt64:
+				binary.LittleEndian.PutUint64(tmp[:], uint64(c.i64(op)))
+				page.Write(tmp[:])
+			}
+		}
+		var t thriftWriter
+		t.field(1, thriftI32)
+		t.varint(parquetDataPage)
+		t.field(2, thriftI32)
+		t.varint(int64(page.Len()))
+		t.field(3, thriftI32)
+		t.varint(int64(page.Len()))
+		t.field(5, thriftStruct)
+		t.begin()
+		t.field(1, thriftI32)
+		t.varint(int64(end - start))
+		t.field(2, thriftI32)
+		t.varint(parquetPlain)
+		t.field(3, thriftI32)
+		t.varint(parquetRLE)
+		t.field(4, thriftI32)
+		t.varint(parquetRLE)
+		t.end()
+		t.stop()
+		if _, err := w.Write(t.buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := w.Write(page.Bytes()); err != nil {
+			return err
+		}
+		if end == len(ops) {
+			break
+		}
+	}
+	c.size = w.n - c.offset
+	return nil
+}
+
+// countWriter counts the bytes written.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes structs using the Thrift compact protocol,
+// which is used for Parquet metadata.
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last field id of the current struct and the enclosing structs.
+	last  int
+	stack []int
+}
+
+// field writes a field header.
+func (t *thriftWriter) field(id, typ int) {
+	if delta := id - t.last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta<<4 | typ))
+	} else {
+		t.buf.WriteByte(byte(typ))
+		t.varint(int64(id))
+	}
+	t.last = id
+}
+
+// varint writes a zigzag encoded integer.
+func (t *thriftWriter) varint(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], uint64((v<<1)^(v>>63)))
+	t.buf.Write(tmp[:n])
+}
+
+func (t *thriftWriter) binary(s string) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], uint64(len(s)))
+	t.buf.Write(tmp[:n])
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) listHeader(size, elemType int) {
+	if size < 15 {
+		t.buf.WriteByte(byte(size<<4 | elemType))
+		return
+	}
+	t.buf.WriteByte(byte(0xf0 | elemType))
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], uint64(size))
+	t.buf.Write(tmp[:n])
+}
+
+// begin a nested struct.
+func (t *thriftWriter) begin() {
+	t.stack = append(t.stack, t.last)
+	t.last = 0
+}
+
+// end a nested struct.
+func (t *thriftWriter) end() {
+	t.stop()
+	t.last = t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+}
+
+// stop ends the top level struct.
+func (t *thriftWriter) stop() {
+	t.buf.WriteByte(0)
+}
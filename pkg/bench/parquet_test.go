@@ -0,0 +1,76 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOperations_Parquet(t *testing.T) {
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		ops  Operations
+	}{
+		{name: "empty"},
+		{name: "one", ops: Operations{{OpType: "GET", Thread: 1, Size: 100, File: "obj", ObjPerOp: 1, Start: start, End: start.Add(time.Second)}}},
+		{name: "many", ops: Operations{
+			{OpType: "PUT", Thread: 1, Size: 10, File: "a", ObjPerOp: 1, Start: start, End: start.Add(time.Second), ClientID: "abcd"},
+			{OpType: "GET", Thread: 2, Size: 0, File: "b", ObjPerOp: 1, Start: start, End: start.Add(time.Second), Err: "failed", RequestID: "req"},
+			{OpType: "DELETE", Thread: 3, File: "c", ObjPerOp: 100, Start: start, End: start.Add(time.Millisecond), Endpoint: "http://host:9000"},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := test.ops.Parquet(&buf); err != nil {
+				t.Fatal(err)
+			}
+			b := buf.Bytes()
+			if len(b) < 12 {
+				t.Fatalf("file too short: %d bytes", len(b))
+			}
+			if string(b[:4]) != parquetMagic || string(b[len(b)-4:]) != parquetMagic {
+				t.Fatalf("missing magic: %q...%q", b[:4], b[len(b)-4:])
+			}
+			n := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+			if n <= 0 || n > len(b)-12 {
+				t.Fatalf("invalid footer length %d of %d bytes", n, len(b))
+			}
+			footer := string(b[len(b)-8-n : len(b)-8])
+			// The footer must start with the version field and end with created_by and the stop field.
+			if footer[0] != 0x15 || !strings.HasSuffix(footer, "\x04warp\x00") {
+				t.Errorf("unexpected footer %q", footer)
+			}
+			for _, col := range []string{"schema", "op", "thread", "client_id", "endpoint", "file", "objects", "bytes", "start", "end", "duration_us", "ttfb_us", "error", "request_id"} {
+				if !strings.Contains(footer, col) {
+					t.Errorf("column %q not in footer", col)
+				}
+			}
+			for _, op := range test.ops {
+				if !bytes.Contains(b[4:len(b)-8-n], []byte(op.OpType)) {
+					t.Errorf("op type %q not in column data", op.OpType)
+				}
+			}
+		})
+	}
+}
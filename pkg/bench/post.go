@@ -0,0 +1,232 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+)
+
+// Post benchmarks browser-style uploads with POST policies.
+type Post struct {
+	Common
+	// PolicyExpiry is how long each POST policy is valid.
+	// Policies are renewed when half of this has passed.
+	PolicyExpiry time.Duration
+
+	// Do sends a raw request.
+	// POST uploads are authenticated by the signed policy in the form, so requests should not be signed.
+	Do       func(req *http.Request) (*http.Response, error)
+	prefixes map[string]struct{}
+}
+
+// opTypePost is the operation type of POST uploads.
+const opTypePost = http.MethodPost
+
+// postPolicy is a signed POST policy.
+type postPolicy struct {
+	url      string
+	formData map[string]string
+	renew    time.Time
+}
+
+// Prepare will create an empty bucket or delete any content already there.
+func (u *Post) Prepare(ctx context.Context) error {
+	console.Infof("\rPOST 策略的有效期为 %v, 过半后将重新生成\n", u.PolicyExpiry)
+	return u.createEmptyBucket(ctx)
+}
+
+// policy returns a POST policy for uploading key with the client.
+// Policies allowing all keys with the prefix are cached per endpoint
+// and reused until half the expiry has passed.
+// Without a prefix a policy is created for the key.
+func (u *Post) policy(ctx context.Context, client *minio.Client, cache map[string]*postPolicy, prefix, key string) (*postPolicy, error) {
+	ep := client.EndpointURL().String()
+	if p := cache[ep]; p != nil && prefix != "" && time.Now().Before(p.renew) {
+		return p, nil
+	}
+	now := time.Now()
+	pp := minio.NewPostPolicy()
+	if err := pp.SetBucket(u.Bucket); err != nil {
+		return nil, err
+	}
+	if err := pp.SetExpires(now.Add(u.PolicyExpiry)); err != nil {
+		return nil, err
+	}
+	var err error
+	if prefix != "" {
+		err = pp.SetKeyStartsWith(prefix)
+	} else {
+		err = pp.SetKey(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	url, formData, err := client.PresignedPostPolicy(ctx, pp)
+	if err != nil {
+		return nil, err
+	}
+	p := &postPolicy{url: url.String(), formData: formData, renew: now.Add(u.PolicyExpiry / 2)}
+	if prefix != "" {
+		cache[ep] = p
+	}
+	return p, nil
+}
+
+// postRequest returns a multipart/form-data POST request uploading the object with the policy.
+// The object is streamed after the form fields, so it is not kept in memory.
+func (u *Post) postRequest(ctx context.Context, p *postPolicy, key string, body io.Reader, size int64) (*http.Request, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range p.formData {
+		if k == "key" {
+			continue
+		}
+		if err := w.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.WriteField("key", key); err != nil {
+		return nil, err
+	}
+	// The file must be the last field.
+	if _, err := w.CreateFormFile("file", key); err != nil {
+		return nil, err
+	}
+	head := append([]byte(nil), buf.Bytes()...)
+	buf.Reset()
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	tail := buf.Bytes()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, io.MultiReader(bytes.NewReader(head), body, bytes.NewReader(tail)))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(head)) + size + int64(len(tail))
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req, nil
+}
+
+// post sends the upload request.
+func (u *Post) post(req *http.Request) error {
+	resp, err := u.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errResp minio.ErrorResponse
+		if xml.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Code != "" {
+			return fmt.Errorf("%s: %s", errResp.Code, errResp.Message)
+		}
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
+
+// Start will execute the main benchmark.
+// Operations should begin executing when the start channel is closed.
+func (u *Post) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(u.Concurrency)
+	c := u.newCollector()
+	if u.AutoTermDur > 0 {
+		ctx = c.AutoTerm(ctx, opTypePost, u.AutoTermScale, autoTermCheck, autoTermSamples, u.AutoTermDur)
+	}
+	u.prefixes = make(map[string]struct{}, u.Concurrency)
+
+	// Non-terminating context.
+	nonTerm := context.Background()
+
+	for i := 0; i < u.Concurrency; i++ {
+		src := u.Source()
+		u.prefixes[src.Prefix()] = struct{}{}
+		go func(i int) {
+			rcv := c.Receiver()
+			reqCtx, rec := u.newRecorder(nonTerm, cancelOnStop(ctx))
+			defer wg.Done()
+			done := ctx.Done()
+			policies := make(map[string]*postPolicy)
+
+			<-wait
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				obj := src.Object()
+				client, cldone := u.Client()
+				op := Operation{
+					OpType:   opTypePost,
+					Thread:   uint16(i),
+					Size:     obj.Size,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				// Creating the policy is not included in the operation time.
+				p, err := u.policy(reqCtx, client, policies, src.Prefix(), obj.Name)
+				opCtx, opDone := u.opContext(reqCtx, opTypePost)
+				op.Start = time.Now()
+				if err != nil {
+					err = fmt.Errorf("creating POST policy: %w", err)
+				} else {
+					var req *http.Request
+					req, err = u.postRequest(opCtx, p, obj.Name, obj.Reader, obj.Size)
+					if err == nil {
+						err = u.post(req)
+					}
+				}
+				op.End = time.Now()
+				if err != nil {
+					u.Error("POST 上传出错: ", err)
+					op.Err = err.Error()
+				}
+				cldone()
+				opDone(&op)
+				rec.fill(&op)
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	return c.Close(), nil
+}
+
+// Cleanup deletes everything uploaded to the bucket.
+func (u *Post) Cleanup(ctx context.Context) {
+	var pf []string
+	for p := range u.prefixes {
+		pf = append(pf, p)
+	}
+	u.deleteAllInBucket(ctx, u.Bucket, pf...)
+}
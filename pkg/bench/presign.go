@@ -0,0 +1,387 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package bench
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/generator"
+)
+
+// Presign benchmarks GET and PUT requests sent to presigned URLs.
+// The URLs are generated before the benchmark starts,
+// so only raw HTTP requests are timed.
+type Presign struct {
+	Common
+	CreateObjects int
+	Collector     *Collector
+	objects       generator.Objects
+
+	// Expiry is how long the presigned URLs are valid.
+	// All URLs are signed again when half of this has passed.
+	Expiry time.Duration
+
+	// PutPct is the percentage of requests that are PUTs.
+	PutPct int
+
+	// Do sends a raw request.
+	// Presigned URLs carry the signature in the query string, so requests should not be signed.
+	Do func(req *http.Request) (*http.Response, error)
+
+	putNames []string
+	prefixes map[string]struct{}
+	pool     atomic.Value // *presignPool
+}
+
+// presignedURL is a presigned URL for a single object.
+type presignedURL struct {
+	url      string
+	endpoint string
+	name     string
+	size     int64
+}
+
+// presignPool is a pool of presigned URLs signed at the same time.
+type presignPool struct {
+	get   []presignedURL
+	put   []presignedURL
+	renew time.Time
+}
+
+// Prepare will create an empty bucket or delete any content already there,
+// upload a number of objects and generate presigned URLs for them.
+func (g *Presign) Prepare(ctx context.Context) error {
+	if err := g.createEmptyBucket(ctx); err != nil {
+		return err
+	}
+	src := g.Source()
+	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
+	var wg sync.WaitGroup
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
+	obj := make(chan struct{}, g.CreateObjects)
+	for i := 0; i < g.CreateObjects; i++ {
+		obj <- struct{}{}
+	}
+	close(obj)
+	var groupErr error
+	var mu sync.Mutex
+
+	for i := 0; i < g.prepareConcurrency(); i++ {
+		go func(i int) {
+			defer wg.Done()
+			src := g.Source()
+			for range obj {
+				opts := g.PutOpts
+				rcv := g.Collector.Receiver()
+				done := ctx.Done()
+
+				select {
+				case <-done:
+					return
+				default:
+				}
+				obj := src.Object()
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   http.MethodPut,
+					Thread:   uint16(i),
+					Size:     obj.Size,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				opts.ContentType = obj.ContentType
+				var res minio.UploadInfo
+				err := g.prepareUpload(ctx, obj.Reader, func() (err error) {
+					op.Start = time.Now()
+					res, err = g.putObject(ctx, client, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+					return err
+				})
+				op.End = time.Now()
+				cldone()
+				if err != nil {
+					err := fmt.Errorf("upload error: %w", err)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				if res.Size != obj.Size {
+					err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				mu.Lock()
+				obj.Reader = nil
+				g.objects = append(g.objects, *obj)
+				g.prepareProgress(float64(len(g.objects)) / float64(g.CreateObjects))
+				mu.Unlock()
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	if groupErr != nil {
+		return groupErr
+	}
+
+	// PUT requests upload to their own names, so GETs keep reading the uploaded objects.
+	g.prefixes = make(map[string]struct{})
+	if g.PutPct > 0 {
+		src := g.Source()
+		g.prefixes[src.Prefix()] = struct{}{}
+		g.putNames = make([]string, 0, g.CreateObjects)
+		for i := 0; i < g.CreateObjects; i++ {
+			g.putNames = append(g.putNames, src.Object().Name)
+		}
+	}
+
+	console.Infof("\r正在生成 %d 个预签名 URL, 有效期为 %v\n", len(g.objects)+len(g.putNames), g.Expiry)
+	if err := g.sign(ctx); err != nil {
+		return err
+	}
+	return g.waitForReadBucket(ctx, g.objects)
+}
+
+// sign generates presigned URLs for all objects and replaces the current pool.
+func (g *Presign) sign(ctx context.Context) error {
+	now := time.Now()
+	p := presignPool{
+		get:   make([]presignedURL, 0, len(g.objects)),
+		put:   make([]presignedURL, 0, len(g.putNames)),
+		renew: now.Add(g.Expiry / 2),
+	}
+	for _, obj := range g.objects {
+		client, cldone := g.Client()
+		u, err := client.PresignedGetObject(ctx, g.Bucket, obj.Name, g.Expiry, nil)
+		cldone()
+		if err != nil {
+			return fmt.Errorf("presigning GET: %w", err)
+		}
+		p.get = append(p.get, presignedURL{url: u.String(), endpoint: client.EndpointURL().String(), name: obj.Name, size: obj.Size})
+	}
+	for _, name := range g.putNames {
+		client, cldone := g.Client()
+		u, err := client.PresignedPutObject(ctx, g.Bucket, name, g.Expiry)
+		cldone()
+		if err != nil {
+			return fmt.Errorf("presigning PUT: %w", err)
+		}
+		p.put = append(p.put, presignedURL{url: u.String(), endpoint: client.EndpointURL().String(), name: name})
+	}
+	g.pool.Store(&p)
+	return nil
+}
+
+// renew signs all URLs again when half the expiry has passed until ctx is canceled.
+func (g *Presign) renew(ctx context.Context) {
+	next := g.pool.Load().(*presignPool).renew
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		if err := g.sign(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			g.Error("重新生成预签名 URL 出错: ", err)
+			// Retry shortly, the current URLs are still valid.
+			next = time.Now().Add(time.Second)
+			continue
+		}
+		next = g.pool.Load().(*presignPool).renew
+	}
+}
+
+// do sends the request and returns the response if the status is the expected one.
+func (g *Presign) do(req *http.Request, want int) (*http.Response, error) {
+	resp, err := g.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != want {
+		defer resp.Body.Close()
+		var errResp minio.ErrorResponse
+		if xml.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Code != "" {
+			return nil, fmt.Errorf("%s: %s", errResp.Code, errResp.Message)
+		}
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// Start will execute the main benchmark.
+// Operations should begin executing when the start channel is closed.
+func (g *Presign) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(g.Concurrency)
+	c := g.Collector
+	if g.AutoTermDur > 0 {
+		opType := http.MethodGet
+		if g.PutPct >= 100 {
+			opType = http.MethodPut
+		}
+		ctx = c.AutoTerm(ctx, opType, g.AutoTermScale, autoTermCheck, autoTermSamples, g.AutoTermDur)
+	}
+
+	// The pool may have been generated a while ago, for instance when waiting for a server to start.
+	if time.Now().After(g.pool.Load().(*presignPool).renew) {
+		if err := g.sign(ctx); err != nil {
+			return nil, err
+		}
+	}
+	renewCtx, stopRenew := context.WithCancel(context.Background())
+	defer stopRenew()
+	go g.renew(renewCtx)
+
+	// Non-terminating context.
+	nonTerm := context.Background()
+
+	for i := 0; i < g.Concurrency; i++ {
+		src := g.Source()
+		go func(i int) {
+			rng := rand.New(rand.NewSource(int64(i)))
+			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
+			defer wg.Done()
+			done := ctx.Done()
+
+			<-wait
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				p := g.pool.Load().(*presignPool)
+				var op Operation
+				if len(p.put) > 0 && rng.Intn(100) < g.PutPct {
+					op = g.put(reqCtx, p.put[rng.Intn(len(p.put))], src.Object())
+				} else {
+					op = g.get(reqCtx, p.get[rng.Intn(len(p.get))])
+				}
+				op.Thread = uint16(i)
+				rec.fill(&op)
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	return c.Close(), nil
+}
+
+// get downloads the object from a presigned URL.
+// The result is named, so the deferred opDone can record the timeout.
+func (g *Presign) get(ctx context.Context, u presignedURL) (op Operation) {
+	op = Operation{
+		OpType:   http.MethodGet,
+		Size:     u.size,
+		File:     u.name,
+		ObjPerOp: 1,
+		Endpoint: u.endpoint,
+	}
+	opCtx, opDone := g.opContext(ctx, http.MethodGet)
+	defer opDone(&op)
+	op.Start = time.Now()
+	req, err := http.NewRequestWithContext(opCtx, http.MethodGet, u.url, nil)
+	var resp *http.Response
+	if err == nil {
+		resp, err = g.do(req, http.StatusOK)
+	}
+	if err != nil {
+		op.End = time.Now()
+		g.Error("下载出错:", err)
+		op.Err = err.Error()
+		return op
+	}
+	defer resp.Body.Close()
+	fbr := firstByteRecorder{r: resp.Body}
+	n, err := io.Copy(ioutil.Discard, &fbr)
+	op.FirstByte = fbr.t
+	op.End = time.Now()
+	if err != nil {
+		g.Error("下载出错:", err)
+		op.Err = err.Error()
+	}
+	if n != op.Size && op.Err == "" {
+		op.Err = fmt.Sprint("不符合期望的下载大小. 需要的是:", op.Size, ", 实际上是:", n)
+		g.Error(op.Err)
+	}
+	return op
+}
+
+// put uploads obj to a presigned URL.
+func (g *Presign) put(ctx context.Context, u presignedURL, obj *generator.Object) (op Operation) {
+	op = Operation{
+		OpType:   http.MethodPut,
+		Size:     obj.Size,
+		File:     u.name,
+		ObjPerOp: 1,
+		Endpoint: u.endpoint,
+	}
+	opCtx, opDone := g.opContext(ctx, http.MethodPut)
+	defer opDone(&op)
+	op.Start = time.Now()
+	req, err := http.NewRequestWithContext(opCtx, http.MethodPut, u.url, obj.Reader)
+	var resp *http.Response
+	if err == nil {
+		req.ContentLength = obj.Size
+		req.Header.Set("Content-Type", obj.ContentType)
+		resp, err = g.do(req, http.StatusOK)
+	}
+	if err == nil {
+		_, err = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	op.End = time.Now()
+	if err != nil {
+		g.Error("上传出错: ", err)
+		op.Err = err.Error()
+	}
+	return op
+}
+
+// Cleanup deletes everything uploaded to the bucket.
+func (g *Presign) Cleanup(ctx context.Context) {
+	pf := g.objects.Prefixes()
+	for p := range g.prefixes {
+		pf = append(pf, p)
+	}
+	g.deleteAllInBucket(ctx, g.Bucket, pf...)
+}
@@ -19,20 +19,116 @@ package bench
 
 import (
 	"context"
+	"encoding/xml"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/generator"
 )
 
 // Put benchmarks upload speed.
 type Put struct {
 	Common
-	prefixes map[string]struct{}
+	// Checksum is the checksum algorithm to send with uploads.
+	// Empty means no checksum is sent.
+	Checksum string
+	// Do signs and sends a raw request.
+	// Required when Checksum is set, since the client cannot send checksums.
+	Do func(req *http.Request) (*http.Response, error)
+	// MultipartThreshold will upload objects of this size or bigger using multipart uploads
+	// and smaller objects using a single PUT. 0 leaves the choice to the client.
+	MultipartThreshold int64
+	// IfNoneMatch will send 'If-None-Match: *' with uploads.
+	// Every other upload by a thread reuses the name of its previous upload,
+	// so these are expected to be rejected by the server.
+	IfNoneMatch bool
+	// Expires will set the Expires header of uploaded objects if not zero.
+	Expires time.Time
+	// StorageClasses will set the storage class of each upload,
+	// picked randomly according to the weights.
+	// The class is recorded with each operation.
+	StorageClasses []WeightedStorageClass
+	prefixes       map[string]struct{}
+}
+
+// WeightedStorageClass is a storage class and its relative weight.
+type WeightedStorageClass struct {
+	Class  string
+	Weight int
+}
+
+// pickStorageClass returns a random storage class according to the weights.
+func (u *Put) pickStorageClass(rng *rand.Rand) string {
+	total := 0
+	for _, c := range u.StorageClasses {
+		total += c.Weight
+	}
+	n := rng.Intn(total)
+	for _, c := range u.StorageClasses {
+		if n < c.Weight {
+			return c.Class
+		}
+		n -= c.Weight
+	}
+	return u.StorageClasses[len(u.StorageClasses)-1].Class
+}
+
+// ExpiresAt returns the Expires header set on uploaded objects.
+// The zero time is returned if none is set.
+func (u *Put) ExpiresAt() time.Time {
+	return u.Expires
+}
+
+// opTypePutMultipart is the operation type of uploads above the multipart threshold.
+const opTypePutMultipart = "PUT-MULTIPART"
+
+// opTypePutExists is the operation type of conditional uploads rejected
+// with 412 Precondition Failed because the object already exists.
+const opTypePutExists = "PUT-412"
+
+// uploadOpts returns the upload options and operation type for an object of the given size.
+func (u *Put) uploadOpts(opts minio.PutObjectOptions, size int64) (minio.PutObjectOptions, string) {
+	if u.MultipartThreshold <= 0 {
+		return opts, http.MethodPut
+	}
+	if size < u.MultipartThreshold {
+		opts.DisableMultipart = true
+		return opts, http.MethodPut
+	}
+	opts.DisableMultipart = false
+	// The client only uses multipart uploads for objects bigger than the part size.
+	if opts.PartSize == 0 || opts.PartSize > uint64(size) {
+		opts.PartSize = uint64(u.MultipartThreshold)
+	}
+	return opts, opTypePutMultipart
 }
 
 // Prepare will create an empty bucket ot delete any content already there.
 func (u *Put) Prepare(ctx context.Context) error {
+	if !u.Expires.IsZero() {
+		console.Infof("\r上传的对象将设置 Expires 头: %s\n", u.Expires.UTC().Format(http.TimeFormat))
+	}
+	if u.Checksum != "" {
+		console.Infof("\r上传的对象将使用校验和算法: %s\n", u.Checksum)
+	}
+	if u.IfNoneMatch {
+		console.Infof("\r上传将发送 If-None-Match 头, 预期一半的上传返回 412\n")
+	}
+	if len(u.StorageClasses) > 0 {
+		classes := make([]string, 0, len(u.StorageClasses))
+		for _, c := range u.StorageClasses {
+			classes = append(classes, fmt.Sprintf("%s:%d", c.Class, c.Weight))
+		}
+		console.Infof("\r上传的对象将按权重使用存储类: %s\n", strings.Join(classes, ", "))
+	}
 	return u.createEmptyBucket(ctx)
 }
 
@@ -41,7 +137,7 @@ func (u *Put) Prepare(ctx context.Context) error {
 func (u *Put) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
 	var wg sync.WaitGroup
 	wg.Add(u.Concurrency)
-	c := NewCollector()
+	c := u.newCollector()
 	if u.AutoTermDur > 0 {
 		ctx = c.AutoTerm(ctx, http.MethodPut, u.AutoTermScale, autoTermCheck, autoTermSamples, u.AutoTermDur)
 	}
@@ -55,9 +151,15 @@ func (u *Put) Start(ctx context.Context, wait chan struct{}) (Operations, error)
 		u.prefixes[src.Prefix()] = struct{}{}
 		go func(i int) {
 			rcv := c.Receiver()
+			reqCtx, rec := u.newRecorder(nonTerm, cancelOnStop(ctx))
+			rec.ifNoneMatch = u.IfNoneMatch
+			if !u.Expires.IsZero() {
+				rec.expires = u.Expires.UTC().Format(http.TimeFormat)
+			}
 			defer wg.Done()
-			opts := u.PutOpts
 			done := ctx.Done()
+			var existing string
+			rng := rand.New(rand.NewSource(int64(i)))
 
 			<-wait
 			for {
@@ -67,20 +169,53 @@ func (u *Put) Start(ctx context.Context, wait chan struct{}) (Operations, error)
 				default:
 				}
 				obj := src.Object()
+				reused := existing != ""
+				if reused {
+					// Upload to an existing object, which should be rejected.
+					obj.Name = existing
+					existing = ""
+				}
+				opts, opType := u.uploadOpts(u.PutOpts, obj.Size)
 				opts.ContentType = obj.ContentType
+				var class string
+				if len(u.StorageClasses) > 0 {
+					class = u.pickStorageClass(rng)
+					opts.StorageClass = class
+				}
 				client, cldone := u.Client()
 				op := Operation{
-					OpType:   http.MethodPut,
-					Thread:   uint16(i),
-					Size:     obj.Size,
-					File:     obj.Name,
-					ObjPerOp: 1,
-					Endpoint: client.EndpointURL().String(),
+					OpType:       opType,
+					Thread:       uint16(i),
+					Size:         obj.Size,
+					File:         obj.Name,
+					ObjPerOp:     1,
+					Endpoint:     client.EndpointURL().String(),
+					StorageClass: class,
 				}
+				// Waiting for buffer memory is not included in the operation time.
+				release := u.reserveUpload(reqCtx, obj.Reader, obj.Size, opts)
 				op.Start = time.Now()
-				res, err := client.PutObject(nonTerm, u.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+				opCtx, opDone := u.opContext(reqCtx, http.MethodPut)
+				var res minio.UploadInfo
+				var err error
+				if u.Checksum != "" && singlePartUpload(obj.Size, opts) {
+					// Checksum calculation is included in the operation time.
+					// Parts of multipart uploads would need checksums of their own, so only single part uploads have one.
+					res, err = u.putChecksum(opCtx, client, obj, opts)
+				} else {
+					res, err = client.PutObject(opCtx, u.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+				}
 				op.End = time.Now()
-				if err != nil {
+				release()
+				switch {
+				case err == nil:
+					if u.IfNoneMatch && !reused {
+						existing = obj.Name
+					}
+				case u.IfNoneMatch && minio.ToErrorResponse(err).StatusCode == http.StatusPreconditionFailed:
+					op.OpType = opTypePutExists
+					res.Size = obj.Size
+				default:
 					u.Error("上传出错: ", err)
 					op.Err = err.Error()
 				}
@@ -93,8 +228,13 @@ func (u *Put) Start(ctx context.Context, wait chan struct{}) (Operations, error)
 					}
 					u.Error(err)
 				}
-				op.Size = res.Size
+				if err == nil || !u.ExactSizes {
+					// Failed uploads of exact sizes keep the requested size, so they are analyzed with it.
+					op.Size = res.Size
+				}
 				cldone()
+				opDone(&op)
+				rec.fill(&op)
 				rcv <- op
 			}
 		}(i)
@@ -103,11 +243,48 @@ func (u *Put) Start(ctx context.Context, wait chan struct{}) (Operations, error)
 	return c.Close(), nil
 }
 
+// putChecksum uploads obj with a single PUT that has the checksum header set.
+// The request is signed including the checksum header.
+func (u *Put) putChecksum(ctx context.Context, client *minio.Client, obj *generator.Object, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	header, value, err := checksumObject(u.Checksum, obj.Reader)
+	if err != nil {
+		return minio.UploadInfo{}, fmt.Errorf("checksum: %w", err)
+	}
+	uri := *client.EndpointURL()
+	uri.Path = "/" + u.Bucket + "/" + obj.Name
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uri.String(), obj.Reader)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	req.ContentLength = obj.Size
+	req.Header = opts.Header()
+	req.Header.Set(header, value)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	resp, err := u.Do(req)
+	if err != nil {
+		return minio.UploadInfo{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		errResp := minio.ErrorResponse{StatusCode: resp.StatusCode, Code: resp.Status}
+		xml.NewDecoder(resp.Body).Decode(&errResp)
+		return minio.UploadInfo{}, errResp
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return minio.UploadInfo{
+		Bucket:    u.Bucket,
+		Key:       obj.Name,
+		ETag:      strings.Trim(resp.Header.Get("ETag"), `"`),
+		Size:      obj.Size,
+		VersionID: resp.Header.Get("x-amz-version-id"),
+	}, err
+}
+
 // Cleanup deletes everything uploaded to the bucket.
 func (u *Put) Cleanup(ctx context.Context) {
 	var pf []string
 	for p := range u.prefixes {
 		pf = append(pf, p)
 	}
-	u.deleteAllInBucket(ctx, pf...)
+	u.deleteAllInBucket(ctx, u.Bucket, pf...)
 }
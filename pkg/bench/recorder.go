@@ -0,0 +1,335 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+type recorderKey struct{}
+
+// opRecorder keeps information about the requests made for an operation
+// with a context returned by newRecorder.
+type opRecorder struct {
+	mu sync.Mutex
+	// stopped is set when requests are canceled because the benchmark was stopped.
+	stopped time.Time
+	// inFlight contains the cancel functions of requests in flight,
+	// if they should be canceled when the benchmark is stopped.
+	inFlight    map[uint64]context.CancelFunc
+	nextID      uint64
+	requestID   string
+	proto       string
+	tracePhases bool
+	phases      PhaseTimings
+	throttled   bool
+
+	// captureHeaders are the response headers to record.
+	captureHeaders []string
+	headers        map[string]string
+
+	// expires is the Expires header to add to object uploads, if set.
+	// minio-go rejects it as metadata, so it is added here.
+	// It is not an x-amz header, so it doesn't have to be signed.
+	expires string
+
+	// recordTenant will record the access key of signed requests.
+	recordTenant bool
+	tenant       string
+
+	// ifNoneMatch will add 'If-None-Match: *' to object uploads.
+	ifNoneMatch bool
+}
+
+// recorderOption modifies the context and recorder returned by newRecorder.
+type recorderOption func(ctx context.Context, r *opRecorder) context.Context
+
+// cancelOnStop will cancel requests in flight when stop is done.
+// Operations that fail after that are marked as canceled.
+// Only the requests are canceled, so requests made after the stop,
+// such as aborting multipart uploads, still complete on the context of the operation.
+func cancelOnStop(stop context.Context) recorderOption {
+	return func(ctx context.Context, r *opRecorder) context.Context {
+		r.inFlight = make(map[uint64]context.CancelFunc)
+		go func() {
+			<-stop.Done()
+			r.mu.Lock()
+			r.stopped = time.Now()
+			for _, cancel := range r.inFlight {
+				cancel()
+			}
+			r.inFlight = nil
+			r.mu.Unlock()
+		}()
+		return ctx
+	}
+}
+
+// track returns req with a context that is canceled if the benchmark is stopped
+// while the request is in flight, and a function to call when the request is done.
+func (r *opRecorder) track(req *http.Request) (*http.Request, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.inFlight == nil {
+		return req, func() {}
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	id := r.nextID
+	r.nextID++
+	r.inFlight[id] = cancel
+	return req.WithContext(ctx), func() {
+		r.mu.Lock()
+		if r.inFlight != nil {
+			delete(r.inFlight, id)
+		}
+		r.mu.Unlock()
+		cancel()
+	}
+}
+
+// doneBody calls done when the body is closed.
+type doneBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+// Close closes the body and calls done.
+func (b *doneBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+// newRecorder returns a context that will record request information into the returned recorder.
+func (c *Common) newRecorder(ctx context.Context, opts ...recorderOption) (context.Context, *opRecorder) {
+	r := &opRecorder{tracePhases: c.TracePhases, captureHeaders: c.CaptureHeaders, recordTenant: c.RecordTenants}
+	for _, opt := range opts {
+		ctx = opt(ctx, r)
+	}
+	return context.WithValue(ctx, recorderKey{}, r), r
+}
+
+// fill sets the recorded information on op and resets the recorder.
+func (r *opRecorder) fill(op *Operation) {
+	r.mu.Lock()
+	// Only operations that ended after their requests were canceled count.
+	// Errors before that, including --timeout deadlines, are genuine failures.
+	op.Canceled = op.Err != "" && !r.stopped.IsZero() && !op.End.Before(r.stopped)
+	op.RequestID = r.requestID
+	op.Proto = r.proto
+	if r.tracePhases {
+		p := r.phases
+		op.Phases = &p
+	}
+	op.Headers = r.headers
+	op.Tenant = r.tenant
+	op.Throttled = r.throttled
+	r.mu.Unlock()
+	r.reset()
+}
+
+// reset discards all recorded information.
+func (r *opRecorder) reset() {
+	r.mu.Lock()
+	r.requestID = ""
+	r.proto = ""
+	r.phases = PhaseTimings{}
+	r.headers = nil
+	r.tenant = ""
+	r.throttled = false
+	r.mu.Unlock()
+}
+
+func (r *opRecorder) setRequestID(id string) {
+	r.mu.Lock()
+	r.requestID = id
+	r.mu.Unlock()
+}
+
+// setHeaders records the captured headers of the response.
+// If several requests are made for an operation, the last value is kept.
+func (r *opRecorder) setHeaders(h http.Header) {
+	r.mu.Lock()
+	for _, name := range r.captureHeaders {
+		v := h.Get(name)
+		if v == "" {
+			continue
+		}
+		if r.headers == nil {
+			r.headers = make(map[string]string, len(r.captureHeaders))
+		}
+		r.headers[name] = v
+	}
+	r.mu.Unlock()
+}
+
+// setTenant records the access key used to sign req, if any.
+func (r *opRecorder) setTenant(req *http.Request) {
+	key := accessKey(req.Header.Get("Authorization"))
+	if key == "" {
+		return
+	}
+	r.mu.Lock()
+	r.tenant = key
+	r.mu.Unlock()
+}
+
+// accessKey returns the access key of a V4 or V2 signed authorization header.
+func accessKey(auth string) string {
+	switch {
+	case strings.HasPrefix(auth, "AWS4-HMAC-SHA256 "):
+		// AWS4-HMAC-SHA256 Credential=KEY/date/region/s3/aws4_request, ...
+		i := strings.Index(auth, "Credential=")
+		if i < 0 {
+			return ""
+		}
+		cred := auth[i+len("Credential="):]
+		if j := strings.IndexByte(cred, '/'); j >= 0 {
+			return cred[:j]
+		}
+	case strings.HasPrefix(auth, "AWS "):
+		// AWS KEY:signature
+		cred := strings.TrimPrefix(auth, "AWS ")
+		if j := strings.LastIndexByte(cred, ':'); j >= 0 {
+			return cred[:j]
+		}
+	}
+	return ""
+}
+
+// setThrottled records that the server throttled a request.
+func (r *opRecorder) setThrottled() {
+	r.mu.Lock()
+	r.throttled = true
+	r.mu.Unlock()
+}
+
+func (r *opRecorder) setProto(proto string) {
+	r.mu.Lock()
+	r.proto = proto
+	r.mu.Unlock()
+}
+
+// uploadStart returns whether req creates an object,
+// which is a single part upload or the start of a multipart upload.
+func uploadStart(req *http.Request) bool {
+	q := req.URL.Query()
+	_, uploads := q["uploads"]
+	return (req.Method == http.MethodPut && q.Get("uploadId") == "" && req.Header.Get("X-Amz-Copy-Source") == "") ||
+		(req.Method == http.MethodPost && uploads)
+}
+
+// conditional returns whether req should have 'If-None-Match: *' added.
+// This applies to single part uploads and completing multipart uploads.
+func (r *opRecorder) conditional(req *http.Request) bool {
+	if !r.ifNoneMatch {
+		return false
+	}
+	uploadID := req.URL.Query().Get("uploadId")
+	return (req.Method == http.MethodPut && uploadID == "") || (req.Method == http.MethodPost && uploadID != "")
+}
+
+func (r *opRecorder) addPhase(dst *time.Duration, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	d := time.Since(start)
+	r.mu.Lock()
+	*dst += d
+	r.mu.Unlock()
+}
+
+// clientTrace returns a trace that will add the time spent in each phase of a request.
+func (r *opRecorder) clientTrace() *httptrace.ClientTrace {
+	var dnsStart, connStart, tlsStart, wrote time.Time
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { r.addPhase(&r.phases.DNS, dnsStart) },
+		ConnectStart:      func(string, string) { connStart = time.Now() },
+		ConnectDone:       func(string, string, error) { r.addPhase(&r.phases.Connect, connStart) },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { r.addPhase(&r.phases.TLS, tlsStart) },
+		WroteRequest:      func(httptrace.WroteRequestInfo) { wrote = time.Now() },
+		GotFirstResponseByte: func() {
+			r.addPhase(&r.phases.Server, wrote)
+		},
+	}
+}
+
+// recorderTransport records information about responses to requests
+// made by benchmark operations.
+type recorderTransport struct {
+	rt http.RoundTripper
+}
+
+// NewRecorderTransport returns a transport that will record the
+// x-amz-request-id response header, the protocol and, if enabled, the request phase
+// timings of operations made by benchmarks.
+func NewRecorderTransport(rt http.RoundTripper) http.RoundTripper {
+	return recorderTransport{rt: rt}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t recorderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r, ok := req.Context().Value(recorderKey{}).(*opRecorder)
+	if !ok {
+		return t.rt.RoundTrip(req)
+	}
+	if r.tracePhases {
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), r.clientTrace()))
+	}
+	if r.expires != "" && uploadStart(req) {
+		req = req.Clone(req.Context())
+		req.Header.Set("Expires", r.expires)
+	}
+	if r.recordTenant {
+		r.setTenant(req)
+	}
+	if r.conditional(req) {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", "*")
+	}
+	req, done := r.track(req)
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		done()
+	} else {
+		resp.Body = &doneBody{ReadCloser: resp.Body, done: done}
+	}
+	if resp != nil {
+		if id := resp.Header.Get("x-amz-request-id"); id != "" {
+			r.setRequestID(id)
+		}
+		r.setProto(resp.Proto)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			r.setThrottled()
+		}
+		if len(r.captureHeaders) > 0 {
+			r.setHeaders(resp.Header)
+		}
+	}
+	return resp, err
+}
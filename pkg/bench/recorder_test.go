@@ -0,0 +1,44 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import "testing"
+
+func TestAccessKey(t *testing.T) {
+	tests := []struct {
+		name string
+		auth string
+		want string
+	}{
+		{name: "v4", auth: "AWS4-HMAC-SHA256 Credential=AKID/20200101/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=abc", want: "AKID"},
+		{name: "v4-no-region", auth: "AWS4-HMAC-SHA256 Credential=AKID", want: ""},
+		{name: "v4-no-credential", auth: "AWS4-HMAC-SHA256 SignedHeaders=host, Signature=abc", want: ""},
+		{name: "v2", auth: "AWS AKID:c2lnbmF0dXJl", want: "AKID"},
+		{name: "v2-colon-in-key", auth: "AWS AK:ID:c2lnbmF0dXJl", want: "AK:ID"},
+		{name: "v2-no-signature", auth: "AWS AKID", want: ""},
+		{name: "anonymous", auth: "", want: ""},
+		{name: "other", auth: "Bearer token", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := accessKey(test.auth); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
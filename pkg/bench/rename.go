@@ -0,0 +1,281 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/generator"
+)
+
+// Rename benchmarks renaming objects with the RenameObject API.
+type Rename struct {
+	CreateObjects int
+	Collector     *Collector
+	objects       generator.Objects
+	// names contains the current name of each object.
+	names []string
+
+	// Do will sign and send a raw request.
+	// Used for RenameObject, since the client doesn't support it.
+	Do func(req *http.Request) (*http.Response, error)
+
+	Common
+}
+
+// renamedSuffix is added to object names when renamed.
+// Objects are renamed back and forth, so they stay within their prefix.
+const renamedSuffix = ".renamed"
+
+// errRenameNotSupported is returned when the server doesn't support RenameObject.
+var errRenameNotSupported = errors.New("server does not support RenameObject")
+
+// Prepare will create an empty bucket or delete any content already there
+// and upload a number of objects.
+// A single rename is made to check that the server supports RenameObject.
+func (g *Rename) Prepare(ctx context.Context) error {
+	if err := g.createEmptyBucket(ctx); err != nil {
+		return err
+	}
+	src := g.Source()
+	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
+	var wg sync.WaitGroup
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
+	obj := make(chan struct{}, g.CreateObjects)
+	for i := 0; i < g.CreateObjects; i++ {
+		obj <- struct{}{}
+	}
+	close(obj)
+	var groupErr error
+	var mu sync.Mutex
+	for i := 0; i < g.prepareConcurrency(); i++ {
+		go func(i int) {
+			defer wg.Done()
+			src := g.Source()
+			for range obj {
+				opts := g.PutOpts
+				rcv := g.Collector.Receiver()
+				done := ctx.Done()
+
+				select {
+				case <-done:
+					return
+				default:
+				}
+				obj := src.Object()
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   http.MethodPut,
+					Thread:   uint16(i),
+					Size:     obj.Size,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				opts.ContentType = obj.ContentType
+				var res minio.UploadInfo
+				err := g.prepareUpload(ctx, obj.Reader, func() (err error) {
+					op.Start = time.Now()
+					res, err = g.putObject(ctx, client, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+					return err
+				})
+				op.End = time.Now()
+				cldone()
+				if err != nil {
+					err := fmt.Errorf("upload error: %w", err)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+
+				if res.Size != obj.Size {
+					err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				mu.Lock()
+				obj.Reader = nil
+				g.objects = append(g.objects, *obj)
+				g.names = append(g.names, obj.Name)
+				g.prepareProgress(float64(len(g.objects)) / float64(g.CreateObjects))
+				mu.Unlock()
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	if groupErr != nil {
+		return groupErr
+	}
+	return g.checkRename(ctx)
+}
+
+// checkRename renames the first object and checks that the source is gone.
+// Servers not supporting RenameObject may treat the request as an empty upload,
+// so the status alone cannot be trusted.
+func (g *Rename) checkRename(ctx context.Context) error {
+	if len(g.names) == 0 {
+		return nil
+	}
+	client, cldone := g.Client()
+	defer cldone()
+	from := g.names[0]
+	to := from + renamedSuffix
+	err := g.rename(ctx, *client.EndpointURL(), from, to)
+	if err != nil {
+		if errors.Is(err, errRenameNotSupported) {
+			return err
+		}
+		return fmt.Errorf("rename check failed: %w", err)
+	}
+	_, err = client.StatObject(ctx, g.Bucket, from, minio.StatObjectOptions{})
+	if err == nil {
+		// The source still exists, remove the created object.
+		if err := client.RemoveObject(ctx, g.Bucket, to, minio.RemoveObjectOptions{}); err != nil {
+			g.Error(err)
+		}
+		return errRenameNotSupported
+	}
+	if minio.ToErrorResponse(err).StatusCode != http.StatusNotFound {
+		return fmt.Errorf("rename check failed: %w", err)
+	}
+	g.names[0] = to
+	return nil
+}
+
+// rename sends a RenameObject request renaming from to to.
+func (g *Rename) rename(ctx context.Context, u url.URL, from, to string) error {
+	u.Path = "/" + g.Bucket + "/" + to
+	u.RawQuery = "renameObject="
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	src := url.URL{Path: "/" + g.Bucket + "/" + from}
+	req.Header.Set("X-Amz-Rename-Source", src.EscapedPath())
+	resp, err := g.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotImplemented, http.StatusMethodNotAllowed:
+		return errRenameNotSupported
+	default:
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return err
+}
+
+// Start will execute the main benchmark.
+// Operations should begin executing when the start channel is closed.
+// Each thread renames its own share of the objects back and forth.
+func (g *Rename) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(g.Concurrency)
+	c := g.Collector
+	if g.AutoTermDur > 0 {
+		ctx = c.AutoTerm(ctx, "RENAME", g.AutoTermScale, autoTermCheck, autoTermSamples, g.AutoTermDur)
+	}
+	// Non-terminating context.
+	nonTerm := context.Background()
+
+	for i := 0; i < g.Concurrency; i++ {
+		go func(i int) {
+			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
+			defer wg.Done()
+			done := ctx.Done()
+			var mine []int
+			for j := i; j < len(g.names); j += g.Concurrency {
+				mine = append(mine, j)
+			}
+			if len(mine) == 0 {
+				return
+			}
+
+			<-wait
+			for n := 0; ; n++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				idx := mine[n%len(mine)]
+				from := g.names[idx]
+				to := g.objects[idx].Name
+				if from == to {
+					to += renamedSuffix
+				}
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   "RENAME",
+					Thread:   uint16(i),
+					Size:     0,
+					File:     from,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				op.Start = time.Now()
+				err := g.rename(reqCtx, *client.EndpointURL(), from, to)
+				op.End = time.Now()
+				cldone()
+				if err != nil {
+					g.Error("rename 出错: ", err)
+					op.Err = err.Error()
+				} else {
+					g.names[idx] = to
+				}
+				rec.fill(&op)
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	return c.Close(), nil
+}
+
+// Cleanup deletes everything uploaded to the bucket.
+// Renamed objects stay within the prefix of the uploaded objects,
+// so they are deleted whatever name they ended up with.
+func (g *Rename) Cleanup(ctx context.Context) {
+	g.deleteAllInBucket(ctx, g.Bucket, g.objects.Prefixes()...)
+}
@@ -0,0 +1,306 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+)
+
+// Replay reissues operations recorded in a previous benchmark,
+// preserving the per-thread ordering and relative start times.
+type Replay struct {
+	// Ops are the recorded operations to replay.
+	// File names must be preserved when loading.
+	Ops       Operations
+	Collector *Collector
+
+	// Default Get options.
+	GetOpts minio.GetObjectOptions
+
+	first    time.Time
+	threads  map[replayThread]Operations
+	prepared map[string]int64
+	skipped  map[string]int
+	Common
+}
+
+// replayThread identifies a recorded thread.
+// Merged benchmark data can contain the same thread number for several clients.
+type replayThread struct {
+	clientID string
+	thread   uint16
+}
+
+// replayable returns whether the operation can be reissued.
+func (g *Replay) replayable(op Operation) bool {
+	if op.File == "" {
+		return false
+	}
+	switch op.OpType {
+	case http.MethodGet, http.MethodPut, http.MethodDelete, "STAT":
+		return true
+	}
+	return false
+}
+
+// Prepare will create the bucket and upload all objects
+// that are accessed before being written by the recorded operations.
+func (g *Replay) Prepare(ctx context.Context) error {
+	if err := g.createEmptyBucket(ctx); err != nil {
+		return err
+	}
+	g.Ops.SortByStartTime()
+	g.threads = make(map[replayThread]Operations)
+	g.prepared = make(map[string]int64)
+	g.skipped = make(map[string]int)
+	written := make(map[string]struct{})
+	sizes := make(map[string]int64)
+	for _, op := range g.Ops {
+		if !g.replayable(op) {
+			g.skipped[op.OpType]++
+			continue
+		}
+		if len(g.threads) == 0 {
+			g.first = op.Start
+		}
+		t := replayThread{clientID: op.ClientID, thread: op.Thread}
+		g.threads[t] = append(g.threads[t], op)
+		if op.Size > sizes[op.File] {
+			sizes[op.File] = op.Size
+		}
+		if _, ok := written[op.File]; ok {
+			continue
+		}
+		written[op.File] = struct{}{}
+		if op.OpType != http.MethodPut {
+			g.prepared[op.File] = 0
+		}
+	}
+	for name := range g.prepared {
+		g.prepared[name] = sizes[name]
+	}
+	for op, n := range g.skipped {
+		console.Infof("\r跳过 %d 个无法重放的 %s 操作\n", n, op)
+	}
+	if len(g.threads) == 0 {
+		return fmt.Errorf("no replayable operations found")
+	}
+
+	g.Collector = g.newCollector()
+	names := make([]string, 0, len(g.prepared))
+	for name := range g.prepared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	console.Info("\r正在上传 ", len(names), " 个对象")
+	obj := make(chan string, len(names))
+	for _, name := range names {
+		obj <- name
+	}
+	close(obj)
+
+	var wg sync.WaitGroup
+	wg.Add(g.prepareConcurrency())
+	var groupErr error
+	var mu sync.Mutex
+	var uploaded int
+	for i := 0; i < g.prepareConcurrency(); i++ {
+		go func(i int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(i)))
+			rcv := g.Collector.Receiver()
+			for name := range obj {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+				size := g.prepared[name]
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   http.MethodPut,
+					Thread:   uint16(i),
+					Size:     size,
+					File:     name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				err := g.prepareUpload(ctx, nil, func() error {
+					op.Start = time.Now()
+					_, err := g.putObject(ctx, client, g.Bucket, name, io.LimitReader(rng, size), size, g.PutOpts)
+					return err
+				})
+				op.End = time.Now()
+				cldone()
+				if err != nil {
+					err := fmt.Errorf("upload error: %w", err)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				mu.Lock()
+				uploaded++
+				g.prepareProgress(float64(uploaded) / float64(len(names)))
+				mu.Unlock()
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	return groupErr
+}
+
+// Start will replay the recorded operations.
+// Each recorded thread is replayed by its own goroutine,
+// and each operation is delayed until its original offset from the first operation.
+func (g *Replay) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(len(g.threads))
+	c := g.Collector
+	// Non-terminating context.
+	nonTerm := context.Background()
+
+	// Number the threads in the order they were recorded.
+	threads := make([]replayThread, 0, len(g.threads))
+	for t := range g.threads {
+		threads = append(threads, t)
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		if threads[i].clientID != threads[j].clientID {
+			return threads[i].clientID < threads[j].clientID
+		}
+		return threads[i].thread < threads[j].thread
+	})
+
+	var start time.Time
+	var startOnce sync.Once
+	for i, t := range threads {
+		go func(thread uint16, ops Operations) {
+			rng := rand.New(rand.NewSource(int64(thread)))
+			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
+			defer wg.Done()
+			done := ctx.Done()
+
+			<-wait
+			startOnce.Do(func() { start = time.Now() })
+			for _, recorded := range ops {
+				delay := time.Until(start.Add(recorded.Start.Sub(g.first)))
+				if delay > 0 {
+					select {
+					case <-done:
+						return
+					case <-time.After(delay):
+					}
+				}
+				select {
+				case <-done:
+					return
+				default:
+				}
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   recorded.OpType,
+					Thread:   thread,
+					Size:     recorded.Size,
+					File:     recorded.File,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				var body io.Reader
+				release := func() {}
+				if recorded.OpType == http.MethodPut {
+					body = io.LimitReader(rng, op.Size)
+					release = g.reserveUpload(reqCtx, body, op.Size, g.PutOpts)
+				}
+				var err error
+				op.Start = time.Now()
+				opCtx, opDone := g.opContext(reqCtx, recorded.OpType)
+				switch recorded.OpType {
+				case http.MethodGet:
+					var o *minio.Object
+					o, err = client.GetObject(opCtx, g.Bucket, op.File, g.GetOpts)
+					if err == nil {
+						fbr := firstByteRecorder{r: o}
+						op.Size, err = io.Copy(ioutil.Discard, &fbr)
+						op.FirstByte = fbr.t
+						o.Close()
+					}
+				case http.MethodPut:
+					_, err = client.PutObject(opCtx, g.Bucket, op.File, body, op.Size, g.PutOpts)
+				case http.MethodDelete:
+					op.Size = 0
+					err = client.RemoveObject(opCtx, g.Bucket, op.File, minio.RemoveObjectOptions{})
+				case "STAT":
+					op.Size = 0
+					_, err = client.StatObject(opCtx, g.Bucket, op.File, minio.StatObjectOptions{})
+				}
+				op.End = time.Now()
+				release()
+				if err != nil {
+					g.Error(op.OpType, " 重放出错: ", err)
+					op.Err = err.Error()
+				}
+				opDone(&op)
+				rec.fill(&op)
+				rcv <- op
+				cldone()
+			}
+		}(uint16(i), g.threads[t])
+	}
+	wg.Wait()
+	return c.Close(), nil
+}
+
+// Cleanup deletes everything written by the replay.
+func (g *Replay) Cleanup(ctx context.Context) {
+	prefixes := make(map[string]struct{})
+	for _, ops := range g.threads {
+		for _, op := range ops {
+			prefix := ""
+			if i := strings.IndexByte(op.File, '/'); i >= 0 {
+				prefix = op.File[:i+1]
+			}
+			prefixes[prefix] = struct{}{}
+		}
+	}
+	if _, ok := prefixes[""]; ok {
+		g.deleteAllInBucket(ctx, g.Bucket)
+		return
+	}
+	res := make([]string, 0, len(prefixes))
+	for p := range prefixes {
+		res = append(res, p)
+	}
+	g.deleteAllInBucket(ctx, g.Bucket, res...)
+}
@@ -0,0 +1,210 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Replicate benchmarks the time until uploaded objects are available on a replica.
+type Replicate struct {
+	Common
+
+	// ReplicaClient returns clients for the replica.
+	ReplicaClient func() (cl *minio.Client, done func())
+	// ReplicaBucket is the bucket objects are replicated to.
+	ReplicaBucket string
+	// Timeout is the maximum lag. Objects not on the replica
+	// within this time are recorded as lag exceeded.
+	Timeout time.Duration
+	// PollInterval is the time between checks on the replica.
+	PollInterval time.Duration
+
+	prefixes map[string]struct{}
+}
+
+const (
+	// opTypeReplicate is the operation type of the replication lag.
+	opTypeReplicate = "REPLICATE"
+	// opTypeReplicateTimeout is the operation type of objects not replicated within the timeout.
+	opTypeReplicateTimeout = "REPLICATE-TIMEOUT"
+)
+
+// Prepare will create an empty bucket or delete any content already there
+// and check that the replica bucket exists.
+func (u *Replicate) Prepare(ctx context.Context) error {
+	if u.ReplicaClient == nil {
+		return errors.New("no replica client")
+	}
+	if err := u.createEmptyBucket(ctx); err != nil {
+		return err
+	}
+	// Replication requires versioning, so all versions must be deleted when cleaning up.
+	src, srcDone := u.Client()
+	ver, err := src.GetBucketVersioning(ctx, u.Bucket)
+	srcDone()
+	if err == nil && ver.Enabled() {
+		u.Versioned = true
+	}
+	cl, done := u.ReplicaClient()
+	defer done()
+	ok, err := cl.BucketExists(ctx, u.ReplicaBucket)
+	if err != nil {
+		return fmt.Errorf("checking replica bucket: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("replica bucket %q does not exist on %s", u.ReplicaBucket, cl.EndpointURL().Host)
+	}
+	return nil
+}
+
+// Start will execute the main benchmark.
+// Operations should begin executing when the start channel is closed.
+func (u *Replicate) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(u.Concurrency)
+	c := u.newCollector()
+	if u.AutoTermDur > 0 {
+		ctx = c.AutoTerm(ctx, opTypeReplicate, u.AutoTermScale, autoTermCheck, autoTermSamples, u.AutoTermDur)
+	}
+	u.prefixes = make(map[string]struct{}, u.Concurrency)
+
+	// Non-terminating context.
+	nonTerm := context.Background()
+
+	for i := 0; i < u.Concurrency; i++ {
+		src := u.Source()
+		u.prefixes[src.Prefix()] = struct{}{}
+		go func(i int) {
+			rcv := c.Receiver()
+			reqCtx, rec := u.newRecorder(nonTerm, cancelOnStop(ctx))
+			defer wg.Done()
+			done := ctx.Done()
+
+			<-wait
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				obj := src.Object()
+				opts := u.PutOpts
+				opts.ContentType = obj.ContentType
+				client, cldone := u.Client()
+				op := Operation{
+					OpType:   http.MethodPut,
+					Thread:   uint16(i),
+					Size:     obj.Size,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				release := u.reserveUpload(reqCtx, obj.Reader, obj.Size, opts)
+				op.Start = time.Now()
+				opCtx, opDone := u.opContext(reqCtx, http.MethodPut)
+				res, err := client.PutObject(opCtx, u.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+				op.End = time.Now()
+				release()
+				cldone()
+				if err != nil {
+					u.Error("上传出错: ", err)
+					op.Err = err.Error()
+				} else if res.Size != obj.Size {
+					op.Err = fmt.Sprint("short upload. want:", obj.Size, ", got:", res.Size)
+					u.Error(op.Err)
+				}
+				opDone(&op)
+				rec.fill(&op)
+				rcv <- op
+				if op.Err != "" {
+					continue
+				}
+
+				// Wait for the object to appear on the replica.
+				rcl, rdone := u.ReplicaClient()
+				lag := Operation{
+					OpType:   opTypeReplicate,
+					Thread:   uint16(i),
+					Size:     obj.Size,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: rcl.EndpointURL().String(),
+					Start:    op.End,
+				}
+				exceeded, err := u.waitReplica(reqCtx, rcl, obj.Name, res.VersionID, lag.Start)
+				lag.End = time.Now()
+				rdone()
+				if err != nil {
+					u.Error("检查副本出错: ", err)
+					lag.Err = err.Error()
+				}
+				if exceeded {
+					lag.OpType = opTypeReplicateTimeout
+				}
+				rec.fill(&lag)
+				rcv <- lag
+			}
+		}(i)
+	}
+	wg.Wait()
+	return c.Close(), nil
+}
+
+// waitReplica will poll the replica until the object version is found.
+// If the object isn't found within the timeout, exceeded is returned as true.
+func (u *Replicate) waitReplica(ctx context.Context, cl *minio.Client, object, versionID string, start time.Time) (exceeded bool, err error) {
+	deadline := start.Add(u.Timeout)
+	for {
+		_, err := cl.StatObject(ctx, u.ReplicaBucket, object, minio.StatObjectOptions{VersionID: versionID})
+		if err == nil {
+			return false, nil
+		}
+		if minio.ToErrorResponse(err).StatusCode != http.StatusNotFound {
+			return false, err
+		}
+		if time.Now().After(deadline) {
+			return true, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(u.PollInterval):
+		}
+	}
+}
+
+// Cleanup deletes everything uploaded to the bucket and the replica bucket.
+func (u *Replicate) Cleanup(ctx context.Context) {
+	var pf []string
+	for p := range u.prefixes {
+		pf = append(pf, p)
+	}
+	u.deleteAllInBucket(ctx, u.Bucket, pf...)
+
+	// Deletes may not be replicated, so remove them from the replica as well.
+	atomic.AddInt64(&u.cleanupFailed, int64(u.deleteAllWith(ctx, u.ReplicaClient, u.ReplicaBucket, pf...)))
+}
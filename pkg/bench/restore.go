@@ -0,0 +1,370 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/generator"
+)
+
+// Restore benchmarks restoring objects from an archive tier.
+type Restore struct {
+	CreateObjects int
+	Collector     *Collector
+	objects       generator.Objects
+
+	// RestoreDays is the number of days a restored copy is kept.
+	RestoreDays int
+	// RestoreTier is the retrieval tier, for instance "Standard", "Bulk" or "Expedited".
+	RestoreTier string
+	// PollInterval is the time between checks for availability of restored objects.
+	PollInterval time.Duration
+
+	// Do signs and sends a raw request.
+	Do func(req *http.Request) (*http.Response, error)
+	Common
+}
+
+// Prepare will create an empty bucket or delete any content already there
+// and upload a number of objects.
+func (g *Restore) Prepare(ctx context.Context) error {
+	if err := g.createEmptyBucket(ctx); err != nil {
+		return err
+	}
+	src := g.Source()
+	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
+	var wg sync.WaitGroup
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
+	obj := make(chan struct{}, g.CreateObjects)
+	for i := 0; i < g.CreateObjects; i++ {
+		obj <- struct{}{}
+	}
+	close(obj)
+	var groupErr error
+	var mu sync.Mutex
+	for i := 0; i < g.prepareConcurrency(); i++ {
+		go func(i int) {
+			defer wg.Done()
+			src := g.Source()
+			for range obj {
+				opts := g.PutOpts
+				rcv := g.Collector.Receiver()
+				done := ctx.Done()
+
+				select {
+				case <-done:
+					return
+				default:
+				}
+				obj := src.Object()
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   http.MethodPut,
+					Thread:   uint16(i),
+					Size:     obj.Size,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				opts.ContentType = obj.ContentType
+				var res minio.UploadInfo
+				err := g.prepareUpload(ctx, obj.Reader, func() (err error) {
+					op.Start = time.Now()
+					res, err = g.putObject(ctx, client, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+					return err
+				})
+				op.End = time.Now()
+				cldone()
+				if err != nil {
+					err := fmt.Errorf("upload error: %w", err)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+
+				obj.VersionID = res.VersionID
+				if res.Size != obj.Size {
+					err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				mu.Lock()
+				obj.Reader = nil
+				g.objects = append(g.objects, *obj)
+				g.prepareProgress(float64(len(g.objects)) / float64(g.CreateObjects))
+				mu.Unlock()
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	if groupErr != nil {
+		return groupErr
+	}
+
+	// Uploaded objects must be archived for restores to do anything.
+	client, cldone := g.Client()
+	defer cldone()
+	available, err := g.restored(ctx, client, g.objects[0])
+	if err != nil {
+		return fmt.Errorf("checking archive state: %w", err)
+	}
+	if available {
+		return errors.New("uploaded objects are readable and not in an archive tier. Use --storage-class with an archive storage class")
+	}
+	return nil
+}
+
+// restoreRequest is the body of a RestoreObject request.
+type restoreRequest struct {
+	XMLName              xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ RestoreRequest"`
+	Days                 int      `xml:"Days"`
+	GlacierJobParameters struct {
+		Tier string `xml:"Tier"`
+	} `xml:"GlacierJobParameters"`
+}
+
+// restoreObject sends a RestoreObject request for the object.
+func (g *Restore) restoreObject(ctx context.Context, client *minio.Client, body []byte, obj generator.Object) error {
+	u := *client.EndpointURL()
+	u.Path = "/" + g.Bucket + "/" + obj.Name
+	q := url.Values{"restore": []string{""}}
+	if obj.VersionID != "" {
+		q.Set("versionId", obj.VersionID)
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	md5sum := md5.Sum(body)
+	req.Header.Set("Content-Md5", base64.StdEncoding.EncodeToString(md5sum[:]))
+	shasum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(shasum[:]))
+	resp, err := g.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// 202 is returned when the restore is started, 200 if a restored copy already exists.
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		var errResp minio.ErrorResponse
+		if xml.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Code != "" {
+			return fmt.Errorf("%s: %s", errResp.Code, errResp.Message)
+		}
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
+
+// pendingRestore is an object that has been requested restored,
+// but is not yet available.
+type pendingRestore struct {
+	obj       generator.Object
+	start     time.Time
+	nextCheck time.Time
+}
+
+// Start will execute the main benchmark.
+// Operations should begin executing when the start channel is closed.
+// Each object is restored once. Objects that have not become available
+// when the benchmark ends are recorded as pending.
+func (g *Restore) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(g.Concurrency)
+	c := g.Collector
+	if g.AutoTermDur > 0 {
+		ctx = c.AutoTerm(ctx, "RESTORE", g.AutoTermScale, autoTermCheck, autoTermSamples, g.AutoTermDur)
+	}
+	objs := make(chan generator.Object, len(g.objects))
+	for _, obj := range g.objects {
+		objs <- obj
+	}
+	close(objs)
+	req := restoreRequest{Days: g.RestoreDays}
+	req.GlacierJobParameters.Tier = g.RestoreTier
+	body, err := xml.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Non-terminating context.
+	nonTerm := context.Background()
+
+	for i := 0; i < g.Concurrency; i++ {
+		go func(i int) {
+			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
+			defer wg.Done()
+			done := ctx.Done()
+			var pending []pendingRestore
+
+			<-wait
+			for {
+				select {
+				case <-done:
+					for _, p := range pending {
+						rcv <- Operation{
+							OpType:   "RESTORE-PENDING",
+							Thread:   uint16(i),
+							Size:     0,
+							File:     p.obj.Name,
+							ObjPerOp: 1,
+							Start:    p.start,
+							End:      time.Now(),
+						}
+					}
+					return
+				default:
+				}
+
+				// Check objects that are due.
+				now := time.Now()
+				remain := pending[:0]
+				for _, p := range pending {
+					if now.Before(p.nextCheck) {
+						remain = append(remain, p)
+						continue
+					}
+					client, cldone := g.Client()
+					op := Operation{
+						OpType:   "RESTORED",
+						Thread:   uint16(i),
+						Size:     0,
+						File:     p.obj.Name,
+						ObjPerOp: 1,
+						Start:    p.start,
+						Endpoint: client.EndpointURL().String(),
+					}
+					available, err := g.restored(reqCtx, client, p.obj)
+					cldone()
+					if err == nil && !available {
+						p.nextCheck = time.Now().Add(g.PollInterval)
+						rec.reset()
+						remain = append(remain, p)
+						continue
+					}
+					op.End = time.Now()
+					if err != nil {
+						g.Error("检查恢复状态出错: ", err)
+						op.Err = err.Error()
+					}
+					rec.fill(&op)
+					rcv <- op
+				}
+				pending = remain
+
+				obj, ok := <-objs
+				if !ok {
+					if len(pending) == 0 {
+						return
+					}
+					// Wait for next check.
+					next := pending[0].nextCheck
+					for _, p := range pending {
+						if p.nextCheck.Before(next) {
+							next = p.nextCheck
+						}
+					}
+					select {
+					case <-done:
+					case <-time.After(time.Until(next)):
+					}
+					continue
+				}
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   "RESTORE",
+					Thread:   uint16(i),
+					Size:     0,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				op.Start = time.Now()
+				err := g.restoreObject(reqCtx, client, body, obj)
+				op.End = time.Now()
+				cldone()
+				if err != nil {
+					g.Error("RestoreObject 出错: ", err)
+					op.Err = err.Error()
+				} else {
+					pending = append(pending, pendingRestore{obj: obj, start: op.Start, nextCheck: op.End.Add(g.PollInterval)})
+				}
+				rec.fill(&op)
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	return c.Close(), nil
+}
+
+// restored returns whether the object can be read.
+// Objects that are still archived will return false with no error.
+func (g *Restore) restored(ctx context.Context, client *minio.Client, obj generator.Object) (bool, error) {
+	opts := minio.GetObjectOptions{VersionID: obj.VersionID}
+	if obj.Size > 0 {
+		opts.SetRange(0, 0)
+	}
+	o, err := client.GetObject(ctx, g.Bucket, obj.Name, opts)
+	if err == nil {
+		_, err = io.Copy(ioutil.Discard, o)
+		o.Close()
+	}
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "InvalidObjectState" {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Cleanup deletes everything uploaded to the bucket.
+func (g *Restore) Cleanup(ctx context.Context) {
+	g.deleteAllInBucket(ctx, g.Bucket, g.objects.Prefixes()...)
+}
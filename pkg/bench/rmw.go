@@ -0,0 +1,293 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+	"github.com/minio/warp/pkg/generator"
+)
+
+// RMW benchmarks read-modify-write operations.
+// Each operation downloads an object and uploads new content to the same key.
+type RMW struct {
+	CreateObjects int
+	Collector     *Collector
+	objects       generator.Objects
+
+	// Default Get options.
+	GetOpts minio.GetObjectOptions
+	Common
+}
+
+// opTypeRMW is the operation type of complete read-modify-write operations.
+// The GET and PUT phases are recorded as separate operations.
+const opTypeRMW = "RMW"
+
+// Prepare will create an empty bucket or delete any content already there
+// and upload a number of objects.
+func (g *RMW) Prepare(ctx context.Context) error {
+	if err := g.createEmptyBucket(ctx); err != nil {
+		return err
+	}
+	src := g.Source()
+	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
+	var wg sync.WaitGroup
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
+	obj := make(chan struct{}, g.CreateObjects)
+	for i := 0; i < g.CreateObjects; i++ {
+		obj <- struct{}{}
+	}
+	close(obj)
+	var groupErr error
+	var mu sync.Mutex
+
+	for i := 0; i < g.prepareConcurrency(); i++ {
+		go func(i int) {
+			defer wg.Done()
+			src := g.Source()
+			for range obj {
+				opts := g.PutOpts
+				rcv := g.Collector.Receiver()
+				done := ctx.Done()
+
+				select {
+				case <-done:
+					return
+				default:
+				}
+				obj := src.Object()
+				client, cldone := g.Client()
+				op := Operation{
+					OpType:   http.MethodPut,
+					Thread:   uint16(i),
+					Size:     obj.Size,
+					File:     obj.Name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				opts.ContentType = obj.ContentType
+				var res minio.UploadInfo
+				err := g.prepareUpload(ctx, obj.Reader, func() (err error) {
+					op.Start = time.Now()
+					res, err = g.putObject(ctx, client, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+					return err
+				})
+				op.End = time.Now()
+				cldone()
+				if err != nil {
+					err := fmt.Errorf("upload error: %w", err)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				if res.Size != obj.Size {
+					err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
+					g.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				mu.Lock()
+				obj.Reader = nil
+				g.objects = append(g.objects, *obj)
+				g.prepareProgress(float64(len(g.objects)) / float64(g.CreateObjects))
+				mu.Unlock()
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	if groupErr != nil {
+		return groupErr
+	}
+	return g.waitForReadBucket(ctx, g.objects)
+}
+
+// Start will execute the main benchmark.
+// Operations should begin executing when the start channel is closed.
+func (g *RMW) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(g.Concurrency)
+	c := g.Collector
+	if g.AutoTermDur > 0 {
+		ctx = c.AutoTerm(ctx, opTypeRMW, g.AutoTermScale, autoTermCheck, autoTermSamples, g.AutoTermDur)
+	}
+
+	// Non-terminating context.
+	nonTerm := context.Background()
+
+	for i := 0; i < g.Concurrency; i++ {
+		src := g.Source()
+		go func(i int) {
+			rng := rand.New(rand.NewSource(int64(i)))
+			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
+			defer wg.Done()
+			done := ctx.Done()
+
+			<-wait
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				idx := rng.Intn(len(g.objects))
+				name := g.objects[idx].Name
+				client, cldone := g.Client()
+				rmw := Operation{
+					OpType:   opTypeRMW,
+					Thread:   uint16(i),
+					File:     name,
+					ObjPerOp: 1,
+					Endpoint: client.EndpointURL().String(),
+				}
+				// The complete operation includes waiting for other workers modifying the object.
+				rmw.Start = time.Now()
+				// Always lock the object, so updates are never lost.
+				unlock := g.objectLocks.lock(name)
+				obj := &g.objects[idx]
+
+				get := g.get(reqCtx, client, obj)
+				get.Thread = uint16(i)
+				rec.fill(&get)
+				rcv <- get
+
+				put := Operation{OpType: http.MethodPut, Thread: uint16(i), File: name, ObjPerOp: 1, Endpoint: rmw.Endpoint}
+				if get.Err != "" {
+					rmw.Err = get.Err
+				} else {
+					next := src.Object()
+					put = g.put(reqCtx, client, name, next)
+					put.Thread = uint16(i)
+					if put.Err == "" {
+						obj.Size = next.Size
+						obj.ContentType = next.ContentType
+					}
+					rec.fill(&put)
+					rcv <- put
+					rmw.Err = put.Err
+				}
+				rmw.End = time.Now()
+				unlock()
+				cldone()
+				rmw.Size = get.Size
+				rmw.FirstByte = get.FirstByte
+				rmw.Timeout = get.Timeout + put.Timeout
+				rmw.Canceled = get.Canceled || put.Canceled
+				rmw.Throttled = get.Throttled || put.Throttled
+				rmw.RequestID = put.RequestID
+				rmw.Proto = get.Proto
+				rmw.Tenant = get.Tenant
+				rcv <- rmw
+			}
+		}(i)
+	}
+	wg.Wait()
+	return c.Close(), nil
+}
+
+// get downloads the object and checks the size.
+// Must be called with the object locked.
+// The result is named, so the deferred opDone can record the timeout.
+func (g *RMW) get(ctx context.Context, client *minio.Client, obj *generator.Object) (op Operation) {
+	op = Operation{
+		OpType:   http.MethodGet,
+		Size:     obj.Size,
+		File:     obj.Name,
+		ObjPerOp: 1,
+		Endpoint: client.EndpointURL().String(),
+	}
+	opCtx, opDone := g.opContext(ctx, http.MethodGet)
+	defer opDone(&op)
+	op.Start = time.Now()
+	o, err := client.GetObject(opCtx, g.Bucket, obj.Name, g.GetOpts)
+	if err != nil {
+		op.End = time.Now()
+		g.Error("下载出错:", err)
+		op.Err = err.Error()
+		return op
+	}
+	defer o.Close()
+	fbr := firstByteRecorder{r: o}
+	n, err := io.Copy(ioutil.Discard, &fbr)
+	op.FirstByte = fbr.t
+	op.End = time.Now()
+	if err != nil {
+		g.Error("下载出错:", err)
+		op.Err = err.Error()
+	}
+	if n != op.Size && op.Err == "" {
+		op.Err = fmt.Sprint("不符合期望的下载大小. 需要的是:", op.Size, ", 实际上是:", n)
+		g.Error(op.Err)
+	}
+	return op
+}
+
+// put uploads obj as the named object.
+// Must be called with the object locked.
+func (g *RMW) put(ctx context.Context, client *minio.Client, name string, obj *generator.Object) (op Operation) {
+	op = Operation{
+		OpType:   http.MethodPut,
+		Size:     obj.Size,
+		File:     name,
+		ObjPerOp: 1,
+		Endpoint: client.EndpointURL().String(),
+	}
+	opts := g.PutOpts
+	opts.ContentType = obj.ContentType
+	release := g.reserveUpload(ctx, obj.Reader, obj.Size, opts)
+	defer release()
+	opCtx, opDone := g.opContext(ctx, http.MethodPut)
+	defer opDone(&op)
+	op.Start = time.Now()
+	res, err := client.PutObject(opCtx, g.Bucket, name, obj.Reader, obj.Size, opts)
+	op.End = time.Now()
+	if err != nil {
+		g.Error("上传出错: ", err)
+		op.Err = err.Error()
+		return op
+	}
+	if res.Size != obj.Size {
+		op.Err = fmt.Sprint("不符合期望的上传大小. 需要的是:", obj.Size, ", 实际上是:", res.Size)
+		g.Error(op.Err)
+	}
+	return op
+}
+
+// Cleanup deletes everything uploaded to the bucket.
+func (g *RMW) Cleanup(ctx context.Context) {
+	g.deleteAllInBucket(ctx, g.Bucket, g.objects.Prefixes()...)
+}
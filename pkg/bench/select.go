@@ -18,7 +18,16 @@
 package bench
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -40,9 +49,34 @@ type Select struct {
 
 	// Default Select options.
 	SelectOpts minio.SelectObjectOptions
+
+	// ScanRange will limit the part of each object scanned, if set.
+	ScanRange *SelectScanRange
+
+	// Do signs and sends a raw request.
+	// Required when ScanRange is set, since the client cannot send scan ranges.
+	Do func(req *http.Request) (*http.Response, error)
 	Common
 }
 
+// SelectScanRange is the inclusive byte range of an object scanned by a select request.
+type SelectScanRange struct {
+	Start, End int64
+}
+
+// Size returns the number of bytes in the scan range.
+func (s SelectScanRange) Size() int64 {
+	return s.End - s.Start + 1
+}
+
+// check returns an error if the scan range is outside an object of the given size.
+func (s SelectScanRange) check(size int64) error {
+	if s.End >= size {
+		return fmt.Errorf("scan range %d-%d is beyond object size %d", s.Start, s.End, size)
+	}
+	return nil
+}
+
 // Prepare will create an empty bucket or delete any content already there
 // and upload a number of objects.
 func (g *Select) Prepare(ctx context.Context) error {
@@ -52,8 +86,8 @@ func (g *Select) Prepare(ctx context.Context) error {
 	src := g.Source()
 	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
 	var wg sync.WaitGroup
-	wg.Add(g.Concurrency)
-	g.Collector = NewCollector()
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
 	obj := make(chan struct{}, g.CreateObjects)
 	for i := 0; i < g.CreateObjects; i++ {
 		obj <- struct{}{}
@@ -61,7 +95,7 @@ func (g *Select) Prepare(ctx context.Context) error {
 	close(obj)
 	var groupErr error
 	var mu sync.Mutex
-	for i := 0; i < g.Concurrency; i++ {
+	for i := 0; i < g.prepareConcurrency(); i++ {
 		go func(i int) {
 			defer wg.Done()
 			src := g.Source()
@@ -76,6 +110,17 @@ func (g *Select) Prepare(ctx context.Context) error {
 				default:
 				}
 				obj := src.Object()
+				if g.ScanRange != nil {
+					if err := g.ScanRange.check(obj.Size); err != nil {
+						g.Error(err)
+						mu.Lock()
+						if groupErr == nil {
+							groupErr = err
+						}
+						mu.Unlock()
+						return
+					}
+				}
 				client, cldone := g.Client()
 				op := Operation{
 					OpType:   http.MethodPut,
@@ -86,9 +131,48 @@ func (g *Select) Prepare(ctx context.Context) error {
 					Endpoint: client.EndpointURL().String(),
 				}
 				opts.ContentType = obj.ContentType
-				op.Start = time.Now()
-				res, err := client.PutObject(ctx, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+				reader, size := obj.Reader, obj.Size
+				if g.SelectOpts.InputSerialization.JSON != nil {
+					var err error
+					reader, size, err = jsonLinesReader(reader)
+					if err != nil {
+						err := fmt.Errorf("json conversion error: %w", err)
+						g.Error(err)
+						mu.Lock()
+						if groupErr == nil {
+							groupErr = err
+						}
+						mu.Unlock()
+						cldone()
+						return
+					}
+					opts.ContentType = "application/json"
+					op.Size = size
+				}
+				if g.SelectOpts.InputSerialization.CompressionType == minio.SelectCompressionGZIP {
+					var err error
+					reader, size, err = gzipReader(reader)
+					if err != nil {
+						err := fmt.Errorf("compress error: %w", err)
+						g.Error(err)
+						mu.Lock()
+						if groupErr == nil {
+							groupErr = err
+						}
+						mu.Unlock()
+						cldone()
+						return
+					}
+					op.Size = size
+				}
+				var res minio.UploadInfo
+				err := g.prepareUpload(ctx, reader, func() (err error) {
+					op.Start = time.Now()
+					res, err = g.putObject(ctx, client, g.Bucket, obj.Name, reader, size, opts)
+					return err
+				})
 				op.End = time.Now()
+				cldone()
 				if err != nil {
 					err := fmt.Errorf("upload error: %w", err)
 					g.Error(err)
@@ -100,8 +184,8 @@ func (g *Select) Prepare(ctx context.Context) error {
 					return
 				}
 				obj.VersionID = res.VersionID
-				if res.Size != obj.Size {
-					err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
+				if res.Size != size {
+					err := fmt.Errorf("short upload. want: %d, got %d", size, res.Size)
 					g.Error(err)
 					mu.Lock()
 					if groupErr == nil {
@@ -110,7 +194,6 @@ func (g *Select) Prepare(ctx context.Context) error {
 					mu.Unlock()
 					return
 				}
-				cldone()
 				mu.Lock()
 				obj.Reader = nil
 				g.objects = append(g.objects, *obj)
@@ -121,7 +204,58 @@ func (g *Select) Prepare(ctx context.Context) error {
 		}(i)
 	}
 	wg.Wait()
-	return groupErr
+	if groupErr != nil {
+		return groupErr
+	}
+	return g.waitForReadBucket(ctx, g.objects)
+}
+
+// gzipReader returns the content of r gzip compressed and the compressed size.
+func gzipReader(r io.Reader) (io.ReadSeeker, int64, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := io.Copy(gz, r); err != nil {
+		return nil, 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(buf.Bytes()), int64(buf.Len()), nil
+}
+
+// jsonLinesReader returns the CSV content of r converted to JSON Lines and the converted size.
+// The first CSV record is used as field names.
+func jsonLinesReader(r io.Reader) (io.ReadSeeker, int64, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, 0, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	rec := make(map[string]string, len(header))
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		for i, name := range header {
+			if i < len(fields) {
+				rec[name] = fields[i]
+			} else {
+				delete(rec, name)
+			}
+		}
+		// Encode adds a newline after each record.
+		if err := enc.Encode(rec); err != nil {
+			return nil, 0, err
+		}
+	}
+	return bytes.NewReader(buf.Bytes()), int64(buf.Len()), nil
 }
 
 // Start will execute the main benchmark.
@@ -141,6 +275,7 @@ func (g *Select) Start(ctx context.Context, wait chan struct{}) (Operations, err
 		go func(i int) {
 			rng := rand.New(rand.NewSource(int64(i)))
 			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
 			defer wg.Done()
 			opts := g.SelectOpts
 			done := ctx.Done()
@@ -154,6 +289,7 @@ func (g *Select) Start(ctx context.Context, wait chan struct{}) (Operations, err
 				}
 				fbr := firstByteRecorder{}
 				obj := g.objects[rng.Intn(len(g.objects))]
+				unlock := g.lockObject(obj.Name)
 				client, cldone := g.Client()
 				op := Operation{
 					OpType:   "SELECT",
@@ -163,16 +299,26 @@ func (g *Select) Start(ctx context.Context, wait chan struct{}) (Operations, err
 					ObjPerOp: 1,
 					Endpoint: client.EndpointURL().String(),
 				}
+				if g.ScanRange != nil {
+					op.Size = g.ScanRange.Size()
+				}
 				op.Start = time.Now()
 				var err error
-				o, err := client.SelectObjectContent(nonTerm, g.Bucket, obj.Name, opts)
+				var o *minio.SelectResults
+				if g.ScanRange != nil {
+					o, err = g.selectScanRange(reqCtx, client, obj.Name, opts)
+				} else {
+					o, err = client.SelectObjectContent(reqCtx, g.readBucket(), obj.Name, opts)
+				}
 				fbr.r = o
 				if err != nil {
 					g.Error("下载出错: ", err)
 					op.Err = err.Error()
 					op.End = time.Now()
+					rec.fill(&op)
 					rcv <- op
 					cldone()
+					unlock()
 					continue
 				}
 				if _, err = io.Copy(ioutil.Discard, &fbr); err != nil {
@@ -182,8 +328,10 @@ func (g *Select) Start(ctx context.Context, wait chan struct{}) (Operations, err
 				}
 				op.FirstByte = fbr.t
 				op.End = time.Now()
+				rec.fill(&op)
 				rcv <- op
 				cldone()
+				unlock()
 				o.Close()
 			}
 		}(i)
@@ -192,7 +340,43 @@ func (g *Select) Start(ctx context.Context, wait chan struct{}) (Operations, err
 	return c.Close(), nil
 }
 
+// selectScanRange will send a select request with the scan range added.
+func (g *Select) selectScanRange(ctx context.Context, client *minio.Client, object string, opts minio.SelectObjectOptions) (*minio.SelectResults, error) {
+	body, err := xml.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	// Insert the scan range before the closing tag.
+	end := bytes.LastIndex(body, []byte("</"))
+	if end < 0 {
+		return nil, fmt.Errorf("unexpected select request: %s", string(body))
+	}
+	scan := fmt.Sprintf("<ScanRange><Start>%d</Start><End>%d</End></ScanRange>", g.ScanRange.Start, g.ScanRange.End)
+	body = append(body[:end:end], append([]byte(scan), body[end:]...)...)
+
+	u := *client.EndpointURL()
+	u.Path = "/" + g.readBucket() + "/" + object
+	u.RawQuery = "select=&select-type=2"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range opts.Header() {
+		req.Header[k] = v
+	}
+	md5sum := md5.Sum(body)
+	req.Header.Set("Content-Md5", base64.StdEncoding.EncodeToString(md5sum[:]))
+	shasum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(shasum[:]))
+	resp, err := g.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return minio.NewSelectResults(resp, g.readBucket())
+}
+
 // Cleanup deletes everything uploaded to the bucket.
 func (g *Select) Cleanup(ctx context.Context) {
-	g.deleteAllInBucket(ctx, g.objects.Prefixes()...)
+	g.deleteAllInBucket(ctx, g.Bucket, g.objects.Prefixes()...)
+	g.cleanupReadBucket(ctx, g.objects.Prefixes()...)
 }
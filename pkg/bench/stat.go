@@ -19,9 +19,13 @@ package bench
 
 import (
 	"context"
+	"encoding/xml"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
@@ -38,9 +42,32 @@ type Stat struct {
 
 	// Default Stat options.
 	StatOpts minio.StatObjectOptions
+
+	// Method is the API used to read the metadata, StatMethodHead or StatMethodAttributes.
+	// HEAD is used if empty.
+	Method string
+
+	// Do signs and sends a raw request.
+	// Required when Method is StatMethodAttributes, since the client cannot send GetObjectAttributes.
+	Do func(req *http.Request) (*http.Response, error)
 	Common
 }
 
+const (
+	// StatMethodHead reads object metadata with HeadObject.
+	StatMethodHead = "head"
+	// StatMethodAttributes reads object metadata with GetObjectAttributes.
+	StatMethodAttributes = "attributes"
+)
+
+// opType returns the operation type recorded for the stat method.
+func (g *Stat) opType() string {
+	if g.Method == StatMethodAttributes {
+		return "STAT-ATTRIBUTES"
+	}
+	return "STAT"
+}
+
 // Prepare will create an empty bucket or delete any content already there
 // and upload a number of objects.
 func (g *Stat) Prepare(ctx context.Context) error {
@@ -50,8 +77,8 @@ func (g *Stat) Prepare(ctx context.Context) error {
 	src := g.Source()
 	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
 	var wg sync.WaitGroup
-	wg.Add(g.Concurrency)
-	g.Collector = NewCollector()
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
 	obj := make(chan struct{}, g.CreateObjects)
 	for i := 0; i < g.CreateObjects; i++ {
 		obj <- struct{}{}
@@ -59,7 +86,7 @@ func (g *Stat) Prepare(ctx context.Context) error {
 	close(obj)
 	var groupErr error
 	var mu sync.Mutex
-	for i := 0; i < g.Concurrency; i++ {
+	for i := 0; i < g.prepareConcurrency(); i++ {
 		go func(i int) {
 			defer wg.Done()
 			src := g.Source()
@@ -84,9 +111,14 @@ func (g *Stat) Prepare(ctx context.Context) error {
 					Endpoint: client.EndpointURL().String(),
 				}
 				opts.ContentType = obj.ContentType
-				op.Start = time.Now()
-				res, err := client.PutObject(ctx, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+				var res minio.UploadInfo
+				err := g.prepareUpload(ctx, obj.Reader, func() (err error) {
+					op.Start = time.Now()
+					res, err = g.putObject(ctx, client, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+					return err
+				})
 				op.End = time.Now()
+				cldone()
 				if err != nil {
 					err := fmt.Errorf("upload error: %w", err)
 					g.Error(err)
@@ -109,7 +141,6 @@ func (g *Stat) Prepare(ctx context.Context) error {
 					mu.Unlock()
 					return
 				}
-				cldone()
 				mu.Lock()
 				obj.Reader = nil
 				g.objects = append(g.objects, *obj)
@@ -120,7 +151,22 @@ func (g *Stat) Prepare(ctx context.Context) error {
 		}(i)
 	}
 	wg.Wait()
-	return groupErr
+	if groupErr != nil {
+		return groupErr
+	}
+	if err := g.waitForReadBucket(ctx, g.objects); err != nil {
+		return err
+	}
+	if g.Method == StatMethodAttributes && len(g.objects) > 0 {
+		// Check that the server supports GetObjectAttributes before starting.
+		client, cldone := g.readClient()
+		defer cldone()
+		obj := g.objects[0]
+		if _, err := g.getObjectAttributes(ctx, client, obj.Name, obj.VersionID); err != nil {
+			return fmt.Errorf("GetObjectAttributes is not supported by the server: %w", err)
+		}
+	}
+	return nil
 }
 
 // Start will execute the main benchmark.
@@ -130,7 +176,7 @@ func (g *Stat) Start(ctx context.Context, wait chan struct{}) (Operations, error
 	wg.Add(g.Concurrency)
 	c := g.Collector
 	if g.AutoTermDur > 0 {
-		ctx = c.AutoTerm(ctx, "STAT", g.AutoTermScale, autoTermCheck, autoTermSamples, g.AutoTermDur)
+		ctx = c.AutoTerm(ctx, g.opType(), g.AutoTermScale, autoTermCheck, autoTermSamples, g.AutoTermDur)
 	}
 	// Non-terminating context.
 	nonTerm := context.Background()
@@ -139,6 +185,7 @@ func (g *Stat) Start(ctx context.Context, wait chan struct{}) (Operations, error
 		go func(i int) {
 			rng := rand.New(rand.NewSource(int64(i)))
 			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
 			defer wg.Done()
 			opts := g.StatOpts
 			done := ctx.Done()
@@ -151,9 +198,10 @@ func (g *Stat) Start(ctx context.Context, wait chan struct{}) (Operations, error
 				default:
 				}
 				obj := g.objects[rng.Intn(len(g.objects))]
-				client, cldone := g.Client()
+				unlock := g.lockObject(obj.Name)
+				client, cldone := g.readClient()
 				op := Operation{
-					OpType:   "STAT",
+					OpType:   g.opType(),
 					Thread:   uint16(i),
 					Size:     0,
 					File:     obj.Name,
@@ -163,22 +211,36 @@ func (g *Stat) Start(ctx context.Context, wait chan struct{}) (Operations, error
 				op.Start = time.Now()
 				var err error
 				opts.VersionID = obj.VersionID
-				objI, err := client.StatObject(nonTerm, g.Bucket, obj.Name, opts)
+				opCtx, opDone := g.opContext(reqCtx, "STAT")
+				var size int64
+				if g.Method == StatMethodAttributes {
+					size, err = g.getObjectAttributes(opCtx, client, obj.Name, obj.VersionID)
+				} else {
+					var objI minio.ObjectInfo
+					objI, err = client.StatObject(opCtx, g.readBucket(), obj.Name, opts)
+					size = objI.Size
+				}
 				if err != nil {
-					g.Error("StatObject 出错: ", err)
+					g.Error(op.OpType, " 出错: ", err)
 					op.Err = err.Error()
 					op.End = time.Now()
+					opDone(&op)
+					rec.fill(&op)
 					rcv <- op
 					cldone()
+					unlock()
 					continue
 				}
 				op.End = time.Now()
-				if objI.Size != obj.Size && op.Err == "" {
-					op.Err = fmt.Sprint("不符合期望的文件大小. 需要的是:", obj.Size, ", 实际上是:", objI.Size)
+				if size != obj.Size && op.Err == "" {
+					op.Err = fmt.Sprint("不符合期望的文件大小. 需要的是:", obj.Size, ", 实际上是:", size)
 					g.Error(op.Err)
 				}
+				opDone(&op)
+				rec.fill(&op)
 				rcv <- op
 				cldone()
+				unlock()
 			}
 		}(i)
 	}
@@ -186,7 +248,46 @@ func (g *Stat) Start(ctx context.Context, wait chan struct{}) (Operations, error
 	return c.Close(), nil
 }
 
+// getObjectAttributes sends a GetObjectAttributes request for the object and returns the object size.
+func (g *Stat) getObjectAttributes(ctx context.Context, client *minio.Client, object, versionID string) (int64, error) {
+	u := *client.EndpointURL()
+	u.Path = "/" + g.readBucket() + "/" + object
+	q := url.Values{"attributes": []string{""}}
+	if versionID != "" {
+		q.Set("versionId", versionID)
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Amz-Object-Attributes", "ObjectSize")
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	for k, v := range g.StatOpts.Header() {
+		req.Header[k] = v
+	}
+	resp, err := g.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var errResp minio.ErrorResponse
+		if xml.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Code != "" {
+			return 0, fmt.Errorf("%s: %s", errResp.Code, errResp.Message)
+		}
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var attrs struct {
+		ObjectSize int64 `xml:"ObjectSize"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&attrs)
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return attrs.ObjectSize, err
+}
+
 // Cleanup deletes everything uploaded to the bucket.
 func (g *Stat) Cleanup(ctx context.Context) {
-	g.deleteAllInBucket(ctx, g.objects.Prefixes()...)
+	g.deleteAllInBucket(ctx, g.Bucket, g.objects.Prefixes()...)
+	g.cleanupReadBucket(ctx, g.objects.Prefixes()...)
 }
@@ -40,6 +40,9 @@ type Versioned struct {
 	Collector     *Collector
 	Dist          *VersionedDistribution
 
+	// VersionsPerObject is the number of versions to create for each object when preparing.
+	VersionsPerObject int
+
 	GetOpts  minio.GetObjectOptions
 	StatOpts minio.StatObjectOptions
 	Common
@@ -63,11 +66,15 @@ func (g *Versioned) Prepare(ctx context.Context) error {
 		}
 		g.Versioned = true
 	}
+	versions := g.VersionsPerObject
+	if versions < 1 {
+		versions = 1
+	}
 	src := g.Source()
-	console.Info("\r正在上传 ", g.CreateObjects, " 个对象: ", src.String())
+	console.Info("\r正在上传 ", g.CreateObjects, " 个对象, 每个对象 ", versions, " 个版本: ", src.String())
 	var wg sync.WaitGroup
-	wg.Add(g.Concurrency)
-	g.Collector = NewCollector()
+	wg.Add(g.prepareConcurrency())
+	g.Collector = g.newCollector()
 	obj := make(chan struct{}, g.CreateObjects)
 	for i := 0; i < g.CreateObjects; i++ {
 		obj <- struct{}{}
@@ -75,7 +82,7 @@ func (g *Versioned) Prepare(ctx context.Context) error {
 	close(obj)
 	var groupErr error
 	var mu sync.Mutex
-	for i := 0; i < g.Concurrency; i++ {
+	for i := 0; i < g.prepareConcurrency(); i++ {
 		go func(i int) {
 			defer wg.Done()
 			src := g.Source()
@@ -88,35 +95,48 @@ func (g *Versioned) Prepare(ctx context.Context) error {
 					return
 				default:
 				}
-				obj := src.Object()
-				client, clDone := g.Client()
-				opts.ContentType = obj.ContentType
-				res, err := client.PutObject(ctx, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
-				if err != nil {
-					err := fmt.Errorf("upload error: %w", err)
-					g.Error(err)
-					mu.Lock()
-					if groupErr == nil {
-						groupErr = err
+				var name, prefix string
+				for v := 0; v < versions; v++ {
+					obj := src.Object()
+					if v == 0 {
+						name, prefix = obj.Name, obj.Prefix
+					} else {
+						// Upload a new version of the same key.
+						obj.Name, obj.Prefix = name, prefix
 					}
-					mu.Unlock()
-					return
-				}
-				obj.VersionID = res.VersionID
-				if res.Size != obj.Size {
-					err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
-					g.Error(err)
-					mu.Lock()
-					if groupErr == nil {
-						groupErr = err
+					client, clDone := g.Client()
+					opts.ContentType = obj.ContentType
+					var res minio.UploadInfo
+					err := g.prepareUpload(ctx, obj.Reader, func() (err error) {
+						res, err = g.putObject(ctx, client, g.Bucket, obj.Name, obj.Reader, obj.Size, opts)
+						return err
+					})
+					clDone()
+					if err != nil {
+						err := fmt.Errorf("upload error: %w", err)
+						g.Error(err)
+						mu.Lock()
+						if groupErr == nil {
+							groupErr = err
+						}
+						mu.Unlock()
+						return
 					}
-					mu.Unlock()
-					return
+					obj.VersionID = res.VersionID
+					if res.Size != obj.Size {
+						err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
+						g.Error(err)
+						mu.Lock()
+						if groupErr == nil {
+							groupErr = err
+						}
+						mu.Unlock()
+						return
+					}
+					obj.Reader = nil
+					g.Dist.addObj(*obj)
 				}
-				clDone()
-				obj.Reader = nil
-				g.Dist.addObj(*obj)
-				g.prepareProgress(float64(len(g.Dist.objects)) / float64(g.CreateObjects))
+				g.prepareProgress(float64(len(g.Dist.objects)) / float64(g.CreateObjects*versions))
 			}
 		}(i)
 	}
@@ -138,6 +158,7 @@ func (g *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations,
 	for i := 0; i < g.Concurrency; i++ {
 		go func(i int) {
 			rcv := c.Receiver()
+			reqCtx, rec := g.newRecorder(nonTerm, cancelOnStop(ctx))
 			defer wg.Done()
 			done := ctx.Done()
 			src := g.Source()
@@ -157,6 +178,7 @@ func (g *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations,
 				case http.MethodGet:
 					fbr := firstByteRecorder{}
 					obj, objDone := g.Dist.randomObjRead()
+					unlock := g.lockObject(obj.Name)
 					client, clDone := g.Client()
 					op := Operation{
 						OpType:   operation,
@@ -169,13 +191,17 @@ func (g *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations,
 					op.Start = time.Now()
 					var err error
 					getOpts.VersionID = obj.VersionID
-					fbr.r, err = client.GetObject(nonTerm, g.Bucket, obj.Name, getOpts)
+					opCtx, opDone := g.opContext(reqCtx, http.MethodGet)
+					fbr.r, err = client.GetObject(opCtx, g.Bucket, obj.Name, getOpts)
 					if err != nil {
 						g.Error("下载出错: ", err)
 						op.Err = err.Error()
 						op.End = time.Now()
+						opDone(&op)
+						rec.fill(&op)
 						rcv <- op
 						clDone()
+						unlock()
 						objDone()
 						continue
 					}
@@ -190,11 +216,15 @@ func (g *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations,
 						op.Err = fmt.Sprint("不符合期望的文件大小. 需要的是:", obj.Size, ", 实际上是:", n)
 						g.Error(op.Err)
 					}
+					opDone(&op)
+					rec.fill(&op)
 					rcv <- op
 					objDone()
 					clDone()
+					unlock()
 				case http.MethodPut:
 					obj, objDone := g.Dist.newVersion(src.Object())
+					unlock := g.lockObject(obj.Name)
 					putOpts.ContentType = obj.ContentType
 					client, clDone := g.Client()
 					op := Operation{
@@ -205,9 +235,12 @@ func (g *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations,
 						ObjPerOp: 1,
 						Endpoint: client.EndpointURL().String(),
 					}
+					release := g.reserveUpload(reqCtx, obj.Reader, obj.Size, putOpts)
 					op.Start = time.Now()
-					res, err := client.PutObject(nonTerm, g.Bucket, obj.Name, obj.Reader, obj.Size, putOpts)
+					opCtx, opDone := g.opContext(reqCtx, http.MethodPut)
+					res, err := client.PutObject(opCtx, g.Bucket, obj.Name, obj.Reader, obj.Size, putOpts)
 					op.End = time.Now()
+					release()
 					if err != nil {
 						g.Error("上传出错: ", err)
 						op.Err = err.Error()
@@ -221,17 +254,24 @@ func (g *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations,
 						}
 						g.Error(err)
 					}
-					op.Size = res.Size
+					if err == nil || !g.ExactSizes {
+						// Failed uploads of exact sizes keep the requested size, so they are analyzed with it.
+						op.Size = res.Size
+					}
 					clDone()
+					unlock()
 					if op.Err != "" {
 						// Don't add if error.
 						res.VersionID = ""
 					}
 					objDone(res.VersionID)
+					opDone(&op)
+					rec.fill(&op)
 					rcv <- op
 				case http.MethodDelete:
 					client, clDone := g.Client()
 					obj := g.Dist.deleteRandomObj()
+					unlock := g.lockObject(obj.Name)
 					op := Operation{
 						OpType:   operation,
 						Thread:   uint16(i),
@@ -241,16 +281,21 @@ func (g *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations,
 						Endpoint: client.EndpointURL().String(),
 					}
 					op.Start = time.Now()
-					err := client.RemoveObject(nonTerm, g.Bucket, obj.Name, minio.RemoveObjectOptions{VersionID: obj.VersionID})
+					opCtx, opDone := g.opContext(reqCtx, http.MethodDelete)
+					err := client.RemoveObject(opCtx, g.Bucket, obj.Name, minio.RemoveObjectOptions{VersionID: obj.VersionID})
 					op.End = time.Now()
 					clDone()
+					unlock()
 					if err != nil {
 						g.Error("删除出错:", err)
 						op.Err = err.Error()
 					}
+					opDone(&op)
+					rec.fill(&op)
 					rcv <- op
 				case "STAT":
 					obj, objDone := g.Dist.randomObjRead()
+					unlock := g.lockObject(obj.Name)
 					client, clDone := g.Client()
 					op := Operation{
 						OpType:   operation,
@@ -263,7 +308,8 @@ func (g *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations,
 					op.Start = time.Now()
 					var err error
 					statOpts.VersionID = obj.VersionID
-					objI, err := client.StatObject(nonTerm, g.Bucket, obj.Name, statOpts)
+					opCtx, opDone := g.opContext(reqCtx, "STAT")
+					objI, err := client.StatObject(opCtx, g.Bucket, obj.Name, statOpts)
 					if err != nil {
 						g.Error("stat 错误:", err)
 						op.Err = err.Error()
@@ -273,9 +319,12 @@ func (g *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations,
 						op.Err = fmt.Sprint("不符合期望的文件大小. 需要的是:", obj.Size, ", 实际上是:", objI.Size)
 						g.Error(op.Err)
 					}
+					opDone(&op)
+					rec.fill(&op)
 					rcv <- op
 					objDone()
 					clDone()
+					unlock()
 				default:
 					g.Error("未知的请求操作:", operation)
 				}
@@ -288,7 +337,7 @@ func (g *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations,
 
 // Cleanup deletes everything uploaded to the bucket.
 func (g *Versioned) Cleanup(ctx context.Context) {
-	g.deleteAllInBucket(ctx, g.Dist.Objects().Prefixes()...)
+	g.deleteAllInBucket(ctx, g.Bucket, g.Dist.Objects().Prefixes()...)
 }
 
 type versionedObj struct {
@@ -152,7 +152,7 @@ func (c *csvSource) Object() *Object {
 	c.obj.Reader = c.buf.Reset(0)
 	var nBuf [16]byte
 	randASCIIBytes(nBuf[:], c.rng)
-	c.obj.setName(string(nBuf[:]) + ".csv")
+	c.obj.setName(c.o.objectName(c.rng, string(nBuf[:])+".csv"))
 	return &c.obj
 
 }
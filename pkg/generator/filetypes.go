@@ -0,0 +1,211 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fileType is a file type that can be emulated by adding a header to random data.
+type fileType struct {
+	name        string
+	ext         string
+	contentType string
+	header      []byte
+}
+
+// fileTypes contains the known file types.
+var fileTypes = map[string]fileType{
+	"jpeg": {
+		name:        "jpeg",
+		ext:         ".jpg",
+		contentType: "image/jpeg",
+		// SOI and JFIF APP0 segment.
+		header: []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0x01, 0x01, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00},
+	},
+	"pdf": {
+		name:        "pdf",
+		ext:         ".pdf",
+		contentType: "application/pdf",
+		header:      []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"),
+	},
+	"zip": {
+		name:        "zip",
+		ext:         ".zip",
+		contentType: "application/zip",
+		// Local file header of a stored file named "data.bin".
+		header: []byte{'P', 'K', 0x03, 0x04, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x21, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00,
+			'd', 'a', 't', 'a', '.', 'b', 'i', 'n'},
+	},
+}
+
+// weightedFileType is a file type with the weight it should be selected with.
+type weightedFileType struct {
+	fileType
+	weight int
+}
+
+// ParseFileTypes parses a file type distribution,
+// for example 'jpeg:50,pdf:30,zip:20'.
+// The weights are relative and do not need to add up to 100.
+func ParseFileTypes(s string) (map[string]int, error) {
+	res := make(map[string]int)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		split := strings.Split(entry, ":")
+		if len(split) != 2 {
+			return nil, fmt.Errorf("格式应为 '类型:权重', 实际为 %q", entry)
+		}
+		name := strings.ToLower(strings.TrimSpace(split[0]))
+		if _, ok := fileTypes[name]; !ok {
+			valid := make([]string, 0, len(fileTypes))
+			for k := range fileTypes {
+				valid = append(valid, k)
+			}
+			sort.Strings(valid)
+			return nil, fmt.Errorf("未知的文件类型 %q, 有效的类型为 %v", name, valid)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(split[1]))
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("%q 中的权重无效", entry)
+		}
+		res[name] += weight
+	}
+	return res, nil
+}
+
+// WithFileTypes will add file headers of the given types to generated random data.
+// Types are selected using the weights given.
+// An empty map will generate plain random data.
+func WithFileTypes(weights map[string]int) Option {
+	return func(o *Options) error {
+		o.fileTypes = nil
+		names := make([]string, 0, len(weights))
+		total := 0
+		for name, w := range weights {
+			if _, ok := fileTypes[name]; !ok {
+				return fmt.Errorf("WithFileTypes: 未知的文件类型 %q", name)
+			}
+			names = append(names, name)
+			total += w
+		}
+		if len(weights) == 0 {
+			return nil
+		}
+		if total <= 0 {
+			return errors.New("WithFileTypes: 权重的总和必须 > 0")
+		}
+		// Keep the order stable, so seeded generators are predictable.
+		sort.Strings(names)
+		for _, name := range names {
+			if weights[name] > 0 {
+				o.fileTypes = append(o.fileTypes, weightedFileType{fileType: fileTypes[name], weight: weights[name]})
+			}
+		}
+		return nil
+	}
+}
+
+// pickFileType returns a file type selected by weight.
+// Returns nil if no file types have been set.
+func (o Options) pickFileType(rng *rand.Rand) *fileType {
+	if len(o.fileTypes) == 0 {
+		return nil
+	}
+	total := 0
+	for _, ft := range o.fileTypes {
+		total += ft.weight
+	}
+	n := rng.Intn(total)
+	for i := range o.fileTypes {
+		n -= o.fileTypes[i].weight
+		if n < 0 {
+			return &o.fileTypes[i].fileType
+		}
+	}
+	return &o.fileTypes[len(o.fileTypes)-1].fileType
+}
+
+// headerReader returns a header followed by the body.
+type headerReader struct {
+	header []byte
+	body   io.ReadSeeker
+	pos    int64
+}
+
+// newHeaderReader returns a reader returning size bytes, starting with the header.
+// reset must return a reader of the given number of bytes.
+func newHeaderReader(header []byte, size int64, reset func(n int64) io.ReadSeeker) io.ReadSeeker {
+	if int64(len(header)) >= size {
+		return bytes.NewReader(header[:size])
+	}
+	return &headerReader{header: header, body: reset(size - int64(len(header)))}
+}
+
+// Read implements io.Reader.
+func (h *headerReader) Read(p []byte) (n int, err error) {
+	if h.pos < int64(len(h.header)) {
+		n = copy(p, h.header[h.pos:])
+		h.pos += int64(n)
+		return n, nil
+	}
+	n, err = h.body.Read(p)
+	h.pos += int64(n)
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (h *headerReader) Seek(offset int64, whence int) (int64, error) {
+	hl := int64(len(h.header))
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += h.pos
+	case io.SeekEnd:
+		end, err := h.body.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		offset += hl + end
+	default:
+		return 0, errors.New("headerReader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("headerReader.Seek: negative position")
+	}
+	bodyPos := offset - hl
+	if bodyPos < 0 {
+		bodyPos = 0
+	}
+	if _, err := h.body.Seek(bodyPos, io.SeekStart); err != nil {
+		return 0, err
+	}
+	h.pos = offset
+	return offset, nil
+}
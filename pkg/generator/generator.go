@@ -123,6 +123,20 @@ func New(opts ...Option) (Source, error) {
 	if options.src == nil {
 		return nil, errors.New("internal error: generator Source was nil")
 	}
+	if err := options.validateKey(); err != nil {
+		return nil, err
+	}
+	if options.precompute > 0 {
+		payloads, err := options.precomputePayloads()
+		if err != nil {
+			return nil, err
+		}
+		src, err := options.src(options)
+		if err != nil {
+			return nil, err
+		}
+		return newPrecomputed(src, payloads, 0), nil
+	}
 	return options.src(options)
 }
 
@@ -138,12 +152,27 @@ func NewFn(opts ...Option) (func() Source, error) {
 	if options.src == nil {
 		return nil, errors.New("internal error: generator Source was nil")
 	}
+	if err := options.validateKey(); err != nil {
+		return nil, err
+	}
+	var payloads []precomputedPayload
+	if options.precompute > 0 {
+		var err error
+		payloads, err = options.precomputePayloads()
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return func() Source {
 		s, err := options.src(options)
 		if err != nil {
 			panic(err)
 		}
+		if len(payloads) > 0 {
+			// Start each source at a random payload.
+			return newPrecomputed(s, payloads, rand.Intn(len(payloads)))
+		}
 		return s
 	}, nil
 }
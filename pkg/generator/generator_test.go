@@ -21,6 +21,7 @@ import (
 	"io"
 	"io/ioutil"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestNew(t *testing.T) {
@@ -28,10 +29,11 @@ func TestNew(t *testing.T) {
 		opts []Option
 	}
 	tests := []struct {
-		name     string
-		args     args
-		wantErr  bool
-		wantSize int
+		name       string
+		args       args
+		wantErr    bool
+		wantSize   int
+		wantKeyLen int
 	}{
 		{
 			name: "Default",
@@ -49,6 +51,46 @@ func TestNew(t *testing.T) {
 			wantErr:  false,
 			wantSize: 1 << 20,
 		},
+		{
+			name: "FileTypes",
+			args: args{
+				opts: []Option{WithFileTypes(map[string]int{"jpeg": 1, "pdf": 1, "zip": 1})},
+			},
+			wantErr:  false,
+			wantSize: 1 << 20,
+		},
+		{
+			name: "KeyLength",
+			args: args{
+				opts: []Option{WithPrefixSize(8), WithKeyLength(64), WithKeyCharset("abcæøå")},
+			},
+			wantErr:    false,
+			wantSize:   1 << 20,
+			wantKeyLen: 64,
+		},
+		{
+			name: "KeyDepth",
+			args: args{
+				opts: []Option{WithPrefixSize(8), WithKeyLength(64), WithKeyDepth(4)},
+			},
+			wantErr:    false,
+			wantSize:   1 << 20,
+			wantKeyLen: 64,
+		},
+		{
+			name: "KeyDepthTooDeep",
+			args: args{
+				opts: []Option{WithKeyDepth(400)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "KeyTooLong",
+			args: args{
+				opts: []Option{WithKeyLength(1000), WithKeyCharset("æøå")},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -57,6 +99,9 @@ func TestNew(t *testing.T) {
 				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErr {
+				return
+			}
 			if err != nil {
 				t.Error(err)
 				return
@@ -66,6 +111,10 @@ func TestNew(t *testing.T) {
 				return
 			}
 			obj := got.Object()
+			if tt.wantKeyLen > 0 && utf8.RuneCountInString(obj.Name) != tt.wantKeyLen {
+				t.Errorf("New() key length = %v, wantKeyLen = %v", utf8.RuneCountInString(obj.Name), tt.wantKeyLen)
+				return
+			}
 			b, err := ioutil.ReadAll(obj.Reader)
 			if err != nil {
 				t.Error(err)
@@ -19,9 +19,17 @@ package generator
 
 import (
 	"errors"
+	"fmt"
 	"math/rand"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
 )
 
+// maxKeyLength is the maximum length of an S3 object key in bytes.
+const maxKeyLength = 1024
+
 // Options provides options.
 // Use WithXXX functions to set them.
 type Options struct {
@@ -31,6 +39,17 @@ type Options struct {
 	csv          CsvOpts
 	random       RandomOpts
 	randomPrefix int
+	keyLength    int
+	keyCharset   []rune
+	keyDepth     int
+	fileTypes    []weightedFileType
+	precompute   int
+
+	// sizes are exact object sizes to use in turn.
+	sizes        []int64
+	shuffleSizes bool
+	// sizeIdx is the next index in sizes, shared by all sources.
+	sizeIdx *uint64
 }
 
 // OptionApplier allows to abstract generator options.
@@ -40,6 +59,13 @@ type OptionApplier interface {
 
 // getSize will return a size for an object.
 func (o Options) getSize(rng *rand.Rand) int64 {
+	if len(o.sizes) > 0 {
+		if o.shuffleSizes {
+			return o.sizes[rng.Intn(len(o.sizes))]
+		}
+		idx := atomic.AddUint64(o.sizeIdx, 1) - 1
+		return o.sizes[idx%uint64(len(o.sizes))]
+	}
 	if !o.randSize {
 		return o.totalSize
 	}
@@ -72,6 +98,33 @@ func WithSize(n int64) Option {
 	}
 }
 
+// WithSizes will use the exact sizes for objects.
+// The sizes are used in turn, or picked randomly if shuffle is set.
+// When creating several sources with NewFn the sources will share the order.
+// This overrides the size and random size options.
+func WithSizes(sizes []int64, shuffle bool) Option {
+	return func(o *Options) error {
+		for _, sz := range sizes {
+			if sz <= 0 {
+				return errors.New("WithSizes: 大小必须 > 0")
+			}
+		}
+		o.sizes = sizes
+		o.shuffleSizes = shuffle
+		o.sizeIdx = new(uint64)
+		if len(sizes) > 0 {
+			// Random data is generated up to the biggest size.
+			o.totalSize = sizes[0]
+			for _, sz := range sizes {
+				if sz > o.totalSize {
+					o.totalSize = sz
+				}
+			}
+		}
+		return nil
+	}
+}
+
 // WithRandomSize will randomize the size from 1 byte to the total size set.
 func WithRandomSize(b bool) Option {
 	return func(o *Options) error {
@@ -96,3 +149,152 @@ func WithPrefixSize(n int) Option {
 		return nil
 	}
 }
+
+// WithKeyLength sets the length of generated object keys in characters,
+// including the prefix and separator.
+// 0 will use the default naming.
+func WithKeyLength(n int) Option {
+	return func(o *Options) error {
+		if n < 0 || n > maxKeyLength {
+			return fmt.Errorf("WithKeyLength: 长度必须 >= 0 和 <= %d", maxKeyLength)
+		}
+		o.keyLength = n
+		return nil
+	}
+}
+
+// keyDirFanout is the number of directory names used at each level of nested keys.
+const keyDirFanout = 10
+
+// keyDirLen is the length of a directory name in nested keys including the separator.
+const keyDirLen = len("d0/")
+
+// maxDefaultNameLen is the maximum length of object names with the default naming.
+const maxDefaultNameLen = 64
+
+// WithKeyDepth sets the number of path segments of generated object keys after the prefix.
+// With n > 1, n-1 directory levels are added before the object name, like 'd3/d0/d7/name'.
+// At each level one of 10 directory names are used.
+// 0 and 1 will not add any directories.
+func WithKeyDepth(n int) Option {
+	return func(o *Options) error {
+		if n < 0 {
+			return errors.New("WithKeyDepth: 深度必须 >= 0")
+		}
+		o.keyDepth = n
+		return nil
+	}
+}
+
+// keyDirs returns the number of directory levels to add to keys.
+func (o Options) keyDirs() int {
+	if o.keyDepth <= 1 {
+		return 0
+	}
+	return o.keyDepth - 1
+}
+
+// WithKeyCharset sets the characters used for generated object keys.
+// An empty string will use the default characters.
+func WithKeyCharset(s string) Option {
+	return func(o *Options) error {
+		if s == "" {
+			o.keyCharset = nil
+			return nil
+		}
+		if !utf8.ValidString(s) {
+			return errors.New("WithKeyCharset: 字符集必须是有效的 UTF-8")
+		}
+		seen := make(map[rune]struct{}, len(s))
+		charset := make([]rune, 0, len(s))
+		for _, r := range s {
+			if r == '/' || unicode.IsControl(r) {
+				return fmt.Errorf("WithKeyCharset: 字符集不能包含 %q", r)
+			}
+			if _, ok := seen[r]; ok {
+				continue
+			}
+			seen[r] = struct{}{}
+			charset = append(charset, r)
+		}
+		o.keyCharset = charset
+		return nil
+	}
+}
+
+// keyNameLen returns the number of characters to generate for object names
+// excluding the prefix.
+func (o Options) keyNameLen() int {
+	if o.keyLength == 0 {
+		return 16
+	}
+	n := o.keyLength
+	if o.randomPrefix > 0 {
+		n -= o.randomPrefix + 1
+	}
+	return n - o.keyDirs()*keyDirLen
+}
+
+// validateKey checks that the generated keys will fit S3 key constraints.
+func (o Options) validateKey() error {
+	prefix := 0
+	if o.randomPrefix > 0 {
+		prefix = o.randomPrefix + 1
+	}
+	dirs := o.keyDirs() * keyDirLen
+	if o.keyLength == 0 && o.keyCharset == nil {
+		if prefix+dirs+maxDefaultNameLen > maxKeyLength {
+			return fmt.Errorf("keys may be up to %d bytes with key depth %d, max is %d", prefix+dirs+maxDefaultNameLen, o.keyDepth, maxKeyLength)
+		}
+		return nil
+	}
+	n := o.keyNameLen()
+	if n < 1 {
+		return fmt.Errorf("key length %d is too short for prefix size %d and key depth %d", o.keyLength, o.randomPrefix, o.keyDepth)
+	}
+	maxRune := 1
+	for _, r := range o.keyCharset {
+		if l := utf8.RuneLen(r); l > maxRune {
+			maxRune = l
+		}
+	}
+	if prefix+dirs+n*maxRune > maxKeyLength {
+		return fmt.Errorf("keys may be up to %d bytes, max is %d", prefix+dirs+n*maxRune, maxKeyLength)
+	}
+	return nil
+}
+
+// objectName returns def, unless key length or charset has been set,
+// in which case a random name is generated from the charset.
+// Directories are added before the name if key depth is set.
+func (o Options) objectName(rng *rand.Rand, def string) string {
+	name := def
+	if o.keyLength != 0 || o.keyCharset != nil {
+		n := o.keyNameLen()
+		if o.keyCharset == nil {
+			b := make([]byte, n)
+			randASCIIBytes(b, rng)
+			name = string(b)
+		} else {
+			var sb strings.Builder
+			sb.Grow(n)
+			for i := 0; i < n; i++ {
+				sb.WriteRune(o.keyCharset[rng.Intn(len(o.keyCharset))])
+			}
+			name = sb.String()
+		}
+	}
+	dirs := o.keyDirs()
+	if dirs == 0 {
+		return name
+	}
+	var sb strings.Builder
+	sb.Grow(dirs*keyDirLen + len(name))
+	for i := 0; i < dirs; i++ {
+		sb.WriteByte('d')
+		sb.WriteByte(byte('0' + rng.Intn(keyDirFanout)))
+		sb.WriteByte('/')
+	}
+	sb.WriteString(name)
+	return sb.String()
+}
@@ -0,0 +1,92 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// WithPrecompute will generate the payloads of n objects when the data source is created.
+// The payloads are then returned in turn, so no data is generated while benchmarking.
+// Names are still generated for each object.
+// Sources created by a function returned by NewFn share the payloads.
+func WithPrecompute(n int) Option {
+	return func(o *Options) error {
+		if n < 0 {
+			return errors.New("WithPrecompute: 对象数必须 >= 0")
+		}
+		o.precompute = n
+		return nil
+	}
+}
+
+// precomputedPayload is the data of a precomputed object.
+type precomputedPayload struct {
+	data        []byte
+	contentType string
+}
+
+// precompute generates the payloads of the objects to precompute.
+func (o Options) precomputePayloads() ([]precomputedPayload, error) {
+	if len(o.fileTypes) > 0 {
+		return nil, errors.New("precompute: cannot be used with file types")
+	}
+	src, err := o.src(o)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]precomputedPayload, o.precompute)
+	for i := range res {
+		obj := src.Object()
+		data := make([]byte, obj.Size)
+		if _, err := io.ReadFull(obj.Reader, data); err != nil {
+			return nil, fmt.Errorf("precompute: %w", err)
+		}
+		res[i] = precomputedPayload{data: data, contentType: obj.ContentType}
+	}
+	return res, nil
+}
+
+// precomputedSrc returns precomputed payloads with names from the wrapped source.
+type precomputedSrc struct {
+	Source
+	payloads []precomputedPayload
+	next     int
+	obj      Object
+}
+
+func newPrecomputed(src Source, payloads []precomputedPayload, start int) *precomputedSrc {
+	return &precomputedSrc{Source: src, payloads: payloads, next: start % len(payloads)}
+}
+
+func (p *precomputedSrc) Object() *Object {
+	p.obj = *p.Source.Object()
+	payload := p.payloads[p.next]
+	p.next = (p.next + 1) % len(p.payloads)
+	p.obj.Size = int64(len(payload.data))
+	p.obj.ContentType = payload.contentType
+	p.obj.Reader = bytes.NewReader(payload.data)
+	return &p.obj
+}
+
+func (p *precomputedSrc) String() string {
+	return fmt.Sprintf("%s; %d precomputed objects", p.Source.String(), len(p.payloads))
+}
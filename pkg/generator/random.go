@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
+	"strings"
 	"sync/atomic"
 )
 
@@ -124,18 +125,36 @@ func (r *randomSrc) Object() *Object {
 	var nBuf [16]byte
 	randASCIIBytes(nBuf[:], r.rng)
 	r.obj.Size = r.o.getSize(r.rng)
-	r.obj.setName(fmt.Sprintf("%d.%s.rnd", atomic.LoadUint64(&r.counter), string(nBuf[:])))
-
-	// Reset scrambler
-	r.obj.Reader = r.buf.Reset(r.obj.Size)
+	ft := r.o.pickFileType(r.rng)
+	if ft == nil {
+		r.obj.setName(r.o.objectName(r.rng, fmt.Sprintf("%d.%s.rnd", atomic.LoadUint64(&r.counter), string(nBuf[:]))))
+		r.obj.ContentType = "application/octet-stream"
+		// Reset scrambler
+		r.obj.Reader = r.buf.Reset(r.obj.Size)
+		return &r.obj
+	}
+	r.obj.setName(r.o.objectName(r.rng, fmt.Sprintf("%d.%s%s", atomic.LoadUint64(&r.counter), string(nBuf[:]), ft.ext)))
+	r.obj.ContentType = ft.contentType
+	r.obj.Reader = newHeaderReader(ft.header, r.obj.Size, r.buf.Reset)
 	return &r.obj
 }
 
 func (r *randomSrc) String() string {
+	var types string
+	if len(r.o.fileTypes) > 0 {
+		names := make([]string, len(r.o.fileTypes))
+		for i, ft := range r.o.fileTypes {
+			names[i] = ft.name
+		}
+		types = " with " + strings.Join(names, ", ") + " headers"
+	}
+	if len(r.o.sizes) > 0 {
+		return fmt.Sprintf("Random data%s; %d sizes up to %d bytes", types, len(r.o.sizes), r.o.totalSize)
+	}
 	if r.o.randSize {
-		return fmt.Sprintf("Random data; random size up to %d bytes", r.o.totalSize)
+		return fmt.Sprintf("Random data%s; random size up to %d bytes", types, r.o.totalSize)
 	}
-	return fmt.Sprintf("Random data; %d bytes total", r.buf.want)
+	return fmt.Sprintf("Random data%s; %d bytes total", types, r.o.totalSize)
 }
 
 func (r *randomSrc) Prefix() string {